@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	cmd2 "github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/database"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var apiKeyNameFlag string
+var apiKeyUserFlag string
+
+var APIKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Gère les clés d'accès à l'API de gestion (/api/v1).",
+	Long: `Les requêtes vers /api/v1 doivent présenter une clé d'API, dans l'en-tête
+"Authorization: Bearer <clé>" ou "X-API-Key: <clé>". La redirection des liens courts
+reste publique.
+
+Une clé créée avec --user n'accède qu'aux liens de cet utilisateur ; sans --user,
+c'est une clé d'administration qui accède à tous les liens.
+
+Seule l'empreinte des clés est enregistrée : une clé n'est affichée qu'à sa création.`,
+}
+
+var APIKeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Crée une clé d'API et l'affiche une seule fois.",
+	Long: `Exemple:
+  url-shortener apikey create --name="intégration CI"
+  url-shortener apikey create --name="script d'Alice" --user="alice@example.com"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, closeDB := openDatabase()
+		defer closeDB()
+		apiKeyService := services.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+
+		var userID *uint
+		if apiKeyUserFlag != "" {
+			userService := services.NewUserService(repository.NewUserRepository(db), repository.NewSessionRepository(db), services.UserServiceOptions{})
+			user, err := userService.GetUserByEmail(apiKeyUserFlag)
+			if err != nil {
+				fmt.Printf("Erreur: Utilisateur '%s' introuvable: %v\n", apiKeyUserFlag, err)
+				os.Exit(1)
+			}
+			userID = &user.ID
+		}
+
+		apiKey, key, err := apiKeyService.CreateAPIKey(apiKeyNameFlag, userID)
+		if err != nil {
+			fmt.Printf("Erreur: Impossible de créer la clé d'API: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Clé d'API créée avec succès:")
+		fmt.Printf("Préfixe: %s\n", apiKey.Prefix)
+		fmt.Printf("Clé: %s\n", key)
+		fmt.Println("Conservez cette clé: elle ne pourra plus être affichée.")
+	},
+}
+
+var APIKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Liste les clés d'API, révoquées comprises.",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiKeyService, closeDB := openAPIKeyService()
+		defer closeDB()
+
+		keys, err := apiKeyService.ListAPIKeys()
+		if err != nil {
+			log.Fatalf("FATAL: Échec de la lecture des clés d'API: %v", err)
+		}
+		if len(keys) == 0 {
+			fmt.Println("Aucune clé d'API. Créez-en une avec 'apikey create'.")
+			return
+		}
+
+		const dateFormat = "2006-01-02 15:04:05"
+		for _, key := range keys {
+			lastUsed := "jamais utilisée"
+			if key.LastUsedAt != nil {
+				lastUsed = "utilisée le " + key.LastUsedAt.Local().Format(dateFormat)
+			}
+			state := "active"
+			if key.IsRevoked() {
+				state = "révoquée le " + key.RevokedAt.Local().Format(dateFormat)
+			}
+			scope := "administration"
+			if key.UserID != nil {
+				scope = fmt.Sprintf("utilisateur %d", *key.UserID)
+			}
+			fmt.Printf("  %-14s  %-20s  %-16s  créée le %s  %-32s  %s\n",
+				key.Prefix, key.Name, scope, key.CreatedAt.Local().Format(dateFormat), lastUsed, state)
+		}
+	},
+}
+
+var APIKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke PREFIX",
+	Short: "Révoque une clé d'API à partir de son préfixe (ex: usk_1a2b3c4d).",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		apiKeyService, closeDB := openAPIKeyService()
+		defer closeDB()
+
+		apiKey, err := apiKeyService.RevokeAPIKey(args[0])
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrAPIKeyNotFound):
+				fmt.Printf("Erreur: Aucune clé d'API avec le préfixe '%s'\n", args[0])
+			case errors.Is(err, services.ErrAPIKeyRevoked):
+				fmt.Printf("Erreur: La clé d'API '%s' est déjà révoquée\n", args[0])
+			default:
+				fmt.Printf("Erreur lors de la révocation de la clé d'API: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("Clé d'API %s révoquée.\n", apiKey.Prefix)
+	},
+}
+
+// openAPIKeyService ouvre la base de données configurée. La fonction retournée ferme la connexion.
+func openAPIKeyService() (*services.APIKeyService, func()) {
+	db, closeDB := openDatabase()
+	return services.NewAPIKeyService(repository.NewAPIKeyRepository(db)), closeDB
+}
+
+// openDatabase ouvre la base de données configurée. La fonction retournée ferme la connexion.
+func openDatabase() (*gorm.DB, func()) {
+	cfg := cmd2.Cfg
+	if cfg == nil {
+		fmt.Println("Erreur: Configuration non chargée.")
+		os.Exit(1)
+	}
+
+	db, err := database.Open(database.OptionsFromConfig(cfg))
+	if err != nil {
+		log.Fatalf("FATAL: Impossible de se connecter à la base de données: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+	}
+
+	return db, func() {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Warning: Failed to close database connection: %v", err)
+		}
+	}
+}
+
+func init() {
+	APIKeyCreateCmd.Flags().StringVar(&apiKeyNameFlag, "name", "", "Nom permettant d'identifier la clé (ex: l'application qui l'utilise)")
+	APIKeyCreateCmd.Flags().StringVar(&apiKeyUserFlag, "user", "", "Adresse e-mail de l'utilisateur dont la clé donne accès aux liens (optionnel)")
+	APIKeyCmd.AddCommand(APIKeyCreateCmd, APIKeyListCmd, APIKeyRevokeCmd)
+	cmd2.RootCmd.AddCommand(APIKeyCmd)
+}
@@ -1,28 +1,47 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
 	"os"
+	"time"
 
 	cmd2 "github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/database"
 	"github.com/Edofo/bitly-clone/internal/repository"
 	"github.com/Edofo/bitly-clone/internal/services"
 	"github.com/spf13/cobra"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
 var longURLFlag string
+var aliasFlag string
+var expiresAtFlag string
+var expiresInFlag time.Duration
+var maxClicksFlag int
+var webhookURLFlag string
+var ownerFlag string
+var workspaceFlag uint
 
 var CreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Crée une URL courte à partir d'une URL longue.",
 	Long: `Cette commande raccourcit une URL longue fournie et affiche le code court généré.
 
+Un alias personnalisé peut être demandé avec --alias (3 à 32 caractères : lettres, chiffres, '-' et '_').
+La durée de vie du lien peut être limitée par une date (--expires-at, format RFC3339),
+une durée (--expires-in) ou un nombre maximal de clics (--max-clicks).
+--webhook-url désigne un webhook prévenu lorsque l'URL longue devient (in)accessible.
+--owner rattache le lien à un utilisateur (adresse e-mail) ; sans --owner, seules les
+clés d'administration y ont accès par l'API. --workspace crée le lien dans un espace de
+travail, où l'utilisateur de --owner doit être éditeur ou propriétaire.
+
 Exemple:
-  url-shortener create --url="https://www.google.com/search?q=go+lang"`,
+  url-shortener create --url="https://www.google.com/search?q=go+lang"
+  url-shortener create --url="https://www.example.com/promo" --alias="spring-sale"
+  url-shortener create --url="https://www.example.com/promo" --expires-in=72h --max-clicks=500
+  url-shortener create --url="https://www.example.com/promo" --workspace=3 --owner="alice@example.com"`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if longURLFlag == "" {
 			fmt.Println("Erreur: Le flag --url est requis.")
@@ -35,13 +54,46 @@ Exemple:
 			os.Exit(1)
 		}
 
+		if aliasFlag != "" {
+			if err := services.ValidateAlias(aliasFlag); err != nil {
+				fmt.Printf("Erreur: Alias invalide '%s': %v\n", aliasFlag, err)
+				os.Exit(1)
+			}
+		}
+
+		if webhookURLFlag != "" {
+			if err := services.ValidateWebhookURL(webhookURLFlag); err != nil {
+				fmt.Printf("Erreur: Webhook invalide '%s': %v\n", webhookURLFlag, err)
+				os.Exit(1)
+			}
+		}
+
+		if expiresAtFlag != "" && expiresInFlag != 0 {
+			fmt.Println("Erreur: Les flags --expires-at et --expires-in sont incompatibles.")
+			os.Exit(1)
+		}
+
+		var expiresAt *time.Time
+		if expiresAtFlag != "" {
+			t, err := time.Parse(time.RFC3339, expiresAtFlag)
+			if err != nil {
+				fmt.Printf("Erreur: Date d'expiration invalide '%s' (format attendu: RFC3339): %v\n", expiresAtFlag, err)
+				os.Exit(1)
+			}
+			expiresAt = &t
+		}
+		if expiresInFlag != 0 {
+			t := time.Now().Add(expiresInFlag)
+			expiresAt = &t
+		}
+
 		cfg := cmd2.Cfg
 		if cfg == nil {
 			fmt.Println("Erreur: Configuration non chargée.")
 			os.Exit(1)
 		}
 
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		db, err := database.Open(database.OptionsFromConfig(cfg))
 		if err != nil {
 			log.Fatalf("FATAL: Impossible de se connecter à la base de données: %v", err)
 		}
@@ -57,11 +109,58 @@ Exemple:
 			}
 		}()
 
+		owner := services.AdminOwner
+		if ownerFlag != "" {
+			userService := services.NewUserService(repository.NewUserRepository(db), repository.NewSessionRepository(db), services.UserServiceOptions{})
+			user, err := userService.GetUserByEmail(ownerFlag)
+			if err != nil {
+				fmt.Printf("Erreur: Utilisateur '%s' introuvable: %v\n", ownerFlag, err)
+				os.Exit(1)
+			}
+			owner = services.OwnedBy(user.ID)
+		}
+
+		var workspaceID *uint
+		if workspaceFlag != 0 {
+			workspaceService := services.NewWorkspaceService(repository.NewWorkspaceRepository(db), repository.NewInvitationRepository(db), repository.NewUserRepository(db))
+			if _, err := workspaceService.GetWorkspace(services.AdminOwner, workspaceFlag); err != nil {
+				fmt.Printf("Erreur: Espace de travail %d introuvable: %v\n", workspaceFlag, err)
+				os.Exit(1)
+			}
+			if !owner.Admin {
+				roles, err := workspaceService.GetRoles(owner.UserID)
+				if err != nil {
+					log.Fatalf("FATAL: Échec de la lecture des rôles de l'utilisateur: %v", err)
+				}
+				owner = owner.WithRoles(roles)
+			}
+			workspaceID = &workspaceFlag
+		}
+
 		linkRepo := repository.NewLinkRepository(db)
 		linkService := services.NewLinkService(linkRepo)
-
-		link, err := linkService.CreateLink(longURLFlag)
+		linkService.SetUsageService(newUsageService(db))
+
+		link, err := linkService.CreateLink(owner, longURLFlag, services.CreateLinkOptions{
+			CustomAlias: aliasFlag,
+			ExpiresAt:   expiresAt,
+			MaxClicks:   maxClicksFlag,
+			WebhookURL:  webhookURLFlag,
+			WorkspaceID: workspaceID,
+		})
 		if err != nil {
+			if errors.Is(err, services.ErrAliasAlreadyExists) {
+				fmt.Printf("Erreur: L'alias '%s' est déjà utilisé.\n", aliasFlag)
+				os.Exit(1)
+			}
+			if errors.Is(err, services.ErrWorkspaceNotFound) || errors.Is(err, services.ErrInsufficientRole) {
+				fmt.Printf("Erreur: '%s' ne peut pas créer de lien dans l'espace de travail %d: %v\n", ownerFlag, workspaceFlag, err)
+				os.Exit(1)
+			}
+			if errors.Is(err, services.ErrLinkQuotaExceeded) {
+				fmt.Printf("Erreur: Quota mensuel de liens atteint: %v\n", err)
+				os.Exit(1)
+			}
 			fmt.Printf("Erreur lors de la création du lien court: %v\n", err)
 			os.Exit(1)
 		}
@@ -70,11 +169,27 @@ Exemple:
 		fmt.Printf("URL courte créée avec succès:\n")
 		fmt.Printf("Code: %s\n", link.ShortCode)
 		fmt.Printf("URL complète: %s\n", fullShortURL)
+		if link.ExpiresAt != nil {
+			fmt.Printf("Expire le: %s\n", link.ExpiresAt.Format(time.RFC3339))
+		}
+		if link.HasClickBudget() {
+			fmt.Printf("Nombre maximal de clics: %d\n", link.MaxClicks)
+		}
+		if link.WebhookSecret != "" {
+			fmt.Printf("Secret du webhook (affiché une seule fois): %s\n", link.WebhookSecret)
+		}
 	},
 }
 
 func init() {
 	CreateCmd.Flags().StringVar(&longURLFlag, "url", "", "URL longue à raccourcir")
+	CreateCmd.Flags().StringVar(&aliasFlag, "alias", "", "Alias personnalisé à utiliser comme code court (optionnel)")
+	CreateCmd.Flags().StringVar(&expiresAtFlag, "expires-at", "", "Date d'expiration du lien au format RFC3339 (optionnel)")
+	CreateCmd.Flags().DurationVar(&expiresInFlag, "expires-in", 0, "Durée de vie du lien, ex: 24h (optionnel)")
+	CreateCmd.Flags().IntVar(&maxClicksFlag, "max-clicks", 0, "Nombre maximal de clics avant expiration, 0 = illimité (optionnel)")
+	CreateCmd.Flags().StringVar(&webhookURLFlag, "webhook-url", "", "Webhook propre au lien, prévenu de ses changements d'état (optionnel)")
+	CreateCmd.Flags().StringVar(&ownerFlag, "owner", "", "Adresse e-mail de l'utilisateur propriétaire du lien (optionnel)")
+	CreateCmd.Flags().UintVar(&workspaceFlag, "workspace", 0, "Identifiant de l'espace de travail du lien (optionnel)")
 
 	if err := CreateCmd.MarkFlagRequired("url"); err != nil {
 		log.Fatalf("Failed to mark url flag as required: %v", err)
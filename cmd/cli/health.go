@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	cmd2 "github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/database"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/spf13/cobra"
+
+	"gorm.io/gorm"
+)
+
+var healthCodeFlag string
+var healthWindowFlag string
+var healthLimitFlag int
+
+var HealthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Affiche l'état de santé de l'URL longue d'un lien court relevé par le moniteur.",
+	Long: `Cette commande affiche le dernier état connu de l'URL longue d'un lien,
+sa disponibilité sur une période (--window, ex: 24h ou 7d) et ses dernières vérifications.
+
+Exemple:
+  url-shortener health --code="xyz123"
+  url-shortener health --code="xyz123" --window=7d --limit=50`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if healthCodeFlag == "" {
+			fmt.Println("Erreur: Le flag --code est requis.")
+			os.Exit(1)
+		}
+
+		window, err := services.ParseWindow(healthWindowFlag)
+		if err != nil {
+			fmt.Printf("Erreur: période invalide '%s' (ex: 24h, 90m ou 7d)\n", healthWindowFlag)
+			os.Exit(1)
+		}
+
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			fmt.Println("Erreur: Configuration non chargée.")
+			os.Exit(1)
+		}
+
+		db, err := database.Open(database.OptionsFromConfig(cfg))
+		if err != nil {
+			log.Fatalf("FATAL: Impossible de se connecter à la base de données: %v", err)
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+		}
+
+		defer func() {
+			if err := sqlDB.Close(); err != nil {
+				log.Printf("Warning: Failed to close database connection: %v", err)
+			}
+		}()
+
+		linkService := services.NewLinkService(repository.NewLinkRepository(db))
+		healthService := services.NewHealthService(repository.NewHealthCheckRepository(db), time.Duration(cfg.Monitor.HistoryRetentionDays)*24*time.Hour)
+
+		link, err := linkService.GetLinkByShortCode(healthCodeFlag)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				fmt.Printf("Erreur: Aucun lien trouvé avec le code '%s'\n", healthCodeFlag)
+			} else {
+				fmt.Printf("Erreur lors de la récupération du lien: %v\n", err)
+			}
+			os.Exit(1)
+		}
+
+		health, err := healthService.GetLinkHealth(link.ID, services.HealthQuery{Window: window, Limit: healthLimitFlag})
+		if err != nil {
+			fmt.Printf("Erreur lors de la récupération de l'état de santé: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("État de santé pour le code court: %s\n", link.ShortCode)
+		fmt.Printf("URL longue: %s\n", link.LongURL)
+		if health.LastCheckedAt == nil {
+			fmt.Println("État actuel: inconnu (aucune vérification enregistrée)")
+			return
+		}
+		fmt.Printf("État actuel: %s (vérifié le %s)\n", strings.ToUpper(health.Status), health.LastCheckedAt.Local().Format("2006-01-02 15:04:05"))
+		if health.UptimePercent != nil {
+			fmt.Printf("Disponibilité sur %s: %.2f%% (%d vérifications)\n", services.FormatWindow(health.Window), *health.UptimePercent, health.Checks)
+		} else {
+			fmt.Printf("Disponibilité sur %s: aucune vérification sur la période\n", services.FormatWindow(health.Window))
+		}
+
+		fmt.Println("\nDernières vérifications:")
+		for _, check := range health.History {
+			status := "-"
+			if check.StatusCode != 0 {
+				status = fmt.Sprintf("%d", check.StatusCode)
+			}
+			fmt.Printf("  %s  %-12s  %-4s  %-4s  %6d ms  %s\n", check.CheckedAt.Local().Format("2006-01-02 15:04:05"),
+				formatHealthState(check), check.Method, status, check.LatencyMs, check.ErrorClass)
+			for _, hop := range check.RedirectChain {
+				fmt.Printf("      -> %s\n", hop)
+			}
+		}
+	},
+}
+
+func formatHealthState(check models.HealthCheck) string {
+	if check.Accessible {
+		return "ACCESSIBLE"
+	}
+	return "INACCESSIBLE"
+}
+
+func init() {
+	HealthCmd.Flags().StringVar(&healthCodeFlag, "code", "", "Code court dont afficher l'état de santé")
+	HealthCmd.Flags().StringVar(&healthWindowFlag, "window", "24h", "Période de calcul de la disponibilité (ex: 24h, 7d)")
+	HealthCmd.Flags().IntVar(&healthLimitFlag, "limit", 10, "Nombre de vérifications récentes affichées")
+
+	if err := HealthCmd.MarkFlagRequired("code"); err != nil {
+		log.Fatalf("Failed to mark code flag as required: %v", err)
+	}
+
+	cmd2.RootCmd.AddCommand(HealthCmd)
+}
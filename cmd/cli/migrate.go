@@ -3,52 +3,175 @@ package cli
 import (
 	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	cmd2 "github.com/Edofo/bitly-clone/cmd"
-	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/database"
+	"github.com/Edofo/bitly-clone/internal/migrations"
 	"github.com/spf13/cobra"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
+var migrationsDirFlag string
+
 var MigrateCmd = &cobra.Command{
 	Use:   "migrate",
-	Short: "Exécute les migrations de la base de données pour créer ou mettre à jour les tables.",
-	Long: `Cette commande se connecte à la base de données configurée (SQLite)
-et exécute les migrations automatiques de GORM pour créer les tables 'links' et 'clicks'
-basées sur les modèles Go.`,
+	Short: "Gère les migrations versionnées de la base de données (par défaut: migrate up).",
+	Long: `Cette commande se connecte à la base de données configurée (SQLite ou PostgreSQL)
+et applique les migrations SQL numérotées intégrées au binaire. Les versions appliquées
+sont enregistrées dans la table 'schema_migrations'.
+
+Une base créée par une version précédente (AutoMigrate de GORM) est détectée et marquée
+à la version de référence avant l'application des migrations suivantes.
+
+Sans sous-commande, 'migrate' équivaut à 'migrate up'.`,
+	Run: runMigrateUp,
+}
+
+var MigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Applique toutes les migrations en attente.",
+	Run:   runMigrateUp,
+}
+
+var MigrateDownCmd = &cobra.Command{
+	Use:   "down N",
+	Short: "Annule les N dernières migrations appliquées.",
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg := cmd2.Cfg
-		if cfg == nil {
-			fmt.Println("Erreur: Configuration non chargée.")
-			return
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			fmt.Printf("Erreur: Nombre de migrations invalide '%s' (entier positif attendu).\n", args[0])
+			os.Exit(1)
 		}
 
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		migrator, closeDB := openMigrator()
+		defer closeDB()
+
+		rolledBack, err := migrator.Down(n)
+		for _, migration := range rolledBack {
+			fmt.Printf("Migration annulée: %s\n", migration)
+		}
 		if err != nil {
-			log.Fatalf("FATAL: Impossible de se connecter à la base de données: %v", err)
+			log.Fatalf("FATAL: Échec de l'annulation: %v", err)
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("Aucune migration à annuler.")
+		}
+	},
+}
+
+var MigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Affiche les migrations appliquées et en attente.",
+	Run: func(cmd *cobra.Command, args []string) {
+		migrator, closeDB := openMigrator()
+		defer closeDB()
+
+		if migrator.NeedsBaseline() {
+			fmt.Printf("Base créée par AutoMigrate: elle sera marquée à la version %04d au prochain 'migrate up'.\n\n", migrations.BaselineVersion)
 		}
 
-		sqlDB, err := db.DB()
+		statuses, err := migrator.Status()
 		if err != nil {
-			log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+			log.Fatalf("FATAL: Échec de la lecture des migrations: %v", err)
 		}
 
-		defer func() {
-			if err := sqlDB.Close(); err != nil {
-				log.Printf("Warning: Failed to close database connection: %v", err)
+		pending := 0
+		for _, status := range statuses {
+			state := "en attente"
+			switch {
+			case status.Unknown:
+				state = fmt.Sprintf("appliquée le %s, inconnue de ce binaire", status.AppliedAt.Local().Format("2006-01-02 15:04:05"))
+			case status.AppliedAt != nil:
+				state = fmt.Sprintf("appliquée le %s", status.AppliedAt.Local().Format("2006-01-02 15:04:05"))
+			default:
+				pending++
 			}
-		}()
+			fmt.Printf("  %04d  %-30s  %s\n", status.Version, status.Name, state)
+		}
+		fmt.Printf("\n%d migration(s) en attente.\n", pending)
+	},
+}
 
-		err = db.AutoMigrate(&models.Link{}, &models.Click{})
+var MigrateCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Crée les fichiers SQL d'une nouvelle migration pour chaque moteur.",
+	Long: `Cette commande crée les fichiers up et down de la migration suivante pour SQLite
+et PostgreSQL dans les sources du projet. Les migrations étant intégrées au binaire,
+il faut le recompiler après les avoir complétées.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := migrations.Create(migrationsDirFlag, args[0])
 		if err != nil {
-			log.Fatalf("FATAL: Échec de la migration: %v", err)
+			fmt.Printf("Erreur: Impossible de créer la migration: %v\n", err)
+			os.Exit(1)
+		}
+		for _, file := range files {
+			fmt.Printf("Fichier créé: %s\n", file)
 		}
-
-		fmt.Println("Migrations de la base de données exécutées avec succès.")
 	},
 }
 
+func runMigrateUp(cmd *cobra.Command, args []string) {
+	migrator, closeDB := openMigrator()
+	defer closeDB()
+
+	baselined, err := migrator.Baseline()
+	if err != nil {
+		log.Fatalf("FATAL: Échec du marquage de la base existante: %v", err)
+	}
+	if baselined {
+		fmt.Printf("Base existante créée par AutoMigrate détectée: marquée à la version %04d.\n", migrations.BaselineVersion)
+	}
+
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		fmt.Printf("Migration appliquée: %s\n", migration)
+	}
+	if err != nil {
+		log.Fatalf("FATAL: Échec de la migration: %v", err)
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("La base de données est déjà à jour.")
+		return
+	}
+	fmt.Println("Migrations de la base de données exécutées avec succès.")
+}
+
+// openMigrator ouvre la base de données configurée. La fonction retournée ferme la connexion.
+func openMigrator() (*migrations.Migrator, func()) {
+	cfg := cmd2.Cfg
+	if cfg == nil {
+		fmt.Println("Erreur: Configuration non chargée.")
+		os.Exit(1)
+	}
+
+	db, err := database.Open(database.OptionsFromConfig(cfg))
+	if err != nil {
+		log.Fatalf("FATAL: Impossible de se connecter à la base de données: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+	}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatalf("FATAL: Échec du chargement des migrations: %v", err)
+	}
+
+	return migrator, func() {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Warning: Failed to close database connection: %v", err)
+		}
+	}
+}
+
 func init() {
+	MigrateCreateCmd.Flags().StringVar(&migrationsDirFlag, "dir", migrations.SourceDir, "Répertoire du paquet migrations (contenant sql/<moteur>)")
+	MigrateCmd.AddCommand(MigrateUpCmd, MigrateDownCmd, MigrateStatusCmd, MigrateCreateCmd)
 	cmd2.RootCmd.AddCommand(MigrateCmd)
 }
@@ -4,39 +4,62 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	cmd2 "github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/database"
+	"github.com/Edofo/bitly-clone/internal/models"
 	"github.com/Edofo/bitly-clone/internal/repository"
 	"github.com/Edofo/bitly-clone/internal/services"
 	"github.com/spf13/cobra"
 
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var shortCodeFlag string
+var intervalFlag string
+var fromFlag string
+var toFlag string
+var timezoneFlag string
+var topFlag int
+var includeBotsFlag bool
 
 var StatsCmd = &cobra.Command{
 	Use:   "stats",
-	Short: "Affiche les statistiques (nombre de clics) pour un lien court.",
+	Short: "Affiche les statistiques (clics et visiteurs uniques) pour un lien court.",
 	Long: `Cette commande permet de récupérer et d'afficher le nombre total de clics
-pour une URL courte spécifique en utilisant son code.
+et de visiteurs uniques pour une URL courte spécifique en utilisant son code.
+
+--from/--to (RFC3339 ou AAAA-MM-JJ, dans le fuseau --tz) restreignent la période.
+Avec --interval (hour, day, week ou month), les clics sont également ventilés
+dans le temps sur cette période.
+
+Les visiteurs uniques sont identifiés par une empreinte anonyme renouvelée chaque jour :
+un visiteur revenu plusieurs jours est compté une fois par jour.
 
 Exemple:
-  url-shortener stats --code="xyz123"`,
+  url-shortener stats --code="xyz123"
+  url-shortener stats --code="xyz123" --from=2024-03-01 --to=2024-04-01
+  url-shortener stats --code="xyz123" --interval=day --from=2024-03-01 --to=2024-03-08 --tz=Europe/Paris`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if shortCodeFlag == "" {
 			fmt.Println("Erreur: Le flag --code est requis.")
 			os.Exit(1)
 		}
 
+		filter, seriesQuery, err := parseStatsFlags()
+		if err != nil {
+			fmt.Printf("Erreur: %v\n", err)
+			os.Exit(1)
+		}
+
 		cfg := cmd2.Cfg
 		if cfg == nil {
 			fmt.Println("Erreur: Configuration non chargée.")
 			os.Exit(1)
 		}
 
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		db, err := database.Open(database.OptionsFromConfig(cfg))
 		if err != nil {
 			log.Fatalf("FATAL: Impossible de se connecter à la base de données: %v", err)
 		}
@@ -54,8 +77,9 @@ Exemple:
 
 		linkRepo := repository.NewLinkRepository(db)
 		linkService := services.NewLinkService(linkRepo)
+		clickService := services.NewClickService(repository.NewClickRepository(db))
 
-		link, totalClicks, err := linkService.GetLinkStats(shortCodeFlag)
+		link, totals, err := linkService.GetLinkStats(services.AdminOwner, shortCodeFlag, filter)
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
 				fmt.Printf("Erreur: Aucun lien trouvé avec le code '%s'\n", shortCodeFlag)
@@ -67,12 +91,138 @@ Exemple:
 
 		fmt.Printf("Statistiques pour le code court: %s\n", link.ShortCode)
 		fmt.Printf("URL longue: %s\n", link.LongURL)
-		fmt.Printf("Total de clics: %d\n", totalClicks)
+		if !filter.From.IsZero() || !filter.To.IsZero() {
+			fmt.Printf("Période: %s\n", formatPeriod(filter.From, filter.To))
+		}
+		fmt.Printf("Total de clics: %d\n", totals.TotalClicks)
+		fmt.Printf("Visiteurs uniques: %d\n", totals.UniqueVisitors)
+		if !includeBotsFlag {
+			fmt.Println("(clics de robots exclus, utilisez --include-bots pour les inclure)")
+		}
+
+		breakdowns := []struct {
+			title     string
+			dimension repository.ClickDimension
+		}{
+			{"Principaux référents", repository.DimensionReferrer},
+			{"Navigateurs", repository.DimensionBrowser},
+			{"Systèmes d'exploitation", repository.DimensionOS},
+			{"Types d'appareils", repository.DimensionDevice},
+			{"Pays", repository.DimensionCountry},
+		}
+		for _, breakdown := range breakdowns {
+			stats, err := clickService.GetClickBreakdown(link.ID, breakdown.dimension, topFlag, filter)
+			if err != nil {
+				fmt.Printf("Erreur lors de la récupération de la répartition '%s': %v\n", breakdown.dimension, err)
+				os.Exit(1)
+			}
+			printBreakdown(breakdown.title, stats)
+		}
+
+		if seriesQuery == nil {
+			return
+		}
+
+		buckets, err := clickService.GetClickTimeSeries(link.ID, *seriesQuery)
+		if err != nil {
+			fmt.Printf("Erreur lors du calcul de la série temporelle: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\nClics par %s (%s):\n", seriesQuery.Interval, seriesQuery.Location)
+		for _, bucket := range buckets {
+			fmt.Printf("  %s  %d clics, %d visiteurs\n", formatBucketStart(bucket.Start, seriesQuery.Interval), bucket.Count, bucket.UniqueVisitors)
+		}
 	},
 }
 
+// parseStatsFlags construit le filtre des totaux et, si --interval est fourni, la série temporelle.
+// La série temporelle vaut nil si aucune ventilation n'est demandée.
+func parseStatsFlags() (repository.ClickFilter, *services.TimeSeriesQuery, error) {
+	filter := repository.ClickFilter{IncludeBots: includeBotsFlag}
+
+	loc := time.UTC
+	if timezoneFlag != "" {
+		var err error
+		loc, err = time.LoadLocation(timezoneFlag)
+		if err != nil {
+			return filter, nil, fmt.Errorf("fuseau horaire inconnu '%s'", timezoneFlag)
+		}
+	}
+	if fromFlag != "" {
+		from, err := services.ParseDateTime(fromFlag, loc)
+		if err != nil {
+			return filter, nil, err
+		}
+		filter.From = from
+	}
+	if toFlag != "" {
+		to, err := services.ParseDateTime(toFlag, loc)
+		if err != nil {
+			return filter, nil, err
+		}
+		filter.To = to
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, nil, fmt.Errorf("--from doit précéder --to")
+	}
+
+	if intervalFlag == "" {
+		return filter, nil, nil
+	}
+	interval := repository.TimeInterval(intervalFlag)
+	if !interval.IsValid() {
+		return filter, nil, fmt.Errorf("intervalle invalide '%s' (hour, day, week ou month)", intervalFlag)
+	}
+	return filter, &services.TimeSeriesQuery{
+		Interval: interval,
+		From:     filter.From,
+		To:       filter.To,
+		Location: loc,
+		Filter:   filter,
+	}, nil
+}
+
+func formatPeriod(from, to time.Time) string {
+	start, end := "début", "maintenant"
+	if !from.IsZero() {
+		start = from.Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		end = to.Format(time.RFC3339)
+	}
+	return start + " → " + end
+}
+
+func printBreakdown(title string, stats []models.ClickStat) {
+	if len(stats) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	for _, stat := range stats {
+		fmt.Printf("  %-30s %d\n", stat.Value, stat.Count)
+	}
+}
+
+func formatBucketStart(start time.Time, interval repository.TimeInterval) string {
+	switch interval {
+	case repository.IntervalHour:
+		return start.Format("2006-01-02 15:04")
+	case repository.IntervalMonth:
+		return start.Format("2006-01")
+	default:
+		return start.Format("2006-01-02")
+	}
+}
+
 func init() {
 	StatsCmd.Flags().StringVar(&shortCodeFlag, "code", "", "Code court pour lequel afficher les statistiques")
+	StatsCmd.Flags().StringVar(&intervalFlag, "interval", "", "Ventilation temporelle des clics: hour, day, week ou month (optionnel)")
+	StatsCmd.Flags().StringVar(&fromFlag, "from", "", "Début de la période (RFC3339 ou AAAA-MM-JJ)")
+	StatsCmd.Flags().StringVar(&toFlag, "to", "", "Fin de la période, exclue (RFC3339 ou AAAA-MM-JJ)")
+	StatsCmd.Flags().StringVar(&timezoneFlag, "tz", "", "Fuseau horaire des intervalles, ex: Europe/Paris (défaut: UTC)")
+	StatsCmd.Flags().IntVar(&topFlag, "top", 5, "Nombre de valeurs affichées dans les répartitions (référents, navigateurs...)")
+	StatsCmd.Flags().BoolVar(&includeBotsFlag, "include-bots", false, "Inclure les clics de robots et d'aperçus de liens dans les statistiques")
 
 	if err := StatsCmd.MarkFlagRequired("code"); err != nil {
 		log.Fatalf("Failed to mark code flag as required: %v", err)
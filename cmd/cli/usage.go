@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	cmd2 "github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var usageWorkspaceFlag uint
+var usageUserFlag string
+var usageMonthFlag string
+
+var UsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Affiche la consommation mensuelle des quotas (liens créés et clics enregistrés).",
+	Long: `Les liens créés et les clics enregistrés sont imputés à l'espace de travail du lien,
+ou à défaut à l'utilisateur qui l'a créé ; les liens créés par un administrateur hors
+de tout espace de travail ne sont imputés à personne. Les quotas se règlent dans la
+section 'quotas' de la configuration et repartent à zéro chaque mois (UTC).
+
+Sans --workspace ni --user, la consommation de tous les comptes actifs sur le mois est listée.
+
+Exemple:
+  url-shortener usage
+  url-shortener usage --workspace=3
+  url-shortener usage --user="alice@example.com" --month=2024-03`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if usageWorkspaceFlag != 0 && usageUserFlag != "" {
+			fmt.Println("Erreur: Les flags --workspace et --user sont incompatibles.")
+			os.Exit(1)
+		}
+
+		db, closeDB := openDatabase()
+		defer closeDB()
+
+		usageService := newUsageService(db)
+
+		var account models.Account
+		switch {
+		case usageWorkspaceFlag != 0:
+			workspaceService := services.NewWorkspaceService(repository.NewWorkspaceRepository(db), repository.NewInvitationRepository(db), repository.NewUserRepository(db))
+			if _, err := workspaceService.GetWorkspace(services.AdminOwner, usageWorkspaceFlag); err != nil {
+				fmt.Printf("Erreur: Espace de travail %d introuvable: %v\n", usageWorkspaceFlag, err)
+				os.Exit(1)
+			}
+			account = models.WorkspaceAccount(usageWorkspaceFlag)
+		case usageUserFlag != "":
+			userService := services.NewUserService(repository.NewUserRepository(db), repository.NewSessionRepository(db), services.UserServiceOptions{})
+			account = models.UserAccount(findUserOrExit(userService, usageUserFlag).ID)
+		default:
+			usages, err := usageService.ListUsage(usageMonthFlag)
+			if err != nil {
+				exitWithUsageError(err)
+			}
+			if len(usages) == 0 {
+				fmt.Println("Aucune consommation sur ce mois.")
+				return
+			}
+			fmt.Printf("Consommation de %s (remise à zéro le %s):\n", usages[0].Period, usages[0].ResetsAt.Format("2006-01-02"))
+			for _, usage := range usages {
+				fmt.Printf("  %-16s  liens: %-16s  clics: %s\n", usage.Account, formatQuotaUsage(usage.Links), formatQuotaUsage(usage.TrackedClicks))
+			}
+			return
+		}
+
+		usage, err := usageService.AccountUsage(account, usageMonthFlag)
+		if err != nil {
+			exitWithUsageError(err)
+		}
+		fmt.Printf("Consommation de %s sur %s:\n", usage.Account, usage.Period)
+		fmt.Printf("Liens créés: %s\n", formatQuotaUsage(usage.Links))
+		fmt.Printf("Clics enregistrés: %s\n", formatQuotaUsage(usage.TrackedClicks))
+		fmt.Printf("Remise à zéro le: %s\n", usage.ResetsAt.Format("2006-01-02"))
+	},
+}
+
+// newUsageService applique les quotas de la configuration.
+func newUsageService(db *gorm.DB) *services.UsageService {
+	quotas := cmd2.Cfg.Quotas
+	return services.NewUsageService(repository.NewUsageRepository(db), repository.NewLinkRepository(db), services.Quotas{
+		LinksPerMonth:         quotas.LinksPerMonth,
+		TrackedClicksPerMonth: quotas.TrackedClicksPerMonth,
+	})
+}
+
+func formatQuotaUsage(usage services.QuotaUsage) string {
+	if usage.Limit == nil {
+		return fmt.Sprintf("%d (illimité)", usage.Used)
+	}
+	return fmt.Sprintf("%d / %d", usage.Used, *usage.Limit)
+}
+
+func exitWithUsageError(err error) {
+	if errors.Is(err, services.ErrInvalidUsagePeriod) {
+		fmt.Printf("Erreur: Mois invalide '%s' (format attendu: AAAA-MM)\n", usageMonthFlag)
+		os.Exit(1)
+	}
+	log.Fatalf("FATAL: Échec de la lecture de la consommation: %v", err)
+}
+
+func init() {
+	UsageCmd.Flags().UintVar(&usageWorkspaceFlag, "workspace", 0, "Identifiant de l'espace de travail (optionnel)")
+	UsageCmd.Flags().StringVar(&usageUserFlag, "user", "", "Adresse e-mail de l'utilisateur (optionnel)")
+	UsageCmd.Flags().StringVar(&usageMonthFlag, "month", "", "Mois au format AAAA-MM, le mois en cours par défaut (optionnel)")
+
+	cmd2.RootCmd.AddCommand(UsageCmd)
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	cmd2 "github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var userEmailFlag string
+var userPasswordFlag string
+
+var UserCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Gère les comptes utilisateurs de l'API de gestion.",
+	Long: `Chaque utilisateur ne voit que ses propres liens et ceux de ses espaces de travail
+(voir la commande workspace). Les comptes peuvent aussi être créés par
+POST /api/v1/auth/register si auth.registration_enabled est vrai.`,
+}
+
+var UserCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Crée un compte utilisateur.",
+	Long: `Le mot de passe peut aussi être fourni par la variable d'environnement
+URL_SHORTENER_PASSWORD, pour ne pas apparaître dans l'historique du shell.
+
+Exemple:
+  url-shortener user create --email="alice@example.com" --password="motdepasse-solide"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if userEmailFlag == "" {
+			fmt.Println("Erreur: Le flag --email est requis.")
+			os.Exit(1)
+		}
+		password := userPasswordFlag
+		if password == "" {
+			password = os.Getenv("URL_SHORTENER_PASSWORD")
+		}
+		if password == "" {
+			fmt.Println("Erreur: Le flag --password (ou la variable URL_SHORTENER_PASSWORD) est requis.")
+			os.Exit(1)
+		}
+
+		userService, closeDB := openUserService()
+		defer closeDB()
+
+		user, err := userService.CreateUser(userEmailFlag, password)
+		if err != nil {
+			if errors.Is(err, services.ErrEmailAlreadyUsed) {
+				fmt.Printf("Erreur: Un compte existe déjà pour '%s'.\n", userEmailFlag)
+			} else {
+				fmt.Printf("Erreur: Impossible de créer le compte: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("Compte créé avec succès: %s (id %d)\n", user.Email, user.ID)
+	},
+}
+
+var UserListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Liste les comptes utilisateurs.",
+	Run: func(cmd *cobra.Command, args []string) {
+		userService, closeDB := openUserService()
+		defer closeDB()
+
+		users, err := userService.ListUsers()
+		if err != nil {
+			log.Fatalf("FATAL: Échec de la lecture des comptes: %v", err)
+		}
+		if len(users) == 0 {
+			fmt.Println("Aucun compte utilisateur.")
+			return
+		}
+		for _, user := range users {
+			fmt.Printf("  %4d  %-40s  créé le %s\n", user.ID, user.Email, user.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+	},
+}
+
+// openUserService ouvre la base de données configurée. La fonction retournée ferme la connexion.
+func openUserService() (*services.UserService, func()) {
+	db, closeDB := openDatabase()
+	userService := services.NewUserService(repository.NewUserRepository(db), repository.NewSessionRepository(db), services.UserServiceOptions{})
+	return userService, closeDB
+}
+
+func init() {
+	UserCreateCmd.Flags().StringVar(&userEmailFlag, "email", "", "Adresse e-mail du compte")
+	UserCreateCmd.Flags().StringVar(&userPasswordFlag, "password", "", "Mot de passe du compte (8 caractères minimum)")
+	UserCmd.AddCommand(UserCreateCmd, UserListCmd)
+	cmd2.RootCmd.AddCommand(UserCmd)
+}
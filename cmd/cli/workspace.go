@@ -0,0 +1,295 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	cmd2 "github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var workspaceNameFlag string
+var workspaceOwnerFlag string
+var memberEmailFlag string
+var memberRoleFlag string
+
+var WorkspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Gère les espaces de travail, leurs membres et les invitations.",
+	Long: `Un espace de travail possède les liens créés en son sein. Chaque membre y a un rôle :
+  owner   gère les membres et les invitations, en plus des droits d'éditeur
+  editor  crée, modifie et supprime les liens
+  viewer  consulte les liens et leurs statistiques
+
+Ces commandes agissent en administrateur, sans vérifier le rôle de qui les lance.`,
+}
+
+var WorkspaceCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Crée un espace de travail.",
+	Long: `Exemple:
+  url-shortener workspace create --name="Marketing" --owner="alice@example.com"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if workspaceNameFlag == "" || workspaceOwnerFlag == "" {
+			fmt.Println("Erreur: Les flags --name et --owner sont requis.")
+			os.Exit(1)
+		}
+
+		workspaceService, userService, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		user := findUserOrExit(userService, workspaceOwnerFlag)
+		workspace, err := workspaceService.CreateWorkspace(services.OwnedBy(user.ID), workspaceNameFlag)
+		if err != nil {
+			exitWithWorkspaceError("créer l'espace de travail", err)
+		}
+		fmt.Printf("Espace de travail '%s' créé avec succès (id %d), propriétaire: %s\n", workspace.Name, workspace.ID, user.Email)
+	},
+}
+
+var WorkspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Liste les espaces de travail.",
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceService, _, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		workspaces, err := workspaceService.ListWorkspaces(services.AdminOwner)
+		if err != nil {
+			log.Fatalf("FATAL: Échec de la lecture des espaces de travail: %v", err)
+		}
+		if len(workspaces) == 0 {
+			fmt.Println("Aucun espace de travail. Créez-en un avec 'workspace create'.")
+			return
+		}
+		for _, workspace := range workspaces {
+			fmt.Printf("  %4d  %-40s  créé le %s\n", workspace.ID, workspace.Name, workspace.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+	},
+}
+
+var WorkspaceMembersCmd = &cobra.Command{
+	Use:   "members WORKSPACE_ID",
+	Short: "Liste les membres d'un espace de travail et leur rôle.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceID := parseIDOrExit("espace de travail", args[0])
+		workspaceService, _, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		members, err := workspaceService.ListMembers(services.AdminOwner, workspaceID)
+		if err != nil {
+			exitWithWorkspaceError("lister les membres", err)
+		}
+		for _, member := range members {
+			email := ""
+			if member.User != nil {
+				email = member.User.Email
+			}
+			fmt.Printf("  %-40s  %-6s  depuis le %s\n", email, member.Role, member.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+	},
+}
+
+var WorkspaceAddMemberCmd = &cobra.Command{
+	Use:   "add-member WORKSPACE_ID",
+	Short: "Ajoute directement un utilisateur existant à un espace de travail.",
+	Long: `Exemple:
+  url-shortener workspace add-member 3 --email="bob@example.com" --role=editor`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceID := parseIDOrExit("espace de travail", args[0])
+		role := parseRoleOrExit()
+		workspaceService, _, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		membership, err := workspaceService.AddMember(services.AdminOwner, workspaceID, memberEmailFlag, role)
+		if err != nil {
+			exitWithWorkspaceError("ajouter le membre", err)
+		}
+		fmt.Printf("%s est maintenant %s de l'espace de travail %d.\n", membership.User.Email, membership.Role, workspaceID)
+	},
+}
+
+var WorkspaceSetRoleCmd = &cobra.Command{
+	Use:   "set-role WORKSPACE_ID",
+	Short: "Change le rôle d'un membre.",
+	Long: `Exemple:
+  url-shortener workspace set-role 3 --email="bob@example.com" --role=viewer`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceID := parseIDOrExit("espace de travail", args[0])
+		role := parseRoleOrExit()
+		workspaceService, userService, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		user := findUserOrExit(userService, memberEmailFlag)
+		if _, err := workspaceService.UpdateMemberRole(services.AdminOwner, workspaceID, user.ID, role); err != nil {
+			exitWithWorkspaceError("changer le rôle", err)
+		}
+		fmt.Printf("%s est maintenant %s de l'espace de travail %d.\n", user.Email, role, workspaceID)
+	},
+}
+
+var WorkspaceRemoveMemberCmd = &cobra.Command{
+	Use:   "remove-member WORKSPACE_ID",
+	Short: "Retire un membre d'un espace de travail.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceID := parseIDOrExit("espace de travail", args[0])
+		if memberEmailFlag == "" {
+			fmt.Println("Erreur: Le flag --email est requis.")
+			os.Exit(1)
+		}
+		workspaceService, userService, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		user := findUserOrExit(userService, memberEmailFlag)
+		if err := workspaceService.RemoveMember(services.AdminOwner, workspaceID, user.ID); err != nil {
+			exitWithWorkspaceError("retirer le membre", err)
+		}
+		fmt.Printf("%s a été retiré de l'espace de travail %d.\n", user.Email, workspaceID)
+	},
+}
+
+var WorkspaceInviteCmd = &cobra.Command{
+	Use:   "invite WORKSPACE_ID",
+	Short: "Invite une adresse e-mail et affiche le jeton d'invitation une seule fois.",
+	Long: `L'invité accepte l'invitation, une fois connecté avec cette adresse, par
+POST /api/v1/invitations/accept {"token": "usi_..."}.
+
+Exemple:
+  url-shortener workspace invite 3 --email="carol@example.com" --role=viewer`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceID := parseIDOrExit("espace de travail", args[0])
+		role := parseRoleOrExit()
+		workspaceService, _, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		invitation, token, err := workspaceService.CreateInvitation(services.AdminOwner, workspaceID, memberEmailFlag, role)
+		if err != nil {
+			exitWithWorkspaceError("créer l'invitation", err)
+		}
+		fmt.Printf("Invitation créée pour %s (%s), valable jusqu'au %s:\n", invitation.Email, invitation.Role, invitation.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("Jeton: %s\n", token)
+		fmt.Println("Transmettez ce jeton à l'invité: il ne pourra plus être affiché.")
+	},
+}
+
+var WorkspaceInvitationsCmd = &cobra.Command{
+	Use:   "invitations WORKSPACE_ID",
+	Short: "Liste les invitations en attente d'un espace de travail.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceID := parseIDOrExit("espace de travail", args[0])
+		workspaceService, _, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		invitations, err := workspaceService.ListInvitations(services.AdminOwner, workspaceID)
+		if err != nil {
+			exitWithWorkspaceError("lister les invitations", err)
+		}
+		if len(invitations) == 0 {
+			fmt.Println("Aucune invitation en attente.")
+			return
+		}
+		for _, invitation := range invitations {
+			fmt.Printf("  %4d  %-40s  %-6s  expire le %s\n", invitation.ID, invitation.Email, invitation.Role, invitation.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+		}
+	},
+}
+
+var WorkspaceRevokeInvitationCmd = &cobra.Command{
+	Use:   "revoke-invitation WORKSPACE_ID INVITATION_ID",
+	Short: "Annule une invitation en attente.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspaceID := parseIDOrExit("espace de travail", args[0])
+		invitationID := parseIDOrExit("invitation", args[1])
+		workspaceService, _, closeDB := openWorkspaceService()
+		defer closeDB()
+
+		if err := workspaceService.RevokeInvitation(services.AdminOwner, workspaceID, invitationID); err != nil {
+			exitWithWorkspaceError("annuler l'invitation", err)
+		}
+		fmt.Printf("Invitation %d annulée.\n", invitationID)
+	},
+}
+
+// openWorkspaceService ouvre la base de données configurée. La fonction retournée ferme la connexion.
+func openWorkspaceService() (*services.WorkspaceService, *services.UserService, func()) {
+	db, closeDB := openDatabase()
+	userRepo := repository.NewUserRepository(db)
+	workspaceService := services.NewWorkspaceService(repository.NewWorkspaceRepository(db), repository.NewInvitationRepository(db), userRepo)
+	userService := services.NewUserService(userRepo, repository.NewSessionRepository(db), services.UserServiceOptions{})
+	return workspaceService, userService, closeDB
+}
+
+func findUserOrExit(userService *services.UserService, email string) *models.User {
+	user, err := userService.GetUserByEmail(email)
+	if err != nil {
+		fmt.Printf("Erreur: Utilisateur '%s' introuvable: %v\n", email, err)
+		os.Exit(1)
+	}
+	return user
+}
+
+func parseIDOrExit(kind string, value string) uint {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		fmt.Printf("Erreur: Identifiant d'%s invalide '%s'\n", kind, value)
+		os.Exit(1)
+	}
+	return uint(id)
+}
+
+// parseRoleOrExit vérifie les flags --email et --role des commandes qui les exigent.
+func parseRoleOrExit() models.Role {
+	if memberEmailFlag == "" || memberRoleFlag == "" {
+		fmt.Println("Erreur: Les flags --email et --role sont requis.")
+		os.Exit(1)
+	}
+	role, err := services.ParseRole(memberRoleFlag)
+	if err != nil {
+		fmt.Printf("Erreur: Rôle invalide '%s' (owner, editor ou viewer)\n", memberRoleFlag)
+		os.Exit(1)
+	}
+	return role
+}
+
+func exitWithWorkspaceError(action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		fmt.Println("Erreur: Espace de travail introuvable.")
+	case errors.Is(err, services.ErrMemberNotFound):
+		fmt.Println("Erreur: Cet utilisateur n'est pas membre de l'espace de travail.")
+	case errors.Is(err, services.ErrAlreadyMember):
+		fmt.Println("Erreur: Cet utilisateur est déjà membre de l'espace de travail.")
+	case errors.Is(err, services.ErrLastOwner):
+		fmt.Println("Erreur: L'espace de travail doit garder au moins un propriétaire.")
+	default:
+		fmt.Printf("Erreur: Impossible de %s: %v\n", action, err)
+	}
+	os.Exit(1)
+}
+
+func init() {
+	WorkspaceCreateCmd.Flags().StringVar(&workspaceNameFlag, "name", "", "Nom de l'espace de travail")
+	WorkspaceCreateCmd.Flags().StringVar(&workspaceOwnerFlag, "owner", "", "Adresse e-mail de l'utilisateur propriétaire")
+	for _, command := range []*cobra.Command{WorkspaceAddMemberCmd, WorkspaceSetRoleCmd, WorkspaceRemoveMemberCmd, WorkspaceInviteCmd} {
+		command.Flags().StringVar(&memberEmailFlag, "email", "", "Adresse e-mail du membre")
+	}
+	for _, command := range []*cobra.Command{WorkspaceAddMemberCmd, WorkspaceSetRoleCmd, WorkspaceInviteCmd} {
+		command.Flags().StringVar(&memberRoleFlag, "role", "", "Rôle du membre: owner, editor ou viewer")
+	}
+	WorkspaceCmd.AddCommand(WorkspaceCreateCmd, WorkspaceListCmd, WorkspaceMembersCmd, WorkspaceAddMemberCmd, WorkspaceSetRoleCmd,
+		WorkspaceRemoveMemberCmd, WorkspaceInviteCmd, WorkspaceInvitationsCmd, WorkspaceRevokeInvitationCmd)
+	cmd2.RootCmd.AddCommand(WorkspaceCmd)
+}
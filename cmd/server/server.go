@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,17 +13,31 @@ import (
 
 	cmd2 "github.com/Edofo/bitly-clone/cmd"
 	"github.com/Edofo/bitly-clone/internal/api"
+	"github.com/Edofo/bitly-clone/internal/cache"
+	"github.com/Edofo/bitly-clone/internal/database"
+	"github.com/Edofo/bitly-clone/internal/geoip"
+	"github.com/Edofo/bitly-clone/internal/migrations"
 	"github.com/Edofo/bitly-clone/internal/models"
 	"github.com/Edofo/bitly-clone/internal/monitor"
+	"github.com/Edofo/bitly-clone/internal/notifier"
+	"github.com/Edofo/bitly-clone/internal/ratelimit"
 	"github.com/Edofo/bitly-clone/internal/repository"
 	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/Edofo/bitly-clone/internal/spool"
+	"github.com/Edofo/bitly-clone/internal/visitor"
 	"github.com/Edofo/bitly-clone/internal/workers"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
+// Stockages disponibles pour --storage.
+const (
+	storageDatabase = "database"
+	storageMemory   = "memory"
+)
+
+var storageFlag string
+
 var RunServerCmd = &cobra.Command{
 	Use:   "run-server",
 	Short: "Lance le serveur API de raccourcissement d'URLs et les processus de fond.",
@@ -34,44 +50,255 @@ puis lance le serveur HTTP.`,
 			log.Fatalf("FATAL: Configuration not loaded.")
 		}
 
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
-		if err != nil {
-			log.Fatalf("FATAL: Unable to connect to database: %v", err)
-		}
+		var (
+			linkRepo       repository.LinkRepository
+			clickRepo      repository.ClickRepository
+			healthRepo     repository.HealthCheckRepository
+			saltRepo       repository.VisitorSaltRepository
+			deadLetterRepo repository.WebhookDeadLetterRepository
+			apiKeyRepo     repository.APIKeyRepository
+			userRepo       repository.UserRepository
+			sessionRepo    repository.SessionRepository
+			workspaceRepo  repository.WorkspaceRepository
+			invitationRepo repository.InvitationRepository
+			usageRepo      repository.UsageRepository
+		)
+		switch storageFlag {
+		case storageDatabase:
+			db, err := database.Open(database.OptionsFromConfig(cfg))
+			if err != nil {
+				log.Fatalf("FATAL: Unable to connect to database: %v", err)
+			}
+
+			sqlDB, err := db.DB()
+			if err != nil {
+				log.Fatalf("FATAL: Failed to get underlying SQL database: %v", err)
+			}
+			defer func() {
+				if err := sqlDB.Close(); err != nil {
+					log.Printf("Warning: Failed to close database connection: %v", err)
+				}
+			}()
+
+			if migrator, err := migrations.New(db); err != nil {
+				log.Printf("Warning: Unable to check database migrations: %v", err)
+			} else if pending, err := migrator.Pending(); err != nil {
+				log.Printf("Warning: Unable to check database migrations: %v", err)
+			} else if len(pending) > 0 {
+				log.Printf("Warning: %d pending database migration(s), run 'migrate up' before serving traffic.", len(pending))
+			}
 
-		sqlDB, err := db.DB()
-		if err != nil {
-			log.Fatalf("FATAL: Failed to get underlying SQL database: %v", err)
+			linkRepo = repository.NewLinkRepository(db)
+			clickRepo = repository.NewClickRepository(db)
+			healthRepo = repository.NewHealthCheckRepository(db)
+			saltRepo = repository.NewVisitorSaltRepository(db)
+			deadLetterRepo = repository.NewWebhookDeadLetterRepository(db)
+			apiKeyRepo = repository.NewAPIKeyRepository(db)
+			userRepo = repository.NewUserRepository(db)
+			sessionRepo = repository.NewSessionRepository(db)
+			workspaceRepo = repository.NewWorkspaceRepository(db)
+			invitationRepo = repository.NewInvitationRepository(db)
+			usageRepo = repository.NewUsageRepository(db)
+		case storageMemory:
+			store := repository.NewMemoryStore()
+			linkRepo = repository.NewMemoryLinkRepository(store)
+			clickRepo = repository.NewMemoryClickRepository(store)
+			healthRepo = repository.NewMemoryHealthCheckRepository(store)
+			saltRepo = repository.NewMemoryVisitorSaltRepository(store)
+			deadLetterRepo = repository.NewMemoryWebhookDeadLetterRepository(store)
+			apiKeyRepo = repository.NewMemoryAPIKeyRepository(store)
+			userRepo = repository.NewMemoryUserRepository(store)
+			sessionRepo = repository.NewMemorySessionRepository(store)
+			workspaceRepo = repository.NewMemoryWorkspaceRepository(store)
+			invitationRepo = repository.NewMemoryInvitationRepository(store)
+			usageRepo = repository.NewMemoryUsageRepository(store)
+			log.Println("Warning: In-memory storage enabled, all data will be lost when the server stops.")
+		default:
+			log.Fatalf("FATAL: Unknown storage '%s' (expected %s or %s).", storageFlag, storageDatabase, storageMemory)
 		}
-		defer func() {
-			if err := sqlDB.Close(); err != nil {
-				log.Printf("Warning: Failed to close database connection: %v", err)
+
+		if cfg.Cache.Enabled {
+			linkCacheStore, err := cache.New(cache.Options{
+				Backend: cfg.Cache.Backend,
+				Size:    cfg.Cache.Size,
+				Redis: cache.RedisOptions{
+					Addr:      cfg.Cache.Redis.Addr,
+					Password:  cfg.Cache.Redis.Password,
+					DB:        cfg.Cache.Redis.DB,
+					KeyPrefix: cfg.Cache.Redis.KeyPrefix,
+					Timeout:   time.Duration(cfg.Cache.Redis.TimeoutMs) * time.Millisecond,
+				},
+			})
+			if err != nil {
+				log.Fatalf("FATAL: Failed to create link cache: %v", err)
+			}
+			if redisStore, ok := linkCacheStore.(*cache.Redis); ok {
+				if err := redisStore.Ping(); err != nil {
+					log.Printf("Warning: Redis link cache unreachable, redirects will use the database until it recovers: %v", err)
+				}
+				defer redisStore.Close()
 			}
-		}()
 
-		linkRepo := repository.NewLinkRepository(db)
-		clickRepo := repository.NewClickRepository(db)
+			cachedLinkRepo := repository.NewCachedLinkRepository(linkRepo, linkCacheStore, repository.LinkCacheOptions{
+				TTL:         time.Duration(cfg.Cache.TTLSeconds) * time.Second,
+				NegativeTTL: time.Duration(cfg.Cache.NegativeTTLSeconds) * time.Second,
+			})
+			expvar.Publish("link_cache", expvar.Func(func() any { return cachedLinkRepo.Stats() }))
+			linkRepo = cachedLinkRepo
+			log.Printf("Link cache enabled with %s backend.", cfg.Cache.Backend)
+		}
 
 		log.Println("Repositories initialized.")
 
 		linkService := services.NewLinkService(linkRepo)
-		_ = services.NewClickService(clickRepo) 
+		clickService := services.NewClickService(clickRepo)
+		healthService := services.NewHealthService(healthRepo, time.Duration(cfg.Monitor.HistoryRetentionDays)*24*time.Hour)
+		apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+		userService := services.NewUserService(userRepo, sessionRepo, services.UserServiceOptions{
+			SessionTTL: time.Duration(cfg.Auth.SessionTTLHours) * time.Hour,
+		})
+		workspaceService := services.NewWorkspaceService(workspaceRepo, invitationRepo, userRepo)
+		usageService := services.NewUsageService(usageRepo, linkRepo, services.Quotas{
+			LinksPerMonth:         cfg.Quotas.LinksPerMonth,
+			TrackedClicksPerMonth: cfg.Quotas.TrackedClicksPerMonth,
+		})
+		linkService.SetUsageService(usageService)
 
 		log.Println("Business services initialized.")
 
+		if storageFlag == storageMemory {
+			// La commande apikey n'a pas accès au stockage en mémoire : une clé est créée au démarrage.
+			apiKey, key, err := apiKeyService.CreateAPIKey("bootstrap", nil)
+			if err != nil {
+				log.Fatalf("FATAL: Failed to create bootstrap API key: %v", err)
+			}
+			// La clé en clair est affichée une seule fois sur la sortie standard, jamais dans les logs
+			// (souvent collectés et conservés) : seul son préfixe y figure.
+			log.Printf("Bootstrap API key %s created for this in-memory session (printed on stdout).", apiKey.Prefix)
+			fmt.Printf("Bootstrap API key: %s\n", key)
+		} else if keys, err := apiKeyService.ListAPIKeys(); err != nil {
+			log.Printf("Warning: Unable to list API keys: %v", err)
+		} else if !hasActiveAPIKey(keys) {
+			log.Println("Warning: No active API key, the management API is only reachable with user sessions. Create one with 'apikey create'.")
+		}
+
+		enrichers := []workers.ClickEnricher{
+			visitor.NewHasher(saltRepo),
+		}
+		if cfg.Analytics.GeoIPDatabase != "" {
+			geoReader, err := geoip.Open(cfg.Analytics.GeoIPDatabase)
+			if err != nil {
+				log.Printf("Warning: GeoIP enrichment disabled: %v", err)
+			} else {
+				defer func() {
+					if err := geoReader.Close(); err != nil {
+						log.Printf("Warning: Failed to close GeoIP database: %v", err)
+					}
+				}()
+				enrichers = append(enrichers, geoReader)
+				log.Printf("GeoIP enrichment enabled with database %s.", cfg.Analytics.GeoIPDatabase)
+			}
+		}
+
 		clickEventsChan := make(chan models.ClickEvent, cfg.Analytics.BufferSize)
-		workers.StartClickWorkers(cfg.Analytics.Workers, clickEventsChan, clickRepo)
+		batchOptions := workers.BatchOptions{
+			Size:       cfg.Analytics.BatchSize,
+			MaxLatency: time.Duration(cfg.Analytics.FlushIntervalMs) * time.Millisecond,
+		}
+		clickWorkers := workers.NewClickWorkerPool(cfg.Analytics.Workers, batchOptions, clickEventsChan, clickRepo, enrichers...)
+		clickWorkers.SetMeter(usageService)
+
+		var clickSink api.ClickEventSink = api.ChannelSink(clickEventsChan)
+		var clickSpool *spool.Spool
+		if cfg.Analytics.Spool.Dir != "" {
+			var err error
+			clickSpool, err = spool.Open(cfg.Analytics.Spool.Dir, spool.Options{
+				SegmentMaxBytes: int64(cfg.Analytics.Spool.SegmentSizeMB) << 20,
+				MaxDiskBytes:    int64(cfg.Analytics.Spool.MaxDiskMB) << 20,
+				Fsync:           spool.FsyncPolicy(cfg.Analytics.Spool.Fsync),
+				FsyncInterval:   time.Duration(cfg.Analytics.Spool.FsyncIntervalMs) * time.Millisecond,
+			})
+			if err != nil {
+				log.Fatalf("FATAL: Failed to open click spool: %v", err)
+			}
+			clickWorkers.SetAcknowledger(clickSpool)
+			clickSink = clickSpool
+			log.Printf("Click spool enabled in %s (%d bytes pending replay).", cfg.Analytics.Spool.Dir, clickSpool.Pending())
+		}
+
+		clickWorkers.Start(context.Background())
+		if clickSpool != nil {
+			clickSpool.Start(clickEventsChan)
+		}
 
 		log.Printf("Click events channel initialized with buffer size %d. %d click worker(s) started.",
 			cfg.Analytics.BufferSize, cfg.Analytics.Workers)
 
 		monitorInterval := time.Duration(cfg.Monitor.IntervalMinutes) * time.Minute
-		urlMonitor := monitor.NewUrlMonitor(linkRepo, monitorInterval)
-		go urlMonitor.Start()
+		urlMonitor := monitor.NewUrlMonitor(linkRepo, healthRepo, monitorInterval, monitor.Options{
+			Concurrency:        cfg.Monitor.Concurrency,
+			PerHostConcurrency: cfg.Monitor.PerHostConcurrency,
+			PerHostInterval:    time.Duration(cfg.Monitor.PerHostIntervalMs) * time.Millisecond,
+			Timeout:            time.Duration(cfg.Monitor.TimeoutSeconds) * time.Second,
+			JitterPercent:      cfg.Monitor.JitterPercent,
+			HistoryRetention:   time.Duration(cfg.Monitor.HistoryRetentionDays) * 24 * time.Hour,
+			FailureThreshold:   cfg.Monitor.FailureThreshold,
+		})
+		webhookNotifier := notifier.NewWebhookNotifier(notifier.Options{
+			URL:            cfg.Notifications.Webhook.URL,
+			Secret:         cfg.Notifications.Webhook.Secret,
+			Timeout:        time.Duration(cfg.Notifications.Webhook.TimeoutSeconds) * time.Second,
+			MaxAttempts:    cfg.Notifications.Webhook.MaxAttempts,
+			InitialBackoff: time.Duration(cfg.Notifications.Webhook.InitialBackoffMs) * time.Millisecond,
+			MaxBackoff:     time.Duration(cfg.Notifications.Webhook.MaxBackoffSeconds) * time.Second,
+		}, deadLetterRepo)
+		webhookNotifier.Start()
+		urlMonitor.SetNotifier(webhookNotifier)
+		urlMonitor.Start(context.Background())
 		log.Printf("URL monitor started with interval %v.", monitorInterval)
 
+		var rateLimits api.RateLimits
+		if cfg.RateLimit.Enabled {
+			rateLimitStore, err := ratelimit.New(ratelimit.Options{
+				Backend: cfg.RateLimit.Backend,
+				Redis: ratelimit.RedisOptions{
+					Addr:      cfg.RateLimit.Redis.Addr,
+					Password:  cfg.RateLimit.Redis.Password,
+					DB:        cfg.RateLimit.Redis.DB,
+					KeyPrefix: cfg.RateLimit.Redis.KeyPrefix,
+					Timeout:   time.Duration(cfg.RateLimit.Redis.TimeoutMs) * time.Millisecond,
+				},
+			})
+			if err != nil {
+				log.Fatalf("FATAL: Failed to create rate limit store: %v", err)
+			}
+			if redisStore, ok := rateLimitStore.(*ratelimit.Redis); ok {
+				if err := redisStore.Ping(); err != nil {
+					log.Printf("Warning: Redis rate limit store unreachable, requests will not be limited until it recovers: %v", err)
+				}
+				defer redisStore.Close()
+			}
+
+			if limit := ratelimit.PerMinute(cfg.RateLimit.IP.RequestsPerMinute, cfg.RateLimit.IP.Burst); limit.Enabled() {
+				rateLimits.IP = ratelimit.NewLimiter(rateLimitStore, "ip", limit)
+			}
+			if limit := ratelimit.PerMinute(cfg.RateLimit.API.RequestsPerMinute, cfg.RateLimit.API.Burst); limit.Enabled() {
+				rateLimits.API = ratelimit.NewLimiter(rateLimitStore, "api", limit)
+			}
+			if limit := ratelimit.PerMinute(cfg.RateLimit.Redirect.RequestsPerMinute, cfg.RateLimit.Redirect.Burst); limit.Enabled() {
+				rateLimits.Redirect = ratelimit.NewLimiter(rateLimitStore, "redirect", limit)
+			}
+			log.Printf("Rate limiting enabled with %s backend: %d req/min per IP and %d req/min per client for the API, %d req/min for redirects.",
+				cfg.RateLimit.Backend, cfg.RateLimit.IP.RequestsPerMinute, cfg.RateLimit.API.RequestsPerMinute, cfg.RateLimit.Redirect.RequestsPerMinute)
+		}
+
 		router := gin.Default()
-		api.SetupRoutes(router, linkService, clickEventsChan)
+		// Sans proxy de confiance, l'adresse du client (limitation de débit, statistiques) est
+		// celle de la connexion : les en-têtes X-Forwarded-For ne peuvent pas être usurpés.
+		if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+			log.Fatalf("FATAL: Invalid server.trusted_proxies: %v", err)
+		}
+		api.SetupRoutes(router, linkService, clickService, healthService, apiKeyService, userService, workspaceService, usageService, clickSink, rateLimits)
 
 		log.Println("API routes configured.")
 
@@ -94,13 +321,58 @@ puis lance le serveur HTTP.`,
 		<-quit
 		log.Println("Shutdown signal received. Stopping server...")
 
-		log.Println("Shutting down... Giving workers time to finish.")
-		time.Sleep(5 * time.Second)
+		shutdownTimeout := time.Duration(cfg.Server.ShutdownTimeoutSeconds) * time.Second
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		shutdownErr := srv.Shutdown(ctx)
+
+		// Le spool cesse d'alimenter les workers : les clics non transmis restent sur disque.
+		if clickSpool != nil {
+			clickSpool.Stop()
+		}
+		if shutdownErr != nil {
+			log.Printf("Warning: HTTP server did not stop within %v: %v", shutdownTimeout, shutdownErr)
+			clickWorkers.Stop()
+		} else {
+			// Le channel n'est fermé qu'une fois toutes les requêtes terminées :
+			// un handler encore actif paniquerait en y envoyant un clic.
+			close(clickEventsChan)
+			log.Println("HTTP server stopped. Flushing pending click events...")
+		}
+
+		report := clickWorkers.Drain(ctx)
+		log.Printf("Click workers stopped: %d click(s) persisted, %d failed, %d rejected, %d abandoned, %d over quota.",
+			report.Persisted, report.Failed, report.Rejected, report.Abandoned, report.OverQuota)
+
+		if clickSpool != nil {
+			if err := clickSpool.Close(); err != nil {
+				log.Printf("Warning: Failed to close click spool: %v", err)
+			}
+			log.Printf("Click spool closed, %d bytes kept for replay on next start.", clickSpool.Pending())
+		}
+
+		// Le moniteur s'arrête avant le notifier qu'il alimente et la base qu'il interroge.
+		urlMonitor.Stop(ctx)
+		log.Println("URL monitor stopped.")
+
+		webhookNotifier.Stop(ctx)
+		log.Println("Webhook notifier stopped.")
 
 		log.Println("Server stopped gracefully.")
 	},
 }
 
+func hasActiveAPIKey(keys []models.APIKey) bool {
+	for _, key := range keys {
+		if !key.IsRevoked() {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
+	RunServerCmd.Flags().StringVar(&storageFlag, "storage", storageDatabase, "Stockage des données: database (configuration database) ou memory (non persistant, pour les tests et démos)")
 	cmd2.RootCmd.AddCommand(RunServerCmd)
 }
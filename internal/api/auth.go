@@ -0,0 +1,209 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader est l'en-tête accepté en alternative à "Authorization: Bearer <clé>".
+const APIKeyHeader = "X-API-Key"
+
+// Clés sous lesquelles Authenticate range l'identité de la requête dans le contexte Gin.
+const (
+	ownerContextKey   = "owner"
+	apiKeyContextKey  = "api_key"
+	sessionContextKey = "session"
+)
+
+// Authenticate rejette avec 401 les requêtes sans jeton de session ni clé d'API valide,
+// et enregistre les liens accessibles à la requête (voir requestOwner).
+func Authenticate(apiKeyService services.APIKeyServiceInterface, userService services.UserServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := extractToken(c.Request)
+		if token == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing API key or session token"})
+			return
+		}
+
+		if services.IsSessionToken(token) {
+			session, err := userService.AuthenticateSession(token)
+			if errors.Is(err, services.ErrInvalidSession) {
+				c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
+				return
+			}
+			if err != nil {
+				log.Printf("Error authenticating session: %v", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
+			c.Set(sessionContextKey, session)
+			c.Set(ownerContextKey, services.OwnedBy(session.UserID))
+			c.Next()
+			return
+		}
+
+		apiKey, err := apiKeyService.Authenticate(token)
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error authenticating API key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.Set(apiKeyContextKey, apiKey)
+		if apiKey.UserID != nil {
+			c.Set(ownerContextKey, services.OwnedBy(*apiKey.UserID))
+		} else {
+			c.Set(ownerContextKey, services.AdminOwner)
+		}
+		c.Next()
+	}
+}
+
+// APIKeyFromContext retourne la clé authentifiée par Authenticate, ou nil.
+func APIKeyFromContext(c *gin.Context) *models.APIKey {
+	value, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return nil
+	}
+	apiKey, _ := value.(*models.APIKey)
+	return apiKey
+}
+
+// SessionFromContext retourne la session authentifiée par Authenticate, ou nil.
+func SessionFromContext(c *gin.Context) *models.Session {
+	value, ok := c.Get(sessionContextKey)
+	if !ok {
+		return nil
+	}
+	session, _ := value.(*models.Session)
+	return session
+}
+
+// requestOwner retourne les liens accessibles à la requête. Sans passage par
+// Authenticate, la valeur zéro n'accède à aucun lien.
+func requestOwner(c *gin.Context) services.Owner {
+	value, _ := c.Get(ownerContextKey)
+	owner, _ := value.(services.Owner)
+	return owner
+}
+
+func extractToken(r *http.Request) string {
+	if scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " "); found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(r.Header.Get(APIKeyHeader))
+}
+
+type CredentialsRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RegisterHandler crée un compte et retourne le jeton de sa première session. L'inscription
+// libre n'est ouverte que si auth.registration_enabled est activé explicitement.
+func RegisterHandler(userService services.UserServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cmd.Cfg == nil || !cmd.Cfg.Auth.RegistrationEnabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Registration is disabled"})
+			return
+		}
+
+		var req CredentialsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		session, err := userService.Register(req.Email, req.Password)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidEmail) || errors.Is(err, services.ErrInvalidPassword) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, services.ErrEmailAlreadyUsed) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error registering user: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, session)
+	}
+}
+
+// LoginHandler ouvre une session et retourne son jeton.
+func LoginHandler(userService services.UserServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CredentialsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		session, err := userService.Login(req.Email, req.Password)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidCredentials) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+				return
+			}
+			log.Printf("Error logging in: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, session)
+	}
+}
+
+// LogoutHandler ferme la session du jeton présenté.
+func LogoutHandler(userService services.UserServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SessionFromContext(c) == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Logout requires a session token"})
+			return
+		}
+
+		if err := userService.Logout(extractToken(c.Request)); err != nil {
+			if errors.Is(err, services.ErrInvalidSession) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
+				return
+			}
+			log.Printf("Error logging out: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// WhoAmIHandler décrit l'identité de la requête : utilisateur de la session ou clé d'API.
+func WhoAmIHandler(c *gin.Context) {
+	if session := SessionFromContext(c); session != nil {
+		c.JSON(http.StatusOK, gin.H{"user": session.User, "session_expires_at": session.ExpiresAt})
+		return
+	}
+
+	response := gin.H{}
+	if apiKey := APIKeyFromContext(c); apiKey != nil {
+		response["api_key"] = apiKey
+	}
+	response["admin"] = requestOwner(c).Admin
+	c.JSON(http.StatusOK, response)
+}
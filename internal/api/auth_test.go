@@ -0,0 +1,270 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/config"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(name string, userID *uint) (*models.APIKey, string, error) {
+	args := m.Called(name, userID)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*models.APIKey), args.String(1), args.Error(2)
+}
+
+func (m *MockAPIKeyService) ListAPIKeys() ([]models.APIKey, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(prefix string) (*models.APIKey, error) {
+	args := m.Called(prefix)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Authenticate(key string) (*models.APIKey, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+type MockUserService struct {
+	mock.Mock
+}
+
+func (m *MockUserService) Register(email, password string) (*services.SessionToken, error) {
+	args := m.Called(email, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.SessionToken), args.Error(1)
+}
+
+func (m *MockUserService) Login(email, password string) (*services.SessionToken, error) {
+	args := m.Called(email, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.SessionToken), args.Error(1)
+}
+
+func (m *MockUserService) AuthenticateSession(token string) (*models.Session, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockUserService) Logout(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+// setupAuthRouter expose l'identité retenue par Authenticate. Le routeur n'accorde
+// aucun accès par défaut, contrairement à setupTestRouter.
+func setupAuthRouter(apiKeyService services.APIKeyServiceInterface, userService services.UserServiceInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/whoami", Authenticate(apiKeyService, userService), func(c *gin.Context) {
+		owner := requestOwner(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": owner.UserID, "admin": owner.Admin})
+	})
+	return router
+}
+
+func performRequest(router *gin.Engine, method, path string, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticate_APIKeys(t *testing.T) {
+	userID := uint(7)
+	mockService := &MockAPIKeyService{}
+	mockService.On("Authenticate", "usk_12345678_admin").Return(&models.APIKey{ID: 1, Prefix: "usk_12345678"}, nil)
+	mockService.On("Authenticate", "usk_87654321_user").Return(&models.APIKey{ID: 2, Prefix: "usk_87654321", UserID: &userID}, nil)
+	router := setupAuthRouter(mockService, &MockUserService{})
+
+	for _, header := range [][2]string{
+		{"Authorization", "Bearer usk_12345678_admin"},
+		{"Authorization", "bearer  usk_12345678_admin"},
+		{APIKeyHeader, "usk_12345678_admin"},
+	} {
+		w := performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{header[0]: header[1]})
+		assert.Equal(t, http.StatusOK, w.Code, header[0])
+		assert.JSONEq(t, `{"user_id":0,"admin":true}`, w.Body.String(), "une clé sans utilisateur accède à tous les liens")
+	}
+
+	w := performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{"Authorization": "Bearer usk_87654321_user"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":7,"admin":false}`, w.Body.String())
+}
+
+func TestAuthenticate_SessionToken(t *testing.T) {
+	mockUserService := &MockUserService{}
+	mockUserService.On("AuthenticateSession", "uss_valid").Return(&models.Session{ID: 3, UserID: 9}, nil)
+	mockUserService.On("AuthenticateSession", "uss_expired").Return(nil, services.ErrInvalidSession)
+	router := setupAuthRouter(&MockAPIKeyService{}, mockUserService)
+
+	w := performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{"Authorization": "Bearer uss_valid"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":9,"admin":false}`, w.Body.String())
+
+	w = performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{"Authorization": "Bearer uss_expired"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid or expired session"}`, w.Body.String())
+}
+
+func TestAuthenticate_RejectsMissingOrInvalidCredentials(t *testing.T) {
+	mockService := &MockAPIKeyService{}
+	mockService.On("Authenticate", "usk_12345678_wrong").Return(nil, services.ErrInvalidAPIKey)
+	router := setupAuthRouter(mockService, &MockUserService{})
+
+	w := performRequest(router, "GET", "/api/v1/whoami", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"Missing API key or session token"}`, w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	// Un schéma autre que Bearer n'est pas une clé d'API
+	w = performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{"Authorization": "Basic dXNlcjpwYXNz"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{"Authorization": "Bearer usk_12345678_wrong"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid API key"}`, w.Body.String())
+}
+
+func TestAuthenticate_StorageError(t *testing.T) {
+	mockService := &MockAPIKeyService{}
+	mockService.On("Authenticate", "usk_12345678_secret").Return(nil, errors.New("database is locked"))
+	router := setupAuthRouter(mockService, &MockUserService{})
+
+	w := performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{APIKeyHeader: "usk_12345678_secret"})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestRequestOwner_DefaultsToNoAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mockLinkService := &MockLinkService{}
+	mockLinkService.On("GetLink", services.Owner{}, "abc123").Return(nil, gorm.ErrRecordNotFound)
+	router.GET("/api/v1/links/:shortCode", GetLinkHandler(mockLinkService))
+
+	w := performRequest(router, "GET", "/api/v1/links/abc123", nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockLinkService.AssertExpectations(t)
+}
+
+func TestSetupRoutes_RequiresCredentialsOnManagementAPIOnly(t *testing.T) {
+	mockLinkService := &MockLinkService{}
+	mockLinkService.On("GetLinkByShortCode", "abc123").Return(nil, gorm.ErrRecordNotFound)
+	mockUserService := &MockUserService{}
+	mockUserService.On("Login", "alice@example.com", "wrong").Return(nil, services.ErrInvalidCredentials)
+	router := setupTestRouter()
+	SetupRoutes(router, mockLinkService, &MockClickService{}, &MockHealthService{}, &MockAPIKeyService{}, mockUserService, &MockWorkspaceService{}, &MockUsageService{}, ChannelSink(make(chan models.ClickEvent, 1)), RateLimits{})
+
+	for _, path := range []string{"/api/v1/links", "/api/v1/links/abc123", "/api/v1/metrics", "/api/v1/auth/me"} {
+		w := performRequest(router, "GET", path, nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+	}
+
+	for path, status := range map[string]int{"/health": http.StatusOK, "/abc123": http.StatusNotFound} {
+		w := performRequest(router, "GET", path, nil, nil)
+		assert.Equal(t, status, w.Code, path)
+	}
+
+	// La connexion ne demande pas de clé d'API
+	w := performRequest(router, "POST", "/api/v1/auth/login", []byte(`{"email":"alice@example.com","password":"wrong"}`), nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid email or password"}`, w.Body.String())
+}
+
+func TestRegisterHandler(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	cmd.Cfg.Auth.RegistrationEnabled = true
+	expiresAt := time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)
+	mockUserService := &MockUserService{}
+	mockUserService.On("Register", "alice@example.com", "correct-horse").Return(&services.SessionToken{
+		Token:     "uss_token",
+		ExpiresAt: expiresAt,
+		User:      &models.User{ID: 1, Email: "alice@example.com", PasswordHash: "$2a$10$hash", CreatedAt: expiresAt},
+	}, nil)
+	mockUserService.On("Register", "alice@example.com", "short").Return(nil, fmt.Errorf("%w: too short", services.ErrInvalidPassword))
+	mockUserService.On("Register", "bob@example.com", "correct-horse").Return(nil, services.ErrEmailAlreadyUsed)
+	router := setupTestRouter()
+	router.POST("/api/v1/auth/register", RegisterHandler(mockUserService))
+
+	w := performRequest(router, "POST", "/api/v1/auth/register", []byte(`{"email":"alice@example.com","password":"correct-horse"}`), nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"token":"uss_token","expires_at":"2024-04-01T10:00:00Z",
+		"user":{"id":1,"email":"alice@example.com","created_at":"2024-04-01T10:00:00Z"}}`, w.Body.String(), "le hash du mot de passe n'est jamais exposé")
+
+	w = performRequest(router, "POST", "/api/v1/auth/register", []byte(`{"email":"alice@example.com","password":"short"}`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/register", []byte(`{"email":"bob@example.com","password":"correct-horse"}`), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/register", []byte(`{"email":"bob@example.com"}`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	cmd.Cfg.Auth.RegistrationEnabled = false
+	w = performRequest(router, "POST", "/api/v1/auth/register", []byte(`{"email":"carol@example.com","password":"correct-horse"}`), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Sans configuration, l'inscription reste fermée
+	cmd.Cfg = nil
+	w = performRequest(router, "POST", "/api/v1/auth/register", []byte(`{"email":"carol@example.com","password":"correct-horse"}`), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockUserService.AssertNotCalled(t, "Register", "carol@example.com", "correct-horse")
+}
+
+func TestLogoutHandler(t *testing.T) {
+	mockUserService := &MockUserService{}
+	mockUserService.On("AuthenticateSession", "uss_valid").Return(&models.Session{ID: 3, UserID: 9}, nil)
+	mockUserService.On("Logout", "uss_valid").Return(nil)
+	mockAPIKeyService := &MockAPIKeyService{}
+	mockAPIKeyService.On("Authenticate", "usk_12345678_admin").Return(&models.APIKey{ID: 1, Prefix: "usk_12345678"}, nil)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/auth/logout", Authenticate(mockAPIKeyService, mockUserService), LogoutHandler(mockUserService))
+
+	w := performRequest(router, "POST", "/api/v1/auth/logout", nil, map[string]string{"Authorization": "Bearer uss_valid"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockUserService.AssertCalled(t, "Logout", "uss_valid")
+
+	w = performRequest(router, "POST", "/api/v1/auth/logout", nil, map[string]string{"Authorization": "Bearer usk_12345678_admin"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "une clé d'API se révoque avec 'apikey revoke'")
+}
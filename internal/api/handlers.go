@@ -2,35 +2,110 @@ package api
 
 import (
 	"errors"
+	"expvar"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/Edofo/bitly-clone/cmd"
+	"github.com/Edofo/bitly-clone/internal/botdetect"
 	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
 	"github.com/Edofo/bitly-clone/internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(router *gin.Engine, linkService services.LinkServiceInterface, clickEventsChan chan<- models.ClickEvent) {
+// ErrClickQueueFull est retourné quand le channel des clics est plein.
+var ErrClickQueueFull = errors.New("click events channel is full")
+
+// ClickEventSink reçoit les clics émis par la redirection, sans bloquer celle-ci :
+// le channel des workers directement, ou le spool sur disque s'il est activé.
+type ClickEventSink interface {
+	Enqueue(event models.ClickEvent) error
+}
+
+// ChannelSink transmet les clics directement au channel des workers.
+type ChannelSink chan<- models.ClickEvent
+
+func (c ChannelSink) Enqueue(event models.ClickEvent) error {
+	select {
+	case c <- event:
+		return nil
+	default:
+		return ErrClickQueueFull
+	}
+}
+
+func SetupRoutes(router *gin.Engine, linkService services.LinkServiceInterface, clickService services.ClickServiceInterface, healthService services.HealthServiceInterface, apiKeyService services.APIKeyServiceInterface, userService services.UserServiceInterface, workspaceService services.WorkspaceServiceInterface, usageService services.UsageServiceInterface, clickSink ClickEventSink, rateLimits RateLimits) {
 	router.GET("/health", HealthCheckHandler)
 
-	api := router.Group("/api/v1")
+	router.POST("/api/v1/auth/register", RateLimit(rateLimits.API), RegisterHandler(userService))
+	router.POST("/api/v1/auth/login", RateLimit(rateLimits.API), LoginHandler(userService))
+
+	// L'API de gestion exige une clé d'API ou un jeton de session ; la redirection reste publique.
+	// Les requêtes sont limitées par IP avant l'authentification (clés ou jetons invalides
+	// compris), puis par clé d'API.
+	api := router.Group("/api/v1", RateLimitByIP(rateLimits.IP), Authenticate(apiKeyService, userService), RateLimit(rateLimits.API), LoadWorkspaceRoles(workspaceService))
 	{
+		api.POST("/auth/logout", LogoutHandler(userService))
+		api.GET("/auth/me", WhoAmIHandler)
 		api.POST("/links", CreateShortLinkHandler(linkService))
+		api.GET("/links", ListLinksHandler(linkService))
+		api.GET("/links/:shortCode", GetLinkHandler(linkService))
+		api.PATCH("/links/:shortCode", UpdateLinkHandler(linkService))
+		api.DELETE("/links/:shortCode", DeleteLinkHandler(linkService))
+		api.PUT("/links/:shortCode/webhook", SetLinkWebhookHandler(linkService))
+		api.DELETE("/links/:shortCode/webhook", DeleteLinkWebhookHandler(linkService))
 		api.GET("/links/:shortCode/stats", GetLinkStatsHandler(linkService))
+		api.GET("/links/:shortCode/stats/timeseries", GetLinkTimeSeriesHandler(linkService, clickService))
+		api.GET("/links/:shortCode/stats/referrers", GetLinkBreakdownHandler(linkService, clickService, repository.DimensionReferrer, "referrers"))
+		api.GET("/links/:shortCode/stats/browsers", GetLinkBreakdownHandler(linkService, clickService, repository.DimensionBrowser, "browsers"))
+		api.GET("/links/:shortCode/stats/os", GetLinkBreakdownHandler(linkService, clickService, repository.DimensionOS, "os"))
+		api.GET("/links/:shortCode/stats/devices", GetLinkBreakdownHandler(linkService, clickService, repository.DimensionDevice, "devices"))
+		api.GET("/links/:shortCode/stats/countries", GetLinkBreakdownHandler(linkService, clickService, repository.DimensionCountry, "countries"))
+		api.GET("/links/:shortCode/stats/cities", GetLinkBreakdownHandler(linkService, clickService, repository.DimensionCity, "cities"))
+		api.GET("/links/:shortCode/health", GetLinkHealthHandler(linkService, healthService))
+		api.GET("/metrics", MetricsHandler)
+		api.GET("/usage", GetUsageHandler(usageService))
+
+		api.POST("/workspaces", CreateWorkspaceHandler(workspaceService))
+		api.GET("/workspaces", ListWorkspacesHandler(workspaceService))
+		api.POST("/invitations/accept", AcceptInvitationHandler(workspaceService))
+
+		workspace := api.Group("/workspaces/:workspaceID")
+		workspace.GET("", RequireWorkspaceRole(models.RoleViewer), GetWorkspaceHandler(workspaceService))
+		workspace.GET("/links", RequireWorkspaceRole(models.RoleViewer), ListLinksHandler(linkService))
+		workspace.POST("/links", RequireWorkspaceRole(models.RoleEditor), CreateShortLinkHandler(linkService))
+		workspace.GET("/members", RequireWorkspaceRole(models.RoleViewer), ListMembersHandler(workspaceService))
+		workspace.PATCH("/members/:userID", RequireWorkspaceRole(models.RoleOwner), UpdateMemberHandler(workspaceService))
+		// Chaque membre peut se retirer lui-même : WorkspaceService vérifie le rôle des autres cas.
+		workspace.DELETE("/members/:userID", RequireWorkspaceRole(models.RoleViewer), RemoveMemberHandler(workspaceService))
+		workspace.GET("/invitations", RequireWorkspaceRole(models.RoleOwner), ListInvitationsHandler(workspaceService))
+		workspace.POST("/invitations", RequireWorkspaceRole(models.RoleOwner), CreateInvitationHandler(workspaceService))
+		workspace.DELETE("/invitations/:invitationID", RequireWorkspaceRole(models.RoleOwner), RevokeInvitationHandler(workspaceService))
 	}
 
-	router.GET("/:shortCode", RedirectHandler(linkService, clickEventsChan))
+	router.GET("/:shortCode", RateLimit(rateLimits.Redirect), RedirectHandler(linkService, clickSink))
 }
 
 func HealthCheckHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// MetricsHandler expose en JSON les compteurs publiés avec expvar (cache des liens,
+// mémoire du processus...).
+func MetricsHandler(c *gin.Context) {
+	expvar.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
 type CreateLinkRequest struct {
-	LongURL string `json:"long_url" binding:"required,url"`
+	LongURL     string     `json:"long_url" binding:"required,url"`
+	CustomAlias string     `json:"custom_alias"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	MaxClicks   int        `json:"max_clicks" binding:"min=0"`
+	WebhookURL  string     `json:"webhook_url"`
 }
 
 func CreateShortLinkHandler(linkService services.LinkServiceInterface) gin.HandlerFunc {
@@ -41,22 +116,288 @@ func CreateShortLinkHandler(linkService services.LinkServiceInterface) gin.Handl
 			return
 		}
 
-		link, err := linkService.CreateLink(req.LongURL)
+		link, err := linkService.CreateLink(requestOwner(c), req.LongURL, services.CreateLinkOptions{
+			CustomAlias: req.CustomAlias,
+			ExpiresAt:   req.ExpiresAt,
+			MaxClicks:   req.MaxClicks,
+			WebhookURL:  req.WebhookURL,
+			WorkspaceID: workspaceIDFromContext(c),
+		})
 		if err != nil {
+			if errors.Is(err, services.ErrInvalidAlias) || errors.Is(err, services.ErrReservedAlias) ||
+				errors.Is(err, services.ErrInvalidExpiration) || errors.Is(err, services.ErrInvalidWebhookURL) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, services.ErrAliasAlreadyExists) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, services.ErrInsufficientRole) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, services.ErrWorkspaceNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+				return
+			}
+			if errors.Is(err, services.ErrLinkQuotaExceeded) {
+				c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+				return
+			}
 			log.Printf("Error creating link: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link"})
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{
-			"short_code":     link.ShortCode,
-			"long_url":       link.LongURL,
-			"full_short_url": cmd.Cfg.Server.BaseURL + "/" + link.ShortCode,
+		c.JSON(http.StatusCreated, withWebhookSecret(linkResponse(link), link))
+	}
+}
+
+// withWebhookSecret ajoute le secret de signature du webhook du lien, affiché seulement
+// lorsqu'il vient d'être généré.
+func withWebhookSecret(response gin.H, link *models.Link) gin.H {
+	if link.WebhookSecret != "" {
+		response["webhook_secret"] = link.WebhookSecret
+	}
+	return response
+}
+
+func linkResponse(link *models.Link) gin.H {
+	response := gin.H{
+		"short_code":     link.ShortCode,
+		"long_url":       link.LongURL,
+		"full_short_url": cmd.Cfg.Server.BaseURL + "/" + link.ShortCode,
+		"created_at":     link.CreatedAt,
+	}
+	if link.ExpiresAt != nil {
+		response["expires_at"] = link.ExpiresAt
+	}
+	if link.HasClickBudget() {
+		response["max_clicks"] = link.MaxClicks
+		response["consumed_clicks"] = link.ConsumedClicks
+	}
+	if link.WebhookURL != "" {
+		response["webhook_url"] = link.WebhookURL
+	}
+	if link.WorkspaceID != nil {
+		response["workspace_id"] = *link.WorkspaceID
+	}
+	return response
+}
+
+type ListLinksQuery struct {
+	Page          int    `form:"page" binding:"omitempty,min=1"`
+	PageSize      int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Sort          string `form:"sort" binding:"omitempty,oneof=created_at short_code long_url"`
+	Order         string `form:"order" binding:"omitempty,oneof=asc desc"`
+	CreatedAfter  string `form:"created_after"`
+	CreatedBefore string `form:"created_before"`
+	Domain        string `form:"domain"`
+}
+
+// parseTimeParam accepte une date RFC3339 ou une date simple (AAAA-MM-JJ, interprétée en UTC).
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := services.ParseDateTime(value, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func ListLinksHandler(linkService services.LinkServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query ListLinksQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		createdAfter, err := parseTimeParam(query.CreatedAfter)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		createdBefore, err := parseTimeParam(query.CreatedBefore)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter := repository.LinkFilter{
+			Page:          query.Page,
+			PageSize:      query.PageSize,
+			SortBy:        query.Sort,
+			SortDesc:      query.Order == "desc",
+			CreatedAfter:  createdAfter,
+			CreatedBefore: createdBefore,
+			Domain:        query.Domain,
+			WorkspaceID:   workspaceIDFromContext(c),
+		}
+
+		links, total, err := linkService.ListLinks(requestOwner(c), filter)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidLinkFilter) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, services.ErrWorkspaceNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+				return
+			}
+			log.Printf("Error listing links: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		items := make([]gin.H, 0, len(links))
+		for i := range links {
+			items = append(items, linkResponse(&links[i]))
+		}
+
+		page := query.Page
+		if page == 0 {
+			page = 1
+		}
+		pageSize := query.PageSize
+		if pageSize == 0 {
+			pageSize = services.DefaultPageSize
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"links":     items,
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
 		})
 	}
 }
 
-func RedirectHandler(linkService services.LinkServiceInterface, clickEventsChan chan<- models.ClickEvent) gin.HandlerFunc {
+func GetLinkHandler(linkService services.LinkServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		link, err := linkService.GetLink(requestOwner(c), shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			log.Printf("Error retrieving link %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, linkResponse(link))
+	}
+}
+
+type UpdateLinkRequest struct {
+	LongURL string `json:"long_url" binding:"required,url"`
+}
+
+func UpdateLinkHandler(linkService services.LinkServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req UpdateLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		link, err := linkService.UpdateLinkURL(requestOwner(c), shortCode, req.LongURL)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			if errors.Is(err, services.ErrInsufficientRole) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error updating link %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update link"})
+			return
+		}
+
+		c.JSON(http.StatusOK, linkResponse(link))
+	}
+}
+
+func DeleteLinkHandler(linkService services.LinkServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		if err := linkService.DeleteLink(requestOwner(c), shortCode); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			if errors.Is(err, services.ErrInsufficientRole) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error deleting link %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete link"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type SetLinkWebhookRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// SetLinkWebhookHandler définit le webhook prévenu des changements d'état d'un lien.
+func SetLinkWebhookHandler(linkService services.LinkServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SetLinkWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateLinkWebhook(c, linkService, req.URL)
+	}
+}
+
+func DeleteLinkWebhookHandler(linkService services.LinkServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		updateLinkWebhook(c, linkService, "")
+	}
+}
+
+func updateLinkWebhook(c *gin.Context, linkService services.LinkServiceInterface, webhookURL string) {
+	shortCode := c.Param("shortCode")
+
+	link, err := linkService.SetLinkWebhook(requestOwner(c), shortCode, webhookURL)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWebhookURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			return
+		}
+		if errors.Is(err, services.ErrInsufficientRole) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error updating webhook of link %s: %v", shortCode, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, withWebhookSecret(linkResponse(link), link))
+}
+
+func RedirectHandler(linkService services.LinkServiceInterface, clickSink ClickEventSink) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		shortCode := c.Param("shortCode")
 
@@ -71,29 +412,125 @@ func RedirectHandler(linkService services.LinkServiceInterface, clickEventsChan
 			return
 		}
 
+		// Les robots (aperçus de liens, crawlers) sont redirigés mais exclus des statistiques :
+		// ils ne consomment pas non plus le budget de clics du lien.
+		isBot := botdetect.IsBotRequest(c.Request)
+		checkAvailability := linkService.ConsumeClick
+		if isBot {
+			checkAvailability = linkService.CheckAvailable
+		}
+		if err := checkAvailability(link); err != nil {
+			if errors.Is(err, services.ErrLinkExpired) {
+				if fallbackURL := expiredFallbackURL(); fallbackURL != "" {
+					c.Redirect(http.StatusFound, fallbackURL)
+					return
+				}
+				c.JSON(http.StatusGone, gin.H{"error": "Link has expired"})
+				return
+			}
+			log.Printf("Error checking availability of %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
 		clickEvent := models.ClickEvent{
 			LinkID:    link.ID,
-			Timestamp: time.Now(),
+			Timestamp: time.Now().UTC(),
 			UserAgent: c.GetHeader("User-Agent"),
 			IPAddress: c.ClientIP(),
+			Referrer:  c.Request.Referer(),
+			IsBot:     isBot,
 		}
 
-		select {
-		case clickEventsChan <- clickEvent:
-			log.Printf("Click event for %s sent to channel", shortCode)
-		default:
-			log.Printf("Warning: ClickEventsChannel is full, dropping click event for %s.", shortCode)
+		if err := clickSink.Enqueue(clickEvent); err != nil {
+			log.Printf("Warning: Dropping click event for %s: %v", shortCode, err)
+		} else {
+			log.Printf("Click event for %s queued", shortCode)
 		}
 
 		c.Redirect(http.StatusFound, link.LongURL)
 	}
 }
 
+func expiredFallbackURL() string {
+	if cmd.Cfg == nil {
+		return ""
+	}
+	return cmd.Cfg.Links.ExpiredFallbackURL
+}
+
+// ClickFilterQuery regroupe les paramètres communs aux endpoints de statistiques.
+// Les clics de robots sont exclus sauf si include_bots=true.
+type ClickFilterQuery struct {
+	IncludeBots bool `form:"include_bots"`
+}
+
+func (q ClickFilterQuery) filter() repository.ClickFilter {
+	return repository.ClickFilter{IncludeBots: q.IncludeBots}
+}
+
+// TimeRangeQuery borne la période des statistiques. Les dates simples (AAAA-MM-JJ)
+// sont interprétées dans le fuseau tz (UTC par défaut).
+type TimeRangeQuery struct {
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Timezone string `form:"tz"`
+}
+
+// parse retourne le fuseau demandé et les bornes de la période, nulles si absentes.
+func (q TimeRangeQuery) parse() (time.Time, time.Time, *time.Location, error) {
+	var from, to time.Time
+	loc := time.UTC
+	if q.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(q.Timezone)
+		if err != nil {
+			return from, to, nil, fmt.Errorf("Unknown timezone '%s'", q.Timezone)
+		}
+	}
+	if q.From != "" {
+		var err error
+		if from, err = services.ParseDateTime(q.From, loc); err != nil {
+			return from, to, nil, err
+		}
+	}
+	if q.To != "" {
+		var err error
+		if to, err = services.ParseDateTime(q.To, loc); err != nil {
+			return from, to, nil, err
+		}
+	}
+	return from, to, loc, nil
+}
+
+type StatsQuery struct {
+	ClickFilterQuery
+	TimeRangeQuery
+}
+
 func GetLinkStatsHandler(linkService services.LinkServiceInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		shortCode := c.Param("shortCode")
 
-		link, totalClicks, err := linkService.GetLinkStats(shortCode)
+		var query StatsQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter := query.filter()
+		var err error
+		filter.From, filter.To, _, err = query.parse()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'from' must be before 'to'"})
+			return
+		}
+
+		link, totals, err := linkService.GetLinkStats(requestOwner(c), shortCode, filter)
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
@@ -104,10 +541,201 @@ func GetLinkStatsHandler(linkService services.LinkServiceInterface) gin.HandlerF
 			return
 		}
 
+		response := gin.H{
+			"short_code":      link.ShortCode,
+			"long_url":        link.LongURL,
+			"total_clicks":    totals.TotalClicks,
+			"unique_visitors": totals.UniqueVisitors,
+			"include_bots":    query.IncludeBots,
+		}
+		if !filter.From.IsZero() {
+			response["from"] = filter.From
+		}
+		if !filter.To.IsZero() {
+			response["to"] = filter.To
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+type TimeSeriesQuery struct {
+	Interval string `form:"interval" binding:"omitempty,oneof=hour day week month"`
+	TimeRangeQuery
+	ClickFilterQuery
+}
+
+func GetLinkTimeSeriesHandler(linkService services.LinkServiceInterface, clickService services.ClickServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var query TimeSeriesQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		from, to, loc, err := query.parse()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		seriesQuery := services.TimeSeriesQuery{
+			Interval: repository.TimeInterval(query.Interval),
+			From:     from,
+			To:       to,
+			Location: loc,
+			Filter:   query.filter(),
+		}
+
+		link, err := linkService.GetLink(requestOwner(c), shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			log.Printf("Error retrieving link %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		buckets, err := clickService.GetClickTimeSeries(link.ID, seriesQuery)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTimeSeries) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error getting time series for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		total := 0
+		for _, bucket := range buckets {
+			total += bucket.Count
+		}
+
+		interval := query.Interval
+		if interval == "" {
+			interval = string(repository.IntervalDay)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"short_code":   link.ShortCode,
+			"interval":     interval,
+			"timezone":     loc.String(),
+			"buckets":      buckets,
+			"total_clicks": total,
+			"include_bots": query.IncludeBots,
+		})
+	}
+}
+
+type BreakdownQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+	ClickFilterQuery
+}
+
+// GetLinkBreakdownHandler expose la répartition des clics d'un lien selon une dimension
+// (référents, navigateurs...), sous la clé JSON responseKey.
+func GetLinkBreakdownHandler(linkService services.LinkServiceInterface, clickService services.ClickServiceInterface, dimension repository.ClickDimension, responseKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var query BreakdownQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		link, err := linkService.GetLink(requestOwner(c), shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			log.Printf("Error retrieving link %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		stats, err := clickService.GetClickBreakdown(link.ID, dimension, query.Limit, query.filter())
+		if err != nil {
+			log.Printf("Error getting %s breakdown for %s: %v", dimension, shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"short_code":   link.ShortCode,
-			"long_url":     link.LongURL,
-			"total_clicks": totalClicks,
+			responseKey:    stats,
+			"include_bots": query.IncludeBots,
+		})
+	}
+}
+
+type HealthQuery struct {
+	Window string `form:"window"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// GetLinkHealthHandler expose l'état de l'URL longue d'un lien relevé par le moniteur :
+// dernier état, disponibilité sur la période window (24h par défaut, ex: 7d) et dernières vérifications.
+func GetLinkHealthHandler(linkService services.LinkServiceInterface, healthService services.HealthServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var query HealthQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		healthQuery := services.HealthQuery{Limit: query.Limit}
+		if query.Window != "" {
+			window, err := services.ParseWindow(query.Window)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			healthQuery.Window = window
+		}
+
+		link, err := linkService.GetLink(requestOwner(c), shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			log.Printf("Error retrieving link %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		health, err := healthService.GetLinkHealth(link.ID, healthQuery)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidHealthQuery) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error getting health history for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		history := health.History
+		if history == nil {
+			history = []models.HealthCheck{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"short_code":      link.ShortCode,
+			"long_url":        link.LongURL,
+			"status":          health.Status,
+			"last_checked_at": health.LastCheckedAt,
+			"uptime_percent":  health.UptimePercent,
+			"checks":          health.Checks,
+			"window":          services.FormatWindow(health.Window),
+			"history":         history,
 		})
 	}
 }
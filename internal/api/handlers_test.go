@@ -3,6 +3,8 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +13,8 @@ import (
 	"github.com/Edofo/bitly-clone/cmd"
 	"github.com/Edofo/bitly-clone/internal/config"
 	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/Edofo/bitly-clone/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -26,8 +30,8 @@ func (m *MockLinkService) GenerateShortCode(length int) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockLinkService) CreateLink(longURL string) (*models.Link, error) {
-	args := m.Called(longURL)
+func (m *MockLinkService) CreateLink(owner services.Owner, longURL string, opts services.CreateLinkOptions) (*models.Link, error) {
+	args := m.Called(owner, longURL, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -42,17 +46,101 @@ func (m *MockLinkService) GetLinkByShortCode(shortCode string) (*models.Link, er
 	return args.Get(0).(*models.Link), args.Error(1)
 }
 
-func (m *MockLinkService) GetLinkStats(shortCode string) (*models.Link, int, error) {
-	args := m.Called(shortCode)
+func (m *MockLinkService) GetLink(owner services.Owner, shortCode string) (*models.Link, error) {
+	args := m.Called(owner, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Link), args.Error(1)
+}
+
+func (m *MockLinkService) GetLinkStats(owner services.Owner, shortCode string, filter repository.ClickFilter) (*models.Link, models.ClickTotals, error) {
+	args := m.Called(owner, shortCode, filter)
+	if args.Get(0) == nil {
+		return nil, models.ClickTotals{}, args.Error(2)
+	}
+	return args.Get(0).(*models.Link), args.Get(1).(models.ClickTotals), args.Error(2)
+}
+
+func (m *MockLinkService) ConsumeClick(link *models.Link) error {
+	args := m.Called(link)
+	return args.Error(0)
+}
+
+func (m *MockLinkService) CheckAvailable(link *models.Link) error {
+	args := m.Called(link)
+	return args.Error(0)
+}
+
+func (m *MockLinkService) ListLinks(owner services.Owner, filter repository.LinkFilter) ([]models.Link, int64, error) {
+	args := m.Called(owner, filter)
 	if args.Get(0) == nil {
-		return nil, args.Int(1), args.Error(2)
+		return nil, 0, args.Error(2)
 	}
-	return args.Get(0).(*models.Link), args.Int(1), args.Error(2)
+	return args.Get(0).([]models.Link), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockLinkService) UpdateLinkURL(owner services.Owner, shortCode string, longURL string) (*models.Link, error) {
+	args := m.Called(owner, shortCode, longURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Link), args.Error(1)
+}
+
+func (m *MockLinkService) DeleteLink(owner services.Owner, shortCode string) error {
+	args := m.Called(owner, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockLinkService) SetLinkWebhook(owner services.Owner, shortCode string, webhookURL string) (*models.Link, error) {
+	args := m.Called(owner, shortCode, webhookURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Link), args.Error(1)
+}
+
+type MockClickService struct {
+	mock.Mock
+}
+
+func (m *MockClickService) GetClickTimeSeries(linkID uint, query services.TimeSeriesQuery) ([]models.ClickBucket, error) {
+	args := m.Called(linkID, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ClickBucket), args.Error(1)
+}
+
+func (m *MockClickService) GetClickBreakdown(linkID uint, dimension repository.ClickDimension, limit int, filter repository.ClickFilter) ([]models.ClickStat, error) {
+	args := m.Called(linkID, dimension, limit, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ClickStat), args.Error(1)
+}
+
+type MockHealthService struct {
+	mock.Mock
+}
+
+func (m *MockHealthService) GetLinkHealth(linkID uint, query services.HealthQuery) (*services.LinkHealth, error) {
+	args := m.Called(linkID, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.LinkHealth), args.Error(1)
+}
+
+// setupTestRouter retourne un routeur dont les requêtes ont accès à tous les liens,
+// comme avec une clé d'API d'administration.
 func setupTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ownerContextKey, services.AdminOwner)
+	})
 	return router
 }
 
@@ -72,6 +160,25 @@ func TestHealthCheckHandler(t *testing.T) {
 	assert.Equal(t, "ok", response["status"])
 }
 
+func TestMetricsHandler(t *testing.T) {
+	expvar.Publish("test_link_cache", expvar.Func(func() any {
+		return repository.LinkCacheStats{Hits: 3, Misses: 1}
+	}))
+	router := setupTestRouter()
+	router.GET("/api/v1/metrics", MetricsHandler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/metrics", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.JSONEq(t, `{"hits":3,"negative_hits":0,"misses":1,"errors":0}`, string(response["test_link_cache"]))
+	assert.Contains(t, response, "memstats")
+}
+
 func TestCreateShortLinkHandler_Success(t *testing.T) {
 	cmd.Cfg = &config.Config{}
 	cmd.Cfg.Server.Port = 8080
@@ -99,7 +206,7 @@ func TestCreateShortLinkHandler_Success(t *testing.T) {
 		LongURL:   "https://www.example.com",
 		CreatedAt: time.Now(),
 	}
-	mockService.On("CreateLink", "https://www.example.com").Return(expectedLink, nil)
+	mockService.On("CreateLink", services.AdminOwner, "https://www.example.com", services.CreateLinkOptions{}).Return(expectedLink, nil)
 	
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/api/v1/links", bytes.NewBuffer(jsonData))
@@ -159,7 +266,7 @@ func TestCreateShortLinkHandler_ServiceError(t *testing.T) {
 	}
 	jsonData, _ := json.Marshal(requestBody)
 	
-	mockService.On("CreateLink", "https://www.example.com").Return(nil, assert.AnError)
+	mockService.On("CreateLink", services.AdminOwner, "https://www.example.com", services.CreateLinkOptions{}).Return(nil, assert.AnError)
 	
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/api/v1/links", bytes.NewBuffer(jsonData))
@@ -177,12 +284,20 @@ func TestCreateShortLinkHandler_ServiceError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+// newBrowserRequest simule la visite d'un navigateur, que botdetect ne prend pas pour un robot.
+func newBrowserRequest(path string) *http.Request {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html")
+	return req
+}
+
 func TestRedirectHandler_Success(t *testing.T) {
 	router := setupTestRouter()
 	mockService := &MockLinkService{}
 	clickEventsChan := make(chan models.ClickEvent, 1)
 	
-	router.GET("/:shortCode", RedirectHandler(mockService, clickEventsChan))
+	router.GET("/:shortCode", RedirectHandler(mockService, ChannelSink(clickEventsChan)))
 	
 	expectedLink := &models.Link{
 		ID:        1,
@@ -191,9 +306,10 @@ func TestRedirectHandler_Success(t *testing.T) {
 		CreatedAt: time.Now(),
 	}
 	mockService.On("GetLinkByShortCode", "abc123").Return(expectedLink, nil)
+	mockService.On("ConsumeClick", expectedLink).Return(nil)
 	
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/abc123", nil)
+	req := newBrowserRequest("/abc123")
 	req.Header.Set("User-Agent", "Mozilla/5.0")
 	req.RemoteAddr = "192.168.1.1:12345"
 	router.ServeHTTP(w, req)
@@ -209,7 +325,7 @@ func TestRedirectHandler_LinkNotFound(t *testing.T) {
 	mockService := &MockLinkService{}
 	clickEventsChan := make(chan models.ClickEvent, 1)
 	
-	router.GET("/:shortCode", RedirectHandler(mockService, clickEventsChan))
+	router.GET("/:shortCode", RedirectHandler(mockService, ChannelSink(clickEventsChan)))
 	
 	mockService.On("GetLinkByShortCode", "nonexistent").Return(nil, gorm.ErrRecordNotFound)
 	
@@ -233,7 +349,7 @@ func TestRedirectHandler_ServiceError(t *testing.T) {
 	mockService := &MockLinkService{}
 	clickEventsChan := make(chan models.ClickEvent, 1)
 	
-	router.GET("/:shortCode", RedirectHandler(mockService, clickEventsChan))
+	router.GET("/:shortCode", RedirectHandler(mockService, ChannelSink(clickEventsChan)))
 	
 	mockService.On("GetLinkByShortCode", "error").Return(nil, assert.AnError)
 	
@@ -267,7 +383,7 @@ func TestGetLinkStatsHandler_Success(t *testing.T) {
 		LongURL:   "https://www.example.com",
 		CreatedAt: time.Now(),
 	}
-	mockService.On("GetLinkStats", "abc123").Return(expectedLink, 42, nil)
+	mockService.On("GetLinkStats", services.AdminOwner, "abc123", repository.ClickFilter{}).Return(expectedLink, models.ClickTotals{TotalClicks: 42, UniqueVisitors: 30}, nil)
 	
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/api/v1/links/abc123/stats", nil)
@@ -281,10 +397,81 @@ func TestGetLinkStatsHandler_Success(t *testing.T) {
 	assert.Equal(t, "abc123", response["short_code"])
 	assert.Equal(t, "https://www.example.com", response["long_url"])
 	assert.Equal(t, float64(42), response["total_clicks"])
+	assert.Equal(t, float64(30), response["unique_visitors"])
 	
 	mockService.AssertExpectations(t)
 }
 
+func TestGetLinkStatsHandler_IncludeBots(t *testing.T) {
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.GET("/api/v1/links/:shortCode/stats", GetLinkStatsHandler(mockService))
+
+	expectedLink := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+	mockService.On("GetLinkStats", services.AdminOwner, "abc123", repository.ClickFilter{IncludeBots: true}).Return(expectedLink, models.ClickTotals{TotalClicks: 57, UniqueVisitors: 31}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/abc123/stats?include_bots=true", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(57), response["total_clicks"])
+	assert.Equal(t, true, response["include_bots"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/links/abc123/stats?include_bots=maybe", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetLinkStatsHandler_TimeRange(t *testing.T) {
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.GET("/api/v1/links/:shortCode/stats", GetLinkStatsHandler(mockService))
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	filter := repository.ClickFilter{
+		From: time.Date(2024, 3, 1, 0, 0, 0, 0, paris),
+		To:   time.Date(2024, 4, 1, 0, 0, 0, 0, paris),
+	}
+	expectedLink := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+	mockService.On("GetLinkStats", services.AdminOwner, "abc123", filter).Return(expectedLink, models.ClickTotals{TotalClicks: 12, UniqueVisitors: 5}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/abc123/stats?from=2024-03-01&to=2024-04-01&tz=Europe/Paris", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(12), response["total_clicks"])
+	assert.Equal(t, float64(5), response["unique_visitors"])
+	assert.Equal(t, "2024-03-01T00:00:00+01:00", response["from"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/links/abc123/stats?from=2024-04-01&to=2024-03-01", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/links/abc123/stats?tz=Mars/Olympus", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
 func TestGetLinkStatsHandler_LinkNotFound(t *testing.T) {
 	router := setupTestRouter()
 	mockService := &MockLinkService{}
@@ -294,7 +481,7 @@ func TestGetLinkStatsHandler_LinkNotFound(t *testing.T) {
 		api.GET("/links/:shortCode/stats", GetLinkStatsHandler(mockService))
 	}
 	
-	mockService.On("GetLinkStats", "nonexistent").Return(nil, 0, gorm.ErrRecordNotFound)
+	mockService.On("GetLinkStats", services.AdminOwner, "nonexistent", repository.ClickFilter{}).Return(nil, models.ClickTotals{}, gorm.ErrRecordNotFound)
 	
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/api/v1/links/nonexistent/stats", nil)
@@ -320,7 +507,7 @@ func TestGetLinkStatsHandler_ServiceError(t *testing.T) {
 		api.GET("/links/:shortCode/stats", GetLinkStatsHandler(mockService))
 	}
 	
-	mockService.On("GetLinkStats", "error").Return(nil, 0, assert.AnError)
+	mockService.On("GetLinkStats", services.AdminOwner, "error", repository.ClickFilter{}).Return(nil, models.ClickTotals{}, assert.AnError)
 	
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/api/v1/links/error/stats", nil)
@@ -343,7 +530,7 @@ func TestRedirectHandler_ClickEventChannel(t *testing.T) {
 	router := setupTestRouter()
 	mockService := &MockLinkService{}
 	
-	router.GET("/:shortCode", RedirectHandler(mockService, clickEventsChan))
+	router.GET("/:shortCode", RedirectHandler(mockService, ChannelSink(clickEventsChan)))
 	
 	expectedLink := &models.Link{
 		ID:        1,
@@ -352,10 +539,13 @@ func TestRedirectHandler_ClickEventChannel(t *testing.T) {
 		CreatedAt: time.Now(),
 	}
 	mockService.On("GetLinkByShortCode", "abc123").Return(expectedLink, nil)
+	mockService.On("ConsumeClick", expectedLink).Return(nil)
 	
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/abc123", nil)
 	req.Header.Set("User-Agent", "TestAgent")
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("Referer", "https://www.google.com/search?q=go")
 	req.RemoteAddr = "192.168.1.1:12345"
 	
 	go router.ServeHTTP(w, req)
@@ -365,9 +555,723 @@ func TestRedirectHandler_ClickEventChannel(t *testing.T) {
 		assert.Equal(t, uint(1), clickEvent.LinkID)
 		assert.Equal(t, "TestAgent", clickEvent.UserAgent)
 		assert.Equal(t, "192.168.1.1", clickEvent.IPAddress)
+		assert.Equal(t, "https://www.google.com/search?q=go", clickEvent.Referrer)
 	case <-time.After(1 * time.Second):
 		t.Fatal("Timeout waiting for click event")
 	}
 	
 	mockService.AssertExpectations(t)
-}
\ No newline at end of file
+}
+
+func TestRedirectHandler_FlagsBotClicks(t *testing.T) {
+	clickEventsChan := make(chan models.ClickEvent, 2)
+
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.GET("/:shortCode", RedirectHandler(mockService, ChannelSink(clickEventsChan)))
+
+	expectedLink := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+	mockService.On("GetLinkByShortCode", "abc123").Return(expectedLink, nil)
+	mockService.On("ConsumeClick", expectedLink).Return(nil).Once()
+	mockService.On("CheckAvailable", expectedLink).Return(nil).Once()
+
+	// Navigateur classique : le clic est compté comme humain
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/abc123", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.False(t, (<-clickEventsChan).IsBot)
+
+	// Robot d'aperçu de lien : toujours redirigé, mais marqué
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/abc123", nil)
+	req.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)")
+	req.Header.Set("Accept", "*/*")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.True(t, (<-clickEventsChan).IsBot)
+
+	// Le robot ne consomme pas le budget de clics du lien
+	mockService.AssertNumberOfCalls(t, "ConsumeClick", 1)
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectHandler_BotOnExhaustedLink(t *testing.T) {
+	clickEventsChan := make(chan models.ClickEvent, 1)
+
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.GET("/:shortCode", RedirectHandler(mockService, ChannelSink(clickEventsChan)))
+
+	exhaustedLink := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com", MaxClicks: 1, ConsumedClicks: 1}
+	mockService.On("GetLinkByShortCode", "abc123").Return(exhaustedLink, nil)
+	mockService.On("CheckAvailable", exhaustedLink).Return(services.ErrLinkExpired)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/abc123", nil)
+	req.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Empty(t, clickEventsChan)
+	mockService.AssertNotCalled(t, "ConsumeClick", mock.Anything)
+}
+type failingClickSink struct{}
+
+func (failingClickSink) Enqueue(event models.ClickEvent) error {
+	return errors.New("click spool is full")
+}
+
+func TestRedirectHandler_RedirectsWhenClickIsDropped(t *testing.T) {
+	mockService := &MockLinkService{}
+
+	// Channel plein puis spool saturé : la redirection ne doit jamais en souffrir
+	fullChan := make(chan models.ClickEvent)
+	for _, sink := range []ClickEventSink{ChannelSink(fullChan), failingClickSink{}} {
+		router := setupTestRouter()
+		router.GET("/:shortCode", RedirectHandler(mockService, sink))
+
+		expectedLink := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+		mockService.On("GetLinkByShortCode", "abc123").Return(expectedLink, nil)
+		mockService.On("ConsumeClick", expectedLink).Return(nil)
+
+		w := httptest.NewRecorder()
+		req := newBrowserRequest("/abc123")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://www.example.com", w.Header().Get("Location"))
+	}
+}
+
+func TestCreateShortLinkHandler_CustomAlias(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	cmd.Cfg.Server.BaseURL = "http://localhost:8080"
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.POST("/api/v1/links", CreateShortLinkHandler(mockService))
+
+	opts := services.CreateLinkOptions{CustomAlias: "spring-sale"}
+	expectedLink := &models.Link{
+		ID:        1,
+		ShortCode: "spring-sale",
+		LongURL:   "https://www.example.com",
+		CreatedAt: time.Now(),
+	}
+	mockService.On("CreateLink", services.AdminOwner, "https://www.example.com", opts).Return(expectedLink, nil)
+
+	jsonData, _ := json.Marshal(CreateLinkRequest{LongURL: "https://www.example.com", CustomAlias: "spring-sale"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/links", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "spring-sale", response["short_code"])
+	assert.Equal(t, "http://localhost:8080/spring-sale", response["full_short_url"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateShortLinkHandler_AliasErrors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{"conflict", services.ErrAliasAlreadyExists, http.StatusConflict},
+		{"invalid", services.ErrInvalidAlias, http.StatusBadRequest},
+		{"reserved", services.ErrReservedAlias, http.StatusBadRequest},
+		{"invalid expiration", services.ErrInvalidExpiration, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := setupTestRouter()
+			mockService := &MockLinkService{}
+			router.POST("/api/v1/links", CreateShortLinkHandler(mockService))
+
+			opts := services.CreateLinkOptions{CustomAlias: "taken"}
+			mockService.On("CreateLink", services.AdminOwner, "https://www.example.com", opts).Return(nil, tc.err)
+
+			jsonData, _ := json.Marshal(CreateLinkRequest{LongURL: "https://www.example.com", CustomAlias: "taken"})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/links", bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRedirectHandler_LinkExpired(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+	clickEventsChan := make(chan models.ClickEvent, 1)
+
+	router.GET("/:shortCode", RedirectHandler(mockService, ChannelSink(clickEventsChan)))
+
+	expiredLink := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+	mockService.On("GetLinkByShortCode", "abc123").Return(expiredLink, nil)
+	mockService.On("ConsumeClick", expiredLink).Return(services.ErrLinkExpired)
+
+	w := httptest.NewRecorder()
+	req := newBrowserRequest("/abc123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Empty(t, clickEventsChan)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectHandler_LinkExpiredWithFallback(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	cmd.Cfg.Links.ExpiredFallbackURL = "https://www.example.com/expired"
+	defer func() { cmd.Cfg.Links.ExpiredFallbackURL = "" }()
+
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+	clickEventsChan := make(chan models.ClickEvent, 1)
+
+	router.GET("/:shortCode", RedirectHandler(mockService, ChannelSink(clickEventsChan)))
+
+	expiredLink := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+	mockService.On("GetLinkByShortCode", "abc123").Return(expiredLink, nil)
+	mockService.On("ConsumeClick", expiredLink).Return(services.ErrLinkExpired)
+
+	w := httptest.NewRecorder()
+	req := newBrowserRequest("/abc123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://www.example.com/expired", w.Header().Get("Location"))
+	assert.Empty(t, clickEventsChan)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateShortLinkHandler_WithExpiration(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	cmd.Cfg.Server.BaseURL = "http://localhost:8080"
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.POST("/api/v1/links", CreateShortLinkHandler(mockService))
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := services.CreateLinkOptions{ExpiresAt: &expiresAt, MaxClicks: 50}
+	expectedLink := &models.Link{
+		ID:        1,
+		ShortCode: "abc123",
+		LongURL:   "https://www.example.com",
+		ExpiresAt: &expiresAt,
+		MaxClicks: 50,
+	}
+	mockService.On("CreateLink", services.AdminOwner, "https://www.example.com", opts).Return(expectedLink, nil)
+
+	body := `{"long_url":"https://www.example.com","expires_at":"2030-01-01T00:00:00Z","max_clicks":50}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/links", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "2030-01-01T00:00:00Z", response["expires_at"])
+	assert.Equal(t, float64(50), response["max_clicks"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListLinksHandler_Success(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	cmd.Cfg.Server.BaseURL = "http://localhost:8080"
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.GET("/api/v1/links", ListLinksHandler(mockService))
+
+	createdAfter := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	expectedFilter := repository.LinkFilter{
+		Page:         2,
+		PageSize:     10,
+		SortBy:       "short_code",
+		SortDesc:     true,
+		CreatedAfter: &createdAfter,
+		Domain:       "example.com",
+	}
+	links := []models.Link{
+		{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"},
+		{ID: 2, ShortCode: "def456", LongURL: "https://example.com/page"},
+	}
+	mockService.On("ListLinks", services.AdminOwner, expectedFilter).Return(links, int64(12), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links?page=2&page_size=10&sort=short_code&order=desc&created_after=2024-03-01&domain=example.com", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(12), response["total"])
+	assert.Equal(t, float64(2), response["page"])
+	assert.Equal(t, float64(10), response["page_size"])
+	assert.Len(t, response["links"], 2)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListLinksHandler_InvalidQuery(t *testing.T) {
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.GET("/api/v1/links", ListLinksHandler(mockService))
+
+	for _, query := range []string{"sort=password", "page_size=1000", "order=sideways", "created_before=yesterday"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/links?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	mockService.AssertNotCalled(t, "ListLinks", mock.Anything)
+}
+
+func TestGetLinkHandler(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	cmd.Cfg.Server.BaseURL = "http://localhost:8080"
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.GET("/api/v1/links/:shortCode", GetLinkHandler(mockService))
+
+	mockService.On("GetLink", services.AdminOwner, "abc123").Return(&models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}, nil)
+	mockService.On("GetLink", services.AdminOwner, "nonexistent").Return(nil, gorm.ErrRecordNotFound)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/abc123", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", response["short_code"])
+	assert.Equal(t, "http://localhost:8080/abc123", response["full_short_url"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/links/nonexistent", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateLinkHandler(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	cmd.Cfg.Server.BaseURL = "http://localhost:8080"
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.PATCH("/api/v1/links/:shortCode", UpdateLinkHandler(mockService))
+
+	updated := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.org"}
+	mockService.On("UpdateLinkURL", services.AdminOwner, "abc123", "https://www.example.org").Return(updated, nil)
+	mockService.On("UpdateLinkURL", services.AdminOwner, "nonexistent", "https://www.example.org").Return(nil, gorm.ErrRecordNotFound)
+
+	body := `{"long_url":"https://www.example.org"}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/api/v1/links/abc123", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.example.org", response["long_url"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/api/v1/links/nonexistent", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/api/v1/links/abc123", bytes.NewBufferString(`{"long_url":"not-a-url"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestSetLinkWebhookHandler(t *testing.T) {
+	cmd.Cfg = &config.Config{}
+	cmd.Cfg.Server.BaseURL = "http://localhost:8080"
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.PUT("/api/v1/links/:shortCode/webhook", SetLinkWebhookHandler(mockService))
+	router.DELETE("/api/v1/links/:shortCode/webhook", DeleteLinkWebhookHandler(mockService))
+
+	updated := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com", WebhookURL: "https://hooks.example.org/links", WebhookSecret: "s3cret"}
+	mockService.On("SetLinkWebhook", services.AdminOwner, "abc123", "https://hooks.example.org/links").Return(updated, nil)
+	mockService.On("SetLinkWebhook", services.AdminOwner, "abc123", "ftp://hooks.example.org").Return(nil, services.ErrInvalidWebhookURL)
+	mockService.On("SetLinkWebhook", services.AdminOwner, "abc123", "").Return(&models.Link{ID: 1, ShortCode: "abc123"}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/links/abc123/webhook", bytes.NewBufferString(`{"url":"https://hooks.example.org/links"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "https://hooks.example.org/links", response["webhook_url"])
+	assert.Equal(t, "s3cret", response["webhook_secret"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/links/abc123/webhook", bytes.NewBufferString(`{"url":"ftp://hooks.example.org"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/links/abc123/webhook", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/links/abc123/webhook", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response = nil
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotContains(t, response, "webhook_url")
+	assert.NotContains(t, response, "webhook_secret")
+
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteLinkHandler(t *testing.T) {
+	router := setupTestRouter()
+	mockService := &MockLinkService{}
+
+	router.DELETE("/api/v1/links/:shortCode", DeleteLinkHandler(mockService))
+
+	mockService.On("DeleteLink", services.AdminOwner, "abc123").Return(nil)
+	mockService.On("DeleteLink", services.AdminOwner, "nonexistent").Return(gorm.ErrRecordNotFound)
+	mockService.On("DeleteLink", services.AdminOwner, "error").Return(assert.AnError)
+
+	testCases := map[string]int{
+		"abc123":      http.StatusNoContent,
+		"nonexistent": http.StatusNotFound,
+		"error":       http.StatusInternalServerError,
+	}
+	for code, expectedStatus := range testCases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/api/v1/links/"+code, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, expectedStatus, w.Code, code)
+	}
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetLinkTimeSeriesHandler_Success(t *testing.T) {
+	router := setupTestRouter()
+	mockLinkService := &MockLinkService{}
+	mockClickService := &MockClickService{}
+
+	router.GET("/api/v1/links/:shortCode/stats/timeseries", GetLinkTimeSeriesHandler(mockLinkService, mockClickService))
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	link := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+	expectedQuery := services.TimeSeriesQuery{
+		Interval: repository.IntervalWeek,
+		From:     time.Date(2024, 3, 4, 0, 0, 0, 0, paris),
+		To:       time.Date(2024, 3, 18, 0, 0, 0, 0, paris),
+		Location: paris,
+	}
+	buckets := []models.ClickBucket{
+		{Start: time.Date(2024, 3, 4, 0, 0, 0, 0, paris), Count: 4},
+		{Start: time.Date(2024, 3, 11, 0, 0, 0, 0, paris), Count: 6},
+	}
+	mockLinkService.On("GetLink", services.AdminOwner, "abc123").Return(link, nil)
+	mockClickService.On("GetClickTimeSeries", uint(1), expectedQuery).Return(buckets, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/abc123/stats/timeseries?interval=week&from=2024-03-04&to=2024-03-18&tz=Europe/Paris", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "week", response["interval"])
+	assert.Equal(t, "Europe/Paris", response["timezone"])
+	assert.Equal(t, float64(10), response["total_clicks"])
+	assert.Len(t, response["buckets"], 2)
+
+	mockLinkService.AssertExpectations(t)
+	mockClickService.AssertExpectations(t)
+}
+
+func TestGetLinkTimeSeriesHandler_BadRequest(t *testing.T) {
+	router := setupTestRouter()
+	mockLinkService := &MockLinkService{}
+	mockClickService := &MockClickService{}
+
+	router.GET("/api/v1/links/:shortCode/stats/timeseries", GetLinkTimeSeriesHandler(mockLinkService, mockClickService))
+
+	for _, query := range []string{"interval=minute", "tz=Mars/Olympus", "from=yesterday"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/links/abc123/stats/timeseries?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	link := &models.Link{ID: 1, ShortCode: "abc123"}
+	mockLinkService.On("GetLink", services.AdminOwner, "abc123").Return(link, nil)
+	mockClickService.On("GetClickTimeSeries", uint(1), mock.Anything).Return(nil, services.ErrInvalidTimeSeries)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/abc123/stats/timeseries?interval=hour&from=2020-01-01", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetLinkTimeSeriesHandler_LinkNotFound(t *testing.T) {
+	router := setupTestRouter()
+	mockLinkService := &MockLinkService{}
+	mockClickService := &MockClickService{}
+
+	router.GET("/api/v1/links/:shortCode/stats/timeseries", GetLinkTimeSeriesHandler(mockLinkService, mockClickService))
+
+	mockLinkService.On("GetLink", services.AdminOwner, "nonexistent").Return(nil, gorm.ErrRecordNotFound)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/nonexistent/stats/timeseries", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockClickService.AssertNotCalled(t, "GetClickTimeSeries", mock.Anything, mock.Anything)
+}
+
+func TestGetLinkBreakdownHandler_Referrers(t *testing.T) {
+	router := setupTestRouter()
+	mockLinkService := &MockLinkService{}
+	mockClickService := &MockClickService{}
+
+	router.GET("/api/v1/links/:shortCode/stats/referrers", GetLinkBreakdownHandler(mockLinkService, mockClickService, repository.DimensionReferrer, "referrers"))
+
+	link := &models.Link{ID: 1, ShortCode: "abc123"}
+	mockLinkService.On("GetLink", services.AdminOwner, "abc123").Return(link, nil)
+	mockLinkService.On("GetLink", services.AdminOwner, "nonexistent").Return(nil, gorm.ErrRecordNotFound)
+	mockClickService.On("GetClickBreakdown", uint(1), repository.DimensionReferrer, 5, repository.ClickFilter{}).Return([]models.ClickStat{
+		{Value: "google.com", Count: 12},
+		{Value: "(direct)", Count: 3},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/abc123/stats/referrers?limit=5", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", response["short_code"])
+	referrers := response["referrers"].([]interface{})
+	assert.Len(t, referrers, 2)
+	assert.Equal(t, "google.com", referrers[0].(map[string]interface{})["value"])
+	assert.Equal(t, float64(12), referrers[0].(map[string]interface{})["count"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/links/nonexistent/stats/referrers", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/links/abc123/stats/referrers?limit=500", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockLinkService.AssertExpectations(t)
+	mockClickService.AssertExpectations(t)
+}
+
+func TestGetLinkBreakdownHandler_UserAgents(t *testing.T) {
+	tests := []struct {
+		path        string
+		dimension   repository.ClickDimension
+		responseKey string
+		stats       []models.ClickStat
+	}{
+		{"browsers", repository.DimensionBrowser, "browsers", []models.ClickStat{{Value: "Chrome", Count: 7}, {Value: "Firefox", Count: 2}}},
+		{"os", repository.DimensionOS, "os", []models.ClickStat{{Value: "Android", Count: 5}, {Value: "Windows", Count: 4}}},
+		{"devices", repository.DimensionDevice, "devices", []models.ClickStat{{Value: "mobile", Count: 6}, {Value: "desktop", Count: 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			router := setupTestRouter()
+			mockLinkService := &MockLinkService{}
+			mockClickService := &MockClickService{}
+
+			router.GET("/api/v1/links/:shortCode/stats/"+tt.path, GetLinkBreakdownHandler(mockLinkService, mockClickService, tt.dimension, tt.responseKey))
+
+			link := &models.Link{ID: 1, ShortCode: "abc123"}
+			mockLinkService.On("GetLink", services.AdminOwner, "abc123").Return(link, nil)
+			mockLinkService.On("GetLink", services.AdminOwner, "nonexistent").Return(nil, gorm.ErrRecordNotFound)
+			mockClickService.On("GetClickBreakdown", uint(1), tt.dimension, 3, repository.ClickFilter{IncludeBots: true}).Return(tt.stats, nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/links/abc123/stats/"+tt.path+"?limit=3&include_bots=true", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "abc123", response["short_code"])
+			assert.Equal(t, true, response["include_bots"])
+			values := response[tt.responseKey].([]interface{})
+			assert.Len(t, values, 2)
+			assert.Equal(t, tt.stats[0].Value, values[0].(map[string]interface{})["value"])
+			assert.Equal(t, float64(tt.stats[0].Count), values[0].(map[string]interface{})["count"])
+
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest("GET", "/api/v1/links/nonexistent/stats/"+tt.path, nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusNotFound, w.Code)
+
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest("GET", "/api/v1/links/abc123/stats/"+tt.path+"?limit=500", nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			mockLinkService.AssertExpectations(t)
+			mockClickService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetLinkHealthHandler(t *testing.T) {
+	router := setupTestRouter()
+	mockLinkService := &MockLinkService{}
+	mockHealthService := &MockHealthService{}
+
+	router.GET("/api/v1/links/:shortCode/health", GetLinkHealthHandler(mockLinkService, mockHealthService))
+
+	link := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+	checkedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	uptime := 75.0
+	mockLinkService.On("GetLink", services.AdminOwner, "abc123").Return(link, nil)
+	mockLinkService.On("GetLink", services.AdminOwner, "nonexistent").Return(nil, gorm.ErrRecordNotFound)
+	mockHealthService.On("GetLinkHealth", uint(1), services.HealthQuery{Window: 7 * 24 * time.Hour, Limit: 2}).Return(&services.LinkHealth{
+		Status:        services.HealthStatusAccessible,
+		LastCheckedAt: &checkedAt,
+		UptimePercent: &uptime,
+		Checks:        4,
+		Window:        7 * 24 * time.Hour,
+		History: []models.HealthCheck{
+			{CheckedAt: checkedAt, Accessible: true, StatusCode: 200, LatencyMs: 120},
+			{CheckedAt: checkedAt.Add(-5 * time.Minute), Accessible: false, LatencyMs: 5000, ErrorClass: "timeout"},
+		},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/abc123/health?window=7d&limit=2", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "accessible", response["status"])
+	assert.Equal(t, 75.0, response["uptime_percent"])
+	assert.Equal(t, float64(4), response["checks"])
+	assert.Equal(t, "7d", response["window"])
+	assert.Equal(t, "2024-03-01T10:00:00Z", response["last_checked_at"])
+	history := response["history"].([]interface{})
+	assert.Len(t, history, 2)
+	assert.Equal(t, float64(200), history[0].(map[string]interface{})["status_code"])
+	assert.Equal(t, "timeout", history[1].(map[string]interface{})["error_class"])
+	assert.NotContains(t, history[0], "error_class")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/links/nonexistent/health", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/links/abc123/health?window=soon", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockLinkService.AssertExpectations(t)
+	mockHealthService.AssertExpectations(t)
+}
+
+func TestGetLinkHealthHandler_NoChecks(t *testing.T) {
+	router := setupTestRouter()
+	mockLinkService := &MockLinkService{}
+	mockHealthService := &MockHealthService{}
+
+	router.GET("/api/v1/links/:shortCode/health", GetLinkHealthHandler(mockLinkService, mockHealthService))
+
+	link := &models.Link{ID: 1, ShortCode: "abc123"}
+	mockLinkService.On("GetLink", services.AdminOwner, "abc123").Return(link, nil)
+	mockHealthService.On("GetLinkHealth", uint(1), services.HealthQuery{}).Return(&services.LinkHealth{
+		Status: services.HealthStatusUnknown,
+		Window: services.DefaultHealthWindow,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/links/abc123/health", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "unknown", response["status"])
+	assert.Nil(t, response["uptime_percent"])
+	assert.Equal(t, "24h", response["window"])
+	assert.Equal(t, []interface{}{}, response["history"])
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimits regroupe les limiteurs appliqués par SetupRoutes. Un limiteur nil n'impose
+// aucune limite.
+type RateLimits struct {
+	// IP limite par adresse IP l'API de gestion avant l'authentification, pour freiner
+	// les essais de clés d'API et de jetons de session.
+	IP *ratelimit.Limiter
+	// API limite l'API de gestion, inscription et connexion comprises.
+	API *ratelimit.Limiter
+	// Redirect limite les redirections.
+	Redirect *ratelimit.Limiter
+}
+
+// RateLimit rejette avec 429 les requêtes d'un client qui a épuisé son seau, et annonce
+// l'état du seau dans les en-têtes RateLimit-Limit, RateLimit-Remaining et RateLimit-Reset.
+// Placé après Authenticate, il compte les requêtes par clé d'API plutôt que par IP.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return rateLimit(limiter, rateLimitClient)
+}
+
+// RateLimitByIP compte les requêtes par adresse IP, même après Authenticate.
+func RateLimitByIP(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return rateLimit(limiter, rateLimitIP)
+}
+
+func rateLimit(limiter *ratelimit.Limiter, client func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(client(c))
+		if err != nil {
+			// Un stockage indisponible ne doit pas rendre le service indisponible.
+			log.Printf("Warning: Rate limiter unavailable, request allowed: %v", err)
+			c.Next()
+			return
+		}
+
+		limit := limiter.Limit()
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Rate, ceilSeconds(limit.Period), result.Limit))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, retry later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitClient identifie le client : sa clé d'API si Authenticate en a retenu une,
+// son adresse IP sinon.
+func rateLimitClient(c *gin.Context) string {
+	if apiKey := APIKeyFromContext(c); apiKey != nil {
+		return fmt.Sprintf("key:%d", apiKey.ID)
+	}
+	return rateLimitIP(c)
+}
+
+// rateLimitIP identifie le client par son adresse IP. Derrière un proxy, celle-ci n'est
+// lue dans X-Forwarded-For que si le proxy fait partie de server.trusted_proxies.
+func rateLimitIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/ratelimit"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+// setupRateLimitRouter range la clé d'API de l'en-tête X-Test-Key, comme le ferait Authenticate.
+func setupRateLimitRouter(limiter *ratelimit.Limiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ping", func(c *gin.Context) {
+		if c.GetHeader("X-Test-Key") == "1" {
+			c.Set(apiKeyContextKey, &models.APIKey{ID: 1})
+		}
+		c.Next()
+	}, RateLimit(limiter), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func performRateLimitedRequest(router *gin.Engine, path, remoteAddr string, apiKey bool) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = remoteAddr
+	if apiKey {
+		req.Header.Set("X-Test-Key", "1")
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_PerClient(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemory(), "api", ratelimit.PerMinute(6, 2))
+	router := setupRateLimitRouter(limiter)
+
+	w := performRateLimitedRequest(router, "/ping", "192.0.2.1:1234", false)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "6;w=60;burst=2", w.Header().Get("RateLimit-Policy"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = performRateLimitedRequest(router, "/ping", "192.0.2.1:5678", false)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = performRateLimitedRequest(router, "/ping", "192.0.2.1:1234", false)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"Too many requests, retry later"}`, w.Body.String())
+
+	// Une autre IP, ou une clé d'API depuis la même IP, a son propre seau
+	w = performRateLimitedRequest(router, "/ping", "192.0.2.2:1234", false)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = performRateLimitedRequest(router, "/ping", "192.0.2.1:1234", true)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	router := setupRateLimitRouter(nil)
+
+	for i := 0; i < 3; i++ {
+		w := performRateLimitedRequest(router, "/ping", "192.0.2.1:1234", false)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimit_StoreUnavailable(t *testing.T) {
+	limiter := ratelimit.NewLimiter(failingRateLimitStore{}, "api", ratelimit.PerMinute(1, 1))
+	router := setupRateLimitRouter(limiter)
+
+	w := performRateLimitedRequest(router, "/ping", "192.0.2.1:1234", false)
+	assert.Equal(t, http.StatusNoContent, w.Code, "un stockage indisponible n'empêche pas de répondre")
+}
+
+func TestSetupRoutes_RateLimits(t *testing.T) {
+	mockLinkService := &MockLinkService{}
+	mockLinkService.On("GetLinkByShortCode", "abc123").Return(nil, gorm.ErrRecordNotFound)
+	mockUserService := &MockUserService{}
+	mockUserService.On("Login", "alice@example.com", "wrong").Return(nil, services.ErrInvalidCredentials)
+	store := ratelimit.NewMemory()
+	router := setupTestRouter()
+	SetupRoutes(router, mockLinkService, &MockClickService{}, &MockHealthService{}, &MockAPIKeyService{}, mockUserService, &MockWorkspaceService{}, &MockUsageService{},
+		ChannelSink(make(chan models.ClickEvent, 1)), RateLimits{
+			API:      ratelimit.NewLimiter(store, "api", ratelimit.PerMinute(1, 1)),
+			Redirect: ratelimit.NewLimiter(store, "redirect", ratelimit.PerMinute(1, 1)),
+		})
+
+	w := performRateLimitedRequest(router, "/abc123", "192.0.2.1:1234", false)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRateLimitedRequest(router, "/abc123", "192.0.2.1:1234", false)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Les redirections et l'API de gestion ont des limites séparées
+	body := []byte(`{"email":"alice@example.com","password":"wrong"}`)
+	for _, status := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(body))
+		router.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code)
+	}
+
+	// /health n'est pas limité
+	for i := 0; i < 2; i++ {
+		w = performRequest(router, "GET", "/health", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestSetupRoutes_ThrottlesInvalidCredentialsByIP(t *testing.T) {
+	mockAPIKeyService := &MockAPIKeyService{}
+	mockAPIKeyService.On("Authenticate", "usk_12345678_guess").Return(nil, services.ErrInvalidAPIKey)
+	store := ratelimit.NewMemory()
+	router := setupTestRouter()
+	SetupRoutes(router, &MockLinkService{}, &MockClickService{}, &MockHealthService{}, mockAPIKeyService, &MockUserService{}, &MockWorkspaceService{}, &MockUsageService{},
+		ChannelSink(make(chan models.ClickEvent, 1)), RateLimits{
+			IP:  ratelimit.NewLimiter(store, "ip", ratelimit.PerMinute(2, 2)),
+			API: ratelimit.NewLimiter(store, "api", ratelimit.PerMinute(100, 100)),
+		})
+
+	// Les clés refusées par Authenticate sont tout de même décomptées par IP
+	for _, status := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		w := performRequest(router, "GET", "/api/v1/links", nil, map[string]string{"Authorization": "Bearer usk_12345678_guess"})
+		assert.Equal(t, status, w.Code)
+	}
+	mockAPIKeyService.AssertNumberOfCalls(t, "Authenticate", 2)
+}
+
+func TestRateLimitByIP_IgnoresForwardedForFromUntrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	assert.NoError(t, router.SetTrustedProxies(nil))
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemory(), "ip", ratelimit.PerMinute(1, 1))
+	router.GET("/ping", RateLimitByIP(limiter), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	// Changer d'en-tête X-Forwarded-For ne donne pas un nouveau seau
+	for i, status := range []int{http.StatusNoContent, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+		router.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUsageHandler retourne la consommation du mois (ou de month=YYYY-MM) de l'espace de
+// travail workspace_id, ou à défaut de l'utilisateur authentifié, et ses quotas.
+func GetUsageHandler(usageService services.UsageServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var workspaceID *uint
+		if value := c.Query("workspace_id"); value != "" {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || id == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+				return
+			}
+			parsed := uint(id)
+			workspaceID = &parsed
+		}
+
+		usage, err := usageService.GetUsage(requestOwner(c), workspaceID, c.Query("month"))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidUsagePeriod):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, services.ErrUserRequired):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required for API keys without a user"})
+			case errors.Is(err, services.ErrWorkspaceNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+			default:
+				log.Printf("Error retrieving usage: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, usage)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUsageService struct {
+	mock.Mock
+}
+
+func (m *MockUsageService) GetUsage(owner services.Owner, workspaceID *uint, period string) (*services.Usage, error) {
+	args := m.Called(owner, workspaceID, period)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.Usage), args.Error(1)
+}
+
+func TestGetUsageHandler(t *testing.T) {
+	roles := map[uint]models.Role{3: models.RoleViewer}
+	owner := services.OwnedBy(7).WithRoles(roles)
+	workspaceID := uint(3)
+	limit := int64(100)
+	mockUsageService := &MockUsageService{}
+	mockUsageService.On("GetUsage", owner, (*uint)(nil), "").Return(&services.Usage{
+		Account:       models.UserAccount(7),
+		Period:        "2024-03",
+		Links:         services.QuotaUsage{Used: 12, Limit: &limit},
+		TrackedClicks: services.QuotaUsage{Used: 345},
+		ResetsAt:      time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+	}, nil)
+	mockUsageService.On("GetUsage", owner, &workspaceID, "2024-02").Return(&services.Usage{Account: models.WorkspaceAccount(3), Period: "2024-02"}, nil)
+	mockUsageService.On("GetUsage", owner, (*uint)(nil), "march").Return(nil, fmt.Errorf("%w: 'march'", services.ErrInvalidUsagePeriod))
+	workspaceID4 := uint(4)
+	mockUsageService.On("GetUsage", owner, &workspaceID4, "").Return(nil, services.ErrWorkspaceNotFound)
+
+	router := setupWorkspaceRouter(roles)
+	router.GET("/api/v1/usage", GetUsageHandler(mockUsageService))
+
+	w := performRequest(router, "GET", "/api/v1/usage", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"account":{"type":"user","id":7},"period":"2024-03","links":{"used":12,"limit":100},
+		"tracked_clicks":{"used":345,"limit":null},"resets_at":"2024-04-01T00:00:00Z"}`, w.Body.String())
+
+	w = performRequest(router, "GET", "/api/v1/usage?workspace_id=3&month=2024-02", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"account":{"type":"workspace","id":3}`)
+
+	for path, status := range map[string]int{
+		"/api/v1/usage?month=march":      http.StatusBadRequest,
+		"/api/v1/usage?workspace_id=abc": http.StatusBadRequest,
+		"/api/v1/usage?workspace_id=4":   http.StatusNotFound,
+	} {
+		w = performRequest(router, "GET", path, nil, nil)
+		assert.Equal(t, status, w.Code, path)
+	}
+	mockUsageService.AssertExpectations(t)
+}
+
+func TestCreateShortLinkHandler_QuotaExceeded(t *testing.T) {
+	owner := services.OwnedBy(7).WithRoles(nil)
+	mockLinkService := &MockLinkService{}
+	mockLinkService.On("CreateLink", owner, "https://www.example.com", services.CreateLinkOptions{}).
+		Return(nil, fmt.Errorf("%w: 100 links per month", services.ErrLinkQuotaExceeded))
+
+	router := setupWorkspaceRouter(nil)
+	router.POST("/api/v1/links", CreateShortLinkHandler(mockLinkService))
+
+	w := performRequest(router, "POST", "/api/v1/links", []byte(`{"long_url":"https://www.example.com"}`), nil)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.JSONEq(t, `{"error":"monthly link quota exceeded: 100 links per month"}`, w.Body.String())
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// workspaceIDContextKey est la clé sous laquelle RequireWorkspaceRole range l'espace de
+// travail de la route dans le contexte Gin.
+const workspaceIDContextKey = "workspace_id"
+
+// LoadWorkspaceRoles complète l'identité retenue par Authenticate avec les rôles de
+// l'utilisateur dans ses espaces de travail.
+func LoadWorkspaceRoles(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owner := requestOwner(c)
+		if owner.Admin || owner.UserID == 0 {
+			c.Next()
+			return
+		}
+
+		roles, err := workspaceService.GetRoles(owner.UserID)
+		if err != nil {
+			log.Printf("Error loading workspace roles of user %d: %v", owner.UserID, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.Set(ownerContextKey, owner.WithRoles(roles))
+		c.Next()
+	}
+}
+
+// RequireWorkspaceRole rejette les requêtes vers /workspaces/:workspaceID de qui n'est pas
+// membre de l'espace de travail (404) ou n'y a pas au moins le rôle demandé (403).
+func RequireWorkspaceRole(required models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspaceID, err := strconv.ParseUint(c.Param("workspaceID"), 10, 64)
+		if err != nil || workspaceID == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+			return
+		}
+
+		role, ok := requestOwner(c).RoleIn(uint(workspaceID))
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+			return
+		}
+		if !role.Allows(required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("This action requires the %s role", required)})
+			return
+		}
+
+		c.Set(workspaceIDContextKey, uint(workspaceID))
+		c.Next()
+	}
+}
+
+// workspaceIDFromContext retourne l'espace de travail retenu par RequireWorkspaceRole, ou nil.
+func workspaceIDFromContext(c *gin.Context) *uint {
+	value, ok := c.Get(workspaceIDContextKey)
+	if !ok {
+		return nil
+	}
+	workspaceID, ok := value.(uint)
+	if !ok {
+		return nil
+	}
+	return &workspaceID
+}
+
+// respondWorkspaceError traduit les erreurs de WorkspaceService en réponse HTTP.
+func respondWorkspaceError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrInvalidWorkspaceName),
+		errors.Is(err, services.ErrInvalidEmail), errors.Is(err, services.ErrUserRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInsufficientRole), errors.Is(err, services.ErrInvitationEmailMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+	case errors.Is(err, services.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+	case errors.Is(err, services.ErrInvitationNotFound), errors.Is(err, services.ErrInvalidInvitation):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+	case errors.Is(err, services.ErrAlreadyMember), errors.Is(err, services.ErrLastOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error %s: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// CreateWorkspaceHandler crée un espace de travail dont l'auteur de la requête est propriétaire.
+func CreateWorkspaceHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateWorkspaceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		workspace, err := workspaceService.CreateWorkspace(requestOwner(c), req.Name)
+		if err != nil {
+			respondWorkspaceError(c, err, "creating workspace")
+			return
+		}
+		c.JSON(http.StatusCreated, workspace)
+	}
+}
+
+func ListWorkspacesHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspaces, err := workspaceService.ListWorkspaces(requestOwner(c))
+		if err != nil {
+			respondWorkspaceError(c, err, "listing workspaces")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"workspaces": workspaces})
+	}
+}
+
+func GetWorkspaceHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspace, err := workspaceService.GetWorkspace(requestOwner(c), *workspaceIDFromContext(c))
+		if err != nil {
+			respondWorkspaceError(c, err, "retrieving workspace")
+			return
+		}
+		c.JSON(http.StatusOK, workspace)
+	}
+}
+
+func ListMembersHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		members, err := workspaceService.ListMembers(requestOwner(c), *workspaceIDFromContext(c))
+		if err != nil {
+			respondWorkspaceError(c, err, "listing members")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"members": members})
+	}
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateMemberHandler change le rôle d'un membre.
+func UpdateMemberHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		var req UpdateMemberRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		role, err := services.ParseRole(req.Role)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		membership, err := workspaceService.UpdateMemberRole(requestOwner(c), *workspaceIDFromContext(c), uint(userID), role)
+		if err != nil {
+			respondWorkspaceError(c, err, "updating member role")
+			return
+		}
+		c.JSON(http.StatusOK, membership)
+	}
+}
+
+// RemoveMemberHandler retire un membre de l'espace de travail, ou l'auteur de la requête
+// lui-même.
+func RemoveMemberHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		if err := workspaceService.RemoveMember(requestOwner(c), *workspaceIDFromContext(c), uint(userID)); err != nil {
+			respondWorkspaceError(c, err, "removing member")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required"`
+	Role  string `json:"role" binding:"required"`
+}
+
+// CreateInvitationHandler invite une adresse e-mail et retourne le jeton à lui transmettre,
+// qui n'est affiché qu'une fois.
+func CreateInvitationHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateInvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		role, err := services.ParseRole(req.Role)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		invitation, token, err := workspaceService.CreateInvitation(requestOwner(c), *workspaceIDFromContext(c), req.Email, role)
+		if err != nil {
+			respondWorkspaceError(c, err, "creating invitation")
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"invitation": invitation, "token": token})
+	}
+}
+
+func ListInvitationsHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invitations, err := workspaceService.ListInvitations(requestOwner(c), *workspaceIDFromContext(c))
+		if err != nil {
+			respondWorkspaceError(c, err, "listing invitations")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+	}
+}
+
+func RevokeInvitationHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invitationID, err := strconv.ParseUint(c.Param("invitationID"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+			return
+		}
+
+		if err := workspaceService.RevokeInvitation(requestOwner(c), *workspaceIDFromContext(c), uint(invitationID)); err != nil {
+			respondWorkspaceError(c, err, "revoking invitation")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// AcceptInvitationHandler fait de l'utilisateur authentifié un membre de l'espace de travail
+// auquel il a été invité.
+func AcceptInvitationHandler(workspaceService services.WorkspaceServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AcceptInvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		membership, err := workspaceService.AcceptInvitation(requestOwner(c), req.Token)
+		if err != nil {
+			respondWorkspaceError(c, err, "accepting invitation")
+			return
+		}
+		c.JSON(http.StatusCreated, membership)
+	}
+}
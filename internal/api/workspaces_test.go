@@ -0,0 +1,248 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWorkspaceService struct {
+	mock.Mock
+}
+
+func (m *MockWorkspaceService) GetRoles(userID uint) (map[uint]models.Role, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uint]models.Role), args.Error(1)
+}
+
+func (m *MockWorkspaceService) CreateWorkspace(owner services.Owner, name string) (*services.WorkspaceWithRole, error) {
+	args := m.Called(owner, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.WorkspaceWithRole), args.Error(1)
+}
+
+func (m *MockWorkspaceService) ListWorkspaces(owner services.Owner) ([]services.WorkspaceWithRole, error) {
+	args := m.Called(owner)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]services.WorkspaceWithRole), args.Error(1)
+}
+
+func (m *MockWorkspaceService) GetWorkspace(owner services.Owner, workspaceID uint) (*services.WorkspaceWithRole, error) {
+	args := m.Called(owner, workspaceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.WorkspaceWithRole), args.Error(1)
+}
+
+func (m *MockWorkspaceService) ListMembers(owner services.Owner, workspaceID uint) ([]models.Membership, error) {
+	args := m.Called(owner, workspaceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Membership), args.Error(1)
+}
+
+func (m *MockWorkspaceService) UpdateMemberRole(owner services.Owner, workspaceID, userID uint, role models.Role) (*models.Membership, error) {
+	args := m.Called(owner, workspaceID, userID, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Membership), args.Error(1)
+}
+
+func (m *MockWorkspaceService) RemoveMember(owner services.Owner, workspaceID, userID uint) error {
+	args := m.Called(owner, workspaceID, userID)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceService) CreateInvitation(owner services.Owner, workspaceID uint, email string, role models.Role) (*models.Invitation, string, error) {
+	args := m.Called(owner, workspaceID, email, role)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*models.Invitation), args.String(1), args.Error(2)
+}
+
+func (m *MockWorkspaceService) ListInvitations(owner services.Owner, workspaceID uint) ([]models.Invitation, error) {
+	args := m.Called(owner, workspaceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Invitation), args.Error(1)
+}
+
+func (m *MockWorkspaceService) RevokeInvitation(owner services.Owner, workspaceID, invitationID uint) error {
+	args := m.Called(owner, workspaceID, invitationID)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceService) AcceptInvitation(owner services.Owner, token string) (*models.Membership, error) {
+	args := m.Called(owner, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Membership), args.Error(1)
+}
+
+// setupWorkspaceRouter authentifie les requêtes comme l'utilisateur 7, avec ces rôles.
+func setupWorkspaceRouter(roles map[uint]models.Role) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ownerContextKey, services.OwnedBy(7).WithRoles(roles))
+		c.Next()
+	})
+	return router
+}
+
+func TestLoadWorkspaceRoles(t *testing.T) {
+	roles := map[uint]models.Role{3: models.RoleEditor}
+	mockWorkspaceService := &MockWorkspaceService{}
+	mockWorkspaceService.On("GetRoles", uint(9)).Return(roles, nil)
+	mockUserService := &MockUserService{}
+	mockUserService.On("AuthenticateSession", "uss_valid").Return(&models.Session{ID: 1, UserID: 9}, nil)
+	mockAPIKeyService := &MockAPIKeyService{}
+	mockAPIKeyService.On("Authenticate", "usk_12345678_admin").Return(&models.APIKey{ID: 1}, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/whoami", Authenticate(mockAPIKeyService, mockUserService), LoadWorkspaceRoles(mockWorkspaceService), func(c *gin.Context) {
+		owner := requestOwner(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": owner.UserID, "admin": owner.Admin, "roles": owner.Roles})
+	})
+
+	w := performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{"Authorization": "Bearer uss_valid"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":9,"admin":false,"roles":{"3":"editor"}}`, w.Body.String())
+
+	// Une clé d'administration n'a pas besoin de rôles
+	w = performRequest(router, "GET", "/api/v1/whoami", nil, map[string]string{"Authorization": "Bearer usk_12345678_admin"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockWorkspaceService.AssertNumberOfCalls(t, "GetRoles", 1)
+}
+
+func TestRequireWorkspaceRole(t *testing.T) {
+	router := setupWorkspaceRouter(map[uint]models.Role{3: models.RoleViewer})
+	router.POST("/workspaces/:workspaceID/links", RequireWorkspaceRole(models.RoleEditor), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"workspace_id": *workspaceIDFromContext(c)})
+	})
+	router.GET("/workspaces/:workspaceID/links", RequireWorkspaceRole(models.RoleViewer), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"workspace_id": *workspaceIDFromContext(c)})
+	})
+
+	w := performRequest(router, "GET", "/workspaces/3/links", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"workspace_id":3}`, w.Body.String())
+
+	w = performRequest(router, "POST", "/workspaces/3/links", nil, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"This action requires the editor role"}`, w.Body.String())
+
+	w = performRequest(router, "GET", "/workspaces/4/links", nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, "un espace de travail dont on n'est pas membre est introuvable")
+
+	w = performRequest(router, "GET", "/workspaces/abc/links", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateShortLinkHandler_InWorkspace(t *testing.T) {
+	roles := map[uint]models.Role{3: models.RoleEditor}
+	owner := services.OwnedBy(7).WithRoles(roles)
+	workspaceID := uint(3)
+	mockLinkService := &MockLinkService{}
+	mockLinkService.On("CreateLink", owner, "https://www.example.com", services.CreateLinkOptions{WorkspaceID: &workspaceID}).
+		Return(&models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", WorkspaceID: &workspaceID}, nil)
+
+	router := setupWorkspaceRouter(roles)
+	router.POST("/api/v1/workspaces/:workspaceID/links", RequireWorkspaceRole(models.RoleEditor), CreateShortLinkHandler(mockLinkService))
+
+	w := performRequest(router, "POST", "/api/v1/workspaces/3/links", []byte(`{"long_url":"https://www.example.com"}`), nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"workspace_id":3`)
+	mockLinkService.AssertExpectations(t)
+}
+
+func TestListLinksHandler_InWorkspace(t *testing.T) {
+	roles := map[uint]models.Role{3: models.RoleViewer}
+	workspaceID := uint(3)
+	mockLinkService := &MockLinkService{}
+	mockLinkService.On("ListLinks", services.OwnedBy(7).WithRoles(roles), repository.LinkFilter{WorkspaceID: &workspaceID}).
+		Return([]models.Link{}, int64(0), nil)
+
+	router := setupWorkspaceRouter(roles)
+	router.GET("/api/v1/workspaces/:workspaceID/links", RequireWorkspaceRole(models.RoleViewer), ListLinksHandler(mockLinkService))
+
+	w := performRequest(router, "GET", "/api/v1/workspaces/3/links", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLinkService.AssertExpectations(t)
+}
+
+func TestLinkHandlers_InsufficientRole(t *testing.T) {
+	roles := map[uint]models.Role{3: models.RoleViewer}
+	owner := services.OwnedBy(7).WithRoles(roles)
+	mockLinkService := &MockLinkService{}
+	mockLinkService.On("UpdateLinkURL", owner, "abc123", "https://www.example.org").Return(nil, services.ErrInsufficientRole)
+	mockLinkService.On("DeleteLink", owner, "abc123").Return(services.ErrInsufficientRole)
+	mockLinkService.On("SetLinkWebhook", owner, "abc123", "").Return(nil, services.ErrInsufficientRole)
+
+	router := setupWorkspaceRouter(roles)
+	router.PATCH("/api/v1/links/:shortCode", UpdateLinkHandler(mockLinkService))
+	router.DELETE("/api/v1/links/:shortCode", DeleteLinkHandler(mockLinkService))
+	router.DELETE("/api/v1/links/:shortCode/webhook", DeleteLinkWebhookHandler(mockLinkService))
+
+	w := performRequest(router, "PATCH", "/api/v1/links/abc123", []byte(`{"long_url":"https://www.example.org"}`), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "DELETE", "/api/v1/links/abc123", nil, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "DELETE", "/api/v1/links/abc123/webhook", nil, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestWorkspaceHandlers_ErrorStatuses(t *testing.T) {
+	roles := map[uint]models.Role{3: models.RoleOwner}
+	owner := services.OwnedBy(7).WithRoles(roles)
+	mockWorkspaceService := &MockWorkspaceService{}
+	mockWorkspaceService.On("UpdateMemberRole", owner, uint(3), uint(7), models.RoleViewer).Return(nil, services.ErrLastOwner)
+	mockWorkspaceService.On("RemoveMember", owner, uint(3), uint(8)).Return(services.ErrMemberNotFound)
+	mockWorkspaceService.On("CreateInvitation", owner, uint(3), "bob@example.com", models.RoleEditor).
+		Return(&models.Invitation{ID: 1, WorkspaceID: 3, Email: "bob@example.com", Role: models.RoleEditor}, "usi_token", nil)
+	mockWorkspaceService.On("AcceptInvitation", owner, "usi_other").Return(nil, services.ErrInvitationEmailMismatch)
+	mockWorkspaceService.On("AcceptInvitation", owner, "usi_expired").Return(nil, services.ErrInvalidInvitation)
+
+	router := setupWorkspaceRouter(roles)
+	router.PATCH("/workspaces/:workspaceID/members/:userID", RequireWorkspaceRole(models.RoleOwner), UpdateMemberHandler(mockWorkspaceService))
+	router.DELETE("/workspaces/:workspaceID/members/:userID", RequireWorkspaceRole(models.RoleViewer), RemoveMemberHandler(mockWorkspaceService))
+	router.POST("/workspaces/:workspaceID/invitations", RequireWorkspaceRole(models.RoleOwner), CreateInvitationHandler(mockWorkspaceService))
+	router.POST("/invitations/accept", AcceptInvitationHandler(mockWorkspaceService))
+
+	w := performRequest(router, "PATCH", "/workspaces/3/members/7", []byte(`{"role":"viewer"}`), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = performRequest(router, "PATCH", "/workspaces/3/members/7", []byte(`{"role":"admin"}`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "DELETE", "/workspaces/3/members/8", nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(router, "POST", "/workspaces/3/invitations", []byte(`{"email":"bob@example.com","role":"editor"}`), nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"token":"usi_token","invitation":{"id":1,"workspace_id":3,"email":"bob@example.com","role":"editor",
+		"created_at":"0001-01-01T00:00:00Z","expires_at":"0001-01-01T00:00:00Z"}}`, w.Body.String())
+
+	w = performRequest(router, "POST", "/invitations/accept", []byte(`{"token":"usi_other"}`), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "POST", "/invitations/accept", []byte(`{"token":"usi_expired"}`), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
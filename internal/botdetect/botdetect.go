@@ -0,0 +1,58 @@
+package botdetect
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+)
+
+//go:embed patterns.txt
+var patternsFile string
+
+var patterns = loadPatterns(patternsFile)
+
+func loadPatterns(content string) []string {
+	var result []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// IsBotUserAgent indique si le User-Agent correspond à un robot connu (voir patterns.txt).
+// Un User-Agent vide est considéré comme un robot : aucun navigateur n'en envoie.
+func IsBotUserAgent(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+	for _, pattern := range patterns {
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBotRequest complète IsBotUserAgent par des heuristiques sur la requête :
+// requêtes HEAD (vérification de lien), préchargements déclarés par le navigateur
+// et clients n'annonçant aucun type de contenu accepté.
+func IsBotRequest(r *http.Request) bool {
+	if IsBotUserAgent(r.UserAgent()) {
+		return true
+	}
+	if r.Method == http.MethodHead {
+		return true
+	}
+	for _, header := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(r.Header.Get(header))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	return r.Header.Get("Accept") == ""
+}
@@ -0,0 +1,67 @@
+package botdetect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const chromeUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+func TestLoadPatterns(t *testing.T) {
+	loaded := loadPatterns("# commentaire\n\n  SlackBot \ntwitterbot\n")
+
+	assert.Equal(t, []string{"slackbot", "twitterbot"}, loaded)
+	assert.NotEmpty(t, patterns)
+}
+
+func TestIsBotUserAgent(t *testing.T) {
+	bots := []string{
+		"",
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+		"Twitterbot/1.0",
+		"WhatsApp/2.23.20.0",
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)",
+		"TelegramBot (like TwitterBot)",
+		"curl/8.4.0",
+		"python-requests/2.31.0",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/124.0.0.0 Safari/537.36",
+	}
+	for _, ua := range bots {
+		assert.True(t, IsBotUserAgent(ua), ua)
+	}
+
+	humans := []string{
+		chromeUA,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	}
+	for _, ua := range humans {
+		assert.False(t, IsBotUserAgent(ua), ua)
+	}
+}
+
+func TestIsBotRequest(t *testing.T) {
+	newRequest := func(method string, headers map[string]string) *http.Request {
+		req := httptest.NewRequest(method, "/abc123", nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		return req
+	}
+	browserHeaders := map[string]string{"User-Agent": chromeUA, "Accept": "text/html"}
+
+	assert.False(t, IsBotRequest(newRequest(http.MethodGet, browserHeaders)))
+	assert.True(t, IsBotRequest(newRequest(http.MethodHead, browserHeaders)))
+	assert.True(t, IsBotRequest(newRequest(http.MethodGet, map[string]string{"User-Agent": chromeUA})))
+	assert.True(t, IsBotRequest(newRequest(http.MethodGet, map[string]string{"User-Agent": "Twitterbot/1.0", "Accept": "*/*"})))
+	assert.True(t, IsBotRequest(newRequest(http.MethodGet, map[string]string{
+		"User-Agent":  chromeUA,
+		"Accept":      "text/html",
+		"Sec-Purpose": "prefetch;prerender",
+	})))
+}
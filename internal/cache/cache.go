@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Moteurs de cache pris en charge (cache.backend).
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Store est un cache clé/valeur dont les entrées expirent après leur TTL.
+// Les implémentations sont utilisables par plusieurs goroutines.
+type Store interface {
+	// Get retourne la valeur de la clé et false si elle est absente ou expirée.
+	Get(key string) ([]byte, bool, error)
+	// Set enregistre la valeur pour la durée ttl (ttl <= 0 : sans expiration).
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete supprime les clés, absentes ou non.
+	Delete(keys ...string) error
+}
+
+type Options struct {
+	Backend string
+	// Size est le nombre maximum d'entrées du cache en mémoire.
+	Size int
+	// Redis est utilisé avec le moteur redis.
+	Redis RedisOptions
+}
+
+// New crée le Store correspondant au moteur demandé (memory par défaut).
+func New(opts Options) (Store, error) {
+	switch strings.ToLower(opts.Backend) {
+	case "", BackendMemory:
+		return NewLRU(opts.Size), nil
+	case BackendRedis:
+		return NewRedis(opts.Redis)
+	default:
+		return nil, fmt.Errorf("unsupported cache backend '%s' (expected %s or %s)", opts.Backend, BackendMemory, BackendRedis)
+	}
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getString(t *testing.T, store Store, key string) (string, bool) {
+	t.Helper()
+	value, ok, err := store.Get(key)
+	require.NoError(t, err)
+	return string(value), ok
+}
+
+func TestLRU_SetGetDelete(t *testing.T) {
+	c := NewLRU(10)
+
+	_, ok := getString(t, c, "a")
+	assert.False(t, ok)
+
+	require.NoError(t, c.Set("a", []byte("1"), time.Minute))
+	value, ok := getString(t, c, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+
+	require.NoError(t, c.Set("a", []byte("2"), time.Minute))
+	value, _ = getString(t, c, "a")
+	assert.Equal(t, "2", value)
+
+	require.NoError(t, c.Delete("a", "missing"))
+	_, ok = getString(t, c, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRU_Expiration(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set("short", []byte("1"), time.Second))
+	require.NoError(t, c.Set("forever", []byte("2"), 0))
+
+	now = now.Add(999 * time.Millisecond)
+	_, ok := getString(t, c, "short")
+	assert.True(t, ok)
+
+	now = now.Add(time.Millisecond)
+	_, ok = getString(t, c, "short")
+	assert.False(t, ok, "l'entrée expire au bout de son TTL")
+	assert.Equal(t, 1, c.Len(), "l'entrée expirée est supprimée à la lecture")
+
+	now = now.Add(24 * time.Hour)
+	_, ok = getString(t, c, "forever")
+	assert.True(t, ok)
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2)
+
+	require.NoError(t, c.Set("a", []byte("1"), 0))
+	require.NoError(t, c.Set("b", []byte("2"), 0))
+	// "a" est relue : "b" devient l'entrée la moins récemment utilisée
+	getString(t, c, "a")
+	require.NoError(t, c.Set("c", []byte("3"), 0))
+
+	_, ok := getString(t, c, "b")
+	assert.False(t, ok)
+	_, ok = getString(t, c, "a")
+	assert.True(t, ok)
+	_, ok = getString(t, c, "c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(1), c.Evictions())
+}
+
+func TestNew(t *testing.T) {
+	store, err := New(Options{Size: 5})
+	require.NoError(t, err)
+	assert.IsType(t, &LRU{}, store)
+
+	_, err = New(Options{Backend: "memcached"})
+	assert.Error(t, err)
+
+	_, err = New(Options{Backend: BackendRedis})
+	assert.Error(t, err, "l'adresse Redis est obligatoire")
+}
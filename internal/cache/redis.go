@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/redisclient"
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultRedisKeyPrefix = "url-shortener:"
+
+type RedisOptions = redisclient.Options
+
+// Redis partage le cache entre plusieurs instances du serveur via un serveur Redis
+// (ou compatible : Valkey, KeyDB...). Les expirations sont gérées par le serveur.
+type Redis struct {
+	*redisclient.Client
+}
+
+func NewRedis(opts RedisOptions) (*Redis, error) {
+	client, err := redisclient.New(opts, DefaultRedisKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{Client: client}, nil
+}
+
+func (c *Redis) Get(key string) ([]byte, bool, error) {
+	ctx, cancel := c.WithTimeout()
+	defer cancel()
+
+	value, err := c.Redis().Get(ctx, c.Key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := c.WithTimeout()
+	defer cancel()
+
+	if ttl < 0 {
+		ttl = 0
+	}
+	return c.Redis().Set(ctx, c.Key(key), value, ttl).Err()
+}
+
+func (c *Redis) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	ctx, cancel := c.WithTimeout()
+	defer cancel()
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.Key(key)
+	}
+	return c.Redis().Del(ctx, prefixed...).Err()
+}
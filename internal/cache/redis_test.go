@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	store, err := NewRedis(RedisOptions{Addr: server.Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store, server
+}
+
+func TestRedis_SetGetDelete(t *testing.T) {
+	store, server := newTestRedis(t)
+	require.NoError(t, store.Ping())
+
+	_, ok := getString(t, store, "a")
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set("a", []byte("1"), time.Minute))
+	value, ok := getString(t, store, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+	assert.True(t, server.Exists(DefaultRedisKeyPrefix+"a"), "les clés sont préfixées")
+
+	// Une valeur vide se distingue d'une clé absente
+	require.NoError(t, store.Set("empty", []byte{}, time.Minute))
+	value, ok = getString(t, store, "empty")
+	assert.True(t, ok)
+	assert.Empty(t, value)
+
+	require.NoError(t, store.Delete("a", "empty", "missing"))
+	require.NoError(t, store.Delete())
+	_, ok = getString(t, store, "a")
+	assert.False(t, ok)
+}
+
+func TestRedis_Expiration(t *testing.T) {
+	store, server := newTestRedis(t)
+
+	require.NoError(t, store.Set("short", []byte("1"), time.Second))
+	require.NoError(t, store.Set("forever", []byte("2"), 0))
+
+	server.FastForward(time.Second)
+	_, ok := getString(t, store, "short")
+	assert.False(t, ok)
+	_, ok = getString(t, store, "forever")
+	assert.True(t, ok)
+}
+
+func TestRedis_ServerUnavailable(t *testing.T) {
+	store, server := newTestRedis(t)
+	server.Close()
+
+	_, _, err := store.Get("a")
+	assert.Error(t, err)
+	assert.Error(t, store.Set("a", []byte("1"), time.Minute))
+}
+
+func TestNew_Redis(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	store, err := New(Options{Backend: "Redis", Redis: RedisOptions{Addr: server.Addr(), KeyPrefix: "test:"}})
+	require.NoError(t, err)
+	require.NoError(t, store.Set("a", []byte("1"), time.Minute))
+	assert.True(t, server.Exists("test:a"))
+}
@@ -11,17 +11,100 @@ type Config struct {
 	Server struct {
 		Port int `mapstructure:"port"`
 		BaseURL string `mapstructure:"base_url"`
+		ShutdownTimeoutSeconds int `mapstructure:"shutdown_timeout_seconds"`
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
 	} `mapstructure:"server"`
 	Database struct {
+		Driver string `mapstructure:"driver"`
 		Name string `mapstructure:"name"`
+		DSN string `mapstructure:"dsn"`
+		MaxOpenConns int `mapstructure:"max_open_conns"`
+		MaxIdleConns int `mapstructure:"max_idle_conns"`
+		ConnMaxLifetimeMinutes int `mapstructure:"conn_max_lifetime_minutes"`
+		ConnMaxIdleTimeMinutes int `mapstructure:"conn_max_idle_time_minutes"`
 	} `mapstructure:"database"`
 	Analytics struct {
 		BufferSize int `mapstructure:"buffer_size"`
 		Workers int `mapstructure:"workers"`
+		BatchSize int `mapstructure:"batch_size"`
+		FlushIntervalMs int `mapstructure:"flush_interval_ms"`
+		GeoIPDatabase string `mapstructure:"geoip_database"`
+		Spool struct {
+			Dir string `mapstructure:"dir"`
+			SegmentSizeMB int `mapstructure:"segment_size_mb"`
+			MaxDiskMB int `mapstructure:"max_disk_mb"`
+			Fsync string `mapstructure:"fsync"`
+			FsyncIntervalMs int `mapstructure:"fsync_interval_ms"`
+		} `mapstructure:"spool"`
 	} `mapstructure:"analytics"`
 	Monitor struct {
 		IntervalMinutes int `mapstructure:"interval_minutes"`
+		Concurrency int `mapstructure:"concurrency"`
+		PerHostConcurrency int `mapstructure:"per_host_concurrency"`
+		PerHostIntervalMs int `mapstructure:"per_host_interval_ms"`
+		TimeoutSeconds int `mapstructure:"timeout_seconds"`
+		JitterPercent int `mapstructure:"jitter_percent"`
+		HistoryRetentionDays int `mapstructure:"history_retention_days"`
+		FailureThreshold int `mapstructure:"failure_threshold"`
 	} `mapstructure:"monitor"`
+	Notifications struct {
+		Webhook struct {
+			URL string `mapstructure:"url"`
+			Secret string `mapstructure:"secret"`
+			TimeoutSeconds int `mapstructure:"timeout_seconds"`
+			MaxAttempts int `mapstructure:"max_attempts"`
+			InitialBackoffMs int `mapstructure:"initial_backoff_ms"`
+			MaxBackoffSeconds int `mapstructure:"max_backoff_seconds"`
+		} `mapstructure:"webhook"`
+	} `mapstructure:"notifications"`
+	Links struct {
+		ExpiredFallbackURL string `mapstructure:"expired_fallback_url"`
+	} `mapstructure:"links"`
+	Cache struct {
+		Enabled bool `mapstructure:"enabled"`
+		Backend string `mapstructure:"backend"`
+		Size int `mapstructure:"size"`
+		TTLSeconds int `mapstructure:"ttl_seconds"`
+		NegativeTTLSeconds int `mapstructure:"negative_ttl_seconds"`
+		Redis struct {
+			Addr string `mapstructure:"addr"`
+			Password string `mapstructure:"password"`
+			DB int `mapstructure:"db"`
+			KeyPrefix string `mapstructure:"key_prefix"`
+			TimeoutMs int `mapstructure:"timeout_ms"`
+		} `mapstructure:"redis"`
+	} `mapstructure:"cache"`
+	Auth struct {
+		RegistrationEnabled bool `mapstructure:"registration_enabled"`
+		SessionTTLHours int `mapstructure:"session_ttl_hours"`
+	} `mapstructure:"auth"`
+	RateLimit struct {
+		Enabled bool `mapstructure:"enabled"`
+		Backend string `mapstructure:"backend"`
+		IP struct {
+			RequestsPerMinute int `mapstructure:"requests_per_minute"`
+			Burst int `mapstructure:"burst"`
+		} `mapstructure:"ip"`
+		API struct {
+			RequestsPerMinute int `mapstructure:"requests_per_minute"`
+			Burst int `mapstructure:"burst"`
+		} `mapstructure:"api"`
+		Redirect struct {
+			RequestsPerMinute int `mapstructure:"requests_per_minute"`
+			Burst int `mapstructure:"burst"`
+		} `mapstructure:"redirect"`
+		Redis struct {
+			Addr string `mapstructure:"addr"`
+			Password string `mapstructure:"password"`
+			DB int `mapstructure:"db"`
+			KeyPrefix string `mapstructure:"key_prefix"`
+			TimeoutMs int `mapstructure:"timeout_ms"`
+		} `mapstructure:"redis"`
+	} `mapstructure:"rate_limit"`
+	Quotas struct {
+		LinksPerMonth int64 `mapstructure:"links_per_month"`
+		TrackedClicksPerMonth int64 `mapstructure:"tracked_clicks_per_month"`
+	} `mapstructure:"quotas"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -31,10 +114,67 @@ func LoadConfig() (*Config, error) {
 
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.base_url", "http://localhost:8080")
+	viper.SetDefault("server.shutdown_timeout_seconds", 15)
+	viper.SetDefault("server.trusted_proxies", []string{})
+	viper.SetDefault("database.driver", "sqlite")
 	viper.SetDefault("database.name", "url_shortener.db")
+	viper.SetDefault("database.dsn", "")
+	viper.SetDefault("database.max_open_conns", 25)
+	viper.SetDefault("database.max_idle_conns", 5)
+	viper.SetDefault("database.conn_max_lifetime_minutes", 30)
+	viper.SetDefault("database.conn_max_idle_time_minutes", 5)
 	viper.SetDefault("analytics.buffer_size", 1000)
 	viper.SetDefault("analytics.workers", 5)
+	viper.SetDefault("analytics.batch_size", 100)
+	viper.SetDefault("analytics.flush_interval_ms", 500)
+	viper.SetDefault("analytics.geoip_database", "")
+	viper.SetDefault("analytics.spool.dir", "")
+	viper.SetDefault("analytics.spool.segment_size_mb", 8)
+	viper.SetDefault("analytics.spool.max_disk_mb", 512)
+	viper.SetDefault("analytics.spool.fsync", "interval")
+	viper.SetDefault("analytics.spool.fsync_interval_ms", 200)
 	viper.SetDefault("monitor.interval_minutes", 5)
+	viper.SetDefault("monitor.concurrency", 20)
+	viper.SetDefault("monitor.per_host_concurrency", 2)
+	viper.SetDefault("monitor.per_host_interval_ms", 500)
+	viper.SetDefault("monitor.timeout_seconds", 5)
+	viper.SetDefault("monitor.jitter_percent", 50)
+	viper.SetDefault("monitor.history_retention_days", 30)
+	viper.SetDefault("monitor.failure_threshold", 2)
+	viper.SetDefault("notifications.webhook.url", "")
+	viper.SetDefault("notifications.webhook.secret", "")
+	viper.SetDefault("notifications.webhook.timeout_seconds", 5)
+	viper.SetDefault("notifications.webhook.max_attempts", 5)
+	viper.SetDefault("notifications.webhook.initial_backoff_ms", 1000)
+	viper.SetDefault("notifications.webhook.max_backoff_seconds", 60)
+	viper.SetDefault("links.expired_fallback_url", "")
+	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.backend", "memory")
+	viper.SetDefault("cache.size", 10000)
+	viper.SetDefault("cache.ttl_seconds", 300)
+	viper.SetDefault("cache.negative_ttl_seconds", 30)
+	viper.SetDefault("cache.redis.addr", "")
+	viper.SetDefault("cache.redis.password", "")
+	viper.SetDefault("cache.redis.db", 0)
+	viper.SetDefault("cache.redis.key_prefix", "url-shortener:")
+	viper.SetDefault("cache.redis.timeout_ms", 200)
+	viper.SetDefault("auth.registration_enabled", false)
+	viper.SetDefault("auth.session_ttl_hours", 720)
+	viper.SetDefault("rate_limit.enabled", true)
+	viper.SetDefault("rate_limit.backend", "memory")
+	viper.SetDefault("rate_limit.ip.requests_per_minute", 300)
+	viper.SetDefault("rate_limit.ip.burst", 60)
+	viper.SetDefault("rate_limit.api.requests_per_minute", 120)
+	viper.SetDefault("rate_limit.api.burst", 30)
+	viper.SetDefault("rate_limit.redirect.requests_per_minute", 600)
+	viper.SetDefault("rate_limit.redirect.burst", 100)
+	viper.SetDefault("rate_limit.redis.addr", "")
+	viper.SetDefault("rate_limit.redis.password", "")
+	viper.SetDefault("rate_limit.redis.db", 0)
+	viper.SetDefault("rate_limit.redis.key_prefix", "url-shortener:ratelimit:")
+	viper.SetDefault("rate_limit.redis.timeout_ms", 200)
+	viper.SetDefault("quotas.links_per_month", 0)
+	viper.SetDefault("quotas.tracked_clicks_per_month", 0)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -47,8 +187,8 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("error unmarshalling config: %w", err)
 	}
 
-	log.Printf("Configuration loaded: Server Port=%d, DB Name=%s, Analytics Buffer=%d, Monitor Interval=%dmin",
-		cfg.Server.Port, cfg.Database.Name, cfg.Analytics.BufferSize, cfg.Monitor.IntervalMinutes)
+	log.Printf("Configuration loaded: Server Port=%d, DB Driver=%s, DB Name=%s, Analytics Buffer=%d, Monitor Interval=%dmin",
+		cfg.Server.Port, cfg.Database.Driver, cfg.Database.Name, cfg.Analytics.BufferSize, cfg.Monitor.IntervalMinutes)
 
 	return &cfg, nil
 }
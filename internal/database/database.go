@@ -0,0 +1,103 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Moteurs de base de données pris en charge (database.driver).
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
+// Options décrit la connexion à ouvrir. Pour SQLite, DSN est le chemin du fichier
+// (ou ":memory:") ; pour PostgreSQL, une chaîne de connexion libpq
+// ("host=... user=... dbname=...") ou une URL postgres://.
+// Les réglages du pool de connexions à zéro gardent les valeurs par défaut de database/sql.
+type Options struct {
+	Driver          string
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// OptionsFromConfig lit la section database de la configuration. Sans DSN, la base
+// SQLite database.name est utilisée, comme avant l'introduction de database.driver.
+func OptionsFromConfig(cfg *config.Config) Options {
+	opts := Options{
+		Driver:          cfg.Database.Driver,
+		DSN:             cfg.Database.DSN,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: time.Duration(cfg.Database.ConnMaxLifetimeMinutes) * time.Minute,
+		ConnMaxIdleTime: time.Duration(cfg.Database.ConnMaxIdleTimeMinutes) * time.Minute,
+	}
+	if opts.Driver == "" {
+		opts.Driver = DriverSQLite
+	}
+	if opts.DSN == "" && opts.Driver == DriverSQLite {
+		opts.DSN = cfg.Database.Name
+	}
+	return opts
+}
+
+// Dialector retourne le dialecte GORM correspondant au moteur.
+func Dialector(driver string, dsn string) (gorm.Dialector, error) {
+	switch strings.ToLower(driver) {
+	case DriverSQLite, "sqlite3":
+		return sqlite.Open(dsn), nil
+	case DriverPostgres, "postgresql", "pgx":
+		return postgres.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("%w '%s' (expected %s or %s)", ErrUnsupportedDriver, driver, DriverSQLite, DriverPostgres)
+	}
+}
+
+// Open ouvre la base de données et règle son pool de connexions. C'est la seule façon
+// dont les commandes se connectent à la base.
+func Open(opts Options) (*gorm.DB, error) {
+	dialector, err := Dialector(opts.Driver, opts.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DSN == "" {
+		return nil, errors.New("database DSN is empty")
+	}
+
+	// TranslateError ramène les erreurs propres au moteur aux erreurs de GORM
+	// (gorm.ErrDuplicatedKey...), identiques quel que soit le dépôt utilisé.
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+	return db, nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsFromConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Database.Name = "url_shortener.db"
+	cfg.Database.MaxOpenConns = 10
+	cfg.Database.ConnMaxLifetimeMinutes = 30
+
+	// Sans moteur ni DSN : le fichier SQLite database.name
+	opts := OptionsFromConfig(cfg)
+	assert.Equal(t, DriverSQLite, opts.Driver)
+	assert.Equal(t, "url_shortener.db", opts.DSN)
+	assert.Equal(t, 10, opts.MaxOpenConns)
+	assert.Equal(t, 30*time.Minute, opts.ConnMaxLifetime)
+
+	cfg.Database.Driver = DriverPostgres
+	cfg.Database.DSN = "host=db user=app dbname=url_shortener"
+	opts = OptionsFromConfig(cfg)
+	assert.Equal(t, DriverPostgres, opts.Driver)
+	assert.Equal(t, "host=db user=app dbname=url_shortener", opts.DSN)
+}
+
+func TestDialector(t *testing.T) {
+	for _, driver := range []string{"sqlite", "sqlite3", "postgres", "PostgreSQL", "pgx"} {
+		dialector, err := Dialector(driver, "dsn")
+		assert.NoError(t, err, driver)
+		assert.NotNil(t, dialector, driver)
+	}
+
+	_, err := Dialector("mysql", "dsn")
+	assert.ErrorIs(t, err, ErrUnsupportedDriver)
+}
+
+func TestOpen(t *testing.T) {
+	db, err := Open(Options{
+		Driver:       DriverSQLite,
+		DSN:          filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns: 3,
+		MaxIdleConns: 2,
+	})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	assert.NoError(t, sqlDB.Ping())
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+
+	_, err = Open(Options{Driver: DriverSQLite})
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+// Package dbtest ouvre les bases de données des tests.
+package dbtest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Edofo/bitly-clone/internal/database"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Les tests utilisent par défaut une base SQLite en mémoire. Avec
+// TEST_DATABASE_DRIVER=postgres et TEST_DATABASE_DSN, ils s'exécutent sur PostgreSQL,
+// chaque test dans un schéma temporaire (voir `make test-postgres`).
+const (
+	testDriverEnv = "TEST_DATABASE_DRIVER"
+	testDSNEnv    = "TEST_DATABASE_DSN"
+)
+
+// Driver retourne le moteur des bases de test.
+func Driver() string {
+	if driver := os.Getenv(testDriverEnv); driver != "" {
+		return driver
+	}
+	return database.DriverSQLite
+}
+
+// Open ouvre une base de test vide et y crée les tables des modèles donnés.
+func Open(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+
+	var db *gorm.DB
+	switch Driver() {
+	case database.DriverSQLite:
+		db = openTestSQLite(t, ":memory:")
+	case database.DriverPostgres:
+		db = openTestPostgres(t)
+	default:
+		t.Fatalf("unsupported %s '%s'", testDriverEnv, Driver())
+	}
+
+	require.NoError(t, db.AutoMigrate(tables...))
+	return db
+}
+
+// OpenConcurrent ouvre une base de test partageable entre plusieurs connexions :
+// une base SQLite en mémoire n'existe que pour la connexion qui l'a créée.
+func OpenConcurrent(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	if Driver() != database.DriverSQLite {
+		return Open(t, tables...)
+	}
+
+	db := openTestSQLite(t, "file:"+filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000")
+	require.NoError(t, db.AutoMigrate(tables...))
+	return db
+}
+
+func openTestSQLite(t *testing.T, dsn string) *gorm.DB {
+	db, err := database.Open(database.Options{Driver: database.DriverSQLite, DSN: dsn})
+	require.NoError(t, err)
+	closeOnCleanup(t, db)
+	return db
+}
+
+func openTestPostgres(t *testing.T) *gorm.DB {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Fatalf("%s is required when %s=postgres", testDSNEnv, testDriverEnv)
+	}
+
+	admin, err := database.Open(database.Options{Driver: database.DriverPostgres, DSN: dsn, MaxOpenConns: 1})
+	require.NoError(t, err)
+	closeOnCleanup(t, admin)
+
+	schema := fmt.Sprintf("test_%d", rand.Uint32())
+	require.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Logf("Failed to drop test schema %s: %v", schema, err)
+		}
+	})
+
+	db, err := database.Open(database.Options{Driver: database.DriverPostgres, DSN: withSearchPath(dsn, schema)})
+	require.NoError(t, err)
+	closeOnCleanup(t, db)
+	return db
+}
+
+// withSearchPath ajoute le schéma de recherche à une chaîne de connexion libpq ou à une URL.
+func withSearchPath(dsn string, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
+
+func closeOnCleanup(t *testing.T, db *gorm.DB) {
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+}
@@ -0,0 +1,87 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"unicode/utf8"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// maxCityLength est la taille de la colonne clicks.city.
+const maxCityLength = 100
+
+type Location struct {
+	Country string
+	City    string
+}
+
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Reader interroge une base GeoIP locale au format MaxMind (.mmdb, ex: GeoLite2-City).
+// Aucune requête réseau n'est effectuée. Le Reader est utilisable par plusieurs goroutines.
+type Reader struct {
+	db *maxminddb.Reader
+}
+
+func Open(path string) (*Reader, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening GeoIP database '%s': %w", path, err)
+	}
+	return &Reader{db: db}, nil
+}
+
+func (r *Reader) Close() error {
+	return r.db.Close()
+}
+
+// Lookup retourne le pays (code ISO 3166-1 alpha-2) et la ville (nom anglais) d'une adresse IP.
+// Une adresse absente de la base donne une Location vide sans erreur.
+func (r *Reader) Lookup(ipAddress string) (Location, error) {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return Location{}, fmt.Errorf("invalid IP address '%s'", ipAddress)
+	}
+
+	var record cityRecord
+	if err := r.db.Lookup(ip, &record); err != nil {
+		return Location{}, err
+	}
+
+	return Location{
+		Country: record.Country.ISOCode,
+		City:    truncate(record.City.Names["en"], maxCityLength),
+	}, nil
+}
+
+// truncate limite value à maxLength octets sans couper de caractère UTF-8.
+func truncate(value string, maxLength int) string {
+	if len(value) <= maxLength {
+		return value
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
+
+// Enrich complète le clic avec sa localisation. Les échecs de résolution sont ignorés :
+// la géolocalisation est une information optionnelle qui ne doit pas empêcher l'enregistrement.
+func (r *Reader) Enrich(click *models.Click) {
+	location, err := r.Lookup(click.IPAddress)
+	if err != nil {
+		return
+	}
+	click.Country = location.Country
+	click.City = location.City
+}
@@ -0,0 +1,75 @@
+package geoip
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testdata/GeoIP2-City-Test.mmdb est une petite base générée avec github.com/maxmind/mmdbwriter,
+// qui ne contient que quelques réseaux de test.
+const fixturePath = "testdata/GeoIP2-City-Test.mmdb"
+
+func openFixture(t *testing.T) *Reader {
+	reader, err := Open(fixturePath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = reader.Close() })
+	return reader
+}
+
+func TestOpen_MissingFile(t *testing.T) {
+	_, err := Open("testdata/missing.mmdb")
+
+	assert.Error(t, err)
+}
+
+func TestReader_Lookup(t *testing.T) {
+	reader := openFixture(t)
+
+	testCases := map[string]Location{
+		"81.2.69.160":   {Country: "GB", City: "London"},
+		"89.160.20.112": {Country: "SE", City: "Linköping"},
+		"90.0.12.34":    {Country: "FR", City: "Paris"},
+		"2001:db8::1":   {Country: "FR", City: "Lyon"},
+		"216.160.83.56": {Country: "US", City: ""},
+		"127.0.0.1":     {},
+	}
+
+	for ip, expected := range testCases {
+		location, err := reader.Lookup(ip)
+		assert.NoError(t, err, ip)
+		assert.Equal(t, expected, location, ip)
+	}
+}
+
+func TestReader_Lookup_InvalidIP(t *testing.T) {
+	reader := openFixture(t)
+
+	_, err := reader.Lookup("not-an-ip")
+
+	assert.Error(t, err)
+}
+
+func TestReader_Enrich(t *testing.T) {
+	reader := openFixture(t)
+
+	click := &models.Click{IPAddress: "81.2.69.160"}
+	reader.Enrich(click)
+	assert.Equal(t, "GB", click.Country)
+	assert.Equal(t, "London", click.City)
+
+	click = &models.Click{IPAddress: "invalid"}
+	reader.Enrich(click)
+	assert.Empty(t, click.Country)
+	assert.Empty(t, click.City)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "Paris", truncate("Paris", maxCityLength))
+	assert.Equal(t, "Link", truncate("Linköping", 5), "un caractère multi-octets n'est pas coupé")
+	assert.Equal(t, "Linkö", truncate("Linköping", 6))
+	assert.Len(t, truncate(strings.Repeat("é", 80), maxCityLength), maxCityLength)
+}
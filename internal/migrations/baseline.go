@@ -0,0 +1,167 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/services"
+	"github.com/Edofo/bitly-clone/internal/useragent"
+	"gorm.io/gorm"
+)
+
+// BaselineVersion est la dernière migration dont le schéma était créé par AutoMigrate.
+const BaselineVersion = 1
+
+// Modèles figés dans l'état du schéma de référence : les bases créées par AutoMigrate
+// y sont amenées avant d'être marquées à BaselineVersion. Ils ne doivent plus changer,
+// les évolutions du schéma passent par de nouvelles migrations.
+type baselineLink struct {
+	ID             uint       `gorm:"primaryKey"`
+	ShortCode      string     `gorm:"uniqueIndex;size:32;not null"`
+	LongURL        string     `gorm:"not null"`
+	Domain         string     `gorm:"index;size:255"`
+	ExpiresAt      *time.Time `gorm:"index"`
+	MaxClicks      int        `gorm:"not null;default:0"`
+	ConsumedClicks int        `gorm:"not null;default:0"`
+	WebhookURL     string     `gorm:"size:2048"`
+	CreatedAt      time.Time
+}
+
+func (baselineLink) TableName() string { return "links" }
+
+type baselineClick struct {
+	ID             uint         `gorm:"primaryKey"`
+	LinkID         uint         `gorm:"index"`
+	Link           baselineLink `gorm:"foreignKey:LinkID"`
+	Timestamp      time.Time
+	UserAgent      string `gorm:"size:255"`
+	IPAddress      string `gorm:"size:50"`
+	Referrer       string `gorm:"size:2048"`
+	ReferrerDomain string `gorm:"index;size:255"`
+	Browser        string `gorm:"index;size:50"`
+	OS             string `gorm:"index;size:50"`
+	DeviceClass    string `gorm:"index;size:20"`
+	Country        string `gorm:"index;size:2"`
+	City           string `gorm:"size:100"`
+	IsBot          bool   `gorm:"index;not null;default:false"`
+	VisitorHash    string `gorm:"index;size:64"`
+}
+
+func (baselineClick) TableName() string { return "clicks" }
+
+type baselineVisitorSalt struct {
+	Day       string `gorm:"primaryKey;size:10"`
+	Salt      []byte `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (baselineVisitorSalt) TableName() string { return "visitor_salts" }
+
+type baselineHealthCheck struct {
+	ID                  uint      `gorm:"primaryKey"`
+	LinkID              uint      `gorm:"index:idx_health_checks_link_checked,priority:1;not null"`
+	CheckedAt           time.Time `gorm:"index:idx_health_checks_link_checked,priority:2;index;not null"`
+	Accessible          bool      `gorm:"not null"`
+	State               string    `gorm:"size:16"`
+	ConsecutiveFailures int
+	Method              string `gorm:"size:8"`
+	StatusCode          int
+	LatencyMs           int64
+	RedirectChain       string `gorm:"type:text"`
+	ErrorClass          string `gorm:"size:32"`
+	Error               string `gorm:"size:512"`
+}
+
+func (baselineHealthCheck) TableName() string { return "health_checks" }
+
+type baselineWebhookDeadLetter struct {
+	ID             uint   `gorm:"primaryKey"`
+	LinkID         uint   `gorm:"index"`
+	EventID        string `gorm:"size:64;index"`
+	URL            string `gorm:"size:2048;not null"`
+	Payload        string `gorm:"type:text;not null"`
+	Attempts       int    `gorm:"not null"`
+	LastStatusCode int
+	LastError      string `gorm:"size:512"`
+	CreatedAt      time.Time
+}
+
+func (baselineWebhookDeadLetter) TableName() string { return "webhook_dead_letters" }
+
+// NeedsBaseline indique une base créée par AutoMigrate : des tables existent mais
+// aucune migration n'y est enregistrée.
+func (m *Migrator) NeedsBaseline() bool {
+	migrator := m.db.Migrator()
+	return !migrator.HasTable(&schemaMigration{}) && migrator.HasTable(&baselineLink{})
+}
+
+// Baseline amène une base créée par AutoMigrate au schéma de référence, complète les
+// données enregistrées avant certaines colonnes, puis la marque à BaselineVersion.
+// Sans effet sur une base vide ou déjà versionnée ; retourne true si la base a été marquée.
+func (m *Migrator) Baseline() (bool, error) {
+	if !m.NeedsBaseline() {
+		return false, nil
+	}
+
+	err := m.db.AutoMigrate(&baselineLink{}, &baselineClick{}, &baselineVisitorSalt{}, &baselineHealthCheck{}, &baselineWebhookDeadLetter{})
+	if err != nil {
+		return false, err
+	}
+	if err := backfillLegacyData(m.db); err != nil {
+		return false, err
+	}
+
+	if err := m.ensureTable(); err != nil {
+		return false, err
+	}
+	now := m.now().UTC()
+	for _, migration := range m.migrations {
+		if migration.Version > BaselineVersion {
+			break
+		}
+		row := schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: now}
+		if err := m.db.Create(&row).Error; err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// backfillLegacyData complète les lignes enregistrées avant l'ajout de certaines colonnes,
+// comme le faisait la commande migrate avant les migrations versionnées.
+func backfillLegacyData(db *gorm.DB) error {
+	// Les liens créés avant l'ajout de la colonne 'domain' doivent être complétés
+	// pour que le filtre par domaine de GET /api/v1/links les prenne en compte.
+	var links []baselineLink
+	if err := db.Where("domain = '' OR domain IS NULL").Find(&links).Error; err != nil {
+		return err
+	}
+	for _, link := range links {
+		if err := db.Model(&link).UpdateColumn("domain", services.ExtractDomain(link.LongURL)).Error; err != nil {
+			return err
+		}
+	}
+
+	// Les clics enregistrés avant l'analyse du User-Agent sont classés a posteriori.
+	var pending []baselineClick
+	err := db.Where("browser = '' OR browser IS NULL").FindInBatches(&pending, 500, func(tx *gorm.DB, batch int) error {
+		for _, click := range pending {
+			agent := useragent.Parse(click.UserAgent)
+			err := tx.Model(&click).UpdateColumns(map[string]interface{}{
+				"browser":      agent.Browser,
+				"os":           agent.OS,
+				"device_class": agent.Device,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	// Les clics déjà identifiés comme robots sont exclus des statistiques.
+	return db.Model(&baselineClick{}).Where("device_class = ? AND is_bot = ?", useragent.DeviceBot, false).
+		UpdateColumn("is_bot", true).Error
+}
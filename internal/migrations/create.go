@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SourceDir est le répertoire du paquet migrations dans les sources du projet.
+const SourceDir = "internal/migrations"
+
+var nameSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create écrit les fichiers up et down d'une nouvelle migration pour chaque moteur, avec
+// le numéro suivant la dernière migration existante dans dir. Retourne les fichiers créés.
+// Les migrations étant intégrées au binaire, celui-ci doit ensuite être recompilé.
+func Create(dir string, name string) ([]string, error) {
+	name = strings.Trim(nameSeparators.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name must contain letters or digits")
+	}
+
+	version := 0
+	for _, dialect := range Dialects {
+		migrations, err := Load(os.DirFS(dir), dialect)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if len(migrations) > 0 && migrations[len(migrations)-1].Version > version {
+			version = migrations[len(migrations)-1].Version
+		}
+	}
+	version++
+
+	var created []string
+	for _, dialect := range Dialects {
+		dialectDir := filepath.Join(dir, "sql", dialect)
+		if err := os.MkdirAll(dialectDir, 0o755); err != nil {
+			return created, err
+		}
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dialectDir, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+			header := fmt.Sprintf("-- Migration %04d_%s (%s, %s)\n", version, name, dialect, direction)
+			if err := os.WriteFile(file, []byte(header), 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, file)
+		}
+	}
+	return created, nil
+}
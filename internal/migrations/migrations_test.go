@@ -0,0 +1,281 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/database/dbtest"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// appModels sont les modèles dont les tables doivent être créées par les migrations.
+var appModels = []interface{}{
+	&models.Link{}, &models.Click{}, &models.VisitorSalt{}, &models.HealthCheck{}, &models.WebhookDeadLetter{},
+	&models.APIKey{}, &models.User{}, &models.Session{}, &models.Workspace{}, &models.Membership{}, &models.Invitation{},
+	&models.UsageCounter{},
+}
+
+func newTestMigrator(t *testing.T, db *gorm.DB) *Migrator {
+	migrator, err := New(db)
+	require.NoError(t, err)
+	return migrator
+}
+
+// assertSchemaMatchesModels vérifie que chaque colonne et chaque index des modèles existe :
+// un champ ajouté à un modèle sans migration fait échouer le test.
+func assertSchemaMatchesModels(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, model := range appModels {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		for _, column := range stmt.Schema.DBNames {
+			assert.True(t, db.Migrator().HasColumn(model, column), "column %s.%s", stmt.Schema.Table, column)
+		}
+		for _, index := range stmt.Schema.ParseIndexes() {
+			assert.True(t, db.Migrator().HasIndex(model, index.Name), "index %s", index.Name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations_SameVersionsForAllDialects(t *testing.T) {
+	reference, err := Load(embedded, Dialects[0])
+	require.NoError(t, err)
+	require.NotEmpty(t, reference)
+	assert.Equal(t, BaselineVersion, reference[0].Version)
+
+	for _, dialect := range Dialects[1:] {
+		migrations, err := Load(embedded, dialect)
+		require.NoError(t, err)
+		require.Len(t, migrations, len(reference), dialect)
+		for i, migration := range migrations {
+			assert.Equal(t, reference[i].String(), migration.String(), dialect)
+		}
+	}
+}
+
+func TestLoad_Validation(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"sql/sqlite/0001_init.up.sql": {Data: []byte("SELECT 1;")},
+	}, "sqlite")
+	assert.Error(t, err, "le fichier down est obligatoire")
+
+	_, err = Load(fstest.MapFS{
+		"sql/sqlite/0001_init.up.sql":    {Data: []byte("SELECT 1;")},
+		"sql/sqlite/0001_init.down.sql":  {Data: []byte("SELECT 1;")},
+		"sql/sqlite/0001_other.up.sql":   {Data: []byte("SELECT 1;")},
+		"sql/sqlite/0001_other.down.sql": {Data: []byte("SELECT 1;")},
+	}, "sqlite")
+	assert.Error(t, err, "deux migrations ne peuvent pas avoir la même version")
+
+	_, err = Load(fstest.MapFS{"sql/sqlite/init.sql": {Data: []byte("SELECT 1;")}}, "sqlite")
+	assert.Error(t, err)
+
+	_, err = Load(fstest.MapFS{}, "mysql")
+	assert.Error(t, err)
+
+	migrations, err := Load(fstest.MapFS{
+		"sql/sqlite/0010_b.up.sql":   {Data: []byte("SELECT 2;")},
+		"sql/sqlite/0010_b.down.sql": {Data: []byte("SELECT 2;")},
+		"sql/sqlite/0002_a.up.sql":   {Data: []byte("SELECT 1;")},
+		"sql/sqlite/0002_a.down.sql": {Data: []byte("SELECT 1;")},
+	}, "sqlite")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, "0002_a", migrations[0].String())
+	assert.Equal(t, "0010_b", migrations[1].String())
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements(`-- commentaire
+CREATE TABLE a (
+    id integer -- colonne
+);
+
+CREATE INDEX idx_a ON a (id);
+UPDATE a SET id = 1`)
+
+	require.Len(t, statements, 3)
+	assert.Equal(t, "CREATE TABLE a (\n    id integer -- colonne\n);", statements[0])
+	assert.Equal(t, "CREATE INDEX idx_a ON a (id);", statements[1])
+	assert.Equal(t, "UPDATE a SET id = 1", statements[2])
+}
+
+func TestMigrator_UpAndDown(t *testing.T) {
+	db := dbtest.Open(t)
+	migrator := newTestMigrator(t, db)
+	all := migrator.Migrations()
+
+	pending, err := migrator.Pending()
+	require.NoError(t, err)
+	assert.Len(t, pending, len(all))
+
+	applied, err := migrator.Up()
+	require.NoError(t, err)
+	assert.Len(t, applied, len(all))
+	assertSchemaMatchesModels(t, db)
+
+	// Une base à jour n'a plus rien à appliquer
+	applied, err = migrator.Up()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, len(all))
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "migration %d", status.Version)
+		assert.False(t, status.Unknown)
+	}
+
+	// Les tables créées sont utilisables
+	require.NoError(t, db.Create(&models.Link{ShortCode: "abc123", LongURL: "https://www.example.com"}).Error)
+
+	rolledBack, err := migrator.Down(len(all) + 5)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, len(all))
+	assert.Equal(t, all[len(all)-1].Version, rolledBack[0].Version, "la migration la plus récente est annulée en premier")
+	for _, model := range appModels {
+		assert.False(t, db.Migrator().HasTable(model))
+	}
+
+	// Les migrations annulées peuvent être réappliquées
+	applied, err = migrator.Up()
+	require.NoError(t, err)
+	assert.Len(t, applied, len(all))
+
+	_, err = migrator.Down(0)
+	assert.Error(t, err)
+}
+
+func TestMigrator_StatusReportsUnknownMigrations(t *testing.T) {
+	db := dbtest.Open(t)
+	migrator := newTestMigrator(t, db)
+	_, err := migrator.Up()
+	require.NoError(t, err)
+
+	require.NoError(t, db.Create(&schemaMigration{Version: 9999, Name: "from_newer_binary", AppliedAt: time.Now()}).Error)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	last := statuses[len(statuses)-1]
+	assert.Equal(t, 9999, last.Version)
+	assert.True(t, last.Unknown)
+
+	_, err = migrator.Down(1)
+	assert.Error(t, err, "une migration inconnue ne peut pas être annulée")
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	db := dbtest.Open(t)
+	migrator := newTestMigrator(t, db)
+	migrator.migrations = append(migrator.migrations, Migration{Version: 9999, Name: "broken", Up: "CREATE TABLE broken (;", Down: "SELECT 1;"})
+
+	_, err := migrator.Up()
+	assert.Error(t, err)
+
+	pending, err := migrator.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 9999, pending[0].Version)
+}
+
+func TestMigrator_BaselinesAutoMigrateDatabase(t *testing.T) {
+	// Base créée par une version précédente : AutoMigrate, sans table schema_migrations
+	db := dbtest.Open(t, &baselineLink{}, &baselineClick{})
+	require.NoError(t, db.Exec("INSERT INTO links (short_code, long_url, domain, max_clicks, consumed_clicks) VALUES (?, ?, '', 0, 0)",
+		"abc123", "https://www.example.com/page").Error)
+	require.NoError(t, db.Exec("INSERT INTO clicks (link_id, user_agent, browser, is_bot) VALUES (1, ?, '', ?)",
+		"Googlebot/2.1 (+http://www.google.com/bot.html)", false).Error)
+
+	migrator := newTestMigrator(t, db)
+	assert.True(t, migrator.NeedsBaseline())
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	assert.Nil(t, statuses[0].AppliedAt)
+
+	baselined, err := migrator.Baseline()
+	require.NoError(t, err)
+	assert.True(t, baselined)
+	assert.False(t, migrator.NeedsBaseline())
+
+	statuses, err = migrator.Status()
+	require.NoError(t, err)
+	for _, status := range statuses {
+		if status.Version <= BaselineVersion {
+			assert.NotNil(t, status.AppliedAt, "migration %d", status.Version)
+		} else {
+			assert.Nil(t, status.AppliedAt, "migration %d", status.Version)
+		}
+	}
+
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	assertSchemaMatchesModels(t, db)
+
+	// Les données antérieures aux colonnes récentes ont été complétées
+	var link models.Link
+	require.NoError(t, db.First(&link).Error)
+	assert.Equal(t, "www.example.com", link.Domain)
+	var click models.Click
+	require.NoError(t, db.First(&click).Error)
+	assert.True(t, click.IsBot)
+	assert.NotEmpty(t, click.Browser)
+
+	baselined, err = migrator.Baseline()
+	require.NoError(t, err)
+	assert.False(t, baselined)
+}
+
+func TestMigrator_EmptyDatabaseIsNotBaselined(t *testing.T) {
+	migrator := newTestMigrator(t, dbtest.Open(t))
+	assert.False(t, migrator.NeedsBaseline())
+
+	baselined, err := migrator.Baseline()
+	require.NoError(t, err)
+	assert.False(t, baselined)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	for _, dialect := range Dialects {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "sql", dialect), 0o755))
+		for _, file := range []string{"0001_init.up.sql", "0001_init.down.sql"} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "sql", dialect, file), []byte("SELECT 1;\n"), 0o644))
+		}
+	}
+	// Une version plus récente pour un seul moteur fixe le numéro suivant
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sql", "postgres", "0002_pg_only.up.sql"), []byte("SELECT 1;\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sql", "postgres", "0002_pg_only.down.sql"), []byte("SELECT 1;\n"), 0o644))
+
+	files, err := Create(dir, "Add API keys!")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "sql", "sqlite", "0003_add_api_keys.up.sql"),
+		filepath.Join(dir, "sql", "sqlite", "0003_add_api_keys.down.sql"),
+		filepath.Join(dir, "sql", "postgres", "0003_add_api_keys.up.sql"),
+		filepath.Join(dir, "sql", "postgres", "0003_add_api_keys.down.sql"),
+	}, files)
+
+	migrations, err := Load(os.DirFS(dir), "sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, "0003_add_api_keys", migrations[len(migrations)-1].String())
+
+	_, err = Create(dir, "!!!")
+	assert.Error(t, err)
+}
+
+func TestCreate_EmptyDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	files, err := Create(dir, "init")
+	require.NoError(t, err)
+	assert.Len(t, files, 4)
+	assert.FileExists(t, filepath.Join(dir, "sql", "postgres", "0001_init.down.sql"))
+}
@@ -0,0 +1,5 @@
+DROP TABLE webhook_dead_letters;
+DROP TABLE health_checks;
+DROP TABLE visitor_salts;
+DROP TABLE clicks;
+DROP TABLE links;
@@ -0,0 +1,80 @@
+-- Schéma initial, identique à celui créé par l'AutoMigrate de GORM avant les migrations versionnées.
+
+CREATE TABLE links (
+    id bigserial PRIMARY KEY,
+    short_code varchar(32) NOT NULL,
+    long_url text NOT NULL,
+    domain varchar(255),
+    expires_at timestamptz,
+    max_clicks bigint NOT NULL DEFAULT 0,
+    consumed_clicks bigint NOT NULL DEFAULT 0,
+    webhook_url varchar(2048),
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX idx_links_short_code ON links (short_code);
+CREATE INDEX idx_links_domain ON links (domain);
+CREATE INDEX idx_links_expires_at ON links (expires_at);
+
+CREATE TABLE clicks (
+    id bigserial PRIMARY KEY,
+    link_id bigint,
+    "timestamp" timestamptz,
+    user_agent varchar(255),
+    ip_address varchar(50),
+    referrer varchar(2048),
+    referrer_domain varchar(255),
+    browser varchar(50),
+    os varchar(50),
+    device_class varchar(20),
+    country varchar(2),
+    city varchar(100),
+    is_bot boolean NOT NULL DEFAULT false,
+    visitor_hash varchar(64),
+    CONSTRAINT fk_clicks_link FOREIGN KEY (link_id) REFERENCES links (id)
+);
+CREATE INDEX idx_clicks_link_id ON clicks (link_id);
+CREATE INDEX idx_clicks_referrer_domain ON clicks (referrer_domain);
+CREATE INDEX idx_clicks_browser ON clicks (browser);
+CREATE INDEX idx_clicks_os ON clicks (os);
+CREATE INDEX idx_clicks_device_class ON clicks (device_class);
+CREATE INDEX idx_clicks_country ON clicks (country);
+CREATE INDEX idx_clicks_is_bot ON clicks (is_bot);
+CREATE INDEX idx_clicks_visitor_hash ON clicks (visitor_hash);
+
+CREATE TABLE visitor_salts (
+    day varchar(10),
+    salt bytea NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (day)
+);
+
+CREATE TABLE health_checks (
+    id bigserial PRIMARY KEY,
+    link_id bigint NOT NULL,
+    checked_at timestamptz NOT NULL,
+    accessible boolean NOT NULL,
+    state varchar(16),
+    consecutive_failures bigint,
+    method varchar(8),
+    status_code bigint,
+    latency_ms bigint,
+    redirect_chain text,
+    error_class varchar(32),
+    "error" varchar(512)
+);
+CREATE INDEX idx_health_checks_link_checked ON health_checks (link_id, checked_at);
+CREATE INDEX idx_health_checks_checked_at ON health_checks (checked_at);
+
+CREATE TABLE webhook_dead_letters (
+    id bigserial PRIMARY KEY,
+    link_id bigint,
+    event_id varchar(64),
+    url varchar(2048) NOT NULL,
+    payload text NOT NULL,
+    attempts bigint NOT NULL,
+    last_status_code bigint,
+    last_error varchar(512),
+    created_at timestamptz
+);
+CREATE INDEX idx_webhook_dead_letters_link_id ON webhook_dead_letters (link_id);
+CREATE INDEX idx_webhook_dead_letters_event_id ON webhook_dead_letters (event_id);
@@ -0,0 +1,12 @@
+-- Clés d'accès à l'API de gestion : seule l'empreinte SHA-256 de la clé est conservée.
+
+CREATE TABLE api_keys (
+    id bigserial PRIMARY KEY,
+    name varchar(100),
+    prefix varchar(16) NOT NULL,
+    key_hash varchar(64) NOT NULL,
+    created_at timestamptz,
+    revoked_at timestamptz,
+    last_used_at timestamptz
+);
+CREATE UNIQUE INDEX idx_api_keys_prefix ON api_keys (prefix);
@@ -0,0 +1,5 @@
+ALTER TABLE api_keys DROP COLUMN user_id;
+ALTER TABLE links DROP COLUMN owner_id;
+
+DROP TABLE sessions;
+DROP TABLE users;
@@ -0,0 +1,31 @@
+-- Comptes utilisateurs, sessions, et propriétaire des liens et des clés d'API.
+-- Les liens et clés existants restent sans propriétaire : seules les clés
+-- d'administration y ont accès.
+
+CREATE TABLE users (
+    id bigserial PRIMARY KEY,
+    email varchar(255) NOT NULL,
+    password_hash varchar(60) NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+
+CREATE TABLE sessions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    created_at timestamptz,
+    expires_at timestamptz NOT NULL,
+    CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+CREATE UNIQUE INDEX idx_sessions_token_hash ON sessions (token_hash);
+CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
+
+ALTER TABLE links ADD COLUMN owner_id bigint;
+ALTER TABLE links ADD CONSTRAINT fk_links_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX idx_links_owner_id ON links (owner_id);
+
+ALTER TABLE api_keys ADD COLUMN user_id bigint;
+ALTER TABLE api_keys ADD CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
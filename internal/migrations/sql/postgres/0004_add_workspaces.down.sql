@@ -0,0 +1,5 @@
+ALTER TABLE links DROP COLUMN workspace_id;
+
+DROP TABLE invitations;
+DROP TABLE memberships;
+DROP TABLE workspaces;
@@ -0,0 +1,37 @@
+-- Espaces de travail, membres avec leur rôle, invitations, et espace de travail des liens.
+-- Les liens existants restent hors de tout espace de travail.
+
+CREATE TABLE workspaces (
+    id bigserial PRIMARY KEY,
+    name varchar(100) NOT NULL,
+    created_at timestamptz
+);
+
+CREATE TABLE memberships (
+    id bigserial PRIMARY KEY,
+    workspace_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    role varchar(16) NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_memberships_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+    CONSTRAINT fk_memberships_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_memberships_workspace_user ON memberships (workspace_id, user_id);
+CREATE INDEX idx_memberships_user_id ON memberships (user_id);
+
+CREATE TABLE invitations (
+    id bigserial PRIMARY KEY,
+    workspace_id bigint NOT NULL,
+    email varchar(255) NOT NULL,
+    role varchar(16) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    created_at timestamptz,
+    expires_at timestamptz NOT NULL,
+    CONSTRAINT fk_invitations_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_invitations_workspace_id ON invitations (workspace_id);
+CREATE UNIQUE INDEX idx_invitations_token_hash ON invitations (token_hash);
+
+ALTER TABLE links ADD COLUMN workspace_id bigint;
+ALTER TABLE links ADD CONSTRAINT fk_links_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE;
+CREATE INDEX idx_links_workspace_id ON links (workspace_id);
@@ -0,0 +1,13 @@
+-- Décompte mensuel des liens créés et des clics enregistrés, par utilisateur ou espace de travail.
+
+CREATE TABLE usage_counters (
+    id bigserial PRIMARY KEY,
+    account_type varchar(16) NOT NULL,
+    account_id bigint NOT NULL,
+    period varchar(7) NOT NULL,
+    links_created bigint NOT NULL DEFAULT 0,
+    tracked_clicks bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_usage_counters_account_period ON usage_counters (account_type, account_id, period);
+CREATE INDEX idx_usage_counters_period ON usage_counters (period);
@@ -0,0 +1,3 @@
+-- Secret de signature propre au webhook de chaque lien.
+
+ALTER TABLE links ADD COLUMN webhook_secret varchar(64);
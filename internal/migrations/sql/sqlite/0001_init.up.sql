@@ -0,0 +1,80 @@
+-- Schéma initial, identique à celui créé par l'AutoMigrate de GORM avant les migrations versionnées.
+
+CREATE TABLE links (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    short_code text NOT NULL,
+    long_url text NOT NULL,
+    domain text,
+    expires_at datetime,
+    max_clicks integer NOT NULL DEFAULT 0,
+    consumed_clicks integer NOT NULL DEFAULT 0,
+    webhook_url text,
+    created_at datetime
+);
+CREATE UNIQUE INDEX idx_links_short_code ON links (short_code);
+CREATE INDEX idx_links_domain ON links (domain);
+CREATE INDEX idx_links_expires_at ON links (expires_at);
+
+CREATE TABLE clicks (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    link_id integer,
+    "timestamp" datetime,
+    user_agent text,
+    ip_address text,
+    referrer text,
+    referrer_domain text,
+    browser text,
+    os text,
+    device_class text,
+    country text,
+    city text,
+    is_bot numeric NOT NULL DEFAULT false,
+    visitor_hash text,
+    CONSTRAINT fk_clicks_link FOREIGN KEY (link_id) REFERENCES links (id)
+);
+CREATE INDEX idx_clicks_link_id ON clicks (link_id);
+CREATE INDEX idx_clicks_referrer_domain ON clicks (referrer_domain);
+CREATE INDEX idx_clicks_browser ON clicks (browser);
+CREATE INDEX idx_clicks_os ON clicks (os);
+CREATE INDEX idx_clicks_device_class ON clicks (device_class);
+CREATE INDEX idx_clicks_country ON clicks (country);
+CREATE INDEX idx_clicks_is_bot ON clicks (is_bot);
+CREATE INDEX idx_clicks_visitor_hash ON clicks (visitor_hash);
+
+CREATE TABLE visitor_salts (
+    day text,
+    salt blob NOT NULL,
+    created_at datetime,
+    PRIMARY KEY (day)
+);
+
+CREATE TABLE health_checks (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    link_id integer NOT NULL,
+    checked_at datetime NOT NULL,
+    accessible numeric NOT NULL,
+    state text,
+    consecutive_failures integer,
+    method text,
+    status_code integer,
+    latency_ms integer,
+    redirect_chain text,
+    error_class text,
+    "error" text
+);
+CREATE INDEX idx_health_checks_link_checked ON health_checks (link_id, checked_at);
+CREATE INDEX idx_health_checks_checked_at ON health_checks (checked_at);
+
+CREATE TABLE webhook_dead_letters (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    link_id integer,
+    event_id text,
+    url text NOT NULL,
+    payload text NOT NULL,
+    attempts integer NOT NULL,
+    last_status_code integer,
+    last_error text,
+    created_at datetime
+);
+CREATE INDEX idx_webhook_dead_letters_link_id ON webhook_dead_letters (link_id);
+CREATE INDEX idx_webhook_dead_letters_event_id ON webhook_dead_letters (event_id);
@@ -0,0 +1,12 @@
+-- Clés d'accès à l'API de gestion : seule l'empreinte SHA-256 de la clé est conservée.
+
+CREATE TABLE api_keys (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text,
+    prefix text NOT NULL,
+    key_hash text NOT NULL,
+    created_at datetime,
+    revoked_at datetime,
+    last_used_at datetime
+);
+CREATE UNIQUE INDEX idx_api_keys_prefix ON api_keys (prefix);
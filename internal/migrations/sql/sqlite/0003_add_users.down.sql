@@ -0,0 +1,8 @@
+DROP INDEX idx_api_keys_user_id;
+ALTER TABLE api_keys DROP COLUMN user_id;
+
+DROP INDEX idx_links_owner_id;
+ALTER TABLE links DROP COLUMN owner_id;
+
+DROP TABLE sessions;
+DROP TABLE users;
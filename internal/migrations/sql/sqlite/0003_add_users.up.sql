@@ -0,0 +1,30 @@
+-- Comptes utilisateurs, sessions, et propriétaire des liens et des clés d'API.
+-- Les liens et clés existants restent sans propriétaire : seules les clés
+-- d'administration y ont accès.
+
+CREATE TABLE users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    email text NOT NULL,
+    password_hash text NOT NULL,
+    created_at datetime
+);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+
+CREATE TABLE sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    token_hash text NOT NULL,
+    created_at datetime,
+    expires_at datetime NOT NULL,
+    CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+CREATE UNIQUE INDEX idx_sessions_token_hash ON sessions (token_hash);
+CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
+
+-- SQLite ne permet pas de nommer une contrainte ajoutée à une table existante.
+ALTER TABLE links ADD COLUMN owner_id integer REFERENCES users (id) ON DELETE SET NULL;
+CREATE INDEX idx_links_owner_id ON links (owner_id);
+
+ALTER TABLE api_keys ADD COLUMN user_id integer REFERENCES users (id) ON DELETE CASCADE;
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,6 @@
+DROP INDEX idx_links_workspace_id;
+ALTER TABLE links DROP COLUMN workspace_id;
+
+DROP TABLE invitations;
+DROP TABLE memberships;
+DROP TABLE workspaces;
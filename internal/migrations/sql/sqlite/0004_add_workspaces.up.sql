@@ -0,0 +1,37 @@
+-- Espaces de travail, membres avec leur rôle, invitations, et espace de travail des liens.
+-- Les liens existants restent hors de tout espace de travail.
+
+CREATE TABLE workspaces (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    created_at datetime
+);
+
+CREATE TABLE memberships (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    workspace_id integer NOT NULL,
+    user_id integer NOT NULL,
+    role text NOT NULL,
+    created_at datetime,
+    CONSTRAINT fk_memberships_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+    CONSTRAINT fk_memberships_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_memberships_workspace_user ON memberships (workspace_id, user_id);
+CREATE INDEX idx_memberships_user_id ON memberships (user_id);
+
+CREATE TABLE invitations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    workspace_id integer NOT NULL,
+    email text NOT NULL,
+    role text NOT NULL,
+    token_hash text NOT NULL,
+    created_at datetime,
+    expires_at datetime NOT NULL,
+    CONSTRAINT fk_invitations_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_invitations_workspace_id ON invitations (workspace_id);
+CREATE UNIQUE INDEX idx_invitations_token_hash ON invitations (token_hash);
+
+-- SQLite ne permet pas de nommer une contrainte ajoutée à une table existante.
+ALTER TABLE links ADD COLUMN workspace_id integer REFERENCES workspaces (id) ON DELETE CASCADE;
+CREATE INDEX idx_links_workspace_id ON links (workspace_id);
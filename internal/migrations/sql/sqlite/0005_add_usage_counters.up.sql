@@ -0,0 +1,13 @@
+-- Décompte mensuel des liens créés et des clics enregistrés, par utilisateur ou espace de travail.
+
+CREATE TABLE usage_counters (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    account_type text NOT NULL,
+    account_id integer NOT NULL,
+    period text NOT NULL,
+    links_created integer NOT NULL DEFAULT 0,
+    tracked_clicks integer NOT NULL DEFAULT 0,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX idx_usage_counters_account_period ON usage_counters (account_type, account_id, period);
+CREATE INDEX idx_usage_counters_period ON usage_counters (period);
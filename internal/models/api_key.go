@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// APIKey donne accès à l'API de gestion (/api/v1). Seule l'empreinte SHA-256 de la clé
+// est conservée ; son préfixe, unique, permet de la retrouver et de l'identifier à l'affichage.
+// Une clé rattachée à un utilisateur n'accède qu'à ses liens ; sans utilisateur, c'est une
+// clé d'administration qui accède à tous les liens.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"size:100" json:"name"`
+	Prefix     string     `gorm:"uniqueIndex;size:16;not null" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;not null" json:"-"`
+	UserID     *uint      `gorm:"index" json:"user_id,omitempty"`
+	User       *User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// IsRevoked indique si la clé a été révoquée.
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
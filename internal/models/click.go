@@ -1,20 +1,79 @@
 package models
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+)
 
 type Click struct {
-	ID        uint      `gorm:"primaryKey"`
-	LinkID    uint      `gorm:"index"`
-	Link      Link      `gorm:"foreignKey:LinkID"`
-	Timestamp time.Time
-	UserAgent string    `gorm:"size:255"`
-	IPAddress string    `gorm:"size:50"`
+	ID             uint `gorm:"primaryKey"`
+	LinkID         uint `gorm:"index"`
+	Link           Link `gorm:"foreignKey:LinkID"`
+	Timestamp      time.Time
+	UserAgent      string `gorm:"size:255"`
+	IPAddress      string `gorm:"size:50"`
+	Referrer       string `gorm:"size:2048"`
+	ReferrerDomain string `gorm:"index;size:255"`
+	Browser        string `gorm:"index;size:50"`
+	OS             string `gorm:"index;size:50"`
+	DeviceClass    string `gorm:"index;size:20"`
+	Country        string `gorm:"index;size:2"`
+	City           string `gorm:"size:100"`
+	IsBot          bool   `gorm:"index;not null;default:false"`
+	VisitorHash    string `gorm:"index;size:64"`
 }
 
-
 type ClickEvent struct {
 	LinkID    uint
 	Timestamp time.Time
 	UserAgent string
 	IPAddress string
+	Referrer  string
+	IsBot     bool
+	// SpoolSegment et SpoolOffset situent l'enregistrement du spool dont provient
+	// l'événement (segment 0 hors spool), afin de l'acquitter une fois le clic enregistré.
+	SpoolSegment uint64 `json:"-"`
+	SpoolOffset  int64  `json:"-"`
+}
+
+// ReferrerDomain normalise l'en-tête Referer en nom de domaine (minuscules, sans port ni "www.").
+// Retourne une chaîne vide pour un accès direct ou un en-tête invalide.
+func ReferrerDomain(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	parsed, err := url.Parse(strings.TrimSpace(referrer))
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(parsed.Hostname())
+	return strings.TrimPrefix(host, "www.")
+}
+
+type ClickStat struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type ClickBucket struct {
+	Start          time.Time `json:"start"`
+	Count          int       `json:"count"`
+	UniqueVisitors int       `json:"unique_visitors"`
+}
+
+// ClickTotals résume l'audience d'un lien : clics bruts et visiteurs distincts.
+// Les identifiants de visiteurs changeant chaque jour, un même visiteur revenu
+// plusieurs jours est compté une fois par jour.
+type ClickTotals struct {
+	TotalClicks    int `json:"total_clicks"`
+	UniqueVisitors int `json:"unique_visitors"`
+}
+
+// VisitorSalt est le sel aléatoire du jour (UTC) utilisé pour anonymiser les visiteurs.
+// Les sels des jours passés sont supprimés, ce qui rend les empreintes irréversibles.
+type VisitorSalt struct {
+	Day       string `gorm:"primaryKey;size:10"`
+	Salt      []byte `gorm:"not null"`
+	CreatedAt time.Time
 }
@@ -46,4 +46,20 @@ func TestClickEvent_Struct(t *testing.T) {
 	assert.Equal(t, now, clickEvent.Timestamp)
 	assert.Equal(t, "Mozilla/5.0", clickEvent.UserAgent)
 	assert.Equal(t, "192.168.1.1", clickEvent.IPAddress)
-} 
\ No newline at end of file
+}
+
+func TestReferrerDomain(t *testing.T) {
+	testCases := map[string]string{
+		"":                                   "",
+		"https://www.Google.com/search?q=go": "google.com",
+		"https://t.co/abc123":                "t.co",
+		"http://news.ycombinator.com:8080/":  "news.ycombinator.com",
+		"android-app://com.slack":            "com.slack",
+		"not a url":                          "",
+		"://broken":                          "",
+	}
+
+	for referrer, expected := range testCases {
+		assert.Equal(t, expected, ReferrerDomain(referrer), referrer)
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// États d'un lien retenus par le moniteur (HealthCheck.State).
+const (
+	LinkStateAccessible   = "accessible"
+	LinkStateInaccessible = "inaccessible"
+)
+
+// HealthCheck est le résultat d'une vérification de l'URL longue d'un lien par le moniteur.
+// Accessible est le résultat de cette seule vérification ; State est l'état du lien retenu
+// après elle, qui ne bascule à inaccessible qu'après plusieurs échecs consécutifs.
+type HealthCheck struct {
+	ID                  uint       `gorm:"primaryKey" json:"-"`
+	LinkID              uint       `gorm:"index:idx_health_checks_link_checked,priority:1;not null" json:"-"`
+	CheckedAt           time.Time  `gorm:"index:idx_health_checks_link_checked,priority:2;index;not null" json:"checked_at"`
+	Accessible          bool       `gorm:"not null" json:"accessible"`
+	State               string     `gorm:"size:16" json:"state,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Method              string     `gorm:"size:8" json:"method,omitempty"`
+	StatusCode          int        `json:"status_code,omitempty"`
+	LatencyMs           int64      `json:"latency_ms"`
+	RedirectChain       StringList `gorm:"type:text" json:"redirect_chain,omitempty"`
+	ErrorClass          string     `gorm:"size:32" json:"error_class,omitempty"`
+	Error               string     `gorm:"size:512" json:"error,omitempty"`
+}
+
+// LinkAccessible retourne l'état du lien retenu après la vérification. Les vérifications
+// enregistrées avant l'introduction de State n'ont que leur propre résultat.
+func (c HealthCheck) LinkAccessible() bool {
+	if c.State == "" {
+		return c.Accessible
+	}
+	return c.State == LinkStateAccessible
+}
+
+// HealthSummary compte les vérifications d'un lien sur une période.
+type HealthSummary struct {
+	Checks     int64
+	Successful int64
+}
+
+// UptimePercent retourne la part de vérifications réussies, arrondie au centième.
+// Retourne nil en l'absence de vérification sur la période.
+func (s HealthSummary) UptimePercent() *float64 {
+	if s.Checks == 0 {
+		return nil
+	}
+	uptime := float64(s.Successful*10000/s.Checks) / 100
+	return &uptime
+}
+
+// StringList est une liste de chaînes stockée en JSON dans une colonne texte.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported type %T for StringList", value)
+	}
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
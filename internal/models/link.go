@@ -3,8 +3,38 @@ package models
 import "time"
 
 type Link struct {
-	ID        uint      `gorm:"primaryKey"`
-	ShortCode string    `gorm:"uniqueIndex;size:10;not null"`
-	LongURL   string    `gorm:"not null"`
-	CreatedAt time.Time
+	ID             uint       `gorm:"primaryKey"`
+	ShortCode      string     `gorm:"uniqueIndex;size:32;not null"`
+	LongURL        string     `gorm:"not null"`
+	Domain         string     `gorm:"index;size:255"`
+	ExpiresAt      *time.Time `gorm:"index"`
+	MaxClicks      int        `gorm:"not null;default:0"`
+	ConsumedClicks int        `gorm:"not null;default:0"`
+	WebhookURL     string     `gorm:"size:2048"`
+	WebhookSecret  string     `gorm:"size:64"`
+	OwnerID        *uint      `gorm:"index"`
+	Owner          *User      `gorm:"foreignKey:OwnerID;constraint:OnDelete:SET NULL"`
+	WorkspaceID    *uint      `gorm:"index"`
+	Workspace      *Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time
+}
+
+// IsExpired indique si la date d'expiration du lien est dépassée.
+func (l *Link) IsExpired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// IsOwnedBy indique si le lien a été créé par cet utilisateur.
+func (l *Link) IsOwnedBy(userID uint) bool {
+	return l.OwnerID != nil && *l.OwnerID == userID
+}
+
+// HasClickBudget indique si le lien est limité en nombre de clics (MaxClicks = 0 : illimité).
+func (l *Link) HasClickBudget() bool {
+	return l.MaxClicks > 0
+}
+
+// IsClickBudgetExhausted indique si tous les clics du budget du lien ont été consommés.
+func (l *Link) IsClickBudgetExhausted() bool {
+	return l.HasClickBudget() && l.ConsumedClicks >= l.MaxClicks
 }
@@ -29,4 +29,25 @@ func TestLink_EmptyStruct(t *testing.T) {
 	assert.Equal(t, "", link.ShortCode)
 	assert.Equal(t, "", link.LongURL)
 	assert.Equal(t, time.Time{}, link.CreatedAt)
-} 
\ No newline at end of file
+} 
+func TestLink_IsExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.False(t, (&Link{}).IsExpired(now))
+	assert.True(t, (&Link{ExpiresAt: &past}).IsExpired(now))
+	assert.True(t, (&Link{ExpiresAt: &now}).IsExpired(now))
+	assert.False(t, (&Link{ExpiresAt: &future}).IsExpired(now))
+}
+
+func TestLink_HasClickBudget(t *testing.T) {
+	assert.False(t, (&Link{}).HasClickBudget())
+	assert.True(t, (&Link{MaxClicks: 10}).HasClickBudget())
+}
+
+func TestLink_IsClickBudgetExhausted(t *testing.T) {
+	assert.False(t, (&Link{ConsumedClicks: 3}).IsClickBudgetExhausted())
+	assert.False(t, (&Link{MaxClicks: 10, ConsumedClicks: 9}).IsClickBudgetExhausted())
+	assert.True(t, (&Link{MaxClicks: 10, ConsumedClicks: 10}).IsClickBudgetExhausted())
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types de comptes auxquels l'usage est imputé.
+const (
+	AccountUser      = "user"
+	AccountWorkspace = "workspace"
+)
+
+// UsagePeriodLayout est le format des périodes de décompte : un mois UTC, ex: "2024-03".
+const UsagePeriodLayout = "2006-01"
+
+// Account désigne le compte auquel sont imputés les liens créés et les clics enregistrés :
+// l'espace de travail du lien, ou à défaut l'utilisateur qui l'a créé.
+type Account struct {
+	Type string `json:"type"`
+	ID   uint   `json:"id"`
+}
+
+func UserAccount(userID uint) Account {
+	return Account{Type: AccountUser, ID: userID}
+}
+
+func WorkspaceAccount(workspaceID uint) Account {
+	return Account{Type: AccountWorkspace, ID: workspaceID}
+}
+
+func (a Account) String() string {
+	return fmt.Sprintf("%s %d", a.Type, a.ID)
+}
+
+// AccountOf retourne le compte du lien, et false pour un lien créé par un administrateur
+// hors de tout espace de travail, qui n'est imputé à personne.
+func AccountOf(link *Link) (Account, bool) {
+	if link.WorkspaceID != nil {
+		return WorkspaceAccount(*link.WorkspaceID), true
+	}
+	if link.OwnerID != nil {
+		return UserAccount(*link.OwnerID), true
+	}
+	return Account{}, false
+}
+
+// UsagePeriod retourne la période de décompte (mois UTC) de l'instant t.
+func UsagePeriod(t time.Time) string {
+	return t.UTC().Format(UsagePeriodLayout)
+}
+
+// UsageCounter décompte l'usage d'un compte sur un mois. Les compteurs sont incrémentés au
+// fil de l'eau et ne diminuent pas quand un lien est supprimé.
+type UsageCounter struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	AccountType   string    `gorm:"size:16;not null;uniqueIndex:idx_usage_counters_account_period,priority:1" json:"account_type"`
+	AccountID     uint      `gorm:"not null;uniqueIndex:idx_usage_counters_account_period,priority:2" json:"account_id"`
+	Period        string    `gorm:"size:7;not null;uniqueIndex:idx_usage_counters_account_period,priority:3;index" json:"period"`
+	LinksCreated  int64     `gorm:"not null;default:0" json:"links_created"`
+	TrackedClicks int64     `gorm:"not null;default:0" json:"tracked_clicks"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (c UsageCounter) Account() Account {
+	return Account{Type: c.AccountType, ID: c.AccountID}
+}
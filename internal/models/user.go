@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// User est un compte de l'API de gestion. Le mot de passe n'est conservé que haché (bcrypt).
+type User struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Email        string    `gorm:"uniqueIndex;size:255;not null" json:"email"`
+	PasswordHash string    `gorm:"size:60;not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Session est une session ouverte par connexion. Seule l'empreinte SHA-256 du jeton
+// remis à l'utilisateur est conservée.
+type Session struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	User      User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+// IsExpired indique si la session a expiré.
+func (s *Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// WebhookDeadLetter conserve une notification webhook abandonnée après épuisement des tentatives,
+// pour diagnostic ou renvoi manuel.
+type WebhookDeadLetter struct {
+	ID             uint   `gorm:"primaryKey"`
+	LinkID         uint   `gorm:"index"`
+	EventID        string `gorm:"size:64;index"`
+	URL            string `gorm:"size:2048;not null"`
+	Payload        string `gorm:"type:text;not null"`
+	Attempts       int    `gorm:"not null"`
+	LastStatusCode int
+	LastError      string `gorm:"size:512"`
+	CreatedAt      time.Time
+}
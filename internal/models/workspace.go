@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Role est le rôle d'un membre dans un espace de travail.
+type Role string
+
+// Rôles d'un membre, du plus au moins privilégié : le propriétaire gère les membres et
+// les invitations, l'éditeur crée, modifie et supprime les liens, le lecteur les consulte.
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// IsValid indique si le rôle est l'un des rôles connus.
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Allows indique si ce rôle a au moins les droits du rôle demandé.
+func (r Role) Allows(required Role) bool {
+	return r.IsValid() && roleRanks[r] >= roleRanks[required]
+}
+
+// Workspace est un espace de travail partagé par une équipe, propriétaire de ses liens.
+type Workspace struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership rattache un utilisateur à un espace de travail avec un rôle.
+type Membership struct {
+	ID          uint       `gorm:"primaryKey" json:"-"`
+	WorkspaceID uint       `gorm:"uniqueIndex:idx_memberships_workspace_user,priority:1;not null" json:"workspace_id"`
+	Workspace   *Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"workspace,omitempty"`
+	UserID      uint       `gorm:"uniqueIndex:idx_memberships_workspace_user,priority:2;index;not null" json:"user_id"`
+	User        *User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Role        Role       `gorm:"size:16;not null" json:"role"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Invitation invite une adresse e-mail à rejoindre un espace de travail. Seule l'empreinte
+// SHA-256 du jeton remis à l'invité est conservée ; l'invitation est supprimée une fois acceptée.
+type Invitation struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint       `gorm:"index;not null" json:"workspace_id"`
+	Workspace   *Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"workspace,omitempty"`
+	Email       string     `gorm:"size:255;not null" json:"email"`
+	Role        Role       `gorm:"size:16;not null" json:"role"`
+	TokenHash   string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+}
+
+// IsExpired indique si l'invitation a expiré.
+func (i *Invitation) IsExpired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
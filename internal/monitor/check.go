@@ -0,0 +1,233 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+)
+
+const (
+	userAgent = "url-shortener-monitor/1.0"
+
+	// maxRedirects borne la chaîne de redirections suivie pour une vérification.
+	maxRedirects = 10
+	// sniffSize est la taille du début de page demandée par le GET de repli, suffisante
+	// pour y trouver le titre.
+	sniffSize = 16 << 10
+)
+
+var errTooManyRedirects = fmt.Errorf("stopped after %d redirects", maxRedirects)
+
+var (
+	loginPathPattern     = regexp.MustCompile(`(?i)(^|/)(login|log-in|signin|sign-in|sign_in|sso|auth|authenticate)(/|\.|$)`)
+	notFoundTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>\s*(404\b|[^<]*\b(not found|error 404|404 error|page introuvable|does not exist|doesn't exist|no longer available)\b)`)
+	parkedPagePattern    = regexp.MustCompile(`(?i)(domain (name )?(is|may be) for sale|buy this domain|this domain is parked|parked free|domain parking)`)
+)
+
+// parkingHosts sont les services de parking de domaines vers lesquels redirigent les domaines expirés.
+var parkingHosts = []string{
+	"sedoparking.com", "parkingcrew.net", "bodis.com", "above.com",
+	"dan.com", "afternic.com", "hugedomains.com", "parklogic.com",
+}
+
+// checkUrl vérifie une URL et retourne le résultat à enregistrer (sans identifiant de lien).
+// La vérification utilise HEAD, puis un GET partiel (Range) si le serveur refuse HEAD ou
+// si la page est du HTML, dont le début est alors examiné.
+func (m *UrlMonitor) checkUrl(rawURL string) models.HealthCheck {
+	start := time.Now()
+	check := models.HealthCheck{CheckedAt: start, Method: http.MethodHead}
+
+	resp, chain, err := m.fetch(http.MethodHead, rawURL)
+	if err == nil && headUnsupported(resp.StatusCode) {
+		closeBody(resp)
+		check.Method = http.MethodGet
+		resp, chain, err = m.fetch(http.MethodGet, rawURL)
+	} else if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 && isHTML(resp.Header.Get("Content-Type")) {
+		// HEAD ne renvoie pas le contenu : une page HTML est relue en GET partiel pour y
+		// détecter une page « introuvable ». Si ce GET échoue, la réponse à HEAD est retenue.
+		if getResp, getChain, getErr := m.fetch(http.MethodGet, rawURL); getErr == nil {
+			closeBody(resp)
+			check.Method = http.MethodGet
+			resp, chain = getResp, getChain
+		}
+	}
+	check.RedirectChain = chain
+	if err != nil {
+		check.LatencyMs = time.Since(start).Milliseconds()
+		log.Printf("[MONITOR] Error accessing URL '%s': %v", rawURL, err)
+		check.ErrorClass = classifyError(err)
+		check.Error = truncate(err.Error(), 512)
+		return check
+	}
+	defer closeBody(resp)
+
+	var body []byte
+	if check.Method == http.MethodGet && isHTML(resp.Header.Get("Content-Type")) {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, sniffSize))
+	}
+	check.LatencyMs = time.Since(start).Milliseconds()
+
+	check.StatusCode = resp.StatusCode
+	switch {
+	case resp.StatusCode >= 500:
+		check.ErrorClass = ErrorClassHTTP5xx
+	case resp.StatusCode >= 400:
+		check.ErrorClass = ErrorClassHTTP4xx
+	case resp.StatusCode < 200:
+		check.ErrorClass = ErrorClassOther
+	default:
+		if reason := softNotFound(rawURL, resp.Request.URL, len(chain) > 0, body); reason != "" {
+			check.ErrorClass = ErrorClassSoft404
+			check.Error = reason
+			return check
+		}
+		check.Accessible = true
+	}
+	return check
+}
+
+// fetch envoie une requête en suivant les redirections et retourne les URL traversées.
+func (m *UrlMonitor) fetch(method string, rawURL string) (*http.Response, models.StringList, error) {
+	req, err := http.NewRequestWithContext(m.checks, method, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if method == http.MethodGet {
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", sniffSize-1))
+	}
+
+	var chain models.StringList
+	client := *m.client
+	client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		chain = append(chain, next.URL.String())
+		if len(via) >= maxRedirects {
+			return errTooManyRedirects
+		}
+		return nil
+	}
+	resp, err := client.Do(req)
+	return resp, chain, err
+}
+
+// headUnsupported indique si un statut signifie probablement que le serveur refuse HEAD
+// plutôt que la ressource elle-même.
+func headUnsupported(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusForbidden:
+		return true
+	}
+	return false
+}
+
+// softNotFound détecte une page répondue en 2xx qui n'est pas celle attendue : redirection
+// vers une page de connexion, un parking de domaine ou la racine du site, ou page dont le
+// contenu annonce une erreur 404. Retourne la raison, ou une chaîne vide.
+func softNotFound(original string, final *url.URL, redirected bool, body []byte) string {
+	if redirected {
+		if isParkingHost(final.Hostname()) {
+			return "redirected to a domain parking page"
+		}
+		if origin, err := url.Parse(original); err == nil {
+			if loginPathPattern.MatchString(final.Path) && !loginPathPattern.MatchString(origin.Path) {
+				return "redirected to a login page"
+			}
+			if isRootPath(final.Path) && !isRootPath(origin.Path) && sameSite(origin.Hostname(), final.Hostname()) {
+				return "redirected to the site root"
+			}
+		}
+	}
+	if notFoundTitlePattern.Match(body) {
+		return "page title indicates the page was not found"
+	}
+	if parkedPagePattern.Match(body) {
+		return "page looks like a parked domain"
+	}
+	return ""
+}
+
+func isParkingHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, parking := range parkingHosts {
+		if host == parking || strings.HasSuffix(host, "."+parking) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRootPath(path string) bool {
+	return path == "" || path == "/"
+}
+
+func sameSite(a, b string) bool {
+	return strings.TrimPrefix(strings.ToLower(a), "www.") == strings.TrimPrefix(strings.ToLower(b), "www.")
+}
+
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		log.Printf("[MONITOR] Warning: Failed to close response body: %v", err)
+	}
+}
+
+// classifyError range une erreur de requête dans une classe stable, exploitable dans les statistiques.
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var opErr *net.OpError
+
+	switch {
+	case errors.Is(err, errTooManyRedirects):
+		return ErrorClassTooManyRedirects
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return ErrorClassTimeout
+		}
+		return ErrorClassDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &unknownAuthority),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidCert):
+		return ErrorClassTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.As(err, &opErr):
+		if opErr.Op == "remote error" {
+			return ErrorClassTLS
+		}
+		return ErrorClassConnection
+	default:
+		return ErrorClassOther
+	}
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
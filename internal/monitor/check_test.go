@@ -0,0 +1,159 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMonitor() *UrlMonitor {
+	return NewUrlMonitor(&fakeLinkRepository{}, newFakeHealthCheckRepository(), time.Minute, Options{})
+}
+
+func TestCheckUrl_FallsBackToRangedGet(t *testing.T) {
+	var rangeHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		rangeHeader = r.Header.Get("Range")
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer server.Close()
+
+	check := newTestMonitor().checkUrl(server.URL)
+
+	assert.True(t, check.Accessible)
+	assert.Equal(t, http.MethodGet, check.Method)
+	assert.Equal(t, http.StatusPartialContent, check.StatusCode)
+	assert.Equal(t, fmt.Sprintf("bytes=0-%d", sniffSize-1), rangeHeader)
+}
+
+func TestCheckUrl_KeepsHeadWhenSupported(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	check := newTestMonitor().checkUrl(server.URL)
+
+	assert.Equal(t, []string{http.MethodHead}, methods)
+	assert.Equal(t, http.MethodHead, check.Method)
+	assert.Equal(t, ErrorClassHTTP4xx, check.ErrorClass)
+}
+
+func TestCheckUrl_SniffsHtmlAfterHead(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head><title>Welcome</title></head></html>")
+	}))
+	defer server.Close()
+
+	check := newTestMonitor().checkUrl(server.URL)
+
+	assert.True(t, check.Accessible)
+	assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods)
+	assert.Equal(t, http.MethodGet, check.Method)
+}
+
+func TestCheckUrl_RecordsRedirectChain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/article", http.StatusFound))
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	check := newTestMonitor().checkUrl(server.URL + "/old")
+
+	assert.True(t, check.Accessible)
+	assert.Equal(t, []string{server.URL + "/moved", server.URL + "/article"}, []string(check.RedirectChain))
+}
+
+func TestCheckUrl_StopsRedirectLoops(t *testing.T) {
+	server := httptest.NewServer(http.RedirectHandler("/loop", http.StatusFound))
+	defer server.Close()
+
+	check := newTestMonitor().checkUrl(server.URL + "/loop")
+
+	assert.False(t, check.Accessible)
+	assert.Equal(t, ErrorClassTooManyRedirects, check.ErrorClass)
+	assert.Len(t, check.RedirectChain, maxRedirects)
+}
+
+func TestCheckUrl_DetectsSoft404(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/private/doc", http.RedirectHandler("/accounts/login?next=/private/doc", http.StatusFound))
+	mux.Handle("/deleted/post", http.RedirectHandler("/", http.StatusMovedPermanently))
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><head><title>Oops! Page Not Found</title></head></html>")
+	})
+	mux.HandleFunc("/parked", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>This domain may be for sale!</body></html>")
+	})
+	mux.HandleFunc("/removed", func(w http.ResponseWriter, r *http.Request) {
+		// HEAD accepté : seul le contenu, obtenu en GET, révèle la page « introuvable »
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><head><title>404 - Not Found</title></head></html>")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	m := newTestMonitor()
+	tests := []struct {
+		path   string
+		reason string
+	}{
+		{"/private/doc", "redirected to a login page"},
+		{"/deleted/post", "redirected to the site root"},
+		{"/missing", "page title indicates the page was not found"},
+		{"/parked", "page looks like a parked domain"},
+		{"/removed", "page title indicates the page was not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			check := m.checkUrl(server.URL + tt.path)
+			assert.False(t, check.Accessible)
+			assert.Equal(t, http.StatusOK, check.StatusCode)
+			assert.Equal(t, ErrorClassSoft404, check.ErrorClass)
+			assert.Equal(t, tt.reason, check.Error)
+		})
+	}
+}
+
+func TestSoftNotFound_IgnoresOrdinaryPages(t *testing.T) {
+	final := mustParseURL(t, "https://example.com/")
+	assert.Empty(t, softNotFound("https://example.com", final, false, nil))
+	// Redirection vers la racine d'un autre site : lien de campagne classique
+	assert.Empty(t, softNotFound("https://t.example/abc", final, true, nil))
+	assert.Empty(t, softNotFound("https://example.com/blog", mustParseURL(t, "https://example.com/blog/"), true,
+		[]byte("<title>How we reduced 404 errors</title>")))
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	parsed, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return parsed
+}
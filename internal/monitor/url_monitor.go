@@ -1,44 +1,257 @@
 package monitor
 
 import (
+	"context"
 	"log"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/models"
 	"github.com/Edofo/bitly-clone/internal/repository"
 )
 
+// Options règle la charge générée par le moniteur : Concurrency vérifications simultanées
+// au total, au plus PerHostConcurrency par hôte de destination et espacées d'au moins
+// PerHostInterval. Les vérifications sont étalées aléatoirement sur les JitterPercent
+// premiers pourcents de l'intervalle pour ne pas toutes partir au même instant.
+// L'historique des vérifications est conservé HistoryRetention (0 : indéfiniment).
+// Un lien n'est considéré inaccessible qu'après FailureThreshold échecs consécutifs ;
+// une seule vérification réussie suffit à le rétablir.
+type Options struct {
+	Concurrency        int
+	PerHostConcurrency int
+	PerHostInterval    time.Duration
+	Timeout            time.Duration
+	JitterPercent      int
+	HistoryRetention   time.Duration
+	FailureThreshold   int
+}
+
+// Classes d'erreur enregistrées avec chaque vérification en échec.
+const (
+	ErrorClassTimeout    = "timeout"
+	ErrorClassDNS        = "dns"
+	ErrorClassTLS        = "tls"
+	ErrorClassConnection = "connection"
+	ErrorClassHTTP4xx    = "http_4xx"
+	ErrorClassHTTP5xx    = "http_5xx"
+	// ErrorClassSoft404 : réponse 2xx qui n'est pas la page attendue (page de connexion,
+	// domaine parqué, page « introuvable »).
+	ErrorClassSoft404          = "soft_404"
+	ErrorClassTooManyRedirects = "too_many_redirects"
+	ErrorClassOther            = "other"
+)
+
+const (
+	DefaultConcurrency        = 20
+	DefaultPerHostConcurrency = 2
+	DefaultTimeout            = 5 * time.Second
+	DefaultFailureThreshold   = 2
+)
+
+func (o Options) withDefaults() Options {
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	if o.PerHostConcurrency <= 0 {
+		o.PerHostConcurrency = DefaultPerHostConcurrency
+	}
+	if o.PerHostInterval < 0 {
+		o.PerHostInterval = 0
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.JitterPercent < 0 {
+		o.JitterPercent = 0
+	}
+	if o.JitterPercent > 100 {
+		o.JitterPercent = 100
+	}
+	if o.HistoryRetention < 0 {
+		o.HistoryRetention = 0
+	}
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = DefaultFailureThreshold
+	}
+	return o
+}
+
+// StateChange décrit le passage d'un lien d'un état à l'autre, avec la vérification qui l'a constaté.
+type StateChange struct {
+	Link     models.Link
+	Previous bool
+	Current  bool
+	Check    models.HealthCheck
+}
+
+// StateChangeNotifier est prévenu de chaque changement d'état, par exemple pour envoyer un webhook.
+// NotifyStateChange ne doit pas bloquer : les vérifications attendent son retour.
+type StateChangeNotifier interface {
+	NotifyStateChange(change StateChange)
+}
+
+// linkState est l'état retenu pour un lien et le nombre d'échecs consécutifs qui le précèdent.
+type linkState struct {
+	accessible bool
+	failures   int
+}
+
 type UrlMonitor struct {
 	linkRepo    repository.LinkRepository
+	healthRepo  repository.HealthCheckRepository
 	interval    time.Duration
-	knownStates map[uint]bool
+	opts        Options
+	client      *http.Client
+	knownStates map[uint]*linkState
 	mu          sync.Mutex
+	running     atomic.Bool
+	notifier    StateChangeNotifier
+
+	// cancel arrête la boucle de Start ; wg suit cette boucle et le passage en cours.
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// checks porte les requêtes des vérifications ; abort les interrompt si Stop expire.
+	checks context.Context
+	abort  context.CancelFunc
 }
 
-func NewUrlMonitor(linkRepo repository.LinkRepository, interval time.Duration) *UrlMonitor {
+func NewUrlMonitor(linkRepo repository.LinkRepository, healthRepo repository.HealthCheckRepository, interval time.Duration, opts Options) *UrlMonitor {
+	opts = opts.withDefaults()
+	checks, abort := context.WithCancel(context.Background())
 	return &UrlMonitor{
 		linkRepo:    linkRepo,
+		healthRepo:  healthRepo,
 		interval:    interval,
-		knownStates: make(map[uint]bool),
+		opts:        opts,
+		client:      newHTTPClient(opts),
+		knownStates: make(map[uint]*linkState),
+		checks:      checks,
+		abort:       abort,
 	}
 }
 
-func (m *UrlMonitor) Start() {
-	log.Printf("[MONITOR] Starting URL monitor with interval %v...", m.interval)
-	ticker := time.NewTicker(m.interval)
-	defer ticker.Stop()
+// newHTTPClient crée le client partagé par toutes les vérifications : les connexions
+// vers un même hôte sont réutilisées d'un passage à l'autre.
+func newHTTPClient(opts Options) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   opts.Timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          opts.Concurrency * 2,
+		MaxIdleConnsPerHost:   opts.PerHostConcurrency,
+		MaxConnsPerHost:       opts.PerHostConcurrency,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		ForceAttemptHTTP2:     true,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+	}
+}
+
+// SetNotifier enregistre le destinataire des changements d'état. À appeler avant Start.
+func (m *UrlMonitor) SetNotifier(notifier StateChangeNotifier) {
+	m.notifier = notifier
+}
+
+// Start lance un passage de vérification toutes les interval, jusqu'à l'annulation de ctx
+// ou l'appel de Stop.
+func (m *UrlMonitor) Start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+	log.Printf("[MONITOR] Starting URL monitor with interval %v (concurrency %d, %d per host)...",
+		m.interval, m.opts.Concurrency, m.opts.PerHostConcurrency)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.loadKnownStates()
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
 
-	m.checkUrls()
+		for {
+			m.wg.Add(1)
+			go func() {
+				defer m.wg.Done()
+				m.runOnce(ctx)
+			}()
 
-	for range ticker.C {
-		m.checkUrls()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cesse de lancer des vérifications et attend la fin du passage en cours. Passé
+// l'échéance de ctx, les vérifications encore en cours sont interrompues sans être
+// enregistrées. Au retour, le moniteur n'utilise plus ni les dépôts ni le notifier.
+func (m *UrlMonitor) Stop(ctx context.Context) {
+	if m.cancel != nil {
+		m.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("[MONITOR] Warning: URL status check still running at shutdown, interrupting it.")
+		m.abort()
+		<-done
+	}
+	m.abort()
+}
+
+// loadKnownStates reprend le dernier état enregistré de chaque lien, pour que les
+// changements d'état survenus pendant un redémarrage soient tout de même notifiés.
+func (m *UrlMonitor) loadKnownStates() {
+	latest, err := m.healthRepo.GetLatestHealthChecks()
+	if err != nil {
+		log.Printf("[MONITOR] Warning: Failed to load previous link states: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for linkID, check := range latest {
+		m.knownStates[linkID] = &linkState{accessible: check.LinkAccessible(), failures: check.ConsecutiveFailures}
 	}
+	log.Printf("[MONITOR] Restored previous state of %d link(s).", len(latest))
 }
 
-func (m *UrlMonitor) checkUrls() {
+// runOnce lance un passage de vérification, sauf si le précédent n'est pas encore terminé.
+func (m *UrlMonitor) runOnce(ctx context.Context) {
+	if !m.running.CompareAndSwap(false, true) {
+		log.Println("[MONITOR] Previous URL status check still running, skipping this run.")
+		return
+	}
+	defer m.running.Store(false)
+	m.checkUrls(ctx)
+}
+
+// checkUrls vérifie tous les liens. Une fois ctx annulé, plus aucune vérification n'est
+// lancée : seules celles en cours sont attendues.
+func (m *UrlMonitor) checkUrls(ctx context.Context) {
 	log.Println("[MONITOR] Starting URL status check...")
+	start := time.Now()
 
 	links, err := m.linkRepo.GetAllLinks()
 	if err != nil {
@@ -46,46 +259,257 @@ func (m *UrlMonitor) checkUrls() {
 		return
 	}
 
-	for _, link := range links {
-		currentState := m.isUrlAccessible(link.LongURL)
+	// Les workers ne font que vérifier : c'est la boucle de répartition qui retient les liens
+	// d'un hôte tant qu'il a atteint sa limite ou que son délai minimum n'est pas écoulé.
+	jobs := make(chan models.Link)
+	// done peut recevoir le résultat de chaque worker sans lecteur, après une annulation.
+	done := make(chan string, m.opts.Concurrency)
 
-		m.mu.Lock()
-		previousState, exists := m.knownStates[link.ID]
-		m.knownStates[link.ID] = currentState
-		m.mu.Unlock()
+	var wg sync.WaitGroup
+	for i := 0; i < m.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				m.checkLink(link)
+				done <- hostOf(link.LongURL)
+			}
+		}()
+	}
 
-		if !exists {
-			log.Printf("[MONITOR] Initial state for link %s (%s): %s",
-				link.ShortCode, link.LongURL, formatState(currentState))
-			continue
+	scheduled := m.schedule(links)
+	queue := newHostQueue(m.opts.PerHostConcurrency, m.opts.PerHostInterval)
+	for remaining := len(scheduled); remaining > 0 && ctx.Err() == nil; {
+		now := time.Now()
+		for len(scheduled) > 0 && !start.Add(scheduled[0].offset).After(now) {
+			queue.push(scheduled[0].link)
+			scheduled = scheduled[1:]
+		}
+
+		var send chan models.Link
+		link, host, ready := queue.peek(now)
+		if ready {
+			send = jobs
 		}
+		wake := queue.nextReady(now)
+		if len(scheduled) > 0 {
+			if at := start.Add(scheduled[0].offset); wake.IsZero() || at.Before(wake) {
+				wake = at
+			}
+		}
+		var timer <-chan time.Time
+		if !wake.IsZero() {
+			timer = time.After(time.Until(wake))
+		}
+
+		select {
+		case send <- link:
+			queue.dispatch(host, now)
+		case host := <-done:
+			queue.release(host)
+			remaining--
+		case <-timer:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		log.Printf("[MONITOR] URL status check interrupted after %v.", time.Since(start).Round(time.Millisecond))
+		return
+	}
+
+	log.Printf("[MONITOR] URL status check completed: %d link(s) in %v.", len(links), time.Since(start).Round(time.Millisecond))
+	m.pruneHistory()
+}
+
+func (m *UrlMonitor) pruneHistory() {
+	if m.opts.HistoryRetention == 0 {
+		return
+	}
+	deleted, err := m.healthRepo.DeleteHealthChecksBefore(time.Now().Add(-m.opts.HistoryRetention))
+	if err != nil {
+		log.Printf("[MONITOR] Warning: Failed to prune health check history: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("[MONITOR] Pruned %d health check(s) older than %v.", deleted, m.opts.HistoryRetention)
+	}
+}
+
+type scheduledLink struct {
+	link   models.Link
+	offset time.Duration
+}
 
-		if previousState != currentState {
-			log.Printf("[NOTIFICATION] Link %s (%s) changed from %s to %s!",
-				link.ShortCode, link.LongURL, formatState(previousState), formatState(currentState))
+// schedule attribue à chaque lien un instant de départ aléatoire dans la fenêtre de jitter.
+func (m *UrlMonitor) schedule(links []models.Link) []scheduledLink {
+	window := m.interval * time.Duration(m.opts.JitterPercent) / 100
+	scheduled := make([]scheduledLink, len(links))
+	for i, link := range links {
+		scheduled[i].link = link
+		if window > 0 {
+			scheduled[i].offset = rand.N(window)
 		}
 	}
-	log.Println("[MONITOR] URL status check completed.")
+	sort.SliceStable(scheduled, func(i, j int) bool { return scheduled[i].offset < scheduled[j].offset })
+	return scheduled
 }
 
-func (m *UrlMonitor) isUrlAccessible(url string) bool {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+func (m *UrlMonitor) checkLink(link models.Link) {
+	check := m.checkUrl(link.LongURL)
+	if m.checks.Err() != nil {
+		// Vérification interrompue par Stop : son échec ne dit rien du lien.
+		return
 	}
 
-	resp, err := client.Head(url)
+	check.LinkID = link.ID
+
+	m.mu.Lock()
+	state, exists := m.knownStates[link.ID]
+	if !exists {
+		state = &linkState{accessible: check.Accessible}
+		m.knownStates[link.ID] = state
+	}
+	previousState := state.accessible
+	if check.Accessible {
+		state.failures = 0
+		state.accessible = true
+	} else {
+		state.failures++
+		if state.failures >= m.opts.FailureThreshold {
+			state.accessible = false
+		}
+	}
+	currentState := state.accessible
+	failures := state.failures
+	check.ConsecutiveFailures = failures
+	check.State = models.LinkStateInaccessible
+	if currentState {
+		check.State = models.LinkStateAccessible
+	}
+	m.mu.Unlock()
+
+	if err := m.healthRepo.CreateHealthCheck(&check); err != nil {
+		log.Printf("[MONITOR] Warning: Failed to record health check for link %s: %v", link.ShortCode, err)
+	}
+
+	if !exists {
+		log.Printf("[MONITOR] Initial state for link %s (%s): %s",
+			link.ShortCode, link.LongURL, formatState(currentState))
+		return
+	}
+
+	if previousState != currentState {
+		log.Printf("[NOTIFICATION] Link %s (%s) changed from %s to %s!",
+			link.ShortCode, link.LongURL, formatState(previousState), formatState(currentState))
+		if m.notifier != nil {
+			m.notifier.NotifyStateChange(StateChange{Link: link, Previous: previousState, Current: currentState, Check: check})
+		}
+	} else if currentState && !check.Accessible {
+		log.Printf("[MONITOR] Link %s (%s) failed check %d/%d (%s), keeping it %s.",
+			link.ShortCode, link.LongURL, failures, m.opts.FailureThreshold, check.ErrorClass, formatState(currentState))
+	}
+}
+
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
 	if err != nil {
-		log.Printf("[MONITOR] Error accessing URL '%s': %v", url, err)
-		return false
+		return ""
 	}
+	return parsed.Hostname()
+}
 
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("[MONITOR] Warning: Failed to close response body: %v", err)
+// hostQueue met en attente les liens à vérifier, hôte par hôte, pour borner le nombre de
+// vérifications simultanées par hôte et imposer un délai minimum entre deux vérifications
+// d'un même hôte. Elle n'est utilisée que par la boucle de répartition de checkUrls.
+type hostQueue struct {
+	concurrency int
+	interval    time.Duration
+
+	hosts map[string]*hostSlot
+	// waiting liste les hôtes ayant des liens en attente, dans l'ordre où ils ont été servis.
+	waiting []string
+}
+
+type hostSlot struct {
+	links    []models.Link
+	inFlight int
+	next     time.Time
+}
+
+func newHostQueue(concurrency int, interval time.Duration) *hostQueue {
+	return &hostQueue{
+		concurrency: concurrency,
+		interval:    interval,
+		hosts:       make(map[string]*hostSlot),
+	}
+}
+
+func (q *hostQueue) push(link models.Link) {
+	host := hostOf(link.LongURL)
+	slot, ok := q.hosts[host]
+	if !ok {
+		slot = &hostSlot{}
+		q.hosts[host] = slot
+	}
+	if len(slot.links) == 0 {
+		q.waiting = append(q.waiting, host)
+	}
+	slot.links = append(slot.links, link)
+}
+
+func (q *hostQueue) isReady(slot *hostSlot, now time.Time) bool {
+	return slot.inFlight < q.concurrency && !slot.next.After(now)
+}
+
+// peek retourne le prochain lien d'un hôte prêt à être vérifié, sans le retirer de la file.
+func (q *hostQueue) peek(now time.Time) (models.Link, string, bool) {
+	for _, host := range q.waiting {
+		if slot := q.hosts[host]; q.isReady(slot, now) {
+			return slot.links[0], host, true
 		}
-	}()
+	}
+	return models.Link{}, "", false
+}
+
+// nextReady retourne l'instant où un hôte en attente de son délai minimum redeviendra prêt
+// (zéro si aucun : les hôtes restants attendent la fin d'une vérification en cours).
+func (q *hostQueue) nextReady(now time.Time) time.Time {
+	var at time.Time
+	for _, host := range q.waiting {
+		slot := q.hosts[host]
+		if slot.inFlight >= q.concurrency || !slot.next.After(now) {
+			continue
+		}
+		if at.IsZero() || slot.next.Before(at) {
+			at = slot.next
+		}
+	}
+	return at
+}
+
+// dispatch retire de la file le lien retourné par peek pour cet hôte.
+func (q *hostQueue) dispatch(host string, now time.Time) {
+	slot := q.hosts[host]
+	slot.links = slot.links[1:]
+	slot.inFlight++
+	slot.next = now.Add(q.interval)
+
+	for i, waiting := range q.waiting {
+		if waiting == host {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			break
+		}
+	}
+	if len(slot.links) > 0 {
+		q.waiting = append(q.waiting, host)
+	}
+}
 
-	return resp.StatusCode >= 200 && resp.StatusCode < 400
+func (q *hostQueue) release(host string) {
+	q.hosts[host].inFlight--
 }
 
 func formatState(accessible bool) string {
@@ -0,0 +1,394 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLinkRepository struct {
+	repository.LinkRepository
+	links []models.Link
+	calls atomic.Int32
+}
+
+func (r *fakeLinkRepository) GetAllLinks() ([]models.Link, error) {
+	r.calls.Add(1)
+	return r.links, nil
+}
+
+// fakeHealthCheckRepository mémorise les vérifications enregistrées par le moniteur.
+type fakeHealthCheckRepository struct {
+	repository.HealthCheckRepository
+	mu     sync.Mutex
+	checks []models.HealthCheck
+	latest map[uint]models.HealthCheck
+}
+
+func newFakeHealthCheckRepository() *fakeHealthCheckRepository {
+	return &fakeHealthCheckRepository{latest: make(map[uint]models.HealthCheck)}
+}
+
+func (r *fakeHealthCheckRepository) CreateHealthCheck(check *models.HealthCheck) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, *check)
+	return nil
+}
+
+func (r *fakeHealthCheckRepository) GetLatestHealthChecks() (map[uint]models.HealthCheck, error) {
+	return r.latest, nil
+}
+
+func (r *fakeHealthCheckRepository) DeleteHealthChecksBefore(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// inFlightServer répond après delay et mémorise le nombre maximum de requêtes simultanées.
+type inFlightServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	current  int
+	max      int
+	requests int
+}
+
+func newInFlightServer(t *testing.T, delay time.Duration, status int) *inFlightServer {
+	s := &inFlightServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.current++
+		s.requests++
+		if s.current > s.max {
+			s.max = s.current
+		}
+		s.mu.Unlock()
+
+		time.Sleep(delay)
+
+		s.mu.Lock()
+		s.current--
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func linksTo(baseURL string, count int) []models.Link {
+	links := make([]models.Link, count)
+	for i := range links {
+		links[i] = models.Link{ID: uint(i + 1), ShortCode: fmt.Sprintf("code%d", i), LongURL: fmt.Sprintf("%s/page/%d", baseURL, i)}
+	}
+	return links
+}
+
+func TestCheckUrls_LimitsConcurrencyPerHost(t *testing.T) {
+	server := newInFlightServer(t, 30*time.Millisecond, http.StatusOK)
+	repo := &fakeLinkRepository{links: linksTo(server.URL, 8)}
+	m := NewUrlMonitor(repo, newFakeHealthCheckRepository(), time.Minute, Options{Concurrency: 8, PerHostConcurrency: 2})
+
+	m.checkUrls(context.Background())
+
+	assert.Equal(t, 8, server.requests)
+	assert.Equal(t, 2, server.max)
+	assert.Len(t, m.knownStates, 8)
+	assert.True(t, m.knownStates[1].accessible)
+}
+
+func TestCheckUrls_SpacesRequestsToSameHost(t *testing.T) {
+	server := newInFlightServer(t, 0, http.StatusOK)
+	repo := &fakeLinkRepository{links: linksTo(server.URL, 3)}
+	m := NewUrlMonitor(repo, newFakeHealthCheckRepository(), time.Minute, Options{Concurrency: 3, PerHostConcurrency: 3, PerHostInterval: 40 * time.Millisecond})
+
+	start := time.Now()
+	m.checkUrls(context.Background())
+
+	// Trois requêtes vers le même hôte : au moins deux délais entre elles
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+	assert.Equal(t, 3, server.requests)
+}
+
+func TestCheckUrls_ChecksHostsInParallel(t *testing.T) {
+	first := newInFlightServer(t, 100*time.Millisecond, http.StatusOK)
+	second := newInFlightServer(t, 100*time.Millisecond, http.StatusOK)
+	// Même adresse, mais deux noms d'hôte distincts pour le limiteur
+	secondURL := strings.Replace(second.URL, "127.0.0.1", "localhost", 1)
+	repo := &fakeLinkRepository{links: append(linksTo(first.URL, 1), linksTo(secondURL, 1)...)}
+	repo.links[1].ID = 2
+	m := NewUrlMonitor(repo, newFakeHealthCheckRepository(), time.Minute, Options{Concurrency: 2, PerHostConcurrency: 1})
+
+	start := time.Now()
+	m.checkUrls(context.Background())
+
+	assert.Less(t, time.Since(start), 190*time.Millisecond)
+}
+
+func TestCheckUrls_DoesNotHoldWorkersForWaitingHosts(t *testing.T) {
+	first := newInFlightServer(t, 0, http.StatusOK)
+	second := newInFlightServer(t, 0, http.StatusOK)
+	secondURL := strings.Replace(second.URL, "127.0.0.1", "localhost", 1)
+	repo := &fakeLinkRepository{links: append(linksTo(first.URL, 2), linksTo(secondURL, 1)...)}
+	repo.links[2].ID = 3
+	healthRepo := newFakeHealthCheckRepository()
+	m := NewUrlMonitor(repo, healthRepo, time.Minute, Options{Concurrency: 1, PerHostConcurrency: 1, PerHostInterval: 100 * time.Millisecond})
+
+	m.checkUrls(context.Background())
+
+	// Le seul worker vérifie l'autre hôte pendant que le premier attend son délai
+	require.Len(t, healthRepo.checks, 3)
+	assert.Equal(t, []uint{1, 3, 2}, []uint{healthRepo.checks[0].LinkID, healthRepo.checks[1].LinkID, healthRepo.checks[2].LinkID})
+}
+
+func TestCheckUrls_DetectsStateChange(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	repo := &fakeLinkRepository{links: linksTo(server.URL, 1)}
+	m := NewUrlMonitor(repo, newFakeHealthCheckRepository(), time.Minute, Options{FailureThreshold: 1})
+
+	m.checkUrls(context.Background())
+	assert.True(t, m.knownStates[1].accessible)
+
+	status.Store(http.StatusServiceUnavailable)
+	m.checkUrls(context.Background())
+	assert.False(t, m.knownStates[1].accessible)
+}
+
+func TestCheckUrls_RequiresConsecutiveFailures(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	health := newFakeHealthCheckRepository()
+	notifier := &recordingNotifier{}
+	m := NewUrlMonitor(&fakeLinkRepository{links: linksTo(server.URL, 1)}, health, time.Minute, Options{FailureThreshold: 3})
+	m.SetNotifier(notifier)
+
+	m.checkUrls(context.Background())
+
+	// Un échec isolé, suivi d'un succès, ne change pas l'état
+	status.Store(http.StatusServiceUnavailable)
+	m.checkUrls(context.Background())
+	status.Store(http.StatusOK)
+	m.checkUrls(context.Background())
+	status.Store(http.StatusServiceUnavailable)
+	m.checkUrls(context.Background())
+	m.checkUrls(context.Background())
+	assert.Empty(t, notifier.changes)
+	assert.True(t, m.knownStates[1].accessible)
+
+	latest := health.checks[len(health.checks)-1]
+	assert.False(t, latest.Accessible)
+	assert.Equal(t, models.LinkStateAccessible, latest.State)
+	assert.Equal(t, 2, latest.ConsecutiveFailures)
+
+	m.checkUrls(context.Background())
+	require.Len(t, notifier.changes, 1)
+	assert.Equal(t, 3, notifier.changes[0].Check.ConsecutiveFailures)
+	assert.Equal(t, models.LinkStateInaccessible, health.checks[len(health.checks)-1].State)
+
+	// Une seule vérification réussie rétablit le lien
+	status.Store(http.StatusOK)
+	m.checkUrls(context.Background())
+	require.Len(t, notifier.changes, 2)
+	assert.True(t, notifier.changes[1].Current)
+	assert.Equal(t, 0, m.knownStates[1].failures)
+}
+
+type recordingNotifier struct {
+	mu      sync.Mutex
+	changes []StateChange
+}
+
+func (n *recordingNotifier) NotifyStateChange(change StateChange) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.changes = append(n.changes, change)
+}
+
+func TestCheckUrls_NotifiesStateChanges(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	notifier := &recordingNotifier{}
+	m := NewUrlMonitor(&fakeLinkRepository{links: linksTo(server.URL, 1)}, newFakeHealthCheckRepository(), time.Minute, Options{FailureThreshold: 1})
+	m.SetNotifier(notifier)
+
+	// État initial puis état inchangé : aucune notification
+	m.checkUrls(context.Background())
+	m.checkUrls(context.Background())
+	assert.Empty(t, notifier.changes)
+
+	status.Store(http.StatusNotFound)
+	m.checkUrls(context.Background())
+
+	require.Len(t, notifier.changes, 1)
+	change := notifier.changes[0]
+	assert.Equal(t, "code0", change.Link.ShortCode)
+	assert.True(t, change.Previous)
+	assert.False(t, change.Current)
+	assert.Equal(t, http.StatusNotFound, change.Check.StatusCode)
+	assert.Equal(t, ErrorClassHTTP4xx, change.Check.ErrorClass)
+}
+
+func TestRunOnce_SkipsOverlappingRuns(t *testing.T) {
+	repo := &fakeLinkRepository{}
+	m := NewUrlMonitor(repo, newFakeHealthCheckRepository(), time.Minute, Options{})
+
+	m.running.Store(true)
+	m.runOnce(context.Background())
+	assert.Equal(t, int32(0), repo.calls.Load())
+
+	m.running.Store(false)
+	m.runOnce(context.Background())
+	assert.Equal(t, int32(1), repo.calls.Load())
+	assert.False(t, m.running.Load())
+}
+
+func TestStop_WaitsForRunningCheck(t *testing.T) {
+	server := newInFlightServer(t, 100*time.Millisecond, http.StatusOK)
+	repo := &fakeLinkRepository{links: linksTo(server.URL, 4)}
+	healthRepo := newFakeHealthCheckRepository()
+	m := NewUrlMonitor(repo, healthRepo, time.Minute, Options{PerHostConcurrency: 1})
+
+	m.Start(context.Background())
+	require.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return server.current > 0
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m.Stop(ctx)
+
+	// La vérification en cours est enregistrée, les suivantes ne sont pas lancées
+	healthRepo.mu.Lock()
+	defer healthRepo.mu.Unlock()
+	assert.Len(t, healthRepo.checks, 1)
+	assert.Equal(t, 1, server.requests)
+	assert.False(t, m.running.Load())
+}
+
+func TestStop_InterruptsChecksAfterDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	healthRepo := newFakeHealthCheckRepository()
+	m := NewUrlMonitor(&fakeLinkRepository{links: linksTo(server.URL, 1)}, healthRepo, time.Minute, Options{Timeout: time.Minute})
+
+	m.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m.Stop(ctx)
+
+	healthRepo.mu.Lock()
+	defer healthRepo.mu.Unlock()
+	assert.Empty(t, healthRepo.checks)
+}
+
+func TestSchedule_SpreadsChecksAcrossJitterWindow(t *testing.T) {
+	m := NewUrlMonitor(&fakeLinkRepository{}, newFakeHealthCheckRepository(), time.Minute, Options{JitterPercent: 50})
+
+	scheduled := m.schedule(linksTo("https://example.com", 50))
+
+	assert.Len(t, scheduled, 50)
+	for i, s := range scheduled {
+		assert.Less(t, s.offset, 30*time.Second)
+		if i > 0 {
+			assert.GreaterOrEqual(t, s.offset, scheduled[i-1].offset)
+		}
+	}
+	assert.NotEqual(t, scheduled[0].offset, scheduled[49].offset)
+}
+
+func TestCheckUrls_RecordsHealthChecks(t *testing.T) {
+	up := newInFlightServer(t, 0, http.StatusOK)
+	down := newInFlightServer(t, 0, http.StatusBadGateway)
+	links := append(linksTo(up.URL, 1), linksTo(down.URL, 1)...)
+	links[1].ID = 2
+	health := newFakeHealthCheckRepository()
+	m := NewUrlMonitor(&fakeLinkRepository{links: links}, health, time.Minute, Options{})
+
+	m.checkUrls(context.Background())
+
+	require.Len(t, health.checks, 2)
+	byLink := map[uint]models.HealthCheck{}
+	for _, check := range health.checks {
+		byLink[check.LinkID] = check
+	}
+	assert.True(t, byLink[1].Accessible)
+	assert.Equal(t, http.StatusOK, byLink[1].StatusCode)
+	assert.Empty(t, byLink[1].ErrorClass)
+	assert.False(t, byLink[1].CheckedAt.IsZero())
+	assert.False(t, byLink[2].Accessible)
+	assert.Equal(t, http.StatusBadGateway, byLink[2].StatusCode)
+	assert.Equal(t, ErrorClassHTTP5xx, byLink[2].ErrorClass)
+}
+
+func TestCheckUrl_ClassifiesNetworkErrors(t *testing.T) {
+	m := NewUrlMonitor(&fakeLinkRepository{}, newFakeHealthCheckRepository(), time.Minute, Options{})
+
+	// Port fermé : connexion refusée
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+	check := m.checkUrl(closedURL)
+	assert.False(t, check.Accessible)
+	assert.Equal(t, ErrorClassConnection, check.ErrorClass)
+	assert.NotEmpty(t, check.Error)
+
+	slow := newInFlightServer(t, 300*time.Millisecond, http.StatusOK)
+	impatient := NewUrlMonitor(&fakeLinkRepository{}, newFakeHealthCheckRepository(), time.Minute, Options{Timeout: 100 * time.Millisecond})
+	assert.Equal(t, ErrorClassTimeout, impatient.checkUrl(slow.URL).ErrorClass)
+
+	assert.Equal(t, ErrorClassDNS, m.checkUrl("http://unknown-host.invalid").ErrorClass)
+
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	assert.Equal(t, ErrorClassTLS, m.checkUrl(tlsServer.URL).ErrorClass)
+}
+
+func TestStart_RestoresKnownStates(t *testing.T) {
+	health := newFakeHealthCheckRepository()
+	health.latest[7] = models.HealthCheck{LinkID: 7, Accessible: false}
+	health.latest[8] = models.HealthCheck{LinkID: 8, Accessible: false, State: models.LinkStateAccessible, ConsecutiveFailures: 1}
+	m := NewUrlMonitor(&fakeLinkRepository{}, health, time.Minute, Options{})
+
+	m.loadKnownStates()
+
+	state, known := m.knownStates[7]
+	assert.True(t, known)
+	assert.False(t, state.accessible)
+	// Un échec en dessous du seuil : le lien reste accessible, le compteur est repris
+	assert.True(t, m.knownStates[8].accessible)
+	assert.Equal(t, 1, m.knownStates[8].failures)
+}
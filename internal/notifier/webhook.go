@@ -0,0 +1,439 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/monitor"
+	"github.com/Edofo/bitly-clone/internal/repository"
+)
+
+// En-têtes envoyés avec chaque notification. La signature est un HMAC-SHA256 hexadécimal
+// de "<timestamp>.<corps>" calculé avec le secret du destinataire (celui du déploiement,
+// ou celui du lien pour son propre webhook), préfixé par "sha256=".
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	EventLinkStateChanged = "link.state_changed"
+)
+
+const (
+	DefaultTimeout        = 5 * time.Second
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+	DefaultQueueSize      = 100
+	DefaultWorkers        = 2
+)
+
+var (
+	ErrQueueFull        = errors.New("webhook queue is full")
+	ErrStopped          = errors.New("webhook notifier stopped")
+	ErrForbiddenAddress = errors.New("webhook address is not public")
+)
+
+// Options règle l'envoi des webhooks. URL reçoit les changements d'état de tous les liens ;
+// un lien peut en plus avoir son propre webhook (Link.WebhookURL), appelé uniquement sur
+// une adresse publique et signé avec le secret du lien. Une notification est
+// retentée jusqu'à MaxAttempts fois, avec un délai doublé à chaque échec (de InitialBackoff
+// à MaxBackoff), puis enregistrée comme lettre morte.
+type Options struct {
+	URL            string
+	Secret         string
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	QueueSize      int
+	Workers        int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = DefaultInitialBackoff
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		o.MaxBackoff = DefaultMaxBackoff
+		if o.MaxBackoff < o.InitialBackoff {
+			o.MaxBackoff = o.InitialBackoff
+		}
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultQueueSize
+	}
+	if o.Workers <= 0 {
+		o.Workers = DefaultWorkers
+	}
+	return o
+}
+
+// Payload est le corps JSON d'une notification de changement d'état.
+type Payload struct {
+	ID            string       `json:"id"`
+	Event         string       `json:"event"`
+	OccurredAt    time.Time    `json:"occurred_at"`
+	Link          PayloadLink  `json:"link"`
+	PreviousState string       `json:"previous_state"`
+	CurrentState  string       `json:"current_state"`
+	Check         PayloadCheck `json:"check"`
+}
+
+type PayloadLink struct {
+	ShortCode string `json:"short_code"`
+	LongURL   string `json:"long_url"`
+}
+
+type PayloadCheck struct {
+	StatusCode          int    `json:"status_code,omitempty"`
+	LatencyMs           int64  `json:"latency_ms"`
+	ErrorClass          string `json:"error_class,omitempty"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+}
+
+type delivery struct {
+	linkID  uint
+	eventID string
+	url     string
+	secret  string
+	// linkWebhook distingue le webhook défini par l'utilisateur de celui du déploiement.
+	linkWebhook bool
+	body        []byte
+}
+
+// WebhookNotifier envoie les changements d'état en arrière-plan : le moniteur n'attend
+// jamais la réponse des destinataires.
+type WebhookNotifier struct {
+	opts        Options
+	client      *http.Client
+	linkClient  *http.Client
+	deadLetters repository.WebhookDeadLetterRepository
+
+	queue  chan delivery
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewWebhookNotifier(opts Options, deadLetters repository.WebhookDeadLetterRepository) *WebhookNotifier {
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookNotifier{
+		opts:        opts,
+		client:      &http.Client{Timeout: opts.Timeout},
+		linkClient:  newPublicClient(opts.Timeout),
+		deadLetters: deadLetters,
+		queue:       make(chan delivery, opts.QueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+func (n *WebhookNotifier) Start() {
+	if n.opts.Secret == "" {
+		log.Println("Warning: Webhook secret is empty, notifications will not be signed.")
+	}
+	for i := 0; i < n.opts.Workers; i++ {
+		n.wg.Add(1)
+		go n.worker()
+	}
+}
+
+// Stop attend la fin des envois en cours jusqu'à l'échéance de ctx. Les notifications
+// encore en attente de nouvelle tentative sont alors enregistrées comme lettres mortes.
+func (n *WebhookNotifier) Stop(ctx context.Context) {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	close(n.queue)
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		n.cancel()
+		<-done
+	}
+	n.cancel()
+}
+
+func (n *WebhookNotifier) NotifyStateChange(change monitor.StateChange) {
+	targets := n.targets(change.Link)
+	if len(targets) == 0 {
+		return
+	}
+
+	payload := Payload{
+		ID:            newEventID(),
+		Event:         EventLinkStateChanged,
+		OccurredAt:    change.Check.CheckedAt.UTC(),
+		Link:          PayloadLink{ShortCode: change.Link.ShortCode, LongURL: change.Link.LongURL},
+		PreviousState: formatState(change.Previous),
+		CurrentState:  formatState(change.Current),
+		Check: PayloadCheck{
+			StatusCode:          change.Check.StatusCode,
+			LatencyMs:           change.Check.LatencyMs,
+			ErrorClass:          change.Check.ErrorClass,
+			ConsecutiveFailures: change.Check.ConsecutiveFailures,
+		},
+	}
+	if payload.OccurredAt.IsZero() {
+		payload.OccurredAt = time.Now().UTC()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("ERROR: Failed to encode webhook payload for link %s: %v", change.Link.ShortCode, err)
+		return
+	}
+
+	for _, target := range targets {
+		target.eventID = payload.ID
+		target.body = body
+		n.enqueue(target)
+	}
+}
+
+// targets retourne le webhook du déploiement et celui du lien, sans doublon.
+func (n *WebhookNotifier) targets(link models.Link) []delivery {
+	var targets []delivery
+	if n.opts.URL != "" {
+		targets = append(targets, delivery{linkID: link.ID, url: n.opts.URL, secret: n.opts.Secret})
+	}
+	if link.WebhookURL != "" && link.WebhookURL != n.opts.URL {
+		// Un webhook défini avant l'ajout des secrets n'en a pas : il reste muet jusqu'à ce
+		// que son propriétaire le redéfinisse, plutôt que de recevoir des notifications non signées.
+		if link.WebhookSecret == "" {
+			log.Printf("Warning: Webhook of link %s has no signing secret, skipping it until it is set again.", link.ShortCode)
+		} else {
+			targets = append(targets, delivery{linkID: link.ID, url: link.WebhookURL, secret: link.WebhookSecret, linkWebhook: true})
+		}
+	}
+	return targets
+}
+
+func (n *WebhookNotifier) enqueue(d delivery) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		n.deadLetter(d, 0, 0, ErrStopped)
+		return
+	}
+	select {
+	case n.queue <- d:
+	default:
+		log.Printf("Warning: Webhook queue is full, dropping notification %s to %s", d.eventID, d.url)
+		n.deadLetter(d, 0, 0, ErrQueueFull)
+	}
+}
+
+func (n *WebhookNotifier) worker() {
+	defer n.wg.Done()
+	for d := range n.queue {
+		n.deliver(d)
+	}
+}
+
+// deliver envoie une notification en retentant les erreurs réseau, les réponses 5xx,
+// 408 et 429. Les autres réponses 4xx et les adresses refusées sont définitives.
+func (n *WebhookNotifier) deliver(d delivery) {
+	var statusCode int
+	var err error
+
+	attempt := 1
+	for ; ; attempt++ {
+		statusCode, err = n.send(d)
+		if err == nil {
+			return
+		}
+		if !retryable(statusCode) || errors.Is(err, ErrForbiddenAddress) || attempt == n.opts.MaxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(n.backoff(attempt))
+		select {
+		case <-timer.C:
+			continue
+		case <-n.ctx.Done():
+			timer.Stop()
+			err = fmt.Errorf("%w before retry: %v", ErrStopped, err)
+		}
+		break
+	}
+
+	log.Printf("ERROR: Webhook %s to %s failed after %d attempt(s): %v", d.eventID, d.url, attempt, err)
+	n.deadLetter(d, attempt, statusCode, err)
+}
+
+func (n *WebhookNotifier) send(d delivery) (int, error) {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "url-shortener-webhook/1.0")
+	req.Header.Set(HeaderID, d.eventID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if d.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, d.body))
+	}
+
+	client := n.client
+	if d.linkWebhook {
+		client = n.linkClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		// Le corps est lu pour que la connexion soit réutilisée.
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Warning: Failed to close webhook response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// newPublicClient crée le client des webhooks définis par les utilisateurs. L'adresse est
+// contrôlée au moment de la connexion, une fois le nom résolu (y compris après une
+// redirection) : les adresses de bouclage, privées (RFC 1918, ULA) et lien-local, dont
+// celle des métadonnées des fournisseurs cloud (169.254.169.254), sont refusées.
+func newPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkPublicAddress(address)
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			ForceAttemptHTTP2:   true,
+		},
+	}
+}
+
+func checkPublicAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+func retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode >= 500 ||
+		statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+}
+
+// backoff retourne le délai avant la tentative suivant la tentative numéro attempt (à partir de 1).
+func (n *WebhookNotifier) backoff(attempt int) time.Duration {
+	delay := n.opts.InitialBackoff
+	for i := 1; i < attempt && delay < n.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > n.opts.MaxBackoff {
+		delay = n.opts.MaxBackoff
+	}
+	return delay
+}
+
+func (n *WebhookNotifier) deadLetter(d delivery, attempts int, statusCode int, cause error) {
+	deadLetter := &models.WebhookDeadLetter{
+		LinkID:         d.linkID,
+		EventID:        d.eventID,
+		URL:            d.url,
+		Payload:        string(d.body),
+		Attempts:       attempts,
+		LastStatusCode: statusCode,
+		LastError:      truncate(cause.Error(), 512),
+	}
+	if err := n.deadLetters.CreateDeadLetter(deadLetter); err != nil {
+		log.Printf("ERROR: Failed to record webhook dead letter %s: %v", d.eventID, err)
+	}
+}
+
+// Sign calcule la signature d'une notification, à comparer par le destinataire
+// (avec hmac.Equal) à l'en-tête X-Webhook-Signature.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+func formatState(accessible bool) string {
+	if accessible {
+		return "accessible"
+	}
+	return "inaccessible"
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
@@ -0,0 +1,309 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/monitor"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDeadLetterRepository struct {
+	repository.WebhookDeadLetterRepository
+	mu          sync.Mutex
+	deadLetters []models.WebhookDeadLetter
+}
+
+func (r *fakeDeadLetterRepository) CreateDeadLetter(deadLetter *models.WebhookDeadLetter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deadLetters = append(r.deadLetters, *deadLetter)
+	return nil
+}
+
+func (r *fakeDeadLetterRepository) all() []models.WebhookDeadLetter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.WebhookDeadLetter(nil), r.deadLetters...)
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver est un destinataire de webhooks local ; statuses fixe le code des réponses
+// successives (200 une fois la liste épuisée).
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	received []receivedWebhook
+	calls    atomic.Int32
+	done     chan struct{}
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses, done: make(chan struct{}, 10)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.calls.Add(1)
+
+		r.mu.Lock()
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		if status == http.StatusOK {
+			r.received = append(r.received, receivedWebhook{header: req.Header.Clone(), body: body})
+		}
+		r.mu.Unlock()
+
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			r.done <- struct{}{}
+		}
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) wait(t *testing.T) receivedWebhook {
+	select {
+	case <-r.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for webhook")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.received[len(r.received)-1]
+}
+
+func stateChange(link models.Link) monitor.StateChange {
+	return monitor.StateChange{
+		Link:     link,
+		Previous: true,
+		Current:  false,
+		Check: models.HealthCheck{
+			CheckedAt:           time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			StatusCode:          503,
+			LatencyMs:           42,
+			ErrorClass:          "http_5xx",
+			ConsecutiveFailures: 2,
+		},
+	}
+}
+
+func fastOptions(url string) Options {
+	return Options{URL: url, Secret: "s3cret", MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+func TestWebhookNotifier_DeliversSignedPayload(t *testing.T) {
+	r := newReceiver(t)
+	n := NewWebhookNotifier(fastOptions(r.URL), &fakeDeadLetterRepository{})
+	n.Start()
+	defer n.Stop(context.Background())
+
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}))
+	webhook := r.wait(t)
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal(webhook.body, &payload))
+	assert.Equal(t, EventLinkStateChanged, payload.Event)
+	assert.Equal(t, "abc123", payload.Link.ShortCode)
+	assert.Equal(t, "accessible", payload.PreviousState)
+	assert.Equal(t, "inaccessible", payload.CurrentState)
+	assert.Equal(t, 503, payload.Check.StatusCode)
+	assert.Equal(t, "http_5xx", payload.Check.ErrorClass)
+	assert.Equal(t, 2, payload.Check.ConsecutiveFailures)
+	assert.Equal(t, payload.ID, webhook.header.Get(HeaderID))
+	assert.Equal(t, "application/json", webhook.header.Get("Content-Type"))
+
+	expected := Sign("s3cret", webhook.header.Get(HeaderTimestamp), webhook.body)
+	assert.True(t, hmac.Equal([]byte(expected), []byte(webhook.header.Get(HeaderSignature))))
+	assert.NotEqual(t, Sign("other", webhook.header.Get(HeaderTimestamp), webhook.body), webhook.header.Get(HeaderSignature))
+}
+
+func TestWebhookNotifier_SendsToLinkWebhook(t *testing.T) {
+	deployment := newReceiver(t)
+	perLink := newReceiver(t)
+	n := NewWebhookNotifier(fastOptions(deployment.URL), &fakeDeadLetterRepository{})
+	// Les récepteurs de test écoutent sur 127.0.0.1, refusé pour les webhooks des liens
+	n.linkClient = n.client
+	n.Start()
+	defer n.Stop(context.Background())
+
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123", WebhookURL: perLink.URL, WebhookSecret: "link-s3cret"}))
+
+	first := deployment.wait(t)
+	second := perLink.wait(t)
+	assert.Equal(t, first.header.Get(HeaderID), second.header.Get(HeaderID))
+
+	// Chaque destinataire vérifie la signature avec son propre secret
+	assert.Equal(t, Sign("s3cret", first.header.Get(HeaderTimestamp), first.body), first.header.Get(HeaderSignature))
+	assert.Equal(t, Sign("link-s3cret", second.header.Get(HeaderTimestamp), second.body), second.header.Get(HeaderSignature))
+}
+
+func TestWebhookNotifier_SkipsLinkWebhookWithoutSecret(t *testing.T) {
+	deployment := newReceiver(t)
+	perLink := newReceiver(t)
+	deadLetters := &fakeDeadLetterRepository{}
+	n := NewWebhookNotifier(fastOptions(deployment.URL), deadLetters)
+	n.linkClient = n.client
+	n.Start()
+
+	// Webhook défini avant l'ajout des secrets de signature
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123", WebhookURL: perLink.URL}))
+	deployment.wait(t)
+	n.Stop(context.Background())
+
+	assert.Equal(t, int32(0), perLink.calls.Load())
+	assert.Empty(t, deadLetters.all())
+}
+
+func TestWebhookNotifier_RefusesPrivateLinkWebhook(t *testing.T) {
+	perLink := newReceiver(t)
+	deadLetters := &fakeDeadLetterRepository{}
+	n := NewWebhookNotifier(fastOptions(""), deadLetters)
+	n.Start()
+
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123", WebhookURL: perLink.URL, WebhookSecret: "link-s3cret"}))
+	n.Stop(context.Background())
+
+	assert.Equal(t, int32(0), perLink.calls.Load())
+	require.Len(t, deadLetters.all(), 1)
+	assert.Equal(t, 1, deadLetters.all()[0].Attempts, "une adresse refusée n'est pas retentée")
+	assert.Contains(t, deadLetters.all()[0].LastError, ErrForbiddenAddress.Error())
+}
+
+func TestCheckPublicAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"0.0.0.0:80", false},
+		{"[::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"[fe80::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+	}
+	for _, tt := range tests {
+		err := checkPublicAddress(tt.address)
+		if tt.allowed {
+			assert.NoError(t, err, tt.address)
+		} else {
+			assert.ErrorIs(t, err, ErrForbiddenAddress, tt.address)
+		}
+	}
+}
+
+func TestWebhookNotifier_WithoutTargets(t *testing.T) {
+	deadLetters := &fakeDeadLetterRepository{}
+	n := NewWebhookNotifier(Options{}, deadLetters)
+	n.Start()
+
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123"}))
+	n.Stop(context.Background())
+
+	assert.Empty(t, deadLetters.all())
+}
+
+func TestWebhookNotifier_RetriesServerErrors(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	deadLetters := &fakeDeadLetterRepository{}
+	n := NewWebhookNotifier(fastOptions(r.URL), deadLetters)
+	n.Start()
+	defer n.Stop(context.Background())
+
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123"}))
+	r.wait(t)
+
+	assert.Equal(t, int32(3), r.calls.Load())
+	assert.Empty(t, deadLetters.all())
+}
+
+func TestWebhookNotifier_DeadLettersAfterMaxAttempts(t *testing.T) {
+	r := newReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	deadLetters := &fakeDeadLetterRepository{}
+	n := NewWebhookNotifier(fastOptions(r.URL), deadLetters)
+	n.Start()
+
+	n.NotifyStateChange(stateChange(models.Link{ID: 7, ShortCode: "abc123"}))
+	n.Stop(context.Background())
+
+	assert.Equal(t, int32(3), r.calls.Load())
+	require.Len(t, deadLetters.all(), 1)
+	deadLetter := deadLetters.all()[0]
+	assert.Equal(t, uint(7), deadLetter.LinkID)
+	assert.Equal(t, r.URL, deadLetter.URL)
+	assert.Equal(t, 3, deadLetter.Attempts)
+	assert.Equal(t, http.StatusBadGateway, deadLetter.LastStatusCode)
+	assert.Contains(t, deadLetter.Payload, `"short_code":"abc123"`)
+	assert.NotEmpty(t, deadLetter.EventID)
+}
+
+func TestWebhookNotifier_DoesNotRetryClientErrors(t *testing.T) {
+	r := newReceiver(t, http.StatusGone)
+	deadLetters := &fakeDeadLetterRepository{}
+	n := NewWebhookNotifier(fastOptions(r.URL), deadLetters)
+	n.Start()
+
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123"}))
+	n.Stop(context.Background())
+
+	assert.Equal(t, int32(1), r.calls.Load())
+	require.Len(t, deadLetters.all(), 1)
+	assert.Equal(t, 1, deadLetters.all()[0].Attempts)
+}
+
+func TestWebhookNotifier_StopAbandonsPendingRetries(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	deadLetters := &fakeDeadLetterRepository{}
+	opts := fastOptions(r.URL)
+	opts.InitialBackoff = time.Hour
+	opts.MaxBackoff = time.Hour
+	n := NewWebhookNotifier(opts, deadLetters)
+	n.Start()
+
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123"}))
+	require.Eventually(t, func() bool { return r.calls.Load() == 1 }, 2*time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	n.Stop(ctx)
+
+	require.Len(t, deadLetters.all(), 1)
+	assert.Contains(t, deadLetters.all()[0].LastError, ErrStopped.Error())
+
+	// Après l'arrêt, les notifications sont directement enregistrées comme lettres mortes
+	n.NotifyStateChange(stateChange(models.Link{ID: 1, ShortCode: "abc123"}))
+	assert.Len(t, deadLetters.all(), 2)
+}
+
+func TestBackoff(t *testing.T) {
+	n := NewWebhookNotifier(Options{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, &fakeDeadLetterRepository{})
+
+	assert.Equal(t, time.Second, n.backoff(1))
+	assert.Equal(t, 2*time.Second, n.backoff(2))
+	assert.Equal(t, 4*time.Second, n.backoff(3))
+	assert.Equal(t, 5*time.Second, n.backoff(4))
+	assert.Equal(t, 5*time.Second, n.backoff(10))
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// takeN consomme n jetons à l'instant now et retourne le dernier résultat.
+func takeN(t *testing.T, store Store, key string, limit Limit, now time.Time, n int) Result {
+	t.Helper()
+	var result Result
+	for i := 0; i < n; i++ {
+		var err error
+		result, err = store.Take(key, limit, now)
+		require.NoError(t, err)
+	}
+	return result
+}
+
+// testTokenBucket vérifie le comportement commun à tous les Store.
+func testTokenBucket(t *testing.T, store Store) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limit := PerMinute(60, 3)
+
+	result := takeN(t, store, "a", limit, now, 1)
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: time.Second}, result)
+
+	result = takeN(t, store, "a", limit, now, 2)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 3*time.Second, result.ResetAfter)
+
+	// Seau vide : refusé jusqu'au prochain jeton
+	result = takeN(t, store, "a", limit, now.Add(400*time.Millisecond), 1)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 600*time.Millisecond, result.RetryAfter)
+
+	// Chaque clé a son propre seau
+	assert.True(t, takeN(t, store, "b", limit, now, 1).Allowed)
+
+	result = takeN(t, store, "a", limit, now.Add(time.Second), 1)
+	assert.True(t, result.Allowed, "un jeton est regagné chaque seconde")
+	assert.Equal(t, 0, result.Remaining)
+
+	// Le seau ne dépasse pas sa capacité
+	result = takeN(t, store, "a", limit, now.Add(time.Hour), 1)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemory_TokenBucket(t *testing.T) {
+	testTokenBucket(t, NewMemory())
+}
+
+func TestMemory_ForgetsFullBuckets(t *testing.T) {
+	store := NewMemory()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limit := PerMinute(60, 100)
+
+	takeN(t, store, "a", limit, now, 1)
+	takeN(t, store, "b", limit, now, 100)
+	assert.Equal(t, 2, store.Len())
+
+	// Une minute plus tard, "a" est plein mais "b" ne le sera qu'à +100s
+	takeN(t, store, "c", limit, now.Add(sweepInterval+time.Second), 1)
+	assert.Equal(t, 2, store.Len())
+	takeN(t, store, "c", limit, now.Add(2*sweepInterval+time.Second), 1)
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	store := NewMemory()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	api := NewLimiter(store, "api", PerMinute(60, 1))
+	api.now = func() time.Time { return now }
+	redirect := NewLimiter(store, "redirect", PerMinute(60, 1))
+	redirect.now = api.now
+
+	result, err := api.Allow("ip:192.0.2.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = api.Allow("ip:192.0.2.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	result, err = redirect.Allow("ip:192.0.2.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed, "chaque limiteur a ses propres seaux")
+}
+
+func TestLimit_Enabled(t *testing.T) {
+	assert.True(t, PerMinute(10, 0).Enabled())
+	assert.False(t, PerMinute(0, 10).Enabled())
+	assert.Equal(t, 10.0, PerMinute(10, 0).capacity(), "sans burst, la capacité est le débit")
+}
+
+func TestNew_UnsupportedBackend(t *testing.T) {
+	_, err := New(Options{Backend: "memcached"})
+	assert.Error(t, err)
+
+	store, err := New(Options{})
+	require.NoError(t, err)
+	assert.IsType(t, &Memory{}, store)
+}
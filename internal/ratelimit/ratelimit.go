@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Moteurs de stockage des compteurs pris en charge (rate_limit.backend).
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Limit décrit un seau à jetons : il contient au plus Burst jetons et en regagne Rate
+// par Period. Chaque requête consomme un jeton ; un seau vide refuse la requête.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	// Burst est le nombre de requêtes acceptées d'affilée (Rate si nul).
+	Burst int
+}
+
+// PerMinute retourne la limite de rate requêtes par minute, par rafales d'au plus burst.
+func PerMinute(rate, burst int) Limit {
+	return Limit{Rate: rate, Period: time.Minute, Burst: burst}
+}
+
+// Enabled indique si la limite est configurée : un débit nul ou négatif n'en impose aucune.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Period > 0
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst <= 0 {
+		return float64(l.Rate)
+	}
+	return float64(l.Burst)
+}
+
+// interval est le délai nécessaire pour regagner un jeton.
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Rate)
+}
+
+// Result est l'état du seau après une requête.
+type Result struct {
+	Allowed bool
+	// Limit est la capacité du seau, Remaining le nombre de jetons encore disponibles.
+	Limit     int
+	Remaining int
+	// ResetAfter est le délai avant que le seau soit de nouveau plein.
+	ResetAfter time.Duration
+	// RetryAfter est le délai avant le prochain jeton, nul si la requête est acceptée.
+	RetryAfter time.Duration
+}
+
+// newResult calcule le résultat d'une requête à partir des jetons restant dans le seau.
+func newResult(limit Limit, allowed bool, tokens float64) Result {
+	capacity := limit.capacity()
+	interval := float64(limit.interval())
+	result := Result{
+		Allowed:    allowed,
+		Limit:      int(capacity),
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration(math.Ceil((capacity - tokens) * interval)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration(math.Ceil((1 - tokens) * interval))
+	}
+	return result
+}
+
+// Store conserve l'état des seaux. Les implémentations sont utilisables par plusieurs
+// goroutines et oublient les seaux redevenus pleins.
+type Store interface {
+	// Take retire un jeton du seau de la clé s'il en reste un à l'instant now. Un Store
+	// partagé entre instances peut lui préférer sa propre horloge.
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+type Options struct {
+	Backend string
+	// Redis est utilisé avec le moteur redis.
+	Redis RedisOptions
+}
+
+// New crée le Store correspondant au moteur demandé (memory par défaut).
+func New(opts Options) (Store, error) {
+	switch strings.ToLower(opts.Backend) {
+	case "", BackendMemory:
+		return NewMemory(), nil
+	case BackendRedis:
+		return NewRedis(opts.Redis)
+	default:
+		return nil, fmt.Errorf("unsupported rate limit backend '%s' (expected %s or %s)", opts.Backend, BackendMemory, BackendRedis)
+	}
+}
+
+// Limiter applique une limite aux clients d'un même usage (API de gestion, redirections...),
+// chacun disposant de son propre seau.
+type Limiter struct {
+	store Store
+	name  string
+	limit Limit
+	now   func() time.Time
+}
+
+// NewLimiter crée un limiteur dont les seaux sont rangés dans store sous le préfixe name.
+func NewLimiter(store Store, name string, limit Limit) *Limiter {
+	return &Limiter{store: store, name: name, limit: limit, now: time.Now}
+}
+
+// Allow consomme un jeton du seau du client.
+func (l *Limiter) Allow(client string) (Result, error) {
+	return l.store.Take(l.name+":"+client, l.limit, l.now())
+}
+
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
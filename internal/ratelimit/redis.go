@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/redisclient"
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultRedisKeyPrefix = "url-shortener:ratelimit:"
+
+type RedisOptions = redisclient.Options
+
+// takeScript met à jour un seau de façon atomique. Il est enregistré dans un hash
+// (tokens, updated_at en microsecondes) qui expire quand le seau est de nouveau plein.
+// L'instant de la requête est lu sur l'horloge du serveur Redis (TIME), commune à toutes
+// les instances : des horloges décalées entre instances fausseraient sinon le remplissage.
+// La réplication des effets du script, par défaut depuis Redis 5, permet d'écrire après TIME.
+//
+// ARGV : capacité, microsecondes par jeton.
+// Retourne 1 si la requête est acceptée (0 sinon) et les jetons restants.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tokens = capacity
+local updated_at = now
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated_at")
+if state[1] then
+	tokens = tonumber(state[1])
+	updated_at = tonumber(state[2])
+end
+if now > updated_at then
+	tokens = math.min(capacity, tokens + (now - updated_at) / interval)
+	updated_at = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated_at", tostring(updated_at))
+redis.call("PEXPIRE", KEYS[1], math.ceil((capacity - tokens) * interval / 1000) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis partage les seaux entre plusieurs instances du serveur via un serveur Redis
+// (ou compatible : Valkey, KeyDB...), pour que les limites s'appliquent globalement.
+type Redis struct {
+	*redisclient.Client
+}
+
+func NewRedis(opts RedisOptions) (*Redis, error) {
+	client, err := redisclient.New(opts, DefaultRedisKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{Client: client}, nil
+}
+
+// Take ignore now : l'instant de la requête est celui du serveur Redis.
+func (r *Redis) Take(key string, limit Limit, now time.Time) (Result, error) {
+	ctx, cancel := r.WithTimeout()
+	defer cancel()
+
+	interval := float64(limit.interval()) / float64(time.Microsecond)
+	reply, err := takeScript.Run(ctx, r.Redis(), []string{r.Key(key)},
+		limit.capacity(), strconv.FormatFloat(interval, 'f', -1, 64)).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply: %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	remaining, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply: %v", reply)
+	}
+	return newResult(limit, allowed == 1, tokens), nil
+}
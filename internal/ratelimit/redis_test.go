@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	store, err := NewRedis(RedisOptions{Addr: server.Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store, server
+}
+
+// serverClock fait suivre à l'horloge du serveur Redis l'instant passé à Take, que Redis ignore.
+type serverClock struct {
+	*Redis
+	server *miniredis.Miniredis
+}
+
+func (s serverClock) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.server.SetTime(now)
+	return s.Redis.Take(key, limit, now)
+}
+
+func TestRedis_TokenBucket(t *testing.T) {
+	store, server := newTestRedis(t)
+	require.NoError(t, store.Ping())
+	testTokenBucket(t, serverClock{Redis: store, server: server})
+}
+
+func TestRedis_UsesServerClock(t *testing.T) {
+	store, server := newTestRedis(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limit := PerMinute(60, 1)
+	server.SetTime(now)
+
+	assert.True(t, takeN(t, store, "a", limit, now, 1).Allowed)
+	// Une instance en avance d'une heure ne remplit pas le seau pour autant
+	assert.False(t, takeN(t, store, "a", limit, now.Add(time.Hour), 1).Allowed)
+
+	server.SetTime(now.Add(time.Second))
+	assert.True(t, takeN(t, store, "a", limit, now, 1).Allowed)
+}
+
+func TestRedis_KeysExpireWhenFull(t *testing.T) {
+	store, server := newTestRedis(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	takeN(t, store, "a", PerMinute(60, 3), now, 2)
+	assert.True(t, server.Exists(DefaultRedisKeyPrefix+"a"), "les clés sont préfixées")
+
+	server.FastForward(2 * time.Second)
+	assert.True(t, server.Exists(DefaultRedisKeyPrefix+"a"))
+	server.FastForward(time.Second)
+	assert.False(t, server.Exists(DefaultRedisKeyPrefix+"a"))
+}
+
+func TestRedis_ServerUnavailable(t *testing.T) {
+	store, server := newTestRedis(t)
+	server.Close()
+
+	_, err := store.Take("a", PerMinute(60, 3), time.Now())
+	assert.Error(t, err)
+}
+
+func TestNewRedis_RequiresAddr(t *testing.T) {
+	_, err := NewRedis(RedisOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package redisclient
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultTimeout = 200 * time.Millisecond
+
+// Options décrit la connexion à un serveur Redis (ou compatible : Valkey, KeyDB...),
+// partagée par le cache des liens et la limitation de débit.
+type Options struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix est ajouté à toutes les clés, pour partager un serveur Redis entre applications.
+	KeyPrefix string
+	// Timeout borne chaque commande : un Redis lent ne doit pas ralentir les requêtes.
+	Timeout time.Duration
+}
+
+// Client est un client Redis dont les commandes sont bornées par Timeout et dont les clés
+// sont préfixées par KeyPrefix.
+type Client struct {
+	client  *redis.Client
+	prefix  string
+	timeout time.Duration
+}
+
+// New ouvre un client ; defaultKeyPrefix est utilisé si opts.KeyPrefix est vide.
+func New(opts Options, defaultKeyPrefix string) (*Client, error) {
+	if opts.Addr == "" {
+		return nil, errors.New("redis address is empty")
+	}
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = defaultKeyPrefix
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:         opts.Addr,
+		Password:     opts.Password,
+		DB:           opts.DB,
+		DialTimeout:  opts.Timeout,
+		ReadTimeout:  opts.Timeout,
+		WriteTimeout: opts.Timeout,
+	})
+	return &Client{client: client, prefix: opts.KeyPrefix, timeout: opts.Timeout}, nil
+}
+
+// Redis retourne le client go-redis sous-jacent.
+func (c *Client) Redis() *redis.Client {
+	return c.client
+}
+
+// Key retourne la clé préfixée.
+func (c *Client) Key(key string) string {
+	return c.prefix + key
+}
+
+// WithTimeout retourne le contexte d'une commande, borné par Timeout.
+func (c *Client) WithTimeout() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// Ping vérifie que le serveur Redis répond.
+func (c *Client) Ping() error {
+	ctx, cancel := c.WithTimeout()
+	defer cancel()
+	return c.client.Ping(ctx).Err()
+}
+
+func (c *Client) Close() error {
+	return c.client.Close()
+}
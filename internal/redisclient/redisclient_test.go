@@ -0,0 +1,30 @@
+package redisclient
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_RequiresAddr(t *testing.T) {
+	_, err := New(Options{}, "test:")
+	assert.Error(t, err)
+}
+
+func TestNew_AppliesDefaults(t *testing.T) {
+	server := miniredis.RunT(t)
+	client, err := New(Options{Addr: server.Addr()}, "test:")
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.Ping())
+	assert.Equal(t, "test:abc", client.Key("abc"))
+	assert.Equal(t, DefaultTimeout, client.timeout)
+
+	client, err = New(Options{Addr: server.Addr(), KeyPrefix: "custom:"}, "test:")
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, "custom:abc", client.Key("abc"))
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(key *models.APIKey) error
+	GetAPIKeyByPrefix(prefix string) (*models.APIKey, error)
+	ListAPIKeys() ([]models.APIKey, error)
+	RevokeAPIKey(keyID uint, revokedAt time.Time) error
+	TouchAPIKey(keyID uint, usedAt time.Time) error
+}
+
+type GormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *GormAPIKeyRepository {
+	return &GormAPIKeyRepository{db: db}
+}
+
+func (r *GormAPIKeyRepository) CreateAPIKey(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *GormAPIKeyRepository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys retourne toutes les clés, révoquées comprises, par ordre de création.
+func (r *GormAPIKeyRepository) ListAPIKeys() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.Order("id").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey révoque une clé encore active. Retourne gorm.ErrRecordNotFound si la clé
+// n'existe pas ou est déjà révoquée.
+func (r *GormAPIKeyRepository) RevokeAPIKey(keyID uint, revokedAt time.Time) error {
+	result := r.db.Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", keyID).Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey enregistre la dernière utilisation d'une clé.
+func (r *GormAPIKeyRepository) TouchAPIKey(keyID uint, usedAt time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", keyID).Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/cache"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLinkCacheTTL         = 5 * time.Minute
+	DefaultLinkCacheNegativeTTL = 30 * time.Second
+)
+
+const linkCacheKeyPrefix = "link:"
+
+// notFoundMarker est mis en cache pour un code court inconnu (cache négatif) :
+// un lien encodé en JSON n'est jamais vide.
+var notFoundMarker = []byte{}
+
+// cachedLink est la forme mise en cache d'un lien : les seuls champs utiles à la redirection.
+// Le secret du webhook, le propriétaire et l'espace de travail ne sont pas copiés dans un
+// cache éventuellement partagé.
+type cachedLink struct {
+	ID        uint
+	ShortCode string
+	LongURL   string
+	ExpiresAt *time.Time
+	MaxClicks int
+}
+
+type LinkCacheOptions struct {
+	// TTL est la durée de conservation d'un lien trouvé.
+	TTL time.Duration
+	// NegativeTTL est la durée de conservation d'un code court inconnu (0 : valeur par défaut).
+	NegativeTTL time.Duration
+}
+
+func (o LinkCacheOptions) withDefaults() LinkCacheOptions {
+	if o.TTL <= 0 {
+		o.TTL = DefaultLinkCacheTTL
+	}
+	if o.NegativeTTL <= 0 {
+		o.NegativeTTL = DefaultLinkCacheNegativeTTL
+	}
+	return o
+}
+
+// LinkCacheStats compte les recherches par code court servies par le cache.
+type LinkCacheStats struct {
+	Hits         int64 `json:"hits"`
+	NegativeHits int64 `json:"negative_hits"`
+	Misses       int64 `json:"misses"`
+	Errors       int64 `json:"errors"`
+}
+
+// CachedLinkRepository ajoute un cache en lecture aux recherches par code court,
+// appelées à chaque redirection. Les entrées sont invalidées par CreateLink (cache
+// négatif), UpdateLinkURL, UpdateLinkWebhook et DeleteLink ; une lecture concurrente d'une modification
+// peut toutefois remettre l'ancienne valeur en cache jusqu'à l'expiration de son TTL.
+// Les liens limités en nombre de clics ne sont pas mis en cache : leur compteur
+// change à chaque redirection. En cas d'erreur du cache, la base est interrogée.
+// Un lien servi par le cache ne porte que les champs utiles à la redirection (voir
+// cachedLink) : GetLinkByID, jamais mis en cache, retourne le lien complet.
+type CachedLinkRepository struct {
+	next  LinkRepository
+	store cache.Store
+	opts  LinkCacheOptions
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+	errors       atomic.Int64
+}
+
+func NewCachedLinkRepository(next LinkRepository, store cache.Store, opts LinkCacheOptions) *CachedLinkRepository {
+	return &CachedLinkRepository{next: next, store: store, opts: opts.withDefaults()}
+}
+
+func (r *CachedLinkRepository) Stats() LinkCacheStats {
+	return LinkCacheStats{
+		Hits:         r.hits.Load(),
+		NegativeHits: r.negativeHits.Load(),
+		Misses:       r.misses.Load(),
+		Errors:       r.errors.Load(),
+	}
+}
+
+func linkCacheKey(shortCode string) string {
+	return linkCacheKeyPrefix + shortCode
+}
+
+func (r *CachedLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link, error) {
+	key := linkCacheKey(shortCode)
+
+	value, ok, err := r.store.Get(key)
+	if err != nil {
+		r.cacheError("reading", shortCode, err)
+	} else if ok {
+		if len(value) == 0 {
+			r.negativeHits.Add(1)
+			return nil, gorm.ErrRecordNotFound
+		}
+		var cached cachedLink
+		if err := json.Unmarshal(value, &cached); err == nil {
+			r.hits.Add(1)
+			return &models.Link{
+				ID:        cached.ID,
+				ShortCode: cached.ShortCode,
+				LongURL:   cached.LongURL,
+				ExpiresAt: cached.ExpiresAt,
+				MaxClicks: cached.MaxClicks,
+			}, nil
+		}
+		r.cacheError("decoding", shortCode, err)
+	}
+	r.misses.Add(1)
+
+	link, err := r.next.GetLinkByShortCode(shortCode)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := r.store.Set(key, notFoundMarker, r.opts.NegativeTTL); err != nil {
+			r.cacheError("writing", shortCode, err)
+		}
+		return nil, err
+	case err != nil:
+		return nil, err
+	}
+
+	if !link.HasClickBudget() {
+		cached := cachedLink{
+			ID:        link.ID,
+			ShortCode: link.ShortCode,
+			LongURL:   link.LongURL,
+			ExpiresAt: link.ExpiresAt,
+			MaxClicks: link.MaxClicks,
+		}
+		if value, err := json.Marshal(cached); err != nil {
+			r.cacheError("encoding", shortCode, err)
+		} else if err := r.store.Set(key, value, r.opts.TTL); err != nil {
+			r.cacheError("writing", shortCode, err)
+		}
+	}
+	return link, nil
+}
+
+func (r *CachedLinkRepository) CreateLink(link *models.Link) error {
+	if err := r.next.CreateLink(link); err != nil {
+		return err
+	}
+	// Le code court a pu être mis en cache comme inconnu, par exemple lors de la
+	// vérification de sa disponibilité.
+	r.invalidate(link.ShortCode)
+	return nil
+}
+
+func (r *CachedLinkRepository) UpdateLinkURL(linkID uint, longURL string, domain string) error {
+	link, err := r.next.GetLinkByID(linkID)
+	if err != nil {
+		return err
+	}
+	if err := r.next.UpdateLinkURL(linkID, longURL, domain); err != nil {
+		return err
+	}
+	r.invalidate(link.ShortCode)
+	return nil
+}
+
+func (r *CachedLinkRepository) UpdateLinkWebhook(linkID uint, webhookURL string, webhookSecret string) error {
+	link, err := r.next.GetLinkByID(linkID)
+	if err != nil {
+		return err
+	}
+	if err := r.next.UpdateLinkWebhook(linkID, webhookURL, webhookSecret); err != nil {
+		return err
+	}
+	r.invalidate(link.ShortCode)
+	return nil
+}
+
+func (r *CachedLinkRepository) DeleteLink(linkID uint) error {
+	link, err := r.next.GetLinkByID(linkID)
+	if err != nil {
+		return err
+	}
+	if err := r.next.DeleteLink(linkID); err != nil {
+		return err
+	}
+	r.invalidate(link.ShortCode)
+	return nil
+}
+
+func (r *CachedLinkRepository) GetLinkByID(linkID uint) (*models.Link, error) {
+	return r.next.GetLinkByID(linkID)
+}
+
+func (r *CachedLinkRepository) GetAllLinks() ([]models.Link, error) {
+	return r.next.GetAllLinks()
+}
+
+func (r *CachedLinkRepository) ListLinks(filter LinkFilter) ([]models.Link, int64, error) {
+	return r.next.ListLinks(filter)
+}
+
+func (r *CachedLinkRepository) CountClicksByLinkID(linkID uint, filter ClickFilter) (int, error) {
+	return r.next.CountClicksByLinkID(linkID, filter)
+}
+
+func (r *CachedLinkRepository) CountClickTotals(linkID uint, filter ClickFilter) (models.ClickTotals, error) {
+	return r.next.CountClickTotals(linkID, filter)
+}
+
+func (r *CachedLinkRepository) ConsumeClick(linkID uint) (bool, error) {
+	return r.next.ConsumeClick(linkID)
+}
+
+func (r *CachedLinkRepository) invalidate(shortCodes ...string) {
+	keys := make([]string, len(shortCodes))
+	for i, shortCode := range shortCodes {
+		keys[i] = linkCacheKey(shortCode)
+	}
+	if err := r.store.Delete(keys...); err != nil {
+		r.cacheError("invalidating", shortCodes[0], err)
+	}
+}
+
+func (r *CachedLinkRepository) cacheError(action string, shortCode string, err error) {
+	r.errors.Add(1)
+	log.Printf("Warning: Link cache error while %s %s: %v", action, shortCode, err)
+}
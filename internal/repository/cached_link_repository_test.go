@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/cache"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// countingLinkRepository compte les recherches par code court qui atteignent le dépôt.
+type countingLinkRepository struct {
+	LinkRepository
+	lookups atomic.Int32
+}
+
+func (r *countingLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link, error) {
+	r.lookups.Add(1)
+	return r.LinkRepository.GetLinkByShortCode(shortCode)
+}
+
+// failingStore simule un cache indisponible.
+type failingStore struct{}
+
+func (failingStore) Get(string) ([]byte, bool, error)        { return nil, false, errors.New("cache down") }
+func (failingStore) Set(string, []byte, time.Duration) error { return errors.New("cache down") }
+func (failingStore) Delete(...string) error                  { return errors.New("cache down") }
+
+var linkCacheStores = []struct {
+	name string
+	open func(t *testing.T) cache.Store
+}{
+	{"lru", func(t *testing.T) cache.Store { return cache.NewLRU(100) }},
+	{"redis", func(t *testing.T) cache.Store {
+		store, err := cache.NewRedis(cache.RedisOptions{Addr: miniredis.RunT(t).Addr()})
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store
+	}},
+}
+
+func runLinkCache(t *testing.T, test func(t *testing.T, next *countingLinkRepository, repo *CachedLinkRepository)) {
+	for _, store := range linkCacheStores {
+		t.Run(store.name, func(t *testing.T) {
+			next := &countingLinkRepository{LinkRepository: NewMemoryLinkRepository(NewMemoryStore())}
+			test(t, next, NewCachedLinkRepository(next, store.open(t), LinkCacheOptions{}))
+		})
+	}
+}
+
+func TestCachedLinkRepository_ReadThrough(t *testing.T) {
+	runLinkCache(t, func(t *testing.T, next *countingLinkRepository, repo *CachedLinkRepository) {
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		require.NoError(t, repo.CreateLink(&models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", ExpiresAt: &expiresAt}))
+
+		for i := 0; i < 3; i++ {
+			link, err := repo.GetLinkByShortCode("abc123")
+			require.NoError(t, err)
+			assert.Equal(t, "https://www.example.com", link.LongURL)
+			require.NotNil(t, link.ExpiresAt)
+			assert.True(t, expiresAt.Equal(*link.ExpiresAt))
+		}
+
+		assert.Equal(t, int32(1), next.lookups.Load())
+		assert.Equal(t, LinkCacheStats{Hits: 2, Misses: 1}, repo.Stats())
+	})
+}
+
+func TestCachedLinkRepository_NegativeCaching(t *testing.T) {
+	runLinkCache(t, func(t *testing.T, next *countingLinkRepository, repo *CachedLinkRepository) {
+		for i := 0; i < 3; i++ {
+			_, err := repo.GetLinkByShortCode("abc123")
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		}
+		assert.Equal(t, int32(1), next.lookups.Load())
+		assert.Equal(t, LinkCacheStats{NegativeHits: 2, Misses: 1}, repo.Stats())
+
+		// La création du lien remplace l'entrée négative
+		require.NoError(t, repo.CreateLink(&models.Link{ShortCode: "abc123", LongURL: "https://www.example.com"}))
+		link, err := repo.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://www.example.com", link.LongURL)
+	})
+}
+
+func TestCachedLinkRepository_InvalidatesOnUpdateWebhook(t *testing.T) {
+	runLinkCache(t, func(t *testing.T, next *countingLinkRepository, repo *CachedLinkRepository) {
+		link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com"}
+		require.NoError(t, repo.CreateLink(link))
+		_, err := repo.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+
+		require.NoError(t, repo.UpdateLinkWebhook(link.ID, "https://hooks.example.org/links", "s3cret"))
+		found, err := repo.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://hooks.example.org/links", found.WebhookURL)
+		assert.Equal(t, "s3cret", found.WebhookSecret)
+	})
+}
+
+func TestCachedLinkRepository_InvalidatesOnUpdateURL(t *testing.T) {
+	runLinkCache(t, func(t *testing.T, next *countingLinkRepository, repo *CachedLinkRepository) {
+		link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com"}
+		require.NoError(t, repo.CreateLink(link))
+		_, err := repo.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+
+		require.NoError(t, repo.UpdateLinkURL(link.ID, "https://www.example.org", "www.example.org"))
+		found, err := repo.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://www.example.org", found.LongURL)
+	})
+}
+
+func TestCachedLinkRepository_InvalidatesOnDelete(t *testing.T) {
+	runLinkCache(t, func(t *testing.T, next *countingLinkRepository, repo *CachedLinkRepository) {
+		link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com"}
+		require.NoError(t, repo.CreateLink(link))
+		_, err := repo.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+
+		require.NoError(t, repo.DeleteLink(link.ID))
+		_, err = repo.GetLinkByShortCode("abc123")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repo.DeleteLink(link.ID), gorm.ErrRecordNotFound)
+	})
+}
+
+func TestCachedLinkRepository_SkipsLinksWithClickBudget(t *testing.T) {
+	runLinkCache(t, func(t *testing.T, next *countingLinkRepository, repo *CachedLinkRepository) {
+		link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", MaxClicks: 5}
+		require.NoError(t, repo.CreateLink(link))
+
+		for i := 0; i < 2; i++ {
+			ok, err := repo.ConsumeClick(link.ID)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			found, err := repo.GetLinkByShortCode("abc123")
+			require.NoError(t, err)
+			assert.Equal(t, i+1, found.ConsumedClicks)
+		}
+		assert.Equal(t, int32(2), next.lookups.Load())
+	})
+}
+
+func TestCachedLinkRepository_FallsBackWhenCacheFails(t *testing.T) {
+	next := &countingLinkRepository{LinkRepository: NewMemoryLinkRepository(NewMemoryStore())}
+	repo := NewCachedLinkRepository(next, failingStore{}, LinkCacheOptions{})
+
+	require.NoError(t, repo.CreateLink(&models.Link{ShortCode: "abc123", LongURL: "https://www.example.com"}))
+	link, err := repo.GetLinkByShortCode("abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.example.com", link.LongURL)
+
+	_, err = repo.GetLinkByShortCode("missing")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	stats := repo.Stats()
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, int64(5), stats.Errors)
+}
+
+func TestCachedLinkRepository_CachesRedirectFieldsOnly(t *testing.T) {
+	runLinkCache(t, func(t *testing.T, next *countingLinkRepository, repo *CachedLinkRepository) {
+		ownerID := uint(7)
+		link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", WebhookURL: "https://hooks.example.org/links", WebhookSecret: "s3cret", OwnerID: &ownerID}
+		require.NoError(t, repo.CreateLink(link))
+		_, err := repo.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+
+		value, ok, err := repo.store.Get(linkCacheKey("abc123"))
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.NotContains(t, string(value), "s3cret")
+		assert.NotContains(t, string(value), "hooks.example.org")
+
+		cached, err := repo.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, link.ID, cached.ID)
+		assert.Equal(t, "https://www.example.com", cached.LongURL)
+		assert.Nil(t, cached.OwnerID)
+
+		// Le lien complet reste disponible par son identifiant
+		full, err := repo.GetLinkByID(link.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", full.WebhookSecret)
+		assert.Equal(t, &ownerID, full.OwnerID)
+	})
+}
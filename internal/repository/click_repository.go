@@ -1,13 +1,134 @@
 package repository
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/Edofo/bitly-clone/internal/models"
 	"gorm.io/gorm"
 )
 
+type TimeInterval string
+
+const (
+	IntervalHour  TimeInterval = "hour"
+	IntervalDay   TimeInterval = "day"
+	IntervalWeek  TimeInterval = "week"
+	IntervalMonth TimeInterval = "month"
+)
+
+func (i TimeInterval) IsValid() bool {
+	switch i {
+	case IntervalHour, IntervalDay, IntervalWeek, IntervalMonth:
+		return true
+	}
+	return false
+}
+
+// Truncate ramène t au début de son intervalle dans le fuseau loc.
+// Les semaines commencent le lundi (ISO 8601).
+func (i TimeInterval) Truncate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch i {
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case IntervalWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// Next retourne le début de l'intervalle suivant celui qui commence à start.
+// Les calculs passent par le calendrier local pour rester justes lors des changements d'heure.
+func (i TimeInterval) Next(start time.Time) time.Time {
+	switch i {
+	case IntervalHour:
+		return start.Add(time.Hour)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// ClickDimension désigne un attribut des clics sur lequel ventiler les statistiques.
+type ClickDimension string
+
+const (
+	DimensionReferrer ClickDimension = "referrer"
+	DimensionBrowser  ClickDimension = "browser"
+	DimensionOS       ClickDimension = "os"
+	DimensionDevice   ClickDimension = "device"
+	DimensionCountry  ClickDimension = "country"
+	DimensionCity     ClickDimension = "city"
+)
+
+// clickDimensionColumns associe à chaque dimension la colonne ou l'expression SQL regroupée.
+var clickDimensionColumns = map[ClickDimension]string{
+	DimensionReferrer: "referrer_domain",
+	DimensionBrowser:  "browser",
+	DimensionOS:       "os",
+	DimensionDevice:   "device_class",
+	DimensionCountry:  "country",
+	// Les villes sont regroupées avec leur pays pour distinguer les homonymes (Paris, FR / Paris, US).
+	// L'opérateur || est commun à SQLite et PostgreSQL.
+	DimensionCity: "CASE WHEN city = '' THEN '' ELSE city || ', ' || country END",
+}
+
+func (d ClickDimension) IsValid() bool {
+	_, ok := clickDimensionColumns[d]
+	return ok
+}
+
+// ClickFilter restreint les clics pris en compte par les statistiques.
+// Par défaut, les clics attribués à des robots sont exclus. From et To (exclu)
+// bornent la période lorsqu'ils sont renseignés.
+type ClickFilter struct {
+	IncludeBots bool
+	From        time.Time
+	To          time.Time
+}
+
+func (f ClickFilter) apply(query *gorm.DB) *gorm.DB {
+	if !f.IncludeBots {
+		query = query.Where("is_bot = ?", false)
+	}
+	if !f.From.IsZero() {
+		query = query.Where("timestamp >= ?", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		query = query.Where("timestamp < ?", f.To.UTC())
+	}
+	return query
+}
+
+// countTotals compte les clics et les empreintes de visiteurs distinctes ;
+// les clics antérieurs au comptage des visiteurs (empreinte vide) sont ignorés pour ces derniers.
+func countTotals(db *gorm.DB, linkID uint, filter ClickFilter) (models.ClickTotals, error) {
+	var totals models.ClickTotals
+	query := db.Model(&models.Click{}).
+		Select("COUNT(*) AS total_clicks, COUNT(DISTINCT NULLIF(visitor_hash, '')) AS unique_visitors").
+		Where("link_id = ?", linkID)
+	err := filter.apply(query).Scan(&totals).Error
+	return totals, err
+}
+
+// maxClicksPerInsert borne le nombre de lignes par INSERT multi-lignes pour rester
+// sous la limite de paramètres liés de SQLite.
+const maxClicksPerInsert = 200
+
 type ClickRepository interface {
 	CreateClick(click *models.Click) error
-	CountClicksByLinkID(linkID uint) (int, error)
+	CreateClicks(clicks []*models.Click) error
+	CountClicksByLinkID(linkID uint, filter ClickFilter) (int, error)
+	CountClicksByInterval(linkID uint, interval TimeInterval, from, to time.Time, loc *time.Location, filter ClickFilter) ([]models.ClickBucket, error)
+	CountClicksByDimension(linkID uint, dimension ClickDimension, limit int, filter ClickFilter) ([]models.ClickStat, error)
 }
 
 type GormClickRepository struct {
@@ -22,8 +143,216 @@ func (r *GormClickRepository) CreateClick(click *models.Click) error {
 	return r.db.Create(click).Error
 }
 
-func (r *GormClickRepository) CountClicksByLinkID(linkID uint) (int, error) {
+// CreateClicks enregistre plusieurs clics en une transaction avec des INSERT multi-lignes.
+// En cas d'erreur, aucun clic du lot n'est enregistré.
+func (r *GormClickRepository) CreateClicks(clicks []*models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(clicks, maxClicksPerInsert).Error
+}
+
+func (r *GormClickRepository) CountClicksByLinkID(linkID uint, filter ClickFilter) (int, error) {
 	var count int64
-	err := r.db.Model(&models.Click{}).Where("link_id = ?", linkID).Count(&count).Error
+	err := filter.apply(r.db.Model(&models.Click{}).Where("link_id = ?", linkID)).Count(&count).Error
 	return int(count), err
 }
+
+// CountClicksByInterval agrège les clics d'un lien sur [from, to[ par intervalle,
+// dans le fuseau horaire loc. La base regroupe les clics sur leur horodatage local
+// tronqué à l'intervalle (voir intervalTruncation) ; les intervalles sans clic sont
+// présents avec un compteur à zéro.
+func (r *GormClickRepository) CountClicksByInterval(linkID uint, interval TimeInterval, from, to time.Time, loc *time.Location, filter ClickFilter) ([]models.ClickBucket, error) {
+	buckets := newClickBuckets(interval, from, to, loc)
+	truncation := newIntervalTruncation(r.db.Dialector.Name(), interval, buckets.starts(), to, loc)
+
+	var rows []struct {
+		Start          string
+		Count          int
+		UniqueVisitors int
+	}
+	query := r.db.Model(&models.Click{}).
+		Select(truncation.selectClause(), truncation.args...).
+		Where("link_id = ? AND timestamp >= ? AND timestamp < ?", linkID, from.UTC(), to.UTC())
+	err := filter.apply(query).Group("start").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		// Comme TimeInterval.Truncate, une heure locale répétée au passage à l'heure
+		// d'hiver désigne un seul intervalle.
+		start, err := time.ParseInLocation(truncatedStartLayout, row.Start, loc)
+		if err != nil {
+			return nil, err
+		}
+		buckets.set(start, row.Count, row.UniqueVisitors)
+	}
+	return buckets.buckets, nil
+}
+
+// intervalTruncation construit l'expression SQL qui ramène l'horodatage d'un clic au début
+// de son intervalle, dans un fuseau horaire. SQLite n'a pas de base de fuseaux horaires :
+// les décalages UTC en vigueur sur la période sont calculés en Go et l'expression passe
+// d'un décalage à l'autre aux changements d'heure. Seules les fonctions de date diffèrent
+// d'un moteur à l'autre.
+type intervalTruncation struct {
+	// start est l'heure locale tronquée, au format "AAAA-MM-JJ HH:MM:SS".
+	start string
+	args  []interface{}
+}
+
+const truncatedStartLayout = "2006-01-02 15:04:05"
+
+func newIntervalTruncation(dialect string, interval TimeInterval, starts []time.Time, to time.Time, loc *time.Location) *intervalTruncation {
+	// offset est le décalage UTC, en secondes, de l'heure locale du clic.
+	offset, args := offsetExpression(zoneTransitions(append(starts, to), loc))
+	t := &intervalTruncation{args: args}
+
+	if dialect == "postgres" {
+		local := `("timestamp" AT TIME ZONE 'UTC') + (` + offset + `) * INTERVAL '1 second'`
+		t.start = `to_char(date_trunc('` + string(interval) + `', ` + local + `), 'YYYY-MM-DD HH24:MI:SS')`
+		return t
+	}
+
+	local := `"timestamp", printf('%+d seconds', ` + offset + `)`
+	switch interval {
+	case IntervalHour:
+		t.start = `strftime('%Y-%m-%d %H:00:00', ` + local + `)`
+	case IntervalWeek:
+		t.start = `strftime('%Y-%m-%d 00:00:00', ` + local + `, '-6 days', 'weekday 1')`
+	case IntervalMonth:
+		t.start = `strftime('%Y-%m-01 00:00:00', ` + local + `)`
+	default:
+		t.start = `strftime('%Y-%m-%d 00:00:00', ` + local + `)`
+	}
+	return t
+}
+
+// zoneTransition est un changement de décalage UTC du fuseau, à l'instant at.
+type zoneTransition struct {
+	at     time.Time
+	offset int
+}
+
+// zoneTransitions retourne le décalage initial puis les changements de décalage entre
+// les instants donnés (croissants), localisés à la seconde près.
+func zoneTransitions(instants []time.Time, loc *time.Location) []zoneTransition {
+	_, offset := instants[0].In(loc).Zone()
+	transitions := []zoneTransition{{offset: offset}}
+	for i := 1; i < len(instants); i++ {
+		_, next := instants[i].In(loc).Zone()
+		if next == offset {
+			continue
+		}
+		before, after := instants[i-1], instants[i]
+		for after.Sub(before) > time.Second {
+			middle := before.Add(after.Sub(before) / 2).Truncate(time.Second)
+			if _, o := middle.In(loc).Zone(); o == offset {
+				before = middle
+			} else {
+				after = middle
+			}
+		}
+		transitions = append(transitions, zoneTransition{at: after, offset: next})
+		offset = next
+	}
+	return transitions
+}
+
+func offsetExpression(transitions []zoneTransition) (string, []interface{}) {
+	if len(transitions) == 1 {
+		return fmt.Sprintf("%d", transitions[0].offset), nil
+	}
+	var args []interface{}
+	expression := "CASE"
+	for i := 1; i < len(transitions); i++ {
+		expression += fmt.Sprintf(` WHEN "timestamp" < ? THEN %d`, transitions[i-1].offset)
+		args = append(args, transitions[i].at.UTC())
+	}
+	return expression + fmt.Sprintf(" ELSE %d END", transitions[len(transitions)-1].offset), args
+}
+
+func (t *intervalTruncation) selectClause() string {
+	return t.start + " AS start, COUNT(*) AS count, COUNT(DISTINCT NULLIF(visitor_hash, '')) AS unique_visitors"
+}
+
+// clickBuckets répartit des clics dans les intervalles successifs de [from, to[.
+type clickBuckets struct {
+	interval TimeInterval
+	loc      *time.Location
+	buckets  []models.ClickBucket
+	index    map[int64]int
+	visitors []map[string]struct{}
+}
+
+func newClickBuckets(interval TimeInterval, from, to time.Time, loc *time.Location) *clickBuckets {
+	b := &clickBuckets{interval: interval, loc: loc, index: make(map[int64]int)}
+	for start := interval.Truncate(from, loc); start.Before(to); start = interval.Next(start) {
+		b.index[start.Unix()] = len(b.buckets)
+		b.buckets = append(b.buckets, models.ClickBucket{Start: start})
+	}
+	b.visitors = make([]map[string]struct{}, len(b.buckets))
+	return b
+}
+
+func (b *clickBuckets) add(timestamp time.Time, visitorHash string) {
+	i, ok := b.index[b.interval.Truncate(timestamp, b.loc).Unix()]
+	if !ok {
+		return
+	}
+	b.buckets[i].Count++
+	if visitorHash == "" {
+		return
+	}
+	if b.visitors[i] == nil {
+		b.visitors[i] = make(map[string]struct{})
+	}
+	b.visitors[i][visitorHash] = struct{}{}
+}
+
+func (b *clickBuckets) starts() []time.Time {
+	starts := make([]time.Time, len(b.buckets))
+	for i, bucket := range b.buckets {
+		starts[i] = bucket.Start
+	}
+	return starts
+}
+
+// set enregistre les compteurs calculés par la base pour l'intervalle contenant start.
+func (b *clickBuckets) set(start time.Time, count int, uniqueVisitors int) {
+	i, ok := b.index[b.interval.Truncate(start, b.loc).Unix()]
+	if !ok {
+		return
+	}
+	b.buckets[i].Count += count
+	b.buckets[i].UniqueVisitors += uniqueVisitors
+}
+
+func (b *clickBuckets) result() []models.ClickBucket {
+	for i := range b.buckets {
+		b.buckets[i].UniqueVisitors = len(b.visitors[i])
+	}
+	return b.buckets
+}
+
+// CountClicksByDimension retourne les valeurs les plus fréquentes de la dimension pour un lien,
+// triées par nombre de clics décroissant.
+func (r *GormClickRepository) CountClicksByDimension(linkID uint, dimension ClickDimension, limit int, filter ClickFilter) ([]models.ClickStat, error) {
+	column, ok := clickDimensionColumns[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unsupported click dimension '%s'", dimension)
+	}
+
+	var stats []models.ClickStat
+	query := r.db.Model(&models.Click{}).
+		Select(column+" AS value, COUNT(*) AS count").
+		Where("link_id = ?", linkID)
+	err := filter.apply(query).
+		Group(column).
+		Order("count DESC").
+		Order(column + " ASC").
+		Limit(limit).
+		Scan(&stats).Error
+	return stats, err
+}
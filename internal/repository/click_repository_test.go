@@ -6,18 +6,11 @@ import (
 
 	"github.com/Edofo/bitly-clone/internal/models"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func setupClickTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	assert.NoError(t, err)
-	
-	err = db.AutoMigrate(&models.Link{}, &models.Click{})
-	assert.NoError(t, err)
-	
-	return db
+	return openTestDB(t, &models.Link{}, &models.Click{})
 }
 
 func TestNewClickRepository(t *testing.T) {
@@ -60,6 +53,32 @@ func TestGormClickRepository_CreateClick(t *testing.T) {
 	assert.Equal(t, click.IPAddress, savedClick.IPAddress)
 }
 
+func TestGormClickRepository_CreateClicks(t *testing.T) {
+	db := setupClickTestDB(t)
+	repo := NewClickRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", CreatedAt: time.Now()}
+	assert.NoError(t, db.Create(link).Error)
+
+	// Plus de clics qu'un seul INSERT n'en accepte
+	clicks := make([]*models.Click, maxClicksPerInsert+50)
+	for i := range clicks {
+		clicks[i] = &models.Click{LinkID: link.ID, Timestamp: time.Now(), IPAddress: "192.168.1.1"}
+	}
+
+	err := repo.CreateClicks(clicks)
+
+	assert.NoError(t, err)
+	for _, click := range clicks {
+		assert.NotZero(t, click.ID)
+	}
+	count, err := repo.CountClicksByLinkID(link.ID, ClickFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, len(clicks), count)
+
+	assert.NoError(t, repo.CreateClicks(nil))
+}
+
 func TestGormClickRepository_CountClicksByLinkID(t *testing.T) {
 	db := setupClickTestDB(t)
 	repo := NewClickRepository(db)
@@ -83,7 +102,7 @@ func TestGormClickRepository_CountClicksByLinkID(t *testing.T) {
 		assert.NoError(t, err)
 	}
 	
-	count, err := repo.CountClicksByLinkID(link.ID)
+	count, err := repo.CountClicksByLinkID(link.ID, ClickFilter{})
 	
 	assert.NoError(t, err)
 	assert.Equal(t, 3, count)
@@ -101,8 +120,197 @@ func TestGormClickRepository_CountClicksByLinkID_NoClicks(t *testing.T) {
 	err := db.Create(link).Error
 	assert.NoError(t, err)
 	
-	count, err := repo.CountClicksByLinkID(link.ID)
+	count, err := repo.CountClicksByLinkID(link.ID, ClickFilter{})
 	
 	assert.NoError(t, err)
 	assert.Equal(t, 0, count)
-} 
\ No newline at end of file
+} 
+func TestTimeInterval_Truncate(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	// Mercredi 13 mars 2024, 23h30 UTC = jeudi 14 mars 00h30 à Paris
+	ts := time.Date(2024, 3, 13, 23, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, 3, 13, 23, 0, 0, 0, time.UTC), IntervalHour.Truncate(ts, time.UTC))
+	assert.Equal(t, time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), IntervalDay.Truncate(ts, time.UTC))
+	assert.Equal(t, time.Date(2024, 3, 14, 0, 0, 0, 0, paris), IntervalDay.Truncate(ts, paris))
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, paris), IntervalWeek.Truncate(ts, paris))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, paris), IntervalMonth.Truncate(ts, paris))
+}
+
+func TestTimeInterval_NextAcrossDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	// Passage à l'heure d'été le 31 mars 2024 : la journée ne dure que 23 heures
+	start := time.Date(2024, 3, 31, 0, 0, 0, 0, paris)
+	next := IntervalDay.Next(start)
+
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, paris), next)
+	assert.Equal(t, 23*time.Hour, next.Sub(start))
+}
+
+func TestGormClickRepository_CountClicksByInterval(t *testing.T) {
+	db := setupClickTestDB(t)
+	repo := NewClickRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", CreatedAt: time.Now()}
+	assert.NoError(t, db.Create(link).Error)
+
+	timestamps := []time.Time{
+		time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 22, 30, 0, 0, time.UTC), // 2 mars à Tokyo
+		time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 3, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC), // hors période
+	}
+	for _, ts := range timestamps {
+		assert.NoError(t, db.Create(&models.Click{LinkID: link.ID, Timestamp: ts}).Error)
+	}
+	assert.NoError(t, db.Create(&models.Click{LinkID: link.ID + 1, Timestamp: timestamps[0]}).Error)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	buckets, err := repo.CountClicksByInterval(link.ID, IntervalDay, from, to, time.UTC, ClickFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, buckets, 3)
+	assert.Equal(t, 2, buckets[0].Count)
+	assert.Equal(t, 0, buckets[1].Count)
+	assert.Equal(t, 2, buckets[2].Count)
+	assert.Equal(t, from, buckets[0].Start)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	from = time.Date(2024, 3, 1, 0, 0, 0, 0, tokyo)
+	to = time.Date(2024, 3, 4, 0, 0, 0, 0, tokyo)
+
+	buckets, err = repo.CountClicksByInterval(link.ID, IntervalDay, from, to, tokyo, ClickFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, buckets, 3)
+	assert.Equal(t, 1, buckets[0].Count)
+	assert.Equal(t, 1, buckets[1].Count)
+	assert.Equal(t, 2, buckets[2].Count)
+}
+
+func TestGormClickRepository_CountClicksByInterval_UniqueVisitors(t *testing.T) {
+	db := setupClickTestDB(t)
+	repo := NewClickRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", CreatedAt: time.Now()}
+	assert.NoError(t, db.Create(link).Error)
+
+	clicks := []models.Click{
+		{LinkID: link.ID, Timestamp: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), VisitorHash: "alice"},
+		{LinkID: link.ID, Timestamp: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), VisitorHash: "alice"},
+		{LinkID: link.ID, Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), VisitorHash: "bob"},
+		{LinkID: link.ID, Timestamp: time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)},
+	}
+	for i := range clicks {
+		assert.NoError(t, db.Create(&clicks[i]).Error)
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	buckets, err := repo.CountClicksByInterval(link.ID, IntervalDay, from, to, time.UTC, ClickFilter{})
+
+	assert.NoError(t, err)
+	assert.Len(t, buckets, 2)
+	assert.Equal(t, 3, buckets[0].Count)
+	assert.Equal(t, 2, buckets[0].UniqueVisitors)
+	assert.Equal(t, 1, buckets[1].Count)
+	assert.Equal(t, 0, buckets[1].UniqueVisitors)
+}
+
+func TestGormClickRepository_CountClicksByDimension(t *testing.T) {
+	db := setupClickTestDB(t)
+	repo := NewClickRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", CreatedAt: time.Now()}
+	assert.NoError(t, db.Create(link).Error)
+
+	for _, domain := range []string{"google.com", "t.co", "google.com", "", "google.com", "t.co", "news.ycombinator.com"} {
+		assert.NoError(t, db.Create(&models.Click{LinkID: link.ID, Timestamp: time.Now(), ReferrerDomain: domain}).Error)
+	}
+	assert.NoError(t, db.Create(&models.Click{LinkID: link.ID + 1, Timestamp: time.Now(), ReferrerDomain: "t.co"}).Error)
+
+	stats, err := repo.CountClicksByDimension(link.ID, DimensionReferrer, 3, ClickFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ClickStat{
+		{Value: "google.com", Count: 3},
+		{Value: "t.co", Count: 2},
+		{Value: "", Count: 1},
+	}, stats)
+}
+
+func TestGormClickRepository_ExcludesBotsByDefault(t *testing.T) {
+	db := setupClickTestDB(t)
+	repo := NewClickRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", CreatedAt: time.Now()}
+	assert.NoError(t, db.Create(link).Error)
+
+	now := time.Now().UTC()
+	clicks := []models.Click{
+		{LinkID: link.ID, Timestamp: now, DeviceClass: "desktop"},
+		{LinkID: link.ID, Timestamp: now, DeviceClass: "mobile"},
+		{LinkID: link.ID, Timestamp: now, DeviceClass: "bot", IsBot: true},
+	}
+	for i := range clicks {
+		assert.NoError(t, db.Create(&clicks[i]).Error)
+	}
+
+	count, err := repo.CountClicksByLinkID(link.ID, ClickFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = repo.CountClicksByLinkID(link.ID, ClickFilter{IncludeBots: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	stats, err := repo.CountClicksByDimension(link.ID, DimensionDevice, 10, ClickFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ClickStat{{Value: "desktop", Count: 1}, {Value: "mobile", Count: 1}}, stats)
+
+	buckets, err := repo.CountClicksByInterval(link.ID, IntervalDay, now.Add(-time.Hour), now.Add(time.Hour), time.UTC, ClickFilter{IncludeBots: true})
+	assert.NoError(t, err)
+	total := 0
+	for _, bucket := range buckets {
+		total += bucket.Count
+	}
+	assert.Equal(t, 3, total)
+}
+
+func TestGormClickRepository_CountClicksByDimension_Invalid(t *testing.T) {
+	db := setupClickTestDB(t)
+	repo := NewClickRepository(db)
+
+	_, err := repo.CountClicksByDimension(1, ClickDimension("ip_address; DROP TABLE clicks"), 10, ClickFilter{})
+
+	assert.Error(t, err)
+}
+
+func TestGormClickRepository_CountClicksByDimension_City(t *testing.T) {
+	db := setupClickTestDB(t)
+	repo := NewClickRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", CreatedAt: time.Now()}
+	assert.NoError(t, db.Create(link).Error)
+
+	locations := [][2]string{{"FR", "Paris"}, {"FR", "Paris"}, {"US", "Paris"}, {"GB", ""}}
+	for _, location := range locations {
+		click := &models.Click{LinkID: link.ID, Timestamp: time.Now(), Country: location[0], City: location[1]}
+		assert.NoError(t, db.Create(click).Error)
+	}
+
+	stats, err := repo.CountClicksByDimension(link.ID, DimensionCity, 10, ClickFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ClickStat{
+		{Value: "Paris, FR", Count: 2},
+		{Value: "", Count: 1},
+		{Value: "Paris, US", Count: 1},
+	}, stats)
+}
@@ -0,0 +1,823 @@
+package repository
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/cache"
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Tests de contrat : toute implémentation des dépôts doit les passer à l'identique.
+// Pour ajouter une implémentation, il suffit de l'ajouter à contractBackends.
+
+type contractRepositories struct {
+	links       LinkRepository
+	clicks      ClickRepository
+	health      HealthCheckRepository
+	salts       VisitorSaltRepository
+	deadLetters WebhookDeadLetterRepository
+	apiKeys     APIKeyRepository
+	users       UserRepository
+	sessions    SessionRepository
+	workspaces  WorkspaceRepository
+	invitations InvitationRepository
+	usage       UsageRepository
+}
+
+var contractBackends = []struct {
+	name string
+	open func(t *testing.T) contractRepositories
+}{
+	{"gorm", func(t *testing.T) contractRepositories {
+		db := openConcurrentTestDB(t, &models.Link{}, &models.Click{}, &models.HealthCheck{},
+			&models.VisitorSalt{}, &models.WebhookDeadLetter{}, &models.APIKey{}, &models.User{}, &models.Session{},
+			&models.Workspace{}, &models.Membership{}, &models.Invitation{}, &models.UsageCounter{})
+		return contractRepositories{
+			links:       NewLinkRepository(db),
+			clicks:      NewClickRepository(db),
+			health:      NewHealthCheckRepository(db),
+			salts:       NewVisitorSaltRepository(db),
+			deadLetters: NewWebhookDeadLetterRepository(db),
+			apiKeys:     NewAPIKeyRepository(db),
+			users:       NewUserRepository(db),
+			sessions:    NewSessionRepository(db),
+			workspaces:  NewWorkspaceRepository(db),
+			invitations: NewInvitationRepository(db),
+			usage:       NewUsageRepository(db),
+		}
+	}},
+	{"memory", func(t *testing.T) contractRepositories {
+		store := NewMemoryStore()
+		return contractRepositories{
+			links:       NewMemoryLinkRepository(store),
+			clicks:      NewMemoryClickRepository(store),
+			health:      NewMemoryHealthCheckRepository(store),
+			salts:       NewMemoryVisitorSaltRepository(store),
+			deadLetters: NewMemoryWebhookDeadLetterRepository(store),
+			apiKeys:     NewMemoryAPIKeyRepository(store),
+			users:       NewMemoryUserRepository(store),
+			sessions:    NewMemorySessionRepository(store),
+			workspaces:  NewMemoryWorkspaceRepository(store),
+			invitations: NewMemoryInvitationRepository(store),
+			usage:       NewMemoryUsageRepository(store),
+		}
+	}},
+	{"cached", func(t *testing.T) contractRepositories {
+		store := NewMemoryStore()
+		return contractRepositories{
+			links:       NewCachedLinkRepository(NewMemoryLinkRepository(store), cache.NewLRU(100), LinkCacheOptions{}),
+			clicks:      NewMemoryClickRepository(store),
+			health:      NewMemoryHealthCheckRepository(store),
+			salts:       NewMemoryVisitorSaltRepository(store),
+			deadLetters: NewMemoryWebhookDeadLetterRepository(store),
+			apiKeys:     NewMemoryAPIKeyRepository(store),
+			users:       NewMemoryUserRepository(store),
+			sessions:    NewMemorySessionRepository(store),
+			workspaces:  NewMemoryWorkspaceRepository(store),
+			invitations: NewMemoryInvitationRepository(store),
+			usage:       NewMemoryUsageRepository(store),
+		}
+	}},
+}
+
+func runContract(t *testing.T, test func(t *testing.T, r contractRepositories)) {
+	for _, backend := range contractBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+}
+
+func createContractLink(t *testing.T, r contractRepositories, shortCode string, createdAt time.Time) *models.Link {
+	link := &models.Link{ShortCode: shortCode, LongURL: "https://www.example.com/" + shortCode, Domain: "example.com", CreatedAt: createdAt}
+	require.NoError(t, r.links.CreateLink(link))
+	return link
+}
+
+func TestLinkRepositoryContract_CreateAndGet(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", Domain: "example.com", MaxClicks: 5}
+		require.NoError(t, r.links.CreateLink(link))
+		assert.NotZero(t, link.ID)
+		assert.False(t, link.CreatedAt.IsZero())
+
+		found, err := r.links.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, link.ID, found.ID)
+		assert.Equal(t, "https://www.example.com", found.LongURL)
+		assert.Equal(t, 5, found.MaxClicks)
+		assert.WithinDuration(t, link.CreatedAt, found.CreatedAt, time.Second)
+
+		_, err = r.links.GetLinkByShortCode("missing")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		found, err = r.links.GetLinkByID(link.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "abc123", found.ShortCode)
+
+		_, err = r.links.GetLinkByID(link.ID + 100)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		err = r.links.CreateLink(&models.Link{ShortCode: "abc123", LongURL: "https://other.example.com"})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	})
+}
+
+func TestLinkRepositoryContract_ReturnsCopies(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		createContractLink(t, r, "abc123", time.Now())
+
+		found, err := r.links.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		found.LongURL = "https://changed.example.com"
+
+		again, err := r.links.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://www.example.com/abc123", again.LongURL)
+	})
+}
+
+func TestLinkRepositoryContract_UpdateAndDelete(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		link := createContractLink(t, r, "abc123", time.Now())
+		other := createContractLink(t, r, "def456", time.Now())
+		require.NoError(t, r.clicks.CreateClick(&models.Click{LinkID: link.ID, Timestamp: time.Now()}))
+		require.NoError(t, r.clicks.CreateClick(&models.Click{LinkID: other.ID, Timestamp: time.Now()}))
+		_, err := r.links.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+
+		require.NoError(t, r.links.UpdateLinkWebhook(link.ID, "https://hooks.example.org/links", "s3cret"))
+
+		found, err := r.links.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://hooks.example.org/links", found.WebhookURL)
+		assert.Equal(t, "s3cret", found.WebhookSecret)
+		assert.Equal(t, link.LongURL, found.LongURL)
+		assert.ErrorIs(t, r.links.UpdateLinkWebhook(9999, "", ""), gorm.ErrRecordNotFound)
+
+		require.NoError(t, r.links.DeleteLink(link.ID))
+		_, err = r.links.GetLinkByShortCode("abc123")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, r.links.DeleteLink(link.ID), gorm.ErrRecordNotFound)
+
+		// Les clics du lien supprimé disparaissent, pas ceux des autres liens
+		count, err := r.clicks.CountClicksByLinkID(link.ID, ClickFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+		count, err = r.clicks.CountClicksByLinkID(other.ID, ClickFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestLinkRepositoryContract_ListLinks(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		for i, code := range []string{"ccc", "aaa", "bbb", "ddd"} {
+			createContractLink(t, r, code, base.Add(time.Duration(i)*time.Hour))
+		}
+		sub := &models.Link{ShortCode: "eee", LongURL: "https://blog.other.org", Domain: "blog.other.org", CreatedAt: base.Add(5 * time.Hour)}
+		require.NoError(t, r.links.CreateLink(sub))
+
+		all, err := r.links.GetAllLinks()
+		require.NoError(t, err)
+		assert.Len(t, all, 5)
+
+		links, total, err := r.links.ListLinks(LinkFilter{Page: 2, PageSize: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		require.Len(t, links, 2)
+		assert.Equal(t, "bbb", links[0].ShortCode)
+		assert.Equal(t, "ddd", links[1].ShortCode)
+
+		links, _, err = r.links.ListLinks(LinkFilter{SortBy: "short_code", SortDesc: true, PageSize: 3})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"eee", "ddd", "ccc"}, shortCodes(links))
+
+		links, total, err = r.links.ListLinks(LinkFilter{Domain: "other.org"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, "eee", links[0].ShortCode)
+
+		after, before := base.Add(time.Hour), base.Add(3*time.Hour)
+		links, total, err = r.links.ListLinks(LinkFilter{CreatedAfter: &after, CreatedBefore: &before})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Equal(t, []string{"aaa", "bbb"}, shortCodes(links))
+
+		links, total, err = r.links.ListLinks(LinkFilter{Page: 10, PageSize: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		assert.Empty(t, links)
+	})
+}
+
+func shortCodes(links []models.Link) []string {
+	codes := make([]string, len(links))
+	for i, link := range links {
+		codes[i] = link.ShortCode
+	}
+	return codes
+}
+
+func TestLinkRepositoryContract_ConsumeClick(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", MaxClicks: 10}
+		require.NoError(t, r.links.CreateLink(link))
+
+		var wg sync.WaitGroup
+		var granted atomic.Int32
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, err := r.links.ConsumeClick(link.ID)
+				assert.NoError(t, err)
+				if ok {
+					granted.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(10), granted.Load())
+		found, err := r.links.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, 10, found.ConsumedClicks)
+
+		ok, err := r.links.ConsumeClick(9999)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestLinkRepositoryContract_UpdateLinkURL(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", Domain: "www.example.com", MaxClicks: 100}
+		require.NoError(t, r.links.CreateLink(link))
+
+		// Les clics consommés pendant la modification de l'URL ne doivent pas être perdus
+		var wg sync.WaitGroup
+		var granted atomic.Int32
+		for i := 0; i < 20; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				ok, err := r.links.ConsumeClick(link.ID)
+				assert.NoError(t, err)
+				if ok {
+					granted.Add(1)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, r.links.UpdateLinkURL(link.ID, "https://docs.example.org/page", "docs.example.org"))
+			}()
+		}
+		wg.Wait()
+
+		found, err := r.links.GetLinkByShortCode("abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://docs.example.org/page", found.LongURL)
+		assert.Equal(t, "docs.example.org", found.Domain)
+		assert.Equal(t, int(granted.Load()), found.ConsumedClicks)
+		assert.Equal(t, 100, found.MaxClicks)
+
+		assert.ErrorIs(t, r.links.UpdateLinkURL(9999, "https://www.example.org", "www.example.org"), gorm.ErrRecordNotFound)
+	})
+}
+
+func TestLinkRepositoryContract_CountClickTotals(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		link := createContractLink(t, r, "abc123", time.Now())
+		now := time.Now()
+		require.NoError(t, r.clicks.CreateClicks([]*models.Click{
+			{LinkID: link.ID, Timestamp: now, VisitorHash: "v1"},
+			{LinkID: link.ID, Timestamp: now, VisitorHash: "v1"},
+			{LinkID: link.ID, Timestamp: now, VisitorHash: "v2"},
+			{LinkID: link.ID, Timestamp: now},
+			{LinkID: link.ID, Timestamp: now, VisitorHash: "bot", IsBot: true},
+		}))
+
+		totals, err := r.links.CountClickTotals(link.ID, ClickFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, models.ClickTotals{TotalClicks: 4, UniqueVisitors: 2}, totals)
+
+		totals, err = r.links.CountClickTotals(link.ID, ClickFilter{IncludeBots: true})
+		require.NoError(t, err)
+		assert.Equal(t, models.ClickTotals{TotalClicks: 5, UniqueVisitors: 3}, totals)
+
+		count, err := r.links.CountClicksByLinkID(link.ID, ClickFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 4, count)
+	})
+}
+
+func TestClickRepositoryContract_CountClicksByLinkID(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		click := &models.Click{LinkID: 1, Timestamp: base}
+		require.NoError(t, r.clicks.CreateClick(click))
+		assert.NotZero(t, click.ID)
+		require.NoError(t, r.clicks.CreateClicks([]*models.Click{
+			{LinkID: 1, Timestamp: base.Add(time.Hour)},
+			{LinkID: 1, Timestamp: base.Add(2 * time.Hour), IsBot: true},
+			{LinkID: 2, Timestamp: base},
+		}))
+		require.NoError(t, r.clicks.CreateClicks(nil))
+
+		count, err := r.clicks.CountClicksByLinkID(1, ClickFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		count, err = r.clicks.CountClicksByLinkID(1, ClickFilter{IncludeBots: true, From: base.Add(time.Hour), To: base.Add(3 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		count, err = r.clicks.CountClicksByLinkID(1, ClickFilter{To: base.Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestClickRepositoryContract_CountClicksByInterval(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, r.clicks.CreateClicks([]*models.Click{
+			{LinkID: 1, Timestamp: base.Add(2 * time.Hour), VisitorHash: "v1"},
+			{LinkID: 1, Timestamp: base.Add(3 * time.Hour), VisitorHash: "v1"},
+			{LinkID: 1, Timestamp: base.Add(50 * time.Hour), VisitorHash: "v2"},
+			{LinkID: 1, Timestamp: base.Add(51 * time.Hour), IsBot: true},
+			{LinkID: 1, Timestamp: base.Add(80 * time.Hour)},
+		}))
+
+		buckets, err := r.clicks.CountClicksByInterval(1, IntervalDay, base, base.Add(72*time.Hour), time.UTC, ClickFilter{})
+		require.NoError(t, err)
+		require.Len(t, buckets, 3)
+		assert.True(t, base.Equal(buckets[0].Start))
+		assert.Equal(t, 2, buckets[0].Count)
+		assert.Equal(t, 1, buckets[0].UniqueVisitors)
+		assert.Equal(t, 0, buckets[1].Count)
+		assert.Equal(t, 1, buckets[2].Count)
+	})
+}
+
+func TestClickRepositoryContract_CountClicksByIntervalDaylightSaving(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		paris, err := time.LoadLocation("Europe/Paris")
+		require.NoError(t, err)
+		require.NoError(t, r.clicks.CreateClicks([]*models.Click{
+			// Passage à l'heure d'été le 31 mars 2024 à 01:00 UTC
+			{LinkID: 1, Timestamp: time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC), VisitorHash: "v1"}, // 31 mars 00:30
+			{LinkID: 1, Timestamp: time.Date(2024, 3, 31, 21, 30, 0, 0, time.UTC), VisitorHash: "v1"}, // 31 mars 23:30
+			{LinkID: 1, Timestamp: time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC), VisitorHash: "v2"}, // 1er avril 00:30
+			// Passage à l'heure d'hiver le 27 octobre 2024 à 01:00 UTC : 02:30 existe deux fois
+			{LinkID: 1, Timestamp: time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC)},
+			{LinkID: 1, Timestamp: time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC)},
+		}))
+
+		from := time.Date(2024, 3, 30, 0, 0, 0, 0, paris)
+		buckets, err := r.clicks.CountClicksByInterval(1, IntervalDay, from, time.Date(2024, 4, 2, 0, 0, 0, 0, paris), paris, ClickFilter{})
+		require.NoError(t, err)
+		require.Len(t, buckets, 3)
+		assert.Equal(t, []int{0, 2, 1}, []int{buckets[0].Count, buckets[1].Count, buckets[2].Count})
+		assert.Equal(t, 1, buckets[1].UniqueVisitors)
+
+		buckets, err = r.clicks.CountClicksByInterval(1, IntervalWeek, from, time.Date(2024, 4, 8, 0, 0, 0, 0, paris), paris, ClickFilter{})
+		require.NoError(t, err)
+		require.Len(t, buckets, 2)
+		assert.True(t, time.Date(2024, 3, 25, 0, 0, 0, 0, paris).Equal(buckets[0].Start))
+		assert.Equal(t, 2, buckets[0].Count)
+		assert.Equal(t, 1, buckets[1].Count)
+
+		from = time.Date(2024, 10, 27, 1, 0, 0, 0, paris)
+		buckets, err = r.clicks.CountClicksByInterval(1, IntervalHour, from, from.Add(4*time.Hour), paris, ClickFilter{})
+		require.NoError(t, err)
+		require.Len(t, buckets, 4)
+		// Comme TimeInterval.Truncate, les deux heures 02:00 sont comptées dans le même intervalle
+		assert.Equal(t, []int{0, 0, 2, 0}, []int{buckets[0].Count, buckets[1].Count, buckets[2].Count, buckets[3].Count})
+	})
+}
+
+func TestClickRepositoryContract_CountClicksByDimension(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		now := time.Now()
+		require.NoError(t, r.clicks.CreateClicks([]*models.Click{
+			{LinkID: 1, Timestamp: now, Browser: "Firefox", Country: "FR", City: "Paris"},
+			{LinkID: 1, Timestamp: now, Browser: "Chrome", Country: "US", City: "Paris"},
+			{LinkID: 1, Timestamp: now, Browser: "Chrome", Country: "FR", City: "Paris"},
+			{LinkID: 1, Timestamp: now, Browser: "Safari", Country: "FR"},
+			{LinkID: 1, Timestamp: now, Browser: "Bot", IsBot: true},
+		}))
+
+		stats, err := r.clicks.CountClicksByDimension(1, DimensionBrowser, 2, ClickFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, []models.ClickStat{{Value: "Chrome", Count: 2}, {Value: "Firefox", Count: 1}}, stats)
+
+		stats, err = r.clicks.CountClicksByDimension(1, DimensionCity, 10, ClickFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, []models.ClickStat{{Value: "Paris, FR", Count: 2}, {Value: "", Count: 1}, {Value: "Paris, US", Count: 1}}, stats)
+
+		stats, err = r.clicks.CountClicksByDimension(2, DimensionCountry, 10, ClickFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, stats)
+
+		_, err = r.clicks.CountClicksByDimension(1, ClickDimension("color"), 10, ClickFilter{})
+		assert.Error(t, err)
+	})
+}
+
+func TestHealthCheckRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		now := time.Now()
+		require.NoError(t, r.health.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now.AddDate(0, 0, -40), Accessible: true}))
+		require.NoError(t, r.health.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now.Add(-time.Hour), Accessible: true}))
+		require.NoError(t, r.health.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now, Accessible: false, ErrorClass: "timeout",
+			RedirectChain: models.StringList{"https://www.example.com/"}}))
+		require.NoError(t, r.health.CreateHealthCheck(&models.HealthCheck{LinkID: 2, CheckedAt: now, Accessible: true}))
+
+		recent, err := r.health.GetRecentHealthChecks(1, 2)
+		require.NoError(t, err)
+		require.Len(t, recent, 2)
+		assert.Equal(t, "timeout", recent[0].ErrorClass)
+		assert.Equal(t, models.StringList{"https://www.example.com/"}, recent[0].RedirectChain)
+		assert.True(t, recent[1].Accessible)
+
+		latest, err := r.health.GetLatestHealthChecks()
+		require.NoError(t, err)
+		assert.Len(t, latest, 2)
+		assert.False(t, latest[1].Accessible)
+
+		summary, err := r.health.SummarizeHealthChecks(1, now.Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, models.HealthSummary{Checks: 2, Successful: 1}, summary)
+
+		deleted, err := r.health.DeleteHealthChecksBefore(now.AddDate(0, 0, -30))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+}
+
+func TestVisitorSaltRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		salt, err := r.salts.GetOrCreateSalt("2024-03-01", []byte("first"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("first"), salt)
+
+		// Le sel déjà enregistré l'emporte sur le candidat
+		salt, err = r.salts.GetOrCreateSalt("2024-03-01", []byte("second"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("first"), salt)
+
+		require.NoError(t, r.salts.DeleteSaltsBefore("2024-03-02"))
+		salt, err = r.salts.GetOrCreateSalt("2024-03-01", []byte("third"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("third"), salt)
+	})
+}
+
+func TestWebhookDeadLetterRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		for _, url := range []string{"https://hooks.example.org/a", "https://hooks.example.org/b"} {
+			require.NoError(t, r.deadLetters.CreateDeadLetter(&models.WebhookDeadLetter{LinkID: 1, EventID: "evt", URL: url, Payload: "{}"}))
+		}
+
+		deadLetters, err := r.deadLetters.ListDeadLetters(10)
+		require.NoError(t, err)
+		require.Len(t, deadLetters, 2)
+		assert.Equal(t, "https://hooks.example.org/b", deadLetters[0].URL)
+		assert.False(t, deadLetters[0].CreatedAt.IsZero())
+
+		deadLetters, err = r.deadLetters.ListDeadLetters(1)
+		require.NoError(t, err)
+		assert.Len(t, deadLetters, 1)
+	})
+}
+
+func TestAPIKeyRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		for _, prefix := range []string{"usk_00000001", "usk_00000002"} {
+			require.NoError(t, r.apiKeys.CreateAPIKey(&models.APIKey{Name: "ci", Prefix: prefix, KeyHash: "hash-" + prefix}))
+		}
+		err := r.apiKeys.CreateAPIKey(&models.APIKey{Prefix: "usk_00000001", KeyHash: "other"})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+		key, err := r.apiKeys.GetAPIKeyByPrefix("usk_00000002")
+		require.NoError(t, err)
+		assert.Equal(t, "hash-usk_00000002", key.KeyHash)
+		assert.False(t, key.CreatedAt.IsZero())
+		assert.Nil(t, key.LastUsedAt)
+		assert.False(t, key.IsRevoked())
+
+		_, err = r.apiKeys.GetAPIKeyByPrefix("usk_ffffffff")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		usedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		require.NoError(t, r.apiKeys.TouchAPIKey(key.ID, usedAt))
+		revokedAt := usedAt.Add(time.Hour)
+		require.NoError(t, r.apiKeys.RevokeAPIKey(key.ID, revokedAt))
+		// Une clé déjà révoquée garde sa date de révocation
+		assert.ErrorIs(t, r.apiKeys.RevokeAPIKey(key.ID, revokedAt.Add(time.Hour)), gorm.ErrRecordNotFound)
+
+		keys, err := r.apiKeys.ListAPIKeys()
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, "usk_00000001", keys[0].Prefix)
+		require.NotNil(t, keys[1].LastUsedAt)
+		assert.True(t, usedAt.Equal(*keys[1].LastUsedAt))
+		require.NotNil(t, keys[1].RevokedAt)
+		assert.True(t, revokedAt.Equal(*keys[1].RevokedAt))
+	})
+}
+
+func TestUserRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		for _, email := range []string{"bob@example.com", "alice@example.com"} {
+			require.NoError(t, r.users.CreateUser(&models.User{Email: email, PasswordHash: "hash"}))
+		}
+		err := r.users.CreateUser(&models.User{Email: "alice@example.com", PasswordHash: "other"})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+		alice, err := r.users.GetUserByEmail("alice@example.com")
+		require.NoError(t, err)
+		assert.False(t, alice.CreatedAt.IsZero())
+
+		byID, err := r.users.GetUserByID(alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", byID.Email)
+
+		_, err = r.users.GetUserByEmail("carol@example.com")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = r.users.GetUserByID(999)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		users, err := r.users.ListUsers()
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.Equal(t, "bob@example.com", users[0].Email)
+	})
+}
+
+func TestSessionRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		user := &models.User{Email: "alice@example.com", PasswordHash: "hash"}
+		require.NoError(t, r.users.CreateUser(user))
+
+		now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		expired := &models.Session{UserID: user.ID, TokenHash: "expired", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+		current := &models.Session{UserID: user.ID, TokenHash: "current", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		require.NoError(t, r.sessions.CreateSession(expired))
+		require.NoError(t, r.sessions.CreateSession(current))
+
+		err := r.sessions.CreateSession(&models.Session{UserID: user.ID, TokenHash: "current", ExpiresAt: now})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+		session, err := r.sessions.GetSessionByTokenHash("current")
+		require.NoError(t, err)
+		assert.Equal(t, current.ID, session.ID)
+		require.NotNil(t, session.User, "la session est retournée avec son utilisateur")
+		assert.Equal(t, "alice@example.com", session.User.Email)
+
+		deleted, err := r.sessions.DeleteExpiredSessions(now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		_, err = r.sessions.GetSessionByTokenHash("expired")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		require.NoError(t, r.sessions.DeleteSession(current.ID))
+		_, err = r.sessions.GetSessionByTokenHash("current")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestLinkRepositoryContract_ListLinksInScope(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		alice := createContractUser(t, r, "alice@example.com")
+		bob := createContractUser(t, r, "bob@example.com")
+		team := &models.Workspace{Name: "team"}
+		require.NoError(t, r.workspaces.CreateWorkspace(team, &models.Membership{UserID: bob.ID, Role: models.RoleOwner}))
+		other := &models.Workspace{Name: "other"}
+		require.NoError(t, r.workspaces.CreateWorkspace(other, &models.Membership{UserID: bob.ID, Role: models.RoleOwner}))
+
+		createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		for i, link := range []models.Link{
+			{OwnerID: &alice.ID}, // lien personnel d'Alice
+			{OwnerID: &bob.ID},   // lien personnel de Bob
+			{},                   // lien d'administration
+			{OwnerID: &bob.ID, WorkspaceID: &team.ID},    // lien de l'équipe créé par Bob
+			{OwnerID: &alice.ID, WorkspaceID: &other.ID}, // créé par Alice, qui n'est plus membre
+		} {
+			link.ShortCode = string(rune('a'+i)) + "link"
+			link.LongURL = "https://www.example.com"
+			link.CreatedAt = createdAt
+			require.NoError(t, r.links.CreateLink(&link))
+		}
+
+		listCodes := func(filter LinkFilter) []string {
+			links, total, err := r.links.ListLinks(filter)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(links)), total)
+			codes := make([]string, 0, len(links))
+			for _, link := range links {
+				codes = append(codes, link.ShortCode)
+			}
+			return codes
+		}
+
+		assert.ElementsMatch(t, []string{"alink"}, listCodes(LinkFilter{Scope: &LinkScope{UserID: alice.ID}}))
+		assert.ElementsMatch(t, []string{"alink", "dlink"}, listCodes(LinkFilter{Scope: &LinkScope{UserID: alice.ID, WorkspaceIDs: []uint{team.ID}}}))
+		assert.ElementsMatch(t, []string{"dlink"}, listCodes(LinkFilter{Scope: &LinkScope{UserID: alice.ID, WorkspaceIDs: []uint{team.ID}}, WorkspaceID: &team.ID}))
+		assert.Len(t, listCodes(LinkFilter{}), 5, "sans périmètre, le filtre retient tous les liens")
+	})
+}
+
+func createContractUser(t *testing.T, r contractRepositories, email string) *models.User {
+	user := &models.User{Email: email, PasswordHash: "hash"}
+	require.NoError(t, r.users.CreateUser(user))
+	return user
+}
+
+func TestWorkspaceRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		alice := createContractUser(t, r, "alice@example.com")
+		bob := createContractUser(t, r, "bob@example.com")
+
+		workspace := &models.Workspace{Name: "team"}
+		owner := &models.Membership{UserID: alice.ID, Role: models.RoleOwner}
+		require.NoError(t, r.workspaces.CreateWorkspace(workspace, owner))
+		assert.NotZero(t, workspace.ID)
+		assert.Equal(t, workspace.ID, owner.WorkspaceID)
+		assert.False(t, workspace.CreatedAt.IsZero())
+
+		stored, err := r.workspaces.GetWorkspaceByID(workspace.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "team", stored.Name)
+		_, err = r.workspaces.GetWorkspaceByID(999)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		require.NoError(t, r.workspaces.CreateMembership(&models.Membership{WorkspaceID: workspace.ID, UserID: bob.ID, Role: models.RoleViewer}))
+		err = r.workspaces.CreateMembership(&models.Membership{WorkspaceID: workspace.ID, UserID: bob.ID, Role: models.RoleEditor})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+		memberships, err := r.workspaces.ListMembershipsByUser(bob.ID)
+		require.NoError(t, err)
+		require.Len(t, memberships, 1)
+		require.NotNil(t, memberships[0].Workspace, "l'adhésion est retournée avec son espace de travail")
+		assert.Equal(t, "team", memberships[0].Workspace.Name)
+
+		members, err := r.workspaces.ListMembers(workspace.ID)
+		require.NoError(t, err)
+		require.Len(t, members, 2)
+		require.NotNil(t, members[1].User, "le membre est retourné avec son utilisateur")
+		assert.Equal(t, "bob@example.com", members[1].User.Email)
+
+		require.NoError(t, r.workspaces.UpdateMembershipRole(workspace.ID, bob.ID, models.RoleOwner))
+		membership, err := r.workspaces.GetMembership(workspace.ID, bob.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleOwner, membership.Role)
+
+		owners, err := r.workspaces.CountMembersByRole(workspace.ID, models.RoleOwner)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), owners)
+
+		require.NoError(t, r.workspaces.DeleteMembership(workspace.ID, alice.ID))
+		_, err = r.workspaces.GetMembership(workspace.ID, alice.ID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, r.workspaces.DeleteMembership(workspace.ID, alice.ID), gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, r.workspaces.UpdateMembershipRole(workspace.ID, alice.ID, models.RoleViewer), gorm.ErrRecordNotFound)
+
+		workspaces, err := r.workspaces.ListWorkspaces()
+		require.NoError(t, err)
+		assert.Len(t, workspaces, 1)
+	})
+}
+
+func TestInvitationRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		alice := createContractUser(t, r, "alice@example.com")
+		bob := createContractUser(t, r, "bob@example.com")
+		workspace := &models.Workspace{Name: "team"}
+		require.NoError(t, r.workspaces.CreateWorkspace(workspace, &models.Membership{UserID: alice.ID, Role: models.RoleOwner}))
+
+		expiresAt := time.Date(2024, 3, 8, 10, 0, 0, 0, time.UTC)
+		invitation := &models.Invitation{WorkspaceID: workspace.ID, Email: "bob@example.com", Role: models.RoleEditor, TokenHash: "bob", ExpiresAt: expiresAt}
+		require.NoError(t, r.invitations.CreateInvitation(invitation))
+		other := &models.Invitation{WorkspaceID: workspace.ID, Email: "carol@example.com", Role: models.RoleViewer, TokenHash: "carol", ExpiresAt: expiresAt}
+		require.NoError(t, r.invitations.CreateInvitation(other))
+		err := r.invitations.CreateInvitation(&models.Invitation{WorkspaceID: workspace.ID, Email: "dave@example.com", Role: models.RoleViewer, TokenHash: "bob", ExpiresAt: expiresAt})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+		stored, err := r.invitations.GetInvitationByTokenHash("bob")
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, stored.ID)
+		assert.True(t, expiresAt.Equal(stored.ExpiresAt))
+		require.NotNil(t, stored.Workspace)
+		assert.Equal(t, "team", stored.Workspace.Name)
+
+		invitations, err := r.invitations.ListInvitations(workspace.ID)
+		require.NoError(t, err)
+		assert.Len(t, invitations, 2)
+
+		require.NoError(t, r.invitations.AcceptInvitation(invitation.ID, &models.Membership{WorkspaceID: workspace.ID, UserID: bob.ID, Role: models.RoleEditor}))
+		_, err = r.invitations.GetInvitationByTokenHash("bob")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "une invitation acceptée est supprimée")
+		membership, err := r.workspaces.GetMembership(workspace.ID, bob.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleEditor, membership.Role)
+		err = r.invitations.AcceptInvitation(invitation.ID, &models.Membership{WorkspaceID: workspace.ID, UserID: alice.ID, Role: models.RoleEditor})
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		// Un membre existant ne peut pas accepter d'invitation, qui reste utilisable
+		err = r.invitations.AcceptInvitation(other.ID, &models.Membership{WorkspaceID: workspace.ID, UserID: bob.ID, Role: models.RoleViewer})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+		_, err = r.invitations.GetInvitationByTokenHash("carol")
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, r.invitations.DeleteInvitation(workspace.ID+1, other.ID), gorm.ErrRecordNotFound)
+		require.NoError(t, r.invitations.DeleteInvitation(workspace.ID, other.ID))
+		invitations, err = r.invitations.ListInvitations(workspace.ID)
+		require.NoError(t, err)
+		assert.Empty(t, invitations)
+	})
+}
+
+func TestUsageRepositoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		alice, marketing := models.UserAccount(1), models.WorkspaceAccount(1)
+
+		_, err := r.usage.GetUsage(alice, "2024-03")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		require.NoError(t, r.usage.AddUsage(alice, "2024-03", 1, 0))
+		require.NoError(t, r.usage.AddUsage(alice, "2024-03", 2, 5))
+		require.NoError(t, r.usage.AddUsage(marketing, "2024-03", 0, 7))
+		require.NoError(t, r.usage.AddUsage(alice, "2024-04", 1, 0))
+
+		counter, err := r.usage.GetUsage(alice, "2024-03")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), counter.LinksCreated)
+		assert.Equal(t, int64(5), counter.TrackedClicks)
+		assert.Equal(t, alice, counter.Account())
+
+		counters, err := r.usage.ListUsage("2024-03")
+		require.NoError(t, err)
+		require.Len(t, counters, 2)
+		assert.Equal(t, alice, counters[0].Account(), "tri par type puis identifiant de compte")
+		assert.Equal(t, int64(7), counters[1].TrackedClicks)
+
+		// Les incréments concurrents ne se perdent pas
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, r.usage.AddUsage(marketing, "2024-03", 1, 1))
+			}()
+		}
+		wg.Wait()
+		counter, err = r.usage.GetUsage(marketing, "2024-03")
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), counter.LinksCreated)
+		assert.Equal(t, int64(17), counter.TrackedClicks)
+	})
+}
+
+func TestUsageRepositoryContract_ReserveLink(t *testing.T) {
+	runContract(t, func(t *testing.T, r contractRepositories) {
+		alice := models.UserAccount(1)
+		require.NoError(t, r.usage.AddUsage(alice, "2024-03", 0, 4))
+
+		// Les réservations concurrentes ne dépassent pas la limite
+		var reserved atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, err := r.usage.ReserveLink(alice, "2024-03", 3)
+				assert.NoError(t, err)
+				if ok {
+					reserved.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(3), reserved.Load())
+
+		counter, err := r.usage.GetUsage(alice, "2024-03")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), counter.LinksCreated)
+		assert.Equal(t, int64(4), counter.TrackedClicks, "les clics ne sont pas modifiés")
+
+		// Le compteur d'une nouvelle période est créé au besoin
+		ok, err := r.usage.ReserveLink(alice, "2024-04", 3)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+type HealthCheckRepository interface {
+	CreateHealthCheck(check *models.HealthCheck) error
+	GetRecentHealthChecks(linkID uint, limit int) ([]models.HealthCheck, error)
+	GetLatestHealthChecks() (map[uint]models.HealthCheck, error)
+	SummarizeHealthChecks(linkID uint, since time.Time) (models.HealthSummary, error)
+	DeleteHealthChecksBefore(before time.Time) (int64, error)
+}
+
+type GormHealthCheckRepository struct {
+	db *gorm.DB
+}
+
+func NewHealthCheckRepository(db *gorm.DB) *GormHealthCheckRepository {
+	return &GormHealthCheckRepository{db: db}
+}
+
+func (r *GormHealthCheckRepository) CreateHealthCheck(check *models.HealthCheck) error {
+	check.CheckedAt = check.CheckedAt.UTC()
+	return r.db.Create(check).Error
+}
+
+// GetRecentHealthChecks retourne les dernières vérifications d'un lien, de la plus récente à la plus ancienne.
+func (r *GormHealthCheckRepository) GetRecentHealthChecks(linkID uint, limit int) ([]models.HealthCheck, error) {
+	var checks []models.HealthCheck
+	err := r.db.Where("link_id = ?", linkID).
+		Order("checked_at DESC").Order("id DESC").
+		Limit(limit).
+		Find(&checks).Error
+	return checks, err
+}
+
+// GetLatestHealthChecks retourne la dernière vérification de chaque lien, indexée par identifiant de lien.
+func (r *GormHealthCheckRepository) GetLatestHealthChecks() (map[uint]models.HealthCheck, error) {
+	var checks []models.HealthCheck
+	latest := r.db.Model(&models.HealthCheck{}).Select("MAX(id)").Group("link_id")
+	if err := r.db.Where("id IN (?)", latest).Find(&checks).Error; err != nil {
+		return nil, err
+	}
+
+	byLink := make(map[uint]models.HealthCheck, len(checks))
+	for _, check := range checks {
+		byLink[check.LinkID] = check
+	}
+	return byLink, nil
+}
+
+func (r *GormHealthCheckRepository) SummarizeHealthChecks(linkID uint, since time.Time) (models.HealthSummary, error) {
+	var summary models.HealthSummary
+	err := r.db.Model(&models.HealthCheck{}).
+		Select("COUNT(*) AS checks, COALESCE(SUM(CASE WHEN accessible THEN 1 ELSE 0 END), 0) AS successful").
+		Where("link_id = ? AND checked_at >= ?", linkID, since.UTC()).
+		Scan(&summary).Error
+	return summary, err
+}
+
+// DeleteHealthChecksBefore supprime l'historique antérieur à before et retourne le nombre de lignes supprimées.
+func (r *GormHealthCheckRepository) DeleteHealthChecksBefore(before time.Time) (int64, error) {
+	result := r.db.Where("checked_at < ?", before.UTC()).Delete(&models.HealthCheck{})
+	return result.RowsAffected, result.Error
+}
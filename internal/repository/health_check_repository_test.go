@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupHealthCheckTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t, &models.Link{}, &models.HealthCheck{})
+}
+
+func TestGormHealthCheckRepository_GetRecentHealthChecks(t *testing.T) {
+	repo := NewHealthCheckRepository(setupHealthCheckTestDB(t))
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		check := &models.HealthCheck{LinkID: 1, CheckedAt: now.Add(time.Duration(i) * time.Minute), Accessible: i%2 == 0, StatusCode: 200}
+		require.NoError(t, repo.CreateHealthCheck(check))
+	}
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 2, CheckedAt: now}))
+
+	checks, err := repo.GetRecentHealthChecks(1, 3)
+
+	assert.NoError(t, err)
+	require.Len(t, checks, 3)
+	assert.True(t, checks[0].CheckedAt.After(checks[1].CheckedAt))
+	assert.Equal(t, now.Add(4*time.Minute).UTC().Truncate(time.Second), checks[0].CheckedAt.Truncate(time.Second))
+	assert.Equal(t, uint(1), checks[2].LinkID)
+}
+
+func TestGormHealthCheckRepository_StoresRedirectChain(t *testing.T) {
+	repo := NewHealthCheckRepository(setupHealthCheckTestDB(t))
+
+	chain := models.StringList{"https://example.com/moved", "https://example.com/article"}
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: time.Now(), Accessible: true, RedirectChain: chain}))
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 2, CheckedAt: time.Now(), Accessible: true}))
+
+	checks, err := repo.GetRecentHealthChecks(1, 1)
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+	assert.Equal(t, chain, checks[0].RedirectChain)
+
+	checks, err = repo.GetRecentHealthChecks(2, 1)
+	require.NoError(t, err)
+	assert.Nil(t, checks[0].RedirectChain)
+}
+
+func TestGormHealthCheckRepository_GetLatestHealthChecks(t *testing.T) {
+	repo := NewHealthCheckRepository(setupHealthCheckTestDB(t))
+	now := time.Now()
+
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now.Add(-time.Minute), Accessible: true}))
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now, Accessible: false, ErrorClass: "timeout"}))
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 2, CheckedAt: now, Accessible: true}))
+
+	latest, err := repo.GetLatestHealthChecks()
+
+	assert.NoError(t, err)
+	assert.Len(t, latest, 2)
+	assert.False(t, latest[1].Accessible)
+	assert.Equal(t, "timeout", latest[1].ErrorClass)
+	assert.True(t, latest[2].Accessible)
+}
+
+func TestGormHealthCheckRepository_SummarizeHealthChecks(t *testing.T) {
+	repo := NewHealthCheckRepository(setupHealthCheckTestDB(t))
+	now := time.Now()
+
+	// Une vérification en dehors de la période, trois réussies et une en échec dedans
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now.Add(-48 * time.Hour), Accessible: false}))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now.Add(-time.Hour), Accessible: true}))
+	}
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now, Accessible: false}))
+
+	summary, err := repo.SummarizeHealthChecks(1, now.Add(-24*time.Hour))
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.HealthSummary{Checks: 4, Successful: 3}, summary)
+	assert.Equal(t, 75.0, *summary.UptimePercent())
+
+	empty, err := repo.SummarizeHealthChecks(2, now.Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Nil(t, empty.UptimePercent())
+}
+
+func TestGormHealthCheckRepository_DeleteHealthChecksBefore(t *testing.T) {
+	db := setupHealthCheckTestDB(t)
+	repo := NewHealthCheckRepository(db)
+	now := time.Now()
+
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now.AddDate(0, 0, -40)}))
+	require.NoError(t, repo.CreateHealthCheck(&models.HealthCheck{LinkID: 1, CheckedAt: now}))
+
+	deleted, err := repo.DeleteHealthChecksBefore(now.AddDate(0, 0, -30))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	var remaining int64
+	db.Model(&models.HealthCheck{}).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+}
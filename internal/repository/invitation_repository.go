@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+type InvitationRepository interface {
+	CreateInvitation(invitation *models.Invitation) error
+	GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error)
+	ListInvitations(workspaceID uint) ([]models.Invitation, error)
+	DeleteInvitation(workspaceID, invitationID uint) error
+	AcceptInvitation(invitationID uint, membership *models.Membership) error
+}
+
+type GormInvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *GormInvitationRepository {
+	return &GormInvitationRepository{db: db}
+}
+
+func (r *GormInvitationRepository) CreateInvitation(invitation *models.Invitation) error {
+	return r.db.Omit("Workspace").Create(invitation).Error
+}
+
+// GetInvitationByTokenHash retourne l'invitation et son espace de travail, même expirée.
+func (r *GormInvitationRepository) GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Preload("Workspace").Where("token_hash = ?", tokenHash).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListInvitations retourne les invitations en attente de l'espace de travail, expirées comprises.
+func (r *GormInvitationRepository) ListInvitations(workspaceID uint) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.Where("workspace_id = ?", workspaceID).Order("id").Find(&invitations).Error
+	return invitations, err
+}
+
+// DeleteInvitation retourne gorm.ErrRecordNotFound si l'invitation n'existe pas dans cet espace de travail.
+func (r *GormInvitationRepository) DeleteInvitation(workspaceID, invitationID uint) error {
+	result := r.db.Where("workspace_id = ?", workspaceID).Delete(&models.Invitation{}, invitationID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation supprime l'invitation et crée l'adhésion dans la même transaction : une
+// invitation ne peut être acceptée qu'une fois (gorm.ErrRecordNotFound ensuite).
+func (r *GormInvitationRepository) AcceptInvitation(invitationID uint, membership *models.Membership) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Invitation{}, invitationID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Omit("Workspace", "User").Create(membership).Error
+	})
+}
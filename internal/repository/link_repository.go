@@ -1,15 +1,65 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/Edofo/bitly-clone/internal/models"
 	"gorm.io/gorm"
 )
 
+// Colonnes autorisées pour le tri des listes de liens.
+var LinkSortColumns = map[string]string{
+	"created_at": "created_at",
+	"short_code": "short_code",
+	"long_url":   "long_url",
+}
+
+type LinkFilter struct {
+	Page          int
+	PageSize      int
+	SortBy        string
+	SortDesc      bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Domain        string
+	// Scope restreint la liste aux liens accessibles à un utilisateur (nil : tous les liens).
+	Scope *LinkScope
+	// WorkspaceID restreint la liste aux liens de cet espace de travail.
+	WorkspaceID *uint
+}
+
+// LinkScope désigne les liens accessibles à un utilisateur : ceux qu'il a créés hors de
+// tout espace de travail, et ceux des espaces de travail dont il est membre.
+type LinkScope struct {
+	UserID       uint
+	WorkspaceIDs []uint
+}
+
+// Includes indique si le lien fait partie du périmètre.
+func (s *LinkScope) Includes(link *models.Link) bool {
+	if link.WorkspaceID == nil {
+		return link.IsOwnedBy(s.UserID)
+	}
+	for _, workspaceID := range s.WorkspaceIDs {
+		if workspaceID == *link.WorkspaceID {
+			return true
+		}
+	}
+	return false
+}
+
 type LinkRepository interface {
 	CreateLink(link *models.Link) error
 	GetLinkByShortCode(shortCode string) (*models.Link, error)
+	GetLinkByID(linkID uint) (*models.Link, error)
 	GetAllLinks() ([]models.Link, error)
-	CountClicksByLinkID(linkID uint) (int, error)
+	ListLinks(filter LinkFilter) ([]models.Link, int64, error)
+	UpdateLinkURL(linkID uint, longURL string, domain string) error
+	UpdateLinkWebhook(linkID uint, webhookURL string, webhookSecret string) error
+	DeleteLink(linkID uint) error
+	CountClicksByLinkID(linkID uint, filter ClickFilter) (int, error)
+	CountClickTotals(linkID uint, filter ClickFilter) (models.ClickTotals, error)
+	ConsumeClick(linkID uint) (bool, error)
 }
 
 type GormLinkRepository struct {
@@ -33,14 +83,142 @@ func (r *GormLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link,
 	return &link, nil
 }
 
+func (r *GormLinkRepository) GetLinkByID(linkID uint) (*models.Link, error) {
+	var link models.Link
+	err := r.db.First(&link, linkID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
 func (r *GormLinkRepository) GetAllLinks() ([]models.Link, error) {
 	var links []models.Link
 	err := r.db.Find(&links).Error
 	return links, err
 }
 
-func (r *GormLinkRepository) CountClicksByLinkID(linkID uint) (int, error) {
+func (r *GormLinkRepository) ListLinks(filter LinkFilter) ([]models.Link, int64, error) {
+	query := r.db.Model(&models.Link{})
+
+	if filter.Scope != nil {
+		scope := r.db.Where("workspace_id IS NULL AND owner_id = ?", filter.Scope.UserID)
+		if len(filter.Scope.WorkspaceIDs) > 0 {
+			scope = scope.Or("workspace_id IN ?", filter.Scope.WorkspaceIDs)
+		}
+		query = query.Where(scope)
+	}
+	if filter.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *filter.WorkspaceID)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.Domain != "" {
+		// Le domaine demandé inclut ses sous-domaines : "example.com" couvre "www.example.com".
+		query = query.Where("domain = ? OR domain LIKE ?", filter.Domain, "%."+filter.Domain)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := LinkSortColumns[filter.SortBy]
+	if !ok {
+		column = "created_at"
+	}
+	order := column + " ASC"
+	if filter.SortDesc {
+		order = column + " DESC"
+	}
+	query = query.Order(order).Order("id ASC")
+
+	if filter.PageSize > 0 {
+		page := filter.Page
+		if page < 1 {
+			page = 1
+		}
+		query = query.Limit(filter.PageSize).Offset((page - 1) * filter.PageSize)
+	}
+
+	var links []models.Link
+	err := query.Find(&links).Error
+	return links, total, err
+}
+
+// UpdateLinkURL ne modifie que la destination du lien : les autres colonnes, dont
+// consumed_clicks incrémenté par ConsumeClick, ne sont pas réécrites.
+func (r *GormLinkRepository) UpdateLinkURL(linkID uint, longURL string, domain string) error {
+	result := r.db.Model(&models.Link{}).Where("id = ?", linkID).
+		Updates(map[string]interface{}{"long_url": longURL, "domain": domain})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateLinkWebhook ne modifie que le webhook du lien et son secret de signature.
+func (r *GormLinkRepository) UpdateLinkWebhook(linkID uint, webhookURL string, webhookSecret string) error {
+	result := r.db.Model(&models.Link{}).Where("id = ?", linkID).
+		Updates(map[string]interface{}{"webhook_url": webhookURL, "webhook_secret": webhookSecret})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteLink supprime le lien ainsi que l'historique de ses clics, de ses vérifications et de ses notifications.
+func (r *GormLinkRepository) DeleteLink(linkID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_id = ?", linkID).Delete(&models.Click{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id = ?", linkID).Delete(&models.HealthCheck{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id = ?", linkID).Delete(&models.WebhookDeadLetter{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Link{}, linkID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *GormLinkRepository) CountClicksByLinkID(linkID uint, filter ClickFilter) (int, error) {
 	var count int64
-	err := r.db.Model(&models.Click{}).Where("link_id = ?", linkID).Count(&count).Error
+	err := filter.apply(r.db.Model(&models.Click{}).Where("link_id = ?", linkID)).Count(&count).Error
 	return int(count), err
 }
+
+func (r *GormLinkRepository) CountClickTotals(linkID uint, filter ClickFilter) (models.ClickTotals, error) {
+	return countTotals(r.db, linkID, filter)
+}
+
+// ConsumeClick décrémente atomiquement le budget de clics du lien.
+// Retourne false si le budget est déjà épuisé : la condition et l'incrément
+// sont évalués dans la même requête UPDATE, ce qui reste correct sous charge concurrente.
+func (r *GormLinkRepository) ConsumeClick(linkID uint) (bool, error) {
+	result := r.db.Model(&models.Link{}).
+		Where("id = ? AND (max_clicks = 0 OR consumed_clicks < max_clicks)", linkID).
+		UpdateColumn("consumed_clicks", gorm.Expr("consumed_clicks + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
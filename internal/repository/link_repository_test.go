@@ -1,23 +1,18 @@
 package repository
 
 import (
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Edofo/bitly-clone/internal/models"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func setupTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	assert.NoError(t, err)
-	
-	err = db.AutoMigrate(&models.Link{}, &models.Click{})
-	assert.NoError(t, err)
-	
-	return db
+	return openTestDB(t, &models.Link{}, &models.Click{}, &models.HealthCheck{}, &models.WebhookDeadLetter{})
 }
 
 func TestNewLinkRepository(t *testing.T) {
@@ -135,7 +130,7 @@ func TestGormLinkRepository_CountClicksByLinkID(t *testing.T) {
 		assert.NoError(t, err)
 	}
 	
-	count, err := repo.CountClicksByLinkID(link.ID)
+	count, err := repo.CountClicksByLinkID(link.ID, ClickFilter{})
 	
 	assert.NoError(t, err)
 	assert.Equal(t, 3, count)
@@ -153,8 +148,217 @@ func TestGormLinkRepository_CountClicksByLinkID_NoClicks(t *testing.T) {
 	err := repo.CreateLink(link)
 	assert.NoError(t, err)
 	
-	count, err := repo.CountClicksByLinkID(link.ID)
+	count, err := repo.CountClicksByLinkID(link.ID, ClickFilter{})
 	
 	assert.NoError(t, err)
 	assert.Equal(t, 0, count)
-} 
\ No newline at end of file
+} 
+
+func TestGormLinkRepository_CountClickTotals(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLinkRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", CreatedAt: time.Now()}
+	assert.NoError(t, repo.CreateLink(link))
+
+	march1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	march2 := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	clicks := []models.Click{
+		{LinkID: link.ID, Timestamp: march1, VisitorHash: "alice-1"},
+		{LinkID: link.ID, Timestamp: march1, VisitorHash: "alice-1"},
+		{LinkID: link.ID, Timestamp: march1, VisitorHash: "bob-1"},
+		{LinkID: link.ID, Timestamp: march1}, // clic enregistré avant le comptage des visiteurs
+		{LinkID: link.ID, Timestamp: march2, VisitorHash: "alice-2"},
+		{LinkID: link.ID, Timestamp: march2, VisitorHash: "crawler-2", IsBot: true},
+	}
+	for i := range clicks {
+		assert.NoError(t, db.Create(&clicks[i]).Error)
+	}
+
+	totals, err := repo.CountClickTotals(link.ID, ClickFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ClickTotals{TotalClicks: 5, UniqueVisitors: 3}, totals)
+
+	totals, err = repo.CountClickTotals(link.ID, ClickFilter{IncludeBots: true})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ClickTotals{TotalClicks: 6, UniqueVisitors: 4}, totals)
+
+	totals, err = repo.CountClickTotals(link.ID, ClickFilter{From: march2})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ClickTotals{TotalClicks: 1, UniqueVisitors: 1}, totals)
+
+	totals, err = repo.CountClickTotals(link.ID, ClickFilter{To: march2})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ClickTotals{TotalClicks: 4, UniqueVisitors: 2}, totals)
+}
+
+func TestGormLinkRepository_ConsumeClick(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLinkRepository(db)
+
+	link := &models.Link{
+		ShortCode: "abc123",
+		LongURL:   "https://www.example.com",
+		MaxClicks: 2,
+		CreatedAt: time.Now(),
+	}
+	err := repo.CreateLink(link)
+	assert.NoError(t, err)
+
+	ok, err := repo.ConsumeClick(link.ID)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.ConsumeClick(link.ID)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.ConsumeClick(link.ID)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	var savedLink models.Link
+	err = db.First(&savedLink, link.ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, 2, savedLink.ConsumedClicks)
+}
+
+func TestGormLinkRepository_ConsumeClick_Concurrent(t *testing.T) {
+	db := openConcurrentTestDB(t, &models.Link{}, &models.Click{}, &models.HealthCheck{}, &models.WebhookDeadLetter{})
+	repo := NewLinkRepository(db)
+
+	link := &models.Link{
+		ShortCode: "abc123",
+		LongURL:   "https://www.example.com",
+		MaxClicks: 10,
+		CreatedAt: time.Now(),
+	}
+	assert.NoError(t, repo.CreateLink(link))
+
+	var wg sync.WaitGroup
+	var granted atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.ConsumeClick(link.ID)
+			assert.NoError(t, err)
+			if ok {
+				granted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(10), granted.Load())
+}
+
+func createListTestLinks(t *testing.T, repo *GormLinkRepository) time.Time {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	links := []*models.Link{
+		{ShortCode: "aaa111", LongURL: "https://www.example.com/a", Domain: "www.example.com", CreatedAt: base},
+		{ShortCode: "bbb222", LongURL: "https://example.com/b", Domain: "example.com", CreatedAt: base.Add(24 * time.Hour)},
+		{ShortCode: "ccc333", LongURL: "https://golang.org/doc", Domain: "golang.org", CreatedAt: base.Add(48 * time.Hour)},
+		{ShortCode: "ddd444", LongURL: "https://notexample.com", Domain: "notexample.com", CreatedAt: base.Add(72 * time.Hour)},
+	}
+	for _, link := range links {
+		assert.NoError(t, repo.CreateLink(link))
+	}
+	return base
+}
+
+func TestGormLinkRepository_ListLinks_Pagination(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLinkRepository(db)
+	createListTestLinks(t, repo)
+
+	links, total, err := repo.ListLinks(LinkFilter{Page: 1, PageSize: 3, SortBy: "created_at"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Len(t, links, 3)
+	assert.Equal(t, "aaa111", links[0].ShortCode)
+
+	links, total, err = repo.ListLinks(LinkFilter{Page: 2, PageSize: 3, SortBy: "created_at"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Len(t, links, 1)
+	assert.Equal(t, "ddd444", links[0].ShortCode)
+}
+
+func TestGormLinkRepository_ListLinks_SortDesc(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLinkRepository(db)
+	createListTestLinks(t, repo)
+
+	links, _, err := repo.ListLinks(LinkFilter{SortBy: "short_code", SortDesc: true})
+	assert.NoError(t, err)
+	assert.Len(t, links, 4)
+	assert.Equal(t, "ddd444", links[0].ShortCode)
+	assert.Equal(t, "aaa111", links[3].ShortCode)
+}
+
+func TestGormLinkRepository_ListLinks_Filters(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLinkRepository(db)
+	base := createListTestLinks(t, repo)
+
+	links, total, err := repo.ListLinks(LinkFilter{Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, links, 2)
+
+	after := base.Add(24 * time.Hour)
+	before := base.Add(72 * time.Hour)
+	links, total, err = repo.ListLinks(LinkFilter{CreatedAfter: &after, CreatedBefore: &before})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, "bbb222", links[0].ShortCode)
+	assert.Equal(t, "ccc333", links[1].ShortCode)
+}
+
+func TestGormLinkRepository_UpdateLinkWebhook(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLinkRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", MaxClicks: 5, CreatedAt: time.Now()}
+	assert.NoError(t, repo.CreateLink(link))
+	_, err := repo.ConsumeClick(link.ID)
+	assert.NoError(t, err)
+
+	// link est resté à 0 clic consommé : seules les colonnes du webhook sont écrites
+	err = repo.UpdateLinkWebhook(link.ID, "https://hooks.example.org/links", "s3cret")
+	assert.NoError(t, err)
+
+	updated, err := repo.GetLinkByShortCode("abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.org/links", updated.WebhookURL)
+	assert.Equal(t, "s3cret", updated.WebhookSecret)
+	assert.Equal(t, 1, updated.ConsumedClicks)
+}
+
+func TestGormLinkRepository_DeleteLink(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLinkRepository(db)
+
+	link := &models.Link{ShortCode: "abc123", LongURL: "https://www.example.com", CreatedAt: time.Now()}
+	assert.NoError(t, repo.CreateLink(link))
+	assert.NoError(t, db.Create(&models.Click{LinkID: link.ID, Timestamp: time.Now()}).Error)
+
+	err := repo.DeleteLink(link.ID)
+	assert.NoError(t, err)
+
+	_, err = repo.GetLinkByShortCode("abc123")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	count, err := repo.CountClicksByLinkID(link.ID, ClickFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestGormLinkRepository_DeleteLink_NotFound(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLinkRepository(db)
+
+	err := repo.DeleteLink(42)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+}
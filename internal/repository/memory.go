@@ -0,0 +1,1253 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+// MemoryStore conserve les données en mémoire, pour les tests et le mode éphémère
+// (run-server --storage=memory). Comme une base de données, il est partagé par les dépôts
+// qui l'utilisent : supprimer un lien supprime aussi ses clics et ses vérifications.
+// Les dépôts mémoire ont la même sémantique que les dépôts GORM, erreurs comprises
+// (gorm.ErrRecordNotFound, gorm.ErrDuplicatedKey), et retournent toujours des copies.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	links      map[uint]models.Link
+	shortCodes map[string]uint
+	nextLinkID uint
+
+	clicks      []models.Click
+	nextClickID uint
+
+	healthChecks      []models.HealthCheck
+	nextHealthCheckID uint
+
+	salts map[string]models.VisitorSalt
+
+	deadLetters      []models.WebhookDeadLetter
+	nextDeadLetterID uint
+
+	apiKeys      []models.APIKey
+	nextAPIKeyID uint
+
+	users      []models.User
+	nextUserID uint
+
+	sessions      []models.Session
+	nextSessionID uint
+
+	workspaces      []models.Workspace
+	nextWorkspaceID uint
+
+	memberships      []models.Membership
+	nextMembershipID uint
+
+	invitations      []models.Invitation
+	nextInvitationID uint
+
+	usage              map[usageKey]models.UsageCounter
+	nextUsageCounterID uint
+}
+
+type usageKey struct {
+	account models.Account
+	period  string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		links:              make(map[uint]models.Link),
+		shortCodes:         make(map[string]uint),
+		nextLinkID:         1,
+		nextClickID:        1,
+		nextHealthCheckID:  1,
+		salts:              make(map[string]models.VisitorSalt),
+		nextDeadLetterID:   1,
+		nextAPIKeyID:       1,
+		nextUserID:         1,
+		nextSessionID:      1,
+		nextWorkspaceID:    1,
+		nextMembershipID:   1,
+		nextInvitationID:   1,
+		usage:              make(map[usageKey]models.UsageCounter),
+		nextUsageCounterID: 1,
+	}
+}
+
+// limitLen applique une limite à la manière de SQL LIMIT : négative, elle est ignorée.
+func limitLen(length int, limit int) int {
+	if limit >= 0 && limit < length {
+		return limit
+	}
+	return length
+}
+
+type MemoryLinkRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryLinkRepository(store *MemoryStore) *MemoryLinkRepository {
+	return &MemoryLinkRepository{store: store}
+}
+
+func (r *MemoryLinkRepository) CreateLink(link *models.Link) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.links[link.ID]; exists && link.ID != 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	if _, taken := s.shortCodes[link.ShortCode]; taken {
+		return gorm.ErrDuplicatedKey
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+	s.saveLink(link)
+	return nil
+}
+
+// saveLink enregistre le lien en lui attribuant un identifiant s'il n'en a pas encore.
+func (s *MemoryStore) saveLink(link *models.Link) {
+	if link.ID == 0 {
+		link.ID = s.nextLinkID
+	}
+	if link.ID >= s.nextLinkID {
+		s.nextLinkID = link.ID + 1
+	}
+	if previous, exists := s.links[link.ID]; exists {
+		delete(s.shortCodes, previous.ShortCode)
+	}
+	s.links[link.ID] = copyLink(*link)
+	s.shortCodes[link.ShortCode] = link.ID
+}
+
+func (r *MemoryLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.shortCodes[shortCode]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	link := copyLink(s.links[id])
+	return &link, nil
+}
+
+func (r *MemoryLinkRepository) GetLinkByID(linkID uint) (*models.Link, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, ok := s.links[linkID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	link = copyLink(link)
+	return &link, nil
+}
+
+func (r *MemoryLinkRepository) GetAllLinks() ([]models.Link, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := make([]models.Link, 0, len(s.links))
+	for _, link := range s.links {
+		links = append(links, copyLink(link))
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links, nil
+}
+
+func (r *MemoryLinkRepository) ListLinks(filter LinkFilter) ([]models.Link, int64, error) {
+	all, _ := r.GetAllLinks()
+
+	links := all[:0]
+	for _, link := range all {
+		if filter.Scope != nil && !filter.Scope.Includes(&link) {
+			continue
+		}
+		if filter.WorkspaceID != nil && (link.WorkspaceID == nil || *link.WorkspaceID != *filter.WorkspaceID) {
+			continue
+		}
+		if filter.CreatedAfter != nil && link.CreatedAt.Before(*filter.CreatedAfter) {
+			continue
+		}
+		if filter.CreatedBefore != nil && !link.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+		if filter.Domain != "" && link.Domain != filter.Domain && !strings.HasSuffix(link.Domain, "."+filter.Domain) {
+			continue
+		}
+		links = append(links, link)
+	}
+	total := int64(len(links))
+
+	less := linkLess(filter.SortBy)
+	sort.SliceStable(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if filter.SortDesc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return links[i].ID < links[j].ID
+	})
+
+	if filter.PageSize > 0 {
+		page := filter.Page
+		if page < 1 {
+			page = 1
+		}
+		start := (page - 1) * filter.PageSize
+		if start > len(links) {
+			start = len(links)
+		}
+		links = links[start:]
+		links = links[:limitLen(len(links), filter.PageSize)]
+	}
+	return links, total, nil
+}
+
+// copyLink duplique la date d'expiration pour que le lien stocké ne partage rien avec l'appelant.
+func copyLink(link models.Link) models.Link {
+	if link.ExpiresAt != nil {
+		expiresAt := *link.ExpiresAt
+		link.ExpiresAt = &expiresAt
+	}
+	if link.OwnerID != nil {
+		ownerID := *link.OwnerID
+		link.OwnerID = &ownerID
+	}
+	if link.WorkspaceID != nil {
+		workspaceID := *link.WorkspaceID
+		link.WorkspaceID = &workspaceID
+	}
+	link.Owner = nil
+	link.Workspace = nil
+	return link
+}
+
+// linkLess compare deux liens selon une clé de LinkSortColumns (created_at par défaut).
+func linkLess(sortBy string) func(a, b models.Link) bool {
+	switch LinkSortColumns[sortBy] {
+	case "short_code":
+		return func(a, b models.Link) bool { return a.ShortCode < b.ShortCode }
+	case "long_url":
+		return func(a, b models.Link) bool { return a.LongURL < b.LongURL }
+	default:
+		return func(a, b models.Link) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	}
+}
+
+func (r *MemoryLinkRepository) UpdateLinkURL(linkID uint, longURL string, domain string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[linkID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	link.LongURL = longURL
+	link.Domain = domain
+	s.links[linkID] = link
+	return nil
+}
+
+func (r *MemoryLinkRepository) UpdateLinkWebhook(linkID uint, webhookURL string, webhookSecret string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[linkID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	link.WebhookURL = webhookURL
+	link.WebhookSecret = webhookSecret
+	s.links[linkID] = link
+	return nil
+}
+
+// DeleteLink supprime le lien ainsi que l'historique de ses clics, de ses vérifications et de ses notifications.
+func (r *MemoryLinkRepository) DeleteLink(linkID uint) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[linkID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(s.links, linkID)
+	delete(s.shortCodes, link.ShortCode)
+
+	s.clicks = removeByLink(s.clicks, linkID, func(c models.Click) uint { return c.LinkID })
+	s.healthChecks = removeByLink(s.healthChecks, linkID, func(c models.HealthCheck) uint { return c.LinkID })
+	s.deadLetters = removeByLink(s.deadLetters, linkID, func(d models.WebhookDeadLetter) uint { return d.LinkID })
+	return nil
+}
+
+func removeByLink[T any](items []T, linkID uint, linkOf func(T) uint) []T {
+	kept := items[:0]
+	for _, item := range items {
+		if linkOf(item) != linkID {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (r *MemoryLinkRepository) CountClicksByLinkID(linkID uint, filter ClickFilter) (int, error) {
+	return r.store.countClicks(linkID, filter), nil
+}
+
+func (r *MemoryLinkRepository) CountClickTotals(linkID uint, filter ClickFilter) (models.ClickTotals, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var totals models.ClickTotals
+	visitors := make(map[string]struct{})
+	for _, click := range s.clicks {
+		if click.LinkID != linkID || !filter.matches(click) {
+			continue
+		}
+		totals.TotalClicks++
+		if click.VisitorHash != "" {
+			visitors[click.VisitorHash] = struct{}{}
+		}
+	}
+	totals.UniqueVisitors = len(visitors)
+	return totals, nil
+}
+
+// ConsumeClick décrémente le budget de clics du lien sous le verrou du stockage.
+func (r *MemoryLinkRepository) ConsumeClick(linkID uint) (bool, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[linkID]
+	if !ok || (link.MaxClicks != 0 && link.ConsumedClicks >= link.MaxClicks) {
+		return false, nil
+	}
+	link.ConsumedClicks++
+	s.links[linkID] = link
+	return true, nil
+}
+
+// matches indique si un clic passe le filtre, comme ClickFilter.apply pour une requête.
+func (f ClickFilter) matches(click models.Click) bool {
+	if !f.IncludeBots && click.IsBot {
+		return false
+	}
+	if !f.From.IsZero() && click.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !click.Timestamp.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+func (s *MemoryStore) countClicks(linkID uint, filter ClickFilter) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, click := range s.clicks {
+		if click.LinkID == linkID && filter.matches(click) {
+			count++
+		}
+	}
+	return count
+}
+
+type MemoryClickRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryClickRepository(store *MemoryStore) *MemoryClickRepository {
+	return &MemoryClickRepository{store: store}
+}
+
+func (r *MemoryClickRepository) CreateClick(click *models.Click) error {
+	return r.CreateClicks([]*models.Click{click})
+}
+
+func (r *MemoryClickRepository) CreateClicks(clicks []*models.Click) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, click := range clicks {
+		click.ID = s.nextClickID
+		s.nextClickID++
+		stored := *click
+		stored.Link = models.Link{}
+		s.clicks = append(s.clicks, stored)
+	}
+	return nil
+}
+
+func (r *MemoryClickRepository) CountClicksByLinkID(linkID uint, filter ClickFilter) (int, error) {
+	return r.store.countClicks(linkID, filter), nil
+}
+
+func (r *MemoryClickRepository) CountClicksByInterval(linkID uint, interval TimeInterval, from, to time.Time, loc *time.Location, filter ClickFilter) ([]models.ClickBucket, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	buckets := newClickBuckets(interval, from, to, loc)
+	for _, click := range s.clicks {
+		if click.LinkID != linkID || click.Timestamp.Before(from) || !click.Timestamp.Before(to) || !filter.matches(click) {
+			continue
+		}
+		buckets.add(click.Timestamp, click.VisitorHash)
+	}
+	return buckets.result(), nil
+}
+
+func (r *MemoryClickRepository) CountClicksByDimension(linkID uint, dimension ClickDimension, limit int, filter ClickFilter) ([]models.ClickStat, error) {
+	if !dimension.IsValid() {
+		return nil, fmt.Errorf("unsupported click dimension '%s'", dimension)
+	}
+
+	s := r.store
+	s.mu.RLock()
+	counts := make(map[string]int)
+	for _, click := range s.clicks {
+		if click.LinkID == linkID && filter.matches(click) {
+			counts[dimensionValue(click, dimension)]++
+		}
+	}
+	s.mu.RUnlock()
+
+	stats := make([]models.ClickStat, 0, len(counts))
+	for value, count := range counts {
+		stats = append(stats, models.ClickStat{Value: value, Count: count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Value < stats[j].Value
+	})
+	return stats[:limitLen(len(stats), limit)], nil
+}
+
+// dimensionValue est l'équivalent en Go des expressions de clickDimensionColumns.
+func dimensionValue(click models.Click, dimension ClickDimension) string {
+	switch dimension {
+	case DimensionReferrer:
+		return click.ReferrerDomain
+	case DimensionBrowser:
+		return click.Browser
+	case DimensionOS:
+		return click.OS
+	case DimensionDevice:
+		return click.DeviceClass
+	case DimensionCountry:
+		return click.Country
+	case DimensionCity:
+		if click.City == "" {
+			return ""
+		}
+		return click.City + ", " + click.Country
+	default:
+		return ""
+	}
+}
+
+type MemoryHealthCheckRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryHealthCheckRepository(store *MemoryStore) *MemoryHealthCheckRepository {
+	return &MemoryHealthCheckRepository{store: store}
+}
+
+func (r *MemoryHealthCheckRepository) CreateHealthCheck(check *models.HealthCheck) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	check.CheckedAt = check.CheckedAt.UTC()
+	check.ID = s.nextHealthCheckID
+	s.nextHealthCheckID++
+	stored := *check
+	stored.RedirectChain = append(models.StringList(nil), check.RedirectChain...)
+	s.healthChecks = append(s.healthChecks, stored)
+	return nil
+}
+
+// GetRecentHealthChecks retourne les dernières vérifications d'un lien, de la plus récente à la plus ancienne.
+func (r *MemoryHealthCheckRepository) GetRecentHealthChecks(linkID uint, limit int) ([]models.HealthCheck, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checks := []models.HealthCheck{}
+	for _, check := range s.healthChecks {
+		if check.LinkID == linkID {
+			checks = append(checks, copyHealthCheck(check))
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		if !checks[i].CheckedAt.Equal(checks[j].CheckedAt) {
+			return checks[i].CheckedAt.After(checks[j].CheckedAt)
+		}
+		return checks[i].ID > checks[j].ID
+	})
+	return checks[:limitLen(len(checks), limit)], nil
+}
+
+// GetLatestHealthChecks retourne la dernière vérification de chaque lien, indexée par identifiant de lien.
+func (r *MemoryHealthCheckRepository) GetLatestHealthChecks() (map[uint]models.HealthCheck, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byLink := make(map[uint]models.HealthCheck)
+	for _, check := range s.healthChecks {
+		if latest, ok := byLink[check.LinkID]; !ok || check.ID > latest.ID {
+			byLink[check.LinkID] = copyHealthCheck(check)
+		}
+	}
+	return byLink, nil
+}
+
+func (r *MemoryHealthCheckRepository) SummarizeHealthChecks(linkID uint, since time.Time) (models.HealthSummary, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var summary models.HealthSummary
+	for _, check := range s.healthChecks {
+		if check.LinkID != linkID || check.CheckedAt.Before(since) {
+			continue
+		}
+		summary.Checks++
+		if check.Accessible {
+			summary.Successful++
+		}
+	}
+	return summary, nil
+}
+
+// DeleteHealthChecksBefore supprime l'historique antérieur à before et retourne le nombre de lignes supprimées.
+func (r *MemoryHealthCheckRepository) DeleteHealthChecksBefore(before time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.healthChecks[:0]
+	for _, check := range s.healthChecks {
+		if !check.CheckedAt.Before(before) {
+			kept = append(kept, check)
+		}
+	}
+	deleted := int64(len(s.healthChecks) - len(kept))
+	s.healthChecks = kept
+	return deleted, nil
+}
+
+func copyHealthCheck(check models.HealthCheck) models.HealthCheck {
+	check.RedirectChain = append(models.StringList(nil), check.RedirectChain...)
+	return check
+}
+
+type MemoryVisitorSaltRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryVisitorSaltRepository(store *MemoryStore) *MemoryVisitorSaltRepository {
+	return &MemoryVisitorSaltRepository{store: store}
+}
+
+// GetOrCreateSalt enregistre candidate comme sel du jour s'il n'en existe pas encore,
+// puis retourne le sel effectivement stocké.
+func (r *MemoryVisitorSaltRepository) GetOrCreateSalt(day string, candidate []byte) ([]byte, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	salt, ok := s.salts[day]
+	if !ok {
+		salt = models.VisitorSalt{Day: day, Salt: append([]byte(nil), candidate...), CreatedAt: time.Now()}
+		s.salts[day] = salt
+	}
+	return append([]byte(nil), salt.Salt...), nil
+}
+
+// DeleteSaltsBefore supprime les sels des jours antérieurs à day (format AAAA-MM-JJ).
+func (r *MemoryVisitorSaltRepository) DeleteSaltsBefore(day string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for d := range s.salts {
+		if d < day {
+			delete(s.salts, d)
+		}
+	}
+	return nil
+}
+
+type MemoryWebhookDeadLetterRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryWebhookDeadLetterRepository(store *MemoryStore) *MemoryWebhookDeadLetterRepository {
+	return &MemoryWebhookDeadLetterRepository{store: store}
+}
+
+func (r *MemoryWebhookDeadLetterRepository) CreateDeadLetter(deadLetter *models.WebhookDeadLetter) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadLetter.ID = s.nextDeadLetterID
+	s.nextDeadLetterID++
+	if deadLetter.CreatedAt.IsZero() {
+		deadLetter.CreatedAt = time.Now()
+	}
+	s.deadLetters = append(s.deadLetters, *deadLetter)
+	return nil
+}
+
+// ListDeadLetters retourne les notifications abandonnées, de la plus récente à la plus ancienne.
+func (r *MemoryWebhookDeadLetterRepository) ListDeadLetters(limit int) ([]models.WebhookDeadLetter, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deadLetters := append([]models.WebhookDeadLetter{}, s.deadLetters...)
+	sort.Slice(deadLetters, func(i, j int) bool {
+		if !deadLetters[i].CreatedAt.Equal(deadLetters[j].CreatedAt) {
+			return deadLetters[i].CreatedAt.After(deadLetters[j].CreatedAt)
+		}
+		return deadLetters[i].ID > deadLetters[j].ID
+	})
+	return deadLetters[:limitLen(len(deadLetters), limit)], nil
+}
+
+type MemoryAPIKeyRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAPIKeyRepository(store *MemoryStore) *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{store: store}
+}
+
+func (r *MemoryAPIKeyRepository) CreateAPIKey(key *models.APIKey) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.apiKeys {
+		if existing.Prefix == key.Prefix {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	key.ID = s.nextAPIKeyID
+	s.nextAPIKeyID++
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	s.apiKeys = append(s.apiKeys, *key)
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.apiKeys {
+		if key.Prefix == prefix {
+			key = copyAPIKey(key)
+			return &key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryAPIKeyRepository) ListAPIKeys() ([]models.APIKey, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]models.APIKey, len(s.apiKeys))
+	for i, key := range s.apiKeys {
+		keys[i] = copyAPIKey(key)
+	}
+	return keys, nil
+}
+
+func copyAPIKey(key models.APIKey) models.APIKey {
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		key.RevokedAt = &revokedAt
+	}
+	if key.LastUsedAt != nil {
+		lastUsedAt := *key.LastUsedAt
+		key.LastUsedAt = &lastUsedAt
+	}
+	return key
+}
+
+func (r *MemoryAPIKeyRepository) RevokeAPIKey(keyID uint, revokedAt time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].ID == keyID && s.apiKeys[i].RevokedAt == nil {
+			s.apiKeys[i].RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *MemoryAPIKeyRepository) TouchAPIKey(keyID uint, usedAt time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].ID == keyID {
+			s.apiKeys[i].LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+type MemoryUserRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserRepository(store *MemoryStore) *MemoryUserRepository {
+	return &MemoryUserRepository{store: store}
+}
+
+func (r *MemoryUserRepository) CreateUser(user *models.User) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	user.ID = s.nextUserID
+	s.nextUserID++
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	s.users = append(s.users, *user)
+	return nil
+}
+
+func (r *MemoryUserRepository) GetUserByID(userID uint) (*models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if user, ok := s.findUser(userID); ok {
+		return &user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryUserRepository) ListUsers() ([]models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.User(nil), s.users...), nil
+}
+
+// findUser doit être appelée avec le verrou du store.
+func (s *MemoryStore) findUser(userID uint) (models.User, bool) {
+	for _, user := range s.users {
+		if user.ID == userID {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+type MemorySessionRepository struct {
+	store *MemoryStore
+}
+
+func NewMemorySessionRepository(store *MemoryStore) *MemorySessionRepository {
+	return &MemorySessionRepository{store: store}
+}
+
+func (r *MemorySessionRepository) CreateSession(session *models.Session) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.findUser(session.UserID); !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	for _, existing := range s.sessions {
+		if existing.TokenHash == session.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	session.ID = s.nextSessionID
+	s.nextSessionID++
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	stored := *session
+	stored.User = models.User{}
+	s.sessions = append(s.sessions, stored)
+	return nil
+}
+
+func (r *MemorySessionRepository) GetSessionByTokenHash(tokenHash string) (*models.Session, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		if session.TokenHash == tokenHash {
+			session.User, _ = s.findUser(session.UserID)
+			return &session, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemorySessionRepository) DeleteSession(sessionID uint) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.sessions[:0]
+	for _, session := range s.sessions {
+		if session.ID != sessionID {
+			kept = append(kept, session)
+		}
+	}
+	s.sessions = kept
+	return nil
+}
+
+func (r *MemorySessionRepository) DeleteExpiredSessions(now time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.sessions[:0]
+	for _, session := range s.sessions {
+		if !session.IsExpired(now) {
+			kept = append(kept, session)
+		}
+	}
+	deleted := int64(len(s.sessions) - len(kept))
+	s.sessions = kept
+	return deleted, nil
+}
+
+type MemoryWorkspaceRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryWorkspaceRepository(store *MemoryStore) *MemoryWorkspaceRepository {
+	return &MemoryWorkspaceRepository{store: store}
+}
+
+func (r *MemoryWorkspaceRepository) CreateWorkspace(workspace *models.Workspace, owner *models.Membership) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.findUser(owner.UserID); !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	workspace.ID = s.nextWorkspaceID
+	s.nextWorkspaceID++
+	if workspace.CreatedAt.IsZero() {
+		workspace.CreatedAt = time.Now()
+	}
+	s.workspaces = append(s.workspaces, *workspace)
+
+	owner.WorkspaceID = workspace.ID
+	return s.createMembership(owner)
+}
+
+func (r *MemoryWorkspaceRepository) GetWorkspaceByID(workspaceID uint) (*models.Workspace, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if workspace, ok := s.findWorkspace(workspaceID); ok {
+		return &workspace, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryWorkspaceRepository) ListWorkspaces() ([]models.Workspace, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.Workspace(nil), s.workspaces...), nil
+}
+
+func (r *MemoryWorkspaceRepository) ListMembershipsByUser(userID uint) ([]models.Membership, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var memberships []models.Membership
+	for _, membership := range s.memberships {
+		if membership.UserID == userID {
+			workspace, _ := s.findWorkspace(membership.WorkspaceID)
+			membership.Workspace = &workspace
+			memberships = append(memberships, membership)
+		}
+	}
+	sort.SliceStable(memberships, func(i, j int) bool {
+		return memberships[i].WorkspaceID < memberships[j].WorkspaceID
+	})
+	return memberships, nil
+}
+
+func (r *MemoryWorkspaceRepository) ListMembers(workspaceID uint) ([]models.Membership, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var memberships []models.Membership
+	for _, membership := range s.memberships {
+		if membership.WorkspaceID == workspaceID {
+			user, _ := s.findUser(membership.UserID)
+			membership.User = &user
+			memberships = append(memberships, membership)
+		}
+	}
+	return memberships, nil
+}
+
+func (r *MemoryWorkspaceRepository) GetMembership(workspaceID, userID uint) (*models.Membership, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.findMembership(workspaceID, userID); i >= 0 {
+		membership := s.memberships[i]
+		return &membership, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryWorkspaceRepository) CreateMembership(membership *models.Membership) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createMembership(membership)
+}
+
+func (r *MemoryWorkspaceRepository) UpdateMembershipRole(workspaceID, userID uint, role models.Role) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findMembership(workspaceID, userID)
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	s.memberships[i].Role = role
+	return nil
+}
+
+func (r *MemoryWorkspaceRepository) DeleteMembership(workspaceID, userID uint) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findMembership(workspaceID, userID)
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	s.memberships = append(s.memberships[:i], s.memberships[i+1:]...)
+	return nil
+}
+
+func (r *MemoryWorkspaceRepository) CountMembersByRole(workspaceID uint, role models.Role) (int64, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, membership := range s.memberships {
+		if membership.WorkspaceID == workspaceID && membership.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// findWorkspace doit être appelée avec le verrou du store.
+func (s *MemoryStore) findWorkspace(workspaceID uint) (models.Workspace, bool) {
+	for _, workspace := range s.workspaces {
+		if workspace.ID == workspaceID {
+			return workspace, true
+		}
+	}
+	return models.Workspace{}, false
+}
+
+// findMembership retourne l'indice de l'adhésion dans s.memberships, ou -1. Elle doit être
+// appelée avec le verrou du store.
+func (s *MemoryStore) findMembership(workspaceID, userID uint) int {
+	for i, membership := range s.memberships {
+		if membership.WorkspaceID == workspaceID && membership.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// createMembership doit être appelée avec le verrou du store en écriture.
+func (s *MemoryStore) createMembership(membership *models.Membership) error {
+	if _, ok := s.findWorkspace(membership.WorkspaceID); !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	if _, ok := s.findUser(membership.UserID); !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	if s.findMembership(membership.WorkspaceID, membership.UserID) >= 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	membership.ID = s.nextMembershipID
+	s.nextMembershipID++
+	if membership.CreatedAt.IsZero() {
+		membership.CreatedAt = time.Now()
+	}
+	stored := *membership
+	stored.Workspace = nil
+	stored.User = nil
+	s.memberships = append(s.memberships, stored)
+	return nil
+}
+
+type MemoryInvitationRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryInvitationRepository(store *MemoryStore) *MemoryInvitationRepository {
+	return &MemoryInvitationRepository{store: store}
+}
+
+func (r *MemoryInvitationRepository) CreateInvitation(invitation *models.Invitation) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.findWorkspace(invitation.WorkspaceID); !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	for _, existing := range s.invitations {
+		if existing.TokenHash == invitation.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	invitation.ID = s.nextInvitationID
+	s.nextInvitationID++
+	if invitation.CreatedAt.IsZero() {
+		invitation.CreatedAt = time.Now()
+	}
+	stored := *invitation
+	stored.Workspace = nil
+	s.invitations = append(s.invitations, stored)
+	return nil
+}
+
+func (r *MemoryInvitationRepository) GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, invitation := range s.invitations {
+		if invitation.TokenHash == tokenHash {
+			workspace, _ := s.findWorkspace(invitation.WorkspaceID)
+			invitation.Workspace = &workspace
+			return &invitation, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryInvitationRepository) ListInvitations(workspaceID uint) ([]models.Invitation, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var invitations []models.Invitation
+	for _, invitation := range s.invitations {
+		if invitation.WorkspaceID == workspaceID {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (r *MemoryInvitationRepository) DeleteInvitation(workspaceID, invitationID uint) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findInvitation(invitationID)
+	if i < 0 || s.invitations[i].WorkspaceID != workspaceID {
+		return gorm.ErrRecordNotFound
+	}
+	s.invitations = append(s.invitations[:i], s.invitations[i+1:]...)
+	return nil
+}
+
+func (r *MemoryInvitationRepository) AcceptInvitation(invitationID uint, membership *models.Membership) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findInvitation(invitationID)
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if err := s.createMembership(membership); err != nil {
+		return err
+	}
+	s.invitations = append(s.invitations[:i], s.invitations[i+1:]...)
+	return nil
+}
+
+// findInvitation retourne l'indice de l'invitation dans s.invitations, ou -1. Elle doit être
+// appelée avec le verrou du store.
+func (s *MemoryStore) findInvitation(invitationID uint) int {
+	for i, invitation := range s.invitations {
+		if invitation.ID == invitationID {
+			return i
+		}
+	}
+	return -1
+}
+
+type MemoryUsageRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUsageRepository(store *MemoryStore) *MemoryUsageRepository {
+	return &MemoryUsageRepository{store: store}
+}
+
+func (r *MemoryUsageRepository) GetUsage(account models.Account, period string) (*models.UsageCounter, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counter, ok := s.usage[usageKey{account: account, period: period}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &counter, nil
+}
+
+func (r *MemoryUsageRepository) ListUsage(period string) ([]models.UsageCounter, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var counters []models.UsageCounter
+	for key, counter := range s.usage {
+		if key.period == period {
+			counters = append(counters, counter)
+		}
+	}
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].AccountType != counters[j].AccountType {
+			return counters[i].AccountType < counters[j].AccountType
+		}
+		return counters[i].AccountID < counters[j].AccountID
+	})
+	return counters, nil
+}
+
+func (r *MemoryUsageRepository) AddUsage(account models.Account, period string, links, clicks int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := usageKey{account: account, period: period}
+	counter, ok := s.usage[key]
+	if !ok {
+		counter = models.UsageCounter{ID: s.nextUsageCounterID, AccountType: account.Type, AccountID: account.ID, Period: period}
+		s.nextUsageCounterID++
+	}
+	counter.LinksCreated += links
+	counter.TrackedClicks += clicks
+	counter.UpdatedAt = time.Now().UTC()
+	s.usage[key] = counter
+	return nil
+}
+
+func (r *MemoryUsageRepository) ReserveLink(account models.Account, period string, limit int64) (bool, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := usageKey{account: account, period: period}
+	counter, ok := s.usage[key]
+	if !ok {
+		counter = models.UsageCounter{ID: s.nextUsageCounterID, AccountType: account.Type, AccountID: account.ID, Period: period}
+		s.nextUsageCounterID++
+	}
+	if counter.LinksCreated >= limit {
+		s.usage[key] = counter
+		return false, nil
+	}
+	counter.LinksCreated++
+	counter.UpdatedAt = time.Now().UTC()
+	s.usage[key] = counter
+	return true, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	CreateSession(session *models.Session) error
+	GetSessionByTokenHash(tokenHash string) (*models.Session, error)
+	DeleteSession(sessionID uint) error
+	DeleteExpiredSessions(now time.Time) (int64, error)
+}
+
+type GormSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *GormSessionRepository {
+	return &GormSessionRepository{db: db}
+}
+
+func (r *GormSessionRepository) CreateSession(session *models.Session) error {
+	return r.db.Omit("User").Create(session).Error
+}
+
+// GetSessionByTokenHash retourne la session et son utilisateur, même expirée.
+func (r *GormSessionRepository) GetSessionByTokenHash(tokenHash string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Preload("User").Where("token_hash = ?", tokenHash).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *GormSessionRepository) DeleteSession(sessionID uint) error {
+	return r.db.Delete(&models.Session{}, sessionID).Error
+}
+
+// DeleteExpiredSessions supprime les sessions expirées à la date donnée et retourne leur nombre.
+func (r *GormSessionRepository) DeleteExpiredSessions(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/Edofo/bitly-clone/internal/database/dbtest"
+	"gorm.io/gorm"
+)
+
+// openTestDB ouvre une base de test vide et y crée les tables des modèles donnés,
+// sur SQLite ou PostgreSQL selon TEST_DATABASE_DRIVER (voir dbtest).
+func openTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	return dbtest.Open(t, tables...)
+}
+
+func openConcurrentTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	return dbtest.OpenConcurrent(t, tables...)
+}
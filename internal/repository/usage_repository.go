@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UsageRepository interface {
+	GetUsage(account models.Account, period string) (*models.UsageCounter, error)
+	ListUsage(period string) ([]models.UsageCounter, error)
+	AddUsage(account models.Account, period string, links, clicks int64) error
+	ReserveLink(account models.Account, period string, limit int64) (bool, error)
+}
+
+type GormUsageRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository(db *gorm.DB) *GormUsageRepository {
+	return &GormUsageRepository{db: db}
+}
+
+// GetUsage retourne gorm.ErrRecordNotFound si le compte n'a rien consommé sur la période.
+func (r *GormUsageRepository) GetUsage(account models.Account, period string) (*models.UsageCounter, error) {
+	var counter models.UsageCounter
+	err := r.db.Where("account_type = ? AND account_id = ? AND period = ?", account.Type, account.ID, period).
+		First(&counter).Error
+	if err != nil {
+		return nil, err
+	}
+	return &counter, nil
+}
+
+// ListUsage retourne les compteurs de tous les comptes sur la période.
+func (r *GormUsageRepository) ListUsage(period string) ([]models.UsageCounter, error) {
+	var counters []models.UsageCounter
+	err := r.db.Where("period = ?", period).Order("account_type, account_id").Find(&counters).Error
+	return counters, err
+}
+
+// AddUsage ajoute links liens créés et clicks clics enregistrés au compteur de la période,
+// créé au besoin. L'incrément est fait par la base : des appels concurrents ne se perdent pas.
+func (r *GormUsageRepository) AddUsage(account models.Account, period string, links, clicks int64) error {
+	counter := models.UsageCounter{
+		AccountType:   account.Type,
+		AccountID:     account.ID,
+		Period:        period,
+		LinksCreated:  links,
+		TrackedClicks: clicks,
+		UpdatedAt:     time.Now().UTC(),
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "account_type"}, {Name: "account_id"}, {Name: "period"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"links_created":  gorm.Expr("usage_counters.links_created + ?", links),
+			"tracked_clicks": gorm.Expr("usage_counters.tracked_clicks + ?", clicks),
+			"updated_at":     counter.UpdatedAt,
+		}),
+	}).Create(&counter).Error
+}
+
+// ReserveLink décompte un lien créé si le compte en a créé moins de limit sur la période,
+// et retourne false sinon. La condition et l'incrément sont évalués dans la même requête
+// UPDATE, ce qui reste correct sous charge concurrente.
+func (r *GormUsageRepository) ReserveLink(account models.Account, period string, limit int64) (bool, error) {
+	if err := r.AddUsage(account, period, 0, 0); err != nil {
+		return false, err
+	}
+	result := r.db.Model(&models.UsageCounter{}).
+		Where("account_type = ? AND account_id = ? AND period = ? AND links_created < ?", account.Type, account.ID, period, limit).
+		UpdateColumns(map[string]interface{}{
+			"links_created": gorm.Expr("links_created + 1"),
+			"updated_at":    time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	CreateUser(user *models.User) error
+	GetUserByID(userID uint) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	ListUsers() ([]models.User, error)
+}
+
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+func (r *GormUserRepository) CreateUser(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *GormUserRepository) GetUserByID(userID uint) (*models.User, error) {
+	var user models.User
+	err := r.db.First(&user, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers retourne tous les utilisateurs par ordre d'inscription.
+func (r *GormUserRepository) ListUsers() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Order("id").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VisitorSaltRepository interface {
+	GetOrCreateSalt(day string, candidate []byte) ([]byte, error)
+	DeleteSaltsBefore(day string) error
+}
+
+type GormVisitorSaltRepository struct {
+	db *gorm.DB
+}
+
+func NewVisitorSaltRepository(db *gorm.DB) *GormVisitorSaltRepository {
+	return &GormVisitorSaltRepository{db: db}
+}
+
+// GetOrCreateSalt enregistre candidate comme sel du jour s'il n'en existe pas encore,
+// puis retourne le sel effectivement stocké : plusieurs processus obtiennent ainsi le même sel.
+func (r *GormVisitorSaltRepository) GetOrCreateSalt(day string, candidate []byte) ([]byte, error) {
+	err := r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.VisitorSalt{Day: day, Salt: candidate}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var salt models.VisitorSalt
+	if err := r.db.Where("day = ?", day).First(&salt).Error; err != nil {
+		return nil, err
+	}
+	return salt.Salt, nil
+}
+
+// DeleteSaltsBefore supprime les sels des jours antérieurs à day (format AAAA-MM-JJ).
+func (r *GormVisitorSaltRepository) DeleteSaltsBefore(day string) error {
+	return r.db.Where("day < ?", day).Delete(&models.VisitorSalt{}).Error
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+type WebhookDeadLetterRepository interface {
+	CreateDeadLetter(deadLetter *models.WebhookDeadLetter) error
+	ListDeadLetters(limit int) ([]models.WebhookDeadLetter, error)
+}
+
+type GormWebhookDeadLetterRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeadLetterRepository(db *gorm.DB) *GormWebhookDeadLetterRepository {
+	return &GormWebhookDeadLetterRepository{db: db}
+}
+
+func (r *GormWebhookDeadLetterRepository) CreateDeadLetter(deadLetter *models.WebhookDeadLetter) error {
+	return r.db.Create(deadLetter).Error
+}
+
+// ListDeadLetters retourne les notifications abandonnées, de la plus récente à la plus ancienne.
+func (r *GormWebhookDeadLetterRepository) ListDeadLetters(limit int) ([]models.WebhookDeadLetter, error) {
+	var deadLetters []models.WebhookDeadLetter
+	err := r.db.Order("created_at DESC").Order("id DESC").Limit(limit).Find(&deadLetters).Error
+	return deadLetters, err
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGormWebhookDeadLetterRepository_CreateAndList(t *testing.T) {
+	db := openTestDB(t, &models.WebhookDeadLetter{})
+	repo := NewWebhookDeadLetterRepository(db)
+
+	for i, url := range []string{"https://hooks.example.org/a", "https://hooks.example.org/b"} {
+		require.NoError(t, repo.CreateDeadLetter(&models.WebhookDeadLetter{
+			LinkID:         uint(i + 1),
+			EventID:        "evt",
+			URL:            url,
+			Payload:        `{"event":"link.state_changed"}`,
+			Attempts:       5,
+			LastStatusCode: 502,
+			LastError:      "unexpected status 502",
+		}))
+	}
+
+	deadLetters, err := repo.ListDeadLetters(10)
+
+	assert.NoError(t, err)
+	require.Len(t, deadLetters, 2)
+	assert.Equal(t, "https://hooks.example.org/b", deadLetters[0].URL)
+	assert.Equal(t, 5, deadLetters[1].Attempts)
+	assert.NotZero(t, deadLetters[1].CreatedAt)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"github.com/Edofo/bitly-clone/internal/models"
+	"gorm.io/gorm"
+)
+
+type WorkspaceRepository interface {
+	CreateWorkspace(workspace *models.Workspace, owner *models.Membership) error
+	GetWorkspaceByID(workspaceID uint) (*models.Workspace, error)
+	ListWorkspaces() ([]models.Workspace, error)
+	ListMembershipsByUser(userID uint) ([]models.Membership, error)
+	ListMembers(workspaceID uint) ([]models.Membership, error)
+	GetMembership(workspaceID, userID uint) (*models.Membership, error)
+	CreateMembership(membership *models.Membership) error
+	UpdateMembershipRole(workspaceID, userID uint, role models.Role) error
+	DeleteMembership(workspaceID, userID uint) error
+	CountMembersByRole(workspaceID uint, role models.Role) (int64, error)
+}
+
+type GormWorkspaceRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceRepository(db *gorm.DB) *GormWorkspaceRepository {
+	return &GormWorkspaceRepository{db: db}
+}
+
+// CreateWorkspace crée l'espace de travail et, dans la même transaction, l'adhésion de son
+// propriétaire.
+func (r *GormWorkspaceRepository) CreateWorkspace(workspace *models.Workspace, owner *models.Membership) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+		owner.WorkspaceID = workspace.ID
+		return tx.Omit("Workspace", "User").Create(owner).Error
+	})
+}
+
+func (r *GormWorkspaceRepository) GetWorkspaceByID(workspaceID uint) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := r.db.First(&workspace, workspaceID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (r *GormWorkspaceRepository) ListWorkspaces() ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	err := r.db.Order("id").Find(&workspaces).Error
+	return workspaces, err
+}
+
+// ListMembershipsByUser retourne les adhésions de l'utilisateur avec leur espace de travail.
+func (r *GormWorkspaceRepository) ListMembershipsByUser(userID uint) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.db.Preload("Workspace").Where("user_id = ?", userID).Order("workspace_id").Find(&memberships).Error
+	return memberships, err
+}
+
+// ListMembers retourne les membres de l'espace de travail avec leur utilisateur.
+func (r *GormWorkspaceRepository) ListMembers(workspaceID uint) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.db.Preload("User").Where("workspace_id = ?", workspaceID).Order("id").Find(&memberships).Error
+	return memberships, err
+}
+
+func (r *GormWorkspaceRepository) GetMembership(workspaceID, userID uint) (*models.Membership, error) {
+	var membership models.Membership
+	err := r.db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&membership).Error
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (r *GormWorkspaceRepository) CreateMembership(membership *models.Membership) error {
+	return r.db.Omit("Workspace", "User").Create(membership).Error
+}
+
+// UpdateMembershipRole retourne gorm.ErrRecordNotFound si l'utilisateur n'est pas membre.
+func (r *GormWorkspaceRepository) UpdateMembershipRole(workspaceID, userID uint, role models.Role) error {
+	result := r.db.Model(&models.Membership{}).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteMembership retourne gorm.ErrRecordNotFound si l'utilisateur n'est pas membre.
+func (r *GormWorkspaceRepository) DeleteMembership(workspaceID, userID uint) error {
+	result := r.db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Delete(&models.Membership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *GormWorkspaceRepository) CountMembersByRole(workspaceID uint, role models.Role) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Membership{}).Where("workspace_id = ? AND role = ?", workspaceID, role).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+)
+
+// Format des clés : "usk_<préfixe>_<secret>", le préfixe (8 caractères hexadécimaux)
+// et le secret (32 octets aléatoires) étant générés aléatoirement.
+const (
+	apiKeyScheme      = "usk_"
+	apiKeyPrefixBytes = 4
+	apiKeySecretBytes = 32
+)
+
+// APIKeyLastUsedResolution est la précision de APIKey.LastUsedAt : la date n'est enregistrée
+// qu'une fois par période, pour ne pas écrire en base à chaque requête.
+const APIKeyLastUsedResolution = time.Minute
+
+const MaxAPIKeyNameLength = 100
+
+var (
+	ErrInvalidAPIKey     = errors.New("invalid api key")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrAPIKeyRevoked     = errors.New("api key already revoked")
+	ErrInvalidAPIKeyName = errors.New("invalid api key name")
+)
+
+type APIKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	now        func() time.Time
+}
+
+type APIKeyServiceInterface interface {
+	CreateAPIKey(name string, userID *uint) (*models.APIKey, string, error)
+	ListAPIKeys() ([]models.APIKey, error)
+	RevokeAPIKey(prefix string) (*models.APIKey, error)
+	Authenticate(key string) (*models.APIKey, error)
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+		now:        time.Now,
+	}
+}
+
+// hashToken retourne l'empreinte conservée d'une clé d'API ou d'un jeton de session.
+func hashToken(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// CreateAPIKey crée une clé et la retourne en clair : elle n'est conservée que sous
+// forme d'empreinte et ne pourra plus être affichée. Une clé rattachée à un utilisateur
+// (userID) n'accède qu'à ses liens ; sans utilisateur, c'est une clé d'administration.
+func (s *APIKeyService) CreateAPIKey(name string, userID *uint) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) > MaxAPIKeyNameLength {
+		return nil, "", fmt.Errorf("%w: must be at most %d characters", ErrInvalidAPIKeyName, MaxAPIKeyNameLength)
+	}
+
+	const maxRetries = 5
+	for i := 0; i < maxRetries; i++ {
+		prefixID, err := randomHex(apiKeyPrefixBytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("error generating api key: %w", err)
+		}
+		secret, err := randomHex(apiKeySecretBytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("error generating api key: %w", err)
+		}
+
+		prefix := apiKeyScheme + prefixID
+		key := prefix + "_" + secret
+		apiKey := &models.APIKey{Name: name, Prefix: prefix, KeyHash: hashToken(key), UserID: userID, CreatedAt: s.now().UTC()}
+		err = s.apiKeyRepo.CreateAPIKey(apiKey)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			log.Printf("API key prefix collision for %s, retrying (%d/%d)...", prefix, i+1, maxRetries)
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("error saving api key: %w", err)
+		}
+		return apiKey, key, nil
+	}
+	return nil, "", errors.New("failed to generate a unique api key prefix after maximum retries")
+}
+
+func (s *APIKeyService) ListAPIKeys() ([]models.APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys()
+}
+
+// RevokeAPIKey révoque la clé de ce préfixe (ex: "usk_1a2b3c4d"). La clé complète est
+// aussi acceptée.
+func (s *APIKeyService) RevokeAPIKey(prefix string) (*models.APIKey, error) {
+	if parsed, _, ok := splitAPIKey(prefix); ok {
+		prefix = parsed
+	}
+
+	apiKey, err := s.apiKeyRepo.GetAPIKeyByPrefix(prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.IsRevoked() {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	revokedAt := s.now().UTC()
+	if err := s.apiKeyRepo.RevokeAPIKey(apiKey.ID, revokedAt); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyRevoked
+		}
+		return nil, fmt.Errorf("error revoking api key: %w", err)
+	}
+	apiKey.RevokedAt = &revokedAt
+	return apiKey, nil
+}
+
+// Authenticate retourne la clé active correspondant à la valeur présentée, ou
+// ErrInvalidAPIKey si elle est inconnue, incorrecte ou révoquée.
+func (s *APIKeyService) Authenticate(key string) (*models.APIKey, error) {
+	prefix, _, ok := splitAPIKey(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeyRepo.GetAPIKeyByPrefix(prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(key)), []byte(apiKey.KeyHash)) != 1 || apiKey.IsRevoked() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := s.now().UTC()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= APIKeyLastUsedResolution {
+		if err := s.apiKeyRepo.TouchAPIKey(apiKey.ID, now); err != nil {
+			log.Printf("Warning: Failed to record last use of API key %s: %v", apiKey.Prefix, err)
+		} else {
+			apiKey.LastUsedAt = &now
+		}
+	}
+	return apiKey, nil
+}
+
+// splitAPIKey sépare une clé "usk_<préfixe>_<secret>" en préfixe ("usk_<préfixe>") et secret.
+func splitAPIKey(key string) (string, string, bool) {
+	if !strings.HasPrefix(key, apiKeyScheme) {
+		return "", "", false
+	}
+	prefixID, secret, found := strings.Cut(strings.TrimPrefix(key, apiKeyScheme), "_")
+	if !found || len(prefixID) != 2*apiKeyPrefixBytes || secret == "" {
+		return "", "", false
+	}
+	return apiKeyScheme + prefixID, secret, true
+}
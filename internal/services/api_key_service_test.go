@@ -0,0 +1,113 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIKeyService() (*APIKeyService, *time.Time) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	service := NewAPIKeyService(repository.NewMemoryAPIKeyRepository(repository.NewMemoryStore()))
+	service.now = func() time.Time { return now }
+	return service, &now
+}
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	service, _ := newTestAPIKeyService()
+
+	apiKey, key, err := service.CreateAPIKey("  ci  ", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", apiKey.Name)
+	assert.Regexp(t, `^usk_[0-9a-f]{8}_[0-9a-f]{64}$`, key)
+	assert.True(t, strings.HasPrefix(key, apiKey.Prefix+"_"))
+	// Seule l'empreinte est conservée
+	assert.NotContains(t, apiKey.KeyHash, key[len(apiKey.Prefix)+1:])
+	assert.Len(t, apiKey.KeyHash, 64)
+
+	authenticated, err := service.Authenticate(key)
+	require.NoError(t, err)
+	assert.Equal(t, apiKey.ID, authenticated.ID)
+
+	_, other, err := service.CreateAPIKey("other", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	_, _, err = service.CreateAPIKey(strings.Repeat("a", MaxAPIKeyNameLength+1), nil)
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyName)
+}
+
+func TestAPIKeyService_AuthenticateRejectsInvalidKeys(t *testing.T) {
+	service, _ := newTestAPIKeyService()
+	apiKey, key, err := service.CreateAPIKey("ci", nil)
+	require.NoError(t, err)
+
+	for _, candidate := range []string{
+		"",
+		"not-a-key",
+		"usk_12345678",
+		apiKey.Prefix + "_" + strings.Repeat("0", 64), // bon préfixe, mauvais secret
+		"usk_ffffffff_" + key[len(apiKey.Prefix)+1:],  // préfixe inconnu
+		key + "x",
+	} {
+		_, err := service.Authenticate(candidate)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, candidate)
+	}
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	service, _ := newTestAPIKeyService()
+	apiKey, key, err := service.CreateAPIKey("ci", nil)
+	require.NoError(t, err)
+
+	revoked, err := service.RevokeAPIKey(apiKey.Prefix)
+	require.NoError(t, err)
+	assert.True(t, revoked.IsRevoked())
+
+	_, err = service.Authenticate(key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, err = service.RevokeAPIKey(key)
+	assert.ErrorIs(t, err, ErrAPIKeyRevoked, "la clé complète est aussi acceptée")
+
+	_, err = service.RevokeAPIKey("usk_ffffffff")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	keys, err := service.ListAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, keys[0].IsRevoked())
+}
+
+func TestAPIKeyService_TracksLastUse(t *testing.T) {
+	service, now := newTestAPIKeyService()
+	_, key, err := service.CreateAPIKey("ci", nil)
+	require.NoError(t, err)
+
+	lastUsedAt := func() time.Time {
+		keys, err := service.ListAPIKeys()
+		require.NoError(t, err)
+		require.NotNil(t, keys[0].LastUsedAt)
+		return *keys[0].LastUsedAt
+	}
+
+	first := *now
+	_, err = service.Authenticate(key)
+	require.NoError(t, err)
+	assert.True(t, first.Equal(lastUsedAt()))
+
+	// Les utilisations rapprochées ne sont pas toutes enregistrées
+	*now = first.Add(APIKeyLastUsedResolution / 2)
+	_, err = service.Authenticate(key)
+	require.NoError(t, err)
+	assert.True(t, first.Equal(lastUsedAt()))
+
+	*now = first.Add(APIKeyLastUsedResolution)
+	_, err = service.Authenticate(key)
+	require.NoError(t, err)
+	assert.True(t, now.Equal(lastUsedAt()))
+}
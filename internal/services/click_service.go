@@ -1,14 +1,53 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/Edofo/bitly-clone/internal/models"
 	"github.com/Edofo/bitly-clone/internal/repository"
 )
 
+const (
+	MaxTimeSeriesBuckets  = 1000
+	DefaultBreakdownLimit = 10
+	MaxBreakdownLimit     = 100
+)
+
+var (
+	ErrInvalidTimeSeries = errors.New("invalid time series query")
+	ErrInvalidBreakdown  = errors.New("invalid breakdown query")
+)
+
+// Libellés des valeurs vides, selon la dimension. Les clics enregistrés avant
+// l'analyse du User-Agent n'ont ni navigateur, ni système, ni classe d'appareil.
+var emptyDimensionLabels = map[repository.ClickDimension]string{
+	repository.DimensionReferrer: "(direct)",
+	repository.DimensionBrowser:  "(unknown)",
+	repository.DimensionOS:       "(unknown)",
+	repository.DimensionDevice:   "(unknown)",
+	repository.DimensionCountry:  "(unknown)",
+	repository.DimensionCity:     "(unknown)",
+}
+
 type ClickService struct {
 	clickRepo repository.ClickRepository
 }
 
+type ClickServiceInterface interface {
+	GetClickTimeSeries(linkID uint, query TimeSeriesQuery) ([]models.ClickBucket, error)
+	GetClickBreakdown(linkID uint, dimension repository.ClickDimension, limit int, filter repository.ClickFilter) ([]models.ClickStat, error)
+}
+
+type TimeSeriesQuery struct {
+	Interval repository.TimeInterval
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Filter   repository.ClickFilter
+}
+
 func NewClickService(clickRepo repository.ClickRepository) *ClickService {
 	return &ClickService{
 		clickRepo: clickRepo,
@@ -20,5 +59,86 @@ func (s *ClickService) RecordClick(click *models.Click) error {
 }
 
 func (s *ClickService) GetClicksCountByLinkID(linkID uint) (int, error) {
-	return s.clickRepo.CountClicksByLinkID(linkID)
+	return s.clickRepo.CountClicksByLinkID(linkID, repository.ClickFilter{})
+}
+
+// DefaultTimeSeriesFrom retourne le début de la période affichée par défaut pour un intervalle.
+func DefaultTimeSeriesFrom(interval repository.TimeInterval, to time.Time) time.Time {
+	switch interval {
+	case repository.IntervalHour:
+		return to.Add(-24 * time.Hour)
+	case repository.IntervalWeek:
+		return to.AddDate(0, 0, -7*12)
+	case repository.IntervalMonth:
+		return to.AddDate(-1, 0, 0)
+	default:
+		return to.AddDate(0, 0, -30)
+	}
+}
+
+// ParseDateTime accepte une date RFC3339 ou une date simple (AAAA-MM-JJ) interprétée dans loc.
+func ParseDateTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s': expected RFC3339 or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
+
+func (s *ClickService) GetClickTimeSeries(linkID uint, query TimeSeriesQuery) ([]models.ClickBucket, error) {
+	if query.Interval == "" {
+		query.Interval = repository.IntervalDay
+	}
+	if !query.Interval.IsValid() {
+		return nil, fmt.Errorf("%w: unsupported interval '%s'", ErrInvalidTimeSeries, query.Interval)
+	}
+	if query.Location == nil {
+		query.Location = time.UTC
+	}
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = DefaultTimeSeriesFrom(query.Interval, query.To)
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: 'from' must be before 'to'", ErrInvalidTimeSeries)
+	}
+
+	buckets := 0
+	for start := query.Interval.Truncate(query.From, query.Location); start.Before(query.To); start = query.Interval.Next(start) {
+		buckets++
+		if buckets > MaxTimeSeriesBuckets {
+			return nil, fmt.Errorf("%w: range too large, at most %d buckets allowed", ErrInvalidTimeSeries, MaxTimeSeriesBuckets)
+		}
+	}
+
+	return s.clickRepo.CountClicksByInterval(linkID, query.Interval, query.From, query.To, query.Location, query.Filter)
+}
+
+func (s *ClickService) GetClickBreakdown(linkID uint, dimension repository.ClickDimension, limit int, filter repository.ClickFilter) ([]models.ClickStat, error) {
+	if !dimension.IsValid() {
+		return nil, fmt.Errorf("%w: unsupported dimension '%s'", ErrInvalidBreakdown, dimension)
+	}
+	if limit <= 0 {
+		limit = DefaultBreakdownLimit
+	}
+	if limit > MaxBreakdownLimit {
+		limit = MaxBreakdownLimit
+	}
+
+	stats, err := s.clickRepo.CountClicksByDimension(linkID, dimension, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		if stats[i].Value == "" {
+			stats[i].Value = emptyDimensionLabels[dimension]
+		}
+	}
+	return stats, nil
 }
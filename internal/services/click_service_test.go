@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -18,11 +19,32 @@ func (m *MockClickRepository) CreateClick(click *models.Click) error {
 	return args.Error(0)
 }
 
-func (m *MockClickRepository) CountClicksByLinkID(linkID uint) (int, error) {
-	args := m.Called(linkID)
+func (m *MockClickRepository) CreateClicks(clicks []*models.Click) error {
+	args := m.Called(clicks)
+	return args.Error(0)
+}
+
+func (m *MockClickRepository) CountClicksByLinkID(linkID uint, filter repository.ClickFilter) (int, error) {
+	args := m.Called(linkID, filter)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockClickRepository) CountClicksByInterval(linkID uint, interval repository.TimeInterval, from, to time.Time, loc *time.Location, filter repository.ClickFilter) ([]models.ClickBucket, error) {
+	args := m.Called(linkID, interval, from, to, loc, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ClickBucket), args.Error(1)
+}
+
+func (m *MockClickRepository) CountClicksByDimension(linkID uint, dimension repository.ClickDimension, limit int, filter repository.ClickFilter) ([]models.ClickStat, error) {
+	args := m.Called(linkID, dimension, limit, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ClickStat), args.Error(1)
+}
+
 func TestNewClickService(t *testing.T) {
 	mockRepo := &MockClickRepository{}
 	service := NewClickService(mockRepo)
@@ -76,7 +98,7 @@ func TestGetClicksCountByLinkID_Success(t *testing.T) {
 	linkID := uint(1)
 	expectedCount := 42
 	
-	mockRepo.On("CountClicksByLinkID", linkID).Return(expectedCount, nil)
+	mockRepo.On("CountClicksByLinkID", linkID, repository.ClickFilter{}).Return(expectedCount, nil)
 	
 	count, err := service.GetClicksCountByLinkID(linkID)
 	
@@ -91,11 +113,139 @@ func TestGetClicksCountByLinkID_Error(t *testing.T) {
 	
 	linkID := uint(1)
 	
-	mockRepo.On("CountClicksByLinkID", linkID).Return(0, assert.AnError)
+	mockRepo.On("CountClicksByLinkID", linkID, repository.ClickFilter{}).Return(0, assert.AnError)
 	
 	count, err := service.GetClicksCountByLinkID(linkID)
 	
 	assert.Error(t, err)
 	assert.Equal(t, 0, count)
 	mockRepo.AssertExpectations(t)
-} 
\ No newline at end of file
+}
+
+func TestGetClickTimeSeries_Success(t *testing.T) {
+	mockRepo := &MockClickRepository{}
+	service := NewClickService(mockRepo)
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, paris)
+	to := time.Date(2024, 3, 8, 0, 0, 0, 0, paris)
+	expected := []models.ClickBucket{{Start: from, Count: 3}}
+
+	mockRepo.On("CountClicksByInterval", uint(1), repository.IntervalDay, from, to, paris, repository.ClickFilter{}).Return(expected, nil)
+
+	buckets, err := service.GetClickTimeSeries(1, TimeSeriesQuery{Interval: repository.IntervalDay, From: from, To: to, Location: paris})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, buckets)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetClickTimeSeries_Defaults(t *testing.T) {
+	mockRepo := &MockClickRepository{}
+	service := NewClickService(mockRepo)
+
+	mockRepo.On("CountClicksByInterval", uint(1), repository.IntervalDay, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), time.UTC, repository.ClickFilter{}).
+		Return([]models.ClickBucket{}, nil)
+
+	_, err := service.GetClickTimeSeries(1, TimeSeriesQuery{})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	from := mockRepo.Calls[0].Arguments.Get(2).(time.Time)
+	to := mockRepo.Calls[0].Arguments.Get(3).(time.Time)
+	assert.Equal(t, to.AddDate(0, 0, -30), from)
+}
+
+func TestGetClickTimeSeries_InvalidQuery(t *testing.T) {
+	mockRepo := &MockClickRepository{}
+	service := NewClickService(mockRepo)
+
+	now := time.Now()
+
+	_, err := service.GetClickTimeSeries(1, TimeSeriesQuery{Interval: "minute"})
+	assert.ErrorIs(t, err, ErrInvalidTimeSeries)
+
+	_, err = service.GetClickTimeSeries(1, TimeSeriesQuery{From: now, To: now.Add(-time.Hour)})
+	assert.ErrorIs(t, err, ErrInvalidTimeSeries)
+
+	_, err = service.GetClickTimeSeries(1, TimeSeriesQuery{Interval: repository.IntervalHour, From: now.AddDate(-1, 0, 0), To: now})
+	assert.ErrorIs(t, err, ErrInvalidTimeSeries)
+
+	mockRepo.AssertNotCalled(t, "CountClicksByInterval", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestParseDateTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	parsed, err := ParseDateTime("2024-03-01", paris)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, paris), parsed)
+
+	parsed, err = ParseDateTime("2024-03-01T10:00:00Z", paris)
+	assert.NoError(t, err)
+	assert.True(t, parsed.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)))
+
+	_, err = ParseDateTime("yesterday", paris)
+	assert.Error(t, err)
+}
+
+func TestGetClickBreakdown(t *testing.T) {
+	mockRepo := &MockClickRepository{}
+	service := NewClickService(mockRepo)
+
+	mockRepo.On("CountClicksByDimension", uint(1), repository.DimensionReferrer, DefaultBreakdownLimit, repository.ClickFilter{}).Return([]models.ClickStat{
+		{Value: "google.com", Count: 5},
+		{Value: "", Count: 2},
+	}, nil)
+
+	stats, err := service.GetClickBreakdown(1, repository.DimensionReferrer, 0, repository.ClickFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ClickStat{
+		{Value: "google.com", Count: 5},
+		{Value: "(direct)", Count: 2},
+	}, stats)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetClickBreakdown_LimitCapped(t *testing.T) {
+	mockRepo := &MockClickRepository{}
+	service := NewClickService(mockRepo)
+
+	mockRepo.On("CountClicksByDimension", uint(1), repository.DimensionReferrer, MaxBreakdownLimit, repository.ClickFilter{}).Return([]models.ClickStat{}, nil)
+
+	_, err := service.GetClickBreakdown(1, repository.DimensionReferrer, 5000, repository.ClickFilter{})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetClickBreakdown_InvalidDimension(t *testing.T) {
+	mockRepo := &MockClickRepository{}
+	service := NewClickService(mockRepo)
+
+	_, err := service.GetClickBreakdown(1, repository.ClickDimension("ip_address"), 10, repository.ClickFilter{})
+
+	assert.ErrorIs(t, err, ErrInvalidBreakdown)
+	mockRepo.AssertNotCalled(t, "CountClicksByDimension", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetClickBreakdown_UnknownDevice(t *testing.T) {
+	mockRepo := &MockClickRepository{}
+	service := NewClickService(mockRepo)
+
+	mockRepo.On("CountClicksByDimension", uint(1), repository.DimensionDevice, 3, repository.ClickFilter{IncludeBots: true}).Return([]models.ClickStat{
+		{Value: "mobile", Count: 8},
+		{Value: "", Count: 1},
+	}, nil)
+
+	stats, err := service.GetClickBreakdown(1, repository.DimensionDevice, 3, repository.ClickFilter{IncludeBots: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "(unknown)", stats[1].Value)
+	mockRepo.AssertExpectations(t)
+}
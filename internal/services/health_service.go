@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+)
+
+const (
+	DefaultHealthWindow       = 24 * time.Hour
+	MaxHealthWindow           = 90 * 24 * time.Hour
+	DefaultHealthHistoryLimit = 20
+	MaxHealthHistoryLimit     = 100
+)
+
+var ErrInvalidHealthQuery = errors.New("invalid health query")
+
+// Valeurs de LinkHealth.Status.
+const (
+	HealthStatusAccessible   = "accessible"
+	HealthStatusInaccessible = "inaccessible"
+	HealthStatusUnknown      = "unknown"
+)
+
+type HealthService struct {
+	healthRepo repository.HealthCheckRepository
+	maxWindow  time.Duration
+}
+
+type HealthServiceInterface interface {
+	GetLinkHealth(linkID uint, query HealthQuery) (*LinkHealth, error)
+}
+
+// HealthQuery délimite la période de calcul de la disponibilité et le nombre de vérifications retournées.
+type HealthQuery struct {
+	Window time.Duration
+	Limit  int
+}
+
+// LinkHealth résume l'état d'un lien : dernier état connu, disponibilité sur la période et historique récent.
+type LinkHealth struct {
+	Status        string
+	LastCheckedAt *time.Time
+	UptimePercent *float64
+	Checks        int64
+	Window        time.Duration
+	History       []models.HealthCheck
+}
+
+// NewHealthService crée le service ; historyRetention est la durée de conservation des
+// vérifications par le moniteur (0 : indéfiniment). La période demandée ne peut pas la
+// dépasser, faute de quoi la disponibilité ne porterait que sur l'historique conservé.
+func NewHealthService(healthRepo repository.HealthCheckRepository, historyRetention time.Duration) *HealthService {
+	maxWindow := MaxHealthWindow
+	if historyRetention > 0 && historyRetention < maxWindow {
+		maxWindow = historyRetention
+	}
+	return &HealthService{
+		healthRepo: healthRepo,
+		maxWindow:  maxWindow,
+	}
+}
+
+func (s *HealthService) GetLinkHealth(linkID uint, query HealthQuery) (*LinkHealth, error) {
+	if query.Window == 0 {
+		query.Window = DefaultHealthWindow
+	}
+	if query.Window < 0 || query.Window > s.maxWindow {
+		return nil, fmt.Errorf("%w: window must be between 0 and %s", ErrInvalidHealthQuery, FormatWindow(s.maxWindow))
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultHealthHistoryLimit
+	}
+	if query.Limit < 0 || query.Limit > MaxHealthHistoryLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidHealthQuery, MaxHealthHistoryLimit)
+	}
+
+	history, err := s.healthRepo.GetRecentHealthChecks(linkID, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.healthRepo.SummarizeHealthChecks(linkID, time.Now().Add(-query.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	health := &LinkHealth{
+		Status:        HealthStatusUnknown,
+		UptimePercent: summary.UptimePercent(),
+		Checks:        summary.Checks,
+		Window:        query.Window,
+		History:       history,
+	}
+	if len(history) > 0 {
+		latest := history[0]
+		health.LastCheckedAt = &latest.CheckedAt
+		health.Status = HealthStatusInaccessible
+		if latest.LinkAccessible() {
+			health.Status = HealthStatusAccessible
+		}
+	}
+	return health, nil
+}
+
+// ParseWindow accepte une durée Go (ex: 12h, 90m) ou un nombre de jours (ex: 7d).
+func ParseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%w: invalid window '%s'", ErrInvalidHealthQuery, value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("%w: invalid window '%s'", ErrInvalidHealthQuery, value)
+	}
+	return window, nil
+}
+
+// FormatWindow affiche une période en jours (7d) ou en heures (24h) entières si possible, sinon en durée Go.
+func FormatWindow(window time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case window > day && window%day == 0:
+		return fmt.Sprintf("%dd", window/day)
+	case window >= time.Hour && window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	default:
+		return window.String()
+	}
+}
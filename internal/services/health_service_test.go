@@ -0,0 +1,155 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockHealthCheckRepository struct {
+	mock.Mock
+}
+
+func (m *MockHealthCheckRepository) CreateHealthCheck(check *models.HealthCheck) error {
+	args := m.Called(check)
+	return args.Error(0)
+}
+
+func (m *MockHealthCheckRepository) GetRecentHealthChecks(linkID uint, limit int) ([]models.HealthCheck, error) {
+	args := m.Called(linkID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.HealthCheck), args.Error(1)
+}
+
+func (m *MockHealthCheckRepository) GetLatestHealthChecks() (map[uint]models.HealthCheck, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uint]models.HealthCheck), args.Error(1)
+}
+
+func (m *MockHealthCheckRepository) SummarizeHealthChecks(linkID uint, since time.Time) (models.HealthSummary, error) {
+	args := m.Called(linkID, since)
+	return args.Get(0).(models.HealthSummary), args.Error(1)
+}
+
+func (m *MockHealthCheckRepository) DeleteHealthChecksBefore(before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestGetLinkHealth(t *testing.T) {
+	mockRepo := &MockHealthCheckRepository{}
+	service := NewHealthService(mockRepo, 0)
+
+	checkedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	history := []models.HealthCheck{
+		{LinkID: 1, CheckedAt: checkedAt, Accessible: false, ErrorClass: "http_5xx", StatusCode: 503},
+		{LinkID: 1, CheckedAt: checkedAt.Add(-5 * time.Minute), Accessible: true, StatusCode: 200},
+	}
+	mockRepo.On("GetRecentHealthChecks", uint(1), DefaultHealthHistoryLimit).Return(history, nil)
+	mockRepo.On("SummarizeHealthChecks", uint(1), mock.MatchedBy(func(since time.Time) bool {
+		return time.Since(since) >= 7*24*time.Hour && time.Since(since) < 7*24*time.Hour+time.Minute
+	})).Return(models.HealthSummary{Checks: 3, Successful: 2}, nil)
+
+	health, err := service.GetLinkHealth(1, HealthQuery{Window: 7 * 24 * time.Hour})
+
+	assert.NoError(t, err)
+	assert.Equal(t, HealthStatusInaccessible, health.Status)
+	assert.Equal(t, checkedAt, *health.LastCheckedAt)
+	assert.Equal(t, 66.66, *health.UptimePercent)
+	assert.Equal(t, int64(3), health.Checks)
+	assert.Len(t, health.History, 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetLinkHealth_NoChecks(t *testing.T) {
+	mockRepo := &MockHealthCheckRepository{}
+	service := NewHealthService(mockRepo, 0)
+
+	mockRepo.On("GetRecentHealthChecks", uint(1), 5).Return([]models.HealthCheck{}, nil)
+	mockRepo.On("SummarizeHealthChecks", uint(1), mock.Anything).Return(models.HealthSummary{}, nil)
+
+	health, err := service.GetLinkHealth(1, HealthQuery{Limit: 5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, HealthStatusUnknown, health.Status)
+	assert.Nil(t, health.LastCheckedAt)
+	assert.Nil(t, health.UptimePercent)
+	assert.Equal(t, DefaultHealthWindow, health.Window)
+}
+
+func TestGetLinkHealth_UsesMonitorState(t *testing.T) {
+	mockRepo := &MockHealthCheckRepository{}
+	service := NewHealthService(mockRepo, 0)
+
+	// Premier échec en dessous du seuil : le lien est toujours considéré accessible
+	history := []models.HealthCheck{
+		{LinkID: 1, CheckedAt: time.Now(), Accessible: false, State: models.LinkStateAccessible, ConsecutiveFailures: 1},
+	}
+	mockRepo.On("GetRecentHealthChecks", uint(1), DefaultHealthHistoryLimit).Return(history, nil)
+	mockRepo.On("SummarizeHealthChecks", uint(1), mock.Anything).Return(models.HealthSummary{Checks: 1}, nil)
+
+	health, err := service.GetLinkHealth(1, HealthQuery{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, HealthStatusAccessible, health.Status)
+}
+
+func TestGetLinkHealth_InvalidQuery(t *testing.T) {
+	service := NewHealthService(&MockHealthCheckRepository{}, 0)
+
+	_, err := service.GetLinkHealth(1, HealthQuery{Window: 365 * 24 * time.Hour})
+	assert.ErrorIs(t, err, ErrInvalidHealthQuery)
+
+	_, err = service.GetLinkHealth(1, HealthQuery{Limit: MaxHealthHistoryLimit + 1})
+	assert.ErrorIs(t, err, ErrInvalidHealthQuery)
+}
+
+func TestGetLinkHealth_WindowCappedByHistoryRetention(t *testing.T) {
+	service := NewHealthService(&MockHealthCheckRepository{}, 30*24*time.Hour)
+
+	_, err := service.GetLinkHealth(1, HealthQuery{Window: 31 * 24 * time.Hour})
+	assert.ErrorIs(t, err, ErrInvalidHealthQuery)
+	assert.Contains(t, err.Error(), "30d")
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"24h", 24 * time.Hour, true},
+		{"90m", 90 * time.Minute, true},
+		{"7d", 7 * 24 * time.Hour, true},
+		{"0d", 0, false},
+		{"-1h", 0, false},
+		{"week", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			window, err := ParseWindow(tt.value)
+			if !tt.valid {
+				assert.ErrorIs(t, err, ErrInvalidHealthQuery)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, window)
+		})
+	}
+}
+
+func TestFormatWindow(t *testing.T) {
+	assert.Equal(t, "7d", FormatWindow(7*24*time.Hour))
+	assert.Equal(t, "24h", FormatWindow(24*time.Hour))
+	assert.Equal(t, "36h", FormatWindow(36*time.Hour))
+	assert.Equal(t, "1h30m0s", FormatWindow(90*time.Minute))
+}
@@ -6,6 +6,10 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,14 +20,151 @@ import (
 
 const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
+const (
+	MinAliasLength = 3
+	MaxAliasLength = 32
+)
+
+var (
+	ErrInvalidAlias       = errors.New("invalid custom alias")
+	ErrReservedAlias      = errors.New("custom alias is reserved")
+	ErrAliasAlreadyExists = errors.New("custom alias already exists")
+	ErrInvalidExpiration  = errors.New("invalid link expiration")
+	ErrLinkExpired        = errors.New("link has expired")
+	ErrInvalidLinkFilter  = errors.New("invalid link filter")
+	ErrInvalidWebhookURL  = errors.New("invalid webhook url")
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// maxInsertAttempts borne les insertions d'un code généré pris par une requête concurrente.
+const maxInsertAttempts = 3
+
+var aliasPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Chemins déjà utilisés par le routeur Gin : un alias identique masquerait la route.
+var reservedAliases = map[string]bool{
+	"health": true,
+	"api":    true,
+}
+
+// Owner désigne, pour les opérations de gestion, l'auteur de la requête et les liens qui
+// lui sont accessibles : ceux qu'un utilisateur a créés hors de tout espace de travail et
+// ceux de ses espaces de travail selon son rôle, ou tous pour un administrateur (CLI, clé
+// d'API sans utilisateur). La valeur zéro n'accède à aucun lien.
+type Owner struct {
+	UserID uint
+	Admin  bool
+	// Roles associe à chaque espace de travail de l'utilisateur son rôle.
+	Roles map[uint]models.Role
+}
+
+// AdminOwner accède à tous les liens ; les liens qu'il crée n'ont pas de propriétaire.
+var AdminOwner = Owner{Admin: true}
+
+// OwnedBy restreint les opérations aux liens de l'utilisateur. Ses rôles dans les espaces
+// de travail s'ajoutent avec WithRoles.
+func OwnedBy(userID uint) Owner {
+	return Owner{UserID: userID}
+}
+
+// WithRoles retourne une copie de o avec ces rôles dans les espaces de travail.
+func (o Owner) WithRoles(roles map[uint]models.Role) Owner {
+	o.Roles = roles
+	return o
+}
+
+// RoleIn retourne le rôle dans l'espace de travail, qu'un administrateur possède toujours.
+func (o Owner) RoleIn(workspaceID uint) (models.Role, bool) {
+	if o.Admin {
+		return models.RoleOwner, true
+	}
+	role, ok := o.Roles[workspaceID]
+	return role, ok
+}
+
+// RoleFor retourne le rôle sur le lien : celui de son espace de travail, ou propriétaire
+// pour l'auteur d'un lien hors de tout espace de travail.
+func (o Owner) RoleFor(link *models.Link) (models.Role, bool) {
+	if link.WorkspaceID != nil {
+		return o.RoleIn(*link.WorkspaceID)
+	}
+	if o.Admin || (o.UserID != 0 && link.IsOwnedBy(o.UserID)) {
+		return models.RoleOwner, true
+	}
+	return "", false
+}
+
+// CanAccess indique si le lien est accessible, au moins en lecture.
+func (o Owner) CanAccess(link *models.Link) bool {
+	_, ok := o.RoleFor(link)
+	return ok
+}
+
+// ownerID retourne l'utilisateur auquel rattacher les liens créés et restreindre les listes
+// (nil pour un administrateur).
+func (o Owner) ownerID() *uint {
+	if o.Admin {
+		return nil
+	}
+	userID := o.UserID
+	return &userID
+}
+
+// scope retourne le périmètre des listes de liens (nil pour un administrateur).
+func (o Owner) scope() *repository.LinkScope {
+	if o.Admin {
+		return nil
+	}
+	scope := &repository.LinkScope{UserID: o.UserID}
+	for workspaceID := range o.Roles {
+		scope.WorkspaceIDs = append(scope.WorkspaceIDs, workspaceID)
+	}
+	sort.Slice(scope.WorkspaceIDs, func(i, j int) bool { return scope.WorkspaceIDs[i] < scope.WorkspaceIDs[j] })
+	return scope
+}
+
+// requireRole retourne ErrWorkspaceNotFound hors de l'espace de travail, ErrInsufficientRole
+// avec un rôle inférieur à required.
+func (o Owner) requireRole(workspaceID uint, required models.Role) error {
+	role, ok := o.RoleIn(workspaceID)
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrWorkspaceNotFound, workspaceID)
+	}
+	if !role.Allows(required) {
+		return fmt.Errorf("%w: %s role required", ErrInsufficientRole, required)
+	}
+	return nil
+}
+
+type CreateLinkOptions struct {
+	CustomAlias string
+	ExpiresAt   *time.Time
+	MaxClicks   int
+	WebhookURL  string
+	// WorkspaceID rattache le lien à un espace de travail, où l'auteur doit être éditeur.
+	WorkspaceID *uint
+}
+
 type LinkService struct {
 	linkRepo repository.LinkRepository
+	usage    *UsageService
 }
 
 type LinkServiceInterface interface {
-	CreateLink(longURL string) (*models.Link, error)
+	CreateLink(owner Owner, longURL string, opts CreateLinkOptions) (*models.Link, error)
 	GetLinkByShortCode(shortCode string) (*models.Link, error)
-	GetLinkStats(shortCode string) (*models.Link, int, error)
+	GetLink(owner Owner, shortCode string) (*models.Link, error)
+	GetLinkStats(owner Owner, shortCode string, filter repository.ClickFilter) (*models.Link, models.ClickTotals, error)
+	ConsumeClick(link *models.Link) error
+	CheckAvailable(link *models.Link) error
+	ListLinks(owner Owner, filter repository.LinkFilter) ([]models.Link, int64, error)
+	UpdateLinkURL(owner Owner, shortCode string, longURL string) (*models.Link, error)
+	DeleteLink(owner Owner, shortCode string) error
+	SetLinkWebhook(owner Owner, shortCode string, webhookURL string) (*models.Link, error)
 }
 
 func NewLinkService(linkRepo repository.LinkRepository) *LinkService {
@@ -32,6 +173,12 @@ func NewLinkService(linkRepo repository.LinkRepository) *LinkService {
 	}
 }
 
+// SetUsageService active le décompte des liens créés et le quota mensuel de liens de
+// chaque compte. À appeler avant de créer des liens.
+func (s *LinkService) SetUsageService(usage *UsageService) {
+	s.usage = usage
+}
+
 func (s *LinkService) GenerateShortCode(length int) (string, error) {
 	if length <= 0 {
 		return "", errors.New("invalid short code length")
@@ -47,59 +194,330 @@ func (s *LinkService) GenerateShortCode(length int) (string, error) {
 	return string(result), nil
 }
 
-func (s *LinkService) CreateLink(longURL string) (*models.Link, error) {
-	var shortCode string
+func ValidateAlias(alias string) error {
+	if len(alias) < MinAliasLength || len(alias) > MaxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d characters", ErrInvalidAlias, MinAliasLength, MaxAliasLength)
+	}
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: only letters, digits, '-' and '_' are allowed", ErrInvalidAlias)
+	}
+	if reservedAliases[strings.ToLower(alias)] {
+		return fmt.Errorf("%w: '%s'", ErrReservedAlias, alias)
+	}
+	return nil
+}
+
+// ValidateWebhookURL vérifie qu'une URL de webhook est une URL HTTP(S) absolue.
+func ValidateWebhookURL(webhookURL string) error {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: '%s' must be an absolute http(s) URL", ErrInvalidWebhookURL, webhookURL)
+	}
+	return nil
+}
+
+// newWebhookSecret génère le secret qui signe les notifications envoyées au webhook d'un lien.
+func newWebhookSecret() (string, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("error generating webhook secret: %w", err)
+	}
+	return secret, nil
+}
+
+// ExtractDomain retourne le nom d'hôte (en minuscules) de l'URL de destination.
+func ExtractDomain(longURL string) string {
+	parsed, err := url.Parse(longURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+func (s *LinkService) CreateLink(owner Owner, longURL string, opts CreateLinkOptions) (*models.Link, error) {
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiration)
+	}
+	if opts.MaxClicks < 0 {
+		return nil, fmt.Errorf("%w: max_clicks must be positive", ErrInvalidExpiration)
+	}
+	if opts.WebhookURL != "" {
+		if err := ValidateWebhookURL(opts.WebhookURL); err != nil {
+			return nil, err
+		}
+	}
+	if opts.WorkspaceID != nil {
+		if err := owner.requireRole(*opts.WorkspaceID, models.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+	var webhookSecret string
+	if opts.WebhookURL != "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		webhookSecret = secret
+	}
+
+	// Le lien est décompté du quota avant sa création, puis restitué si elle échoue.
+	var reservation *linkReservation
+	if account, metered := models.AccountOf(&models.Link{OwnerID: owner.ownerID(), WorkspaceID: opts.WorkspaceID}); metered && s.usage != nil {
+		var err error
+		if reservation, err = s.usage.reserveLink(account); err != nil {
+			return nil, err
+		}
+	}
+
+	link, err := s.insertLink(owner, longURL, opts, webhookSecret)
+	if err != nil {
+		if reservation != nil {
+			s.usage.releaseLink(reservation)
+		}
+		return nil, err
+	}
+	return link, nil
+}
+
+// insertLink attribue au lien son code court (alias ou code généré) et l'enregistre.
+func (s *LinkService) insertLink(owner Owner, longURL string, opts CreateLinkOptions, webhookSecret string) (*models.Link, error) {
+	link := &models.Link{
+		LongURL:       longURL,
+		Domain:        ExtractDomain(longURL),
+		ExpiresAt:     opts.ExpiresAt,
+		MaxClicks:     opts.MaxClicks,
+		WebhookURL:    opts.WebhookURL,
+		WebhookSecret: webhookSecret,
+		OwnerID:       owner.ownerID(),
+		WorkspaceID:   opts.WorkspaceID,
+		CreatedAt:     time.Now(),
+	}
+
+	// La vérification préalable du code ne suffit pas : une autre requête peut l'insérer
+	// entre-temps. C'est la contrainte d'unicité qui tranche, et un code généré est
+	// alors simplement tiré à nouveau.
+	for attempt := 1; ; attempt++ {
+		var shortCode string
+		var err error
+		if opts.CustomAlias != "" {
+			shortCode, err = s.reserveAlias(opts.CustomAlias)
+		} else {
+			shortCode, err = s.generateUniqueShortCode()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		link.ShortCode = shortCode
+		err = s.linkRepo.CreateLink(link)
+		if err == nil {
+			return link, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("error creating link: %w", err)
+		}
+		if opts.CustomAlias != "" {
+			return nil, fmt.Errorf("%w: '%s'", ErrAliasAlreadyExists, shortCode)
+		}
+		if attempt == maxInsertAttempts {
+			return nil, fmt.Errorf("failed to insert a unique short code after %d attempts: %w", attempt, err)
+		}
+		log.Printf("Short code '%s' was taken concurrently, retrying insertion (%d/%d)...", shortCode, attempt, maxInsertAttempts)
+	}
+}
+
+func (s *LinkService) reserveAlias(alias string) (string, error) {
+	if err := ValidateAlias(alias); err != nil {
+		return "", err
+	}
+
+	_, err := s.linkRepo.GetLinkByShortCode(alias)
+	if err == nil {
+		return "", fmt.Errorf("%w: '%s'", ErrAliasAlreadyExists, alias)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("database error checking alias availability: %w", err)
+	}
+
+	return alias, nil
+}
+
+func (s *LinkService) generateUniqueShortCode() (string, error) {
 	maxRetries := 5
 
 	for i := 0; i < maxRetries; i++ {
 		code, err := s.GenerateShortCode(6)
 		if err != nil {
-			return nil, fmt.Errorf("error generating short code: %w", err)
+			return "", fmt.Errorf("error generating short code: %w", err)
 		}
 
 		_, err = s.linkRepo.GetLinkByShortCode(code)
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				shortCode = code
-				break
+				return code, nil
 			}
-			return nil, fmt.Errorf("database error checking short code uniqueness: %w", err)
+			return "", fmt.Errorf("database error checking short code uniqueness: %w", err)
 		}
 
 		log.Printf("Short code '%s' already exists, retrying generation (%d/%d)...", code, i+1, maxRetries)
 	}
 
-	if shortCode == "" {
-		return nil, errors.New("failed to generate unique short code after maximum retries")
+	return "", errors.New("failed to generate unique short code after maximum retries")
+}
+
+// GetLinkByShortCode retourne un lien quel que soit son propriétaire, pour la redirection.
+// Lu depuis le cache, le lien ne porte que les champs utiles à la redirection.
+func (s *LinkService) GetLinkByShortCode(shortCode string) (*models.Link, error) {
+	return s.linkRepo.GetLinkByShortCode(shortCode)
+}
+
+// GetLink retourne un lien accessible à owner. Un lien inaccessible est signalé comme
+// introuvable (gorm.ErrRecordNotFound), pour ne pas révéler son existence.
+func (s *LinkService) GetLink(owner Owner, shortCode string) (*models.Link, error) {
+	return s.authorize(owner, shortCode, models.RoleViewer)
+}
+
+// authorize retourne le lien si owner y a au moins le rôle required, ErrInsufficientRole
+// s'il n'y a qu'un rôle inférieur.
+func (s *LinkService) authorize(owner Owner, shortCode string, required models.Role) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, err
 	}
+	// Le cache ne conserve ni le propriétaire ni le webhook du lien : il est relu en base.
+	if link, err = s.linkRepo.GetLinkByID(link.ID); err != nil {
+		return nil, err
+	}
+	role, ok := owner.RoleFor(link)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if !role.Allows(required) {
+		return nil, fmt.Errorf("%w: %s role required", ErrInsufficientRole, required)
+	}
+	return link, nil
+}
 
-	link := &models.Link{
-		ShortCode: shortCode,
-		LongURL:   longURL,
-		CreatedAt: time.Now(),
+func (s *LinkService) GetLinkStats(owner Owner, shortCode string, filter repository.ClickFilter) (*models.Link, models.ClickTotals, error) {
+	link, err := s.GetLink(owner, shortCode)
+	if err != nil {
+		return nil, models.ClickTotals{}, err
 	}
 
-	if err := s.linkRepo.CreateLink(link); err != nil {
-		return nil, fmt.Errorf("error creating link: %w", err)
+	totals, err := s.linkRepo.CountClickTotals(link.ID, filter)
+	if err != nil {
+		return nil, models.ClickTotals{}, err
 	}
 
-	return link, nil
+	return link, totals, nil
 }
 
-func (s *LinkService) GetLinkByShortCode(shortCode string) (*models.Link, error) {
-	return s.linkRepo.GetLinkByShortCode(shortCode)
+// ConsumeClick vérifie qu'un lien peut encore être suivi et, s'il est limité
+// en nombre de clics, consomme une unité de son budget.
+func (s *LinkService) ConsumeClick(link *models.Link) error {
+	if link.IsExpired(time.Now()) {
+		return ErrLinkExpired
+	}
+	if !link.HasClickBudget() {
+		return nil
+	}
+
+	ok, err := s.linkRepo.ConsumeClick(link.ID)
+	if err != nil {
+		return fmt.Errorf("error consuming click budget: %w", err)
+	}
+	if !ok {
+		return ErrLinkExpired
+	}
+	return nil
 }
 
-func (s *LinkService) GetLinkStats(shortCode string) (*models.Link, int, error) {
-	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+// CheckAvailable vérifie qu'un lien peut encore être suivi sans consommer son budget,
+// pour les visites qui ne comptent pas comme des clics (robots).
+func (s *LinkService) CheckAvailable(link *models.Link) error {
+	if link.IsExpired(time.Now()) || link.IsClickBudgetExhausted() {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+func (s *LinkService) ListLinks(owner Owner, filter repository.LinkFilter) ([]models.Link, int64, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = "created_at"
+	}
+	if _, ok := repository.LinkSortColumns[filter.SortBy]; !ok {
+		return nil, 0, fmt.Errorf("%w: unsupported sort field '%s'", ErrInvalidLinkFilter, filter.SortBy)
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = DefaultPageSize
+	}
+	if filter.PageSize > MaxPageSize {
+		filter.PageSize = MaxPageSize
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return nil, 0, fmt.Errorf("%w: created_after must be before created_before", ErrInvalidLinkFilter)
+	}
+	filter.Domain = strings.ToLower(strings.TrimSpace(filter.Domain))
+	if filter.WorkspaceID != nil {
+		if err := owner.requireRole(*filter.WorkspaceID, models.RoleViewer); err != nil {
+			return nil, 0, err
+		}
+	}
+	filter.Scope = owner.scope()
+
+	return s.linkRepo.ListLinks(filter)
+}
+
+func (s *LinkService) UpdateLinkURL(owner Owner, shortCode string, longURL string) (*models.Link, error) {
+	link, err := s.authorize(owner, shortCode, models.RoleEditor)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	domain := ExtractDomain(longURL)
+	if err := s.linkRepo.UpdateLinkURL(link.ID, longURL, domain); err != nil {
+		return nil, fmt.Errorf("error updating link: %w", err)
 	}
+	link.LongURL = longURL
+	link.Domain = domain
+	return link, nil
+}
 
-	totalClicks, err := s.linkRepo.CountClicksByLinkID(link.ID)
+func (s *LinkService) DeleteLink(owner Owner, shortCode string) error {
+	link, err := s.authorize(owner, shortCode, models.RoleEditor)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
+	return s.linkRepo.DeleteLink(link.ID)
+}
 
-	return link, totalClicks, nil
+// SetLinkWebhook définit le webhook propre à un lien, prévenu de ses changements d'état
+// en plus du webhook du déploiement, avec un nouveau secret de signature. Une URL vide le supprime.
+func (s *LinkService) SetLinkWebhook(owner Owner, shortCode string, webhookURL string) (*models.Link, error) {
+	if webhookURL != "" {
+		if err := ValidateWebhookURL(webhookURL); err != nil {
+			return nil, err
+		}
+	}
+
+	link, err := s.authorize(owner, shortCode, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhookSecret string
+	if webhookURL != "" {
+		if webhookSecret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.linkRepo.UpdateLinkWebhook(link.ID, webhookURL, webhookSecret); err != nil {
+		return nil, fmt.Errorf("error updating link webhook: %w", err)
+	}
+	link.WebhookURL = webhookURL
+	link.WebhookSecret = webhookSecret
+	return link, nil
 }
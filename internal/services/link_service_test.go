@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
@@ -28,6 +29,14 @@ func (m *MockLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link,
 	return args.Get(0).(*models.Link), args.Error(1)
 }
 
+func (m *MockLinkRepository) GetLinkByID(linkID uint) (*models.Link, error) {
+	args := m.Called(linkID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Link), args.Error(1)
+}
+
 func (m *MockLinkRepository) GetAllLinks() ([]models.Link, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -36,11 +45,44 @@ func (m *MockLinkRepository) GetAllLinks() ([]models.Link, error) {
 	return args.Get(0).([]models.Link), args.Error(1)
 }
 
-func (m *MockLinkRepository) CountClicksByLinkID(linkID uint) (int, error) {
+func (m *MockLinkRepository) ListLinks(filter repository.LinkFilter) ([]models.Link, int64, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]models.Link), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockLinkRepository) UpdateLinkWebhook(linkID uint, webhookURL string, webhookSecret string) error {
+	args := m.Called(linkID, webhookURL, webhookSecret)
+	return args.Error(0)
+}
+
+func (m *MockLinkRepository) UpdateLinkURL(linkID uint, longURL string, domain string) error {
+	args := m.Called(linkID, longURL, domain)
+	return args.Error(0)
+}
+
+func (m *MockLinkRepository) DeleteLink(linkID uint) error {
 	args := m.Called(linkID)
+	return args.Error(0)
+}
+
+func (m *MockLinkRepository) CountClicksByLinkID(linkID uint, filter repository.ClickFilter) (int, error) {
+	args := m.Called(linkID, filter)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockLinkRepository) CountClickTotals(linkID uint, filter repository.ClickFilter) (models.ClickTotals, error) {
+	args := m.Called(linkID, filter)
+	return args.Get(0).(models.ClickTotals), args.Error(1)
+}
+
+func (m *MockLinkRepository) ConsumeClick(linkID uint) (bool, error) {
+	args := m.Called(linkID)
+	return args.Bool(0), args.Error(1)
+}
+
 func TestNewLinkService(t *testing.T) {
 	mockRepo := &MockLinkRepository{}
 	service := NewLinkService(mockRepo)
@@ -88,12 +130,13 @@ func TestCreateLink_Success(t *testing.T) {
 	// Mock pour CreateLink - succès
 	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(nil)
 	
-	link, err := service.CreateLink(longURL)
+	link, err := service.CreateLink(AdminOwner, longURL, CreateLinkOptions{})
 	
 	assert.NoError(t, err)
 	assert.NotNil(t, link)
 	assert.Equal(t, longURL, link.LongURL)
 	assert.Len(t, link.ShortCode, 6)
+	assert.Equal(t, "www.example.com", link.Domain)
 	assert.WithinDuration(t, time.Now(), link.CreatedAt, 2*time.Second)
 	
 	mockRepo.AssertExpectations(t)
@@ -119,7 +162,7 @@ func TestCreateLink_RetryOnCollision(t *testing.T) {
 	// Mock pour CreateLink - succès
 	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(nil)
 	
-	link, err := service.CreateLink(longURL)
+	link, err := service.CreateLink(AdminOwner, longURL, CreateLinkOptions{})
 	
 	assert.NoError(t, err)
 	assert.NotNil(t, link)
@@ -139,7 +182,7 @@ func TestCreateLink_MaxRetriesExceeded(t *testing.T) {
 		&models.Link{}, nil, // Toujours des collisions
 	).Times(5)
 	
-	link, err := service.CreateLink(longURL)
+	link, err := service.CreateLink(AdminOwner, longURL, CreateLinkOptions{})
 	
 	assert.Error(t, err)
 	assert.Nil(t, link)
@@ -159,7 +202,7 @@ func TestCreateLink_DatabaseError(t *testing.T) {
 		nil, errors.New("database connection error"),
 	)
 	
-	link, err := service.CreateLink(longURL)
+	link, err := service.CreateLink(AdminOwner, longURL, CreateLinkOptions{})
 	
 	assert.Error(t, err)
 	assert.Nil(t, link)
@@ -184,7 +227,7 @@ func TestCreateLink_CreateLinkError(t *testing.T) {
 		errors.New("create link error"),
 	)
 	
-	link, err := service.CreateLink(longURL)
+	link, err := service.CreateLink(AdminOwner, longURL, CreateLinkOptions{})
 	
 	assert.Error(t, err)
 	assert.Nil(t, link)
@@ -226,16 +269,18 @@ func TestGetLinkStats(t *testing.T) {
 		LongURL:   "https://www.example.com",
 		CreatedAt: time.Now(),
 	}
-	expectedClicks := 42
+	expectedTotals := models.ClickTotals{TotalClicks: 42, UniqueVisitors: 17}
+	filter := repository.ClickFilter{From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
 	
 	mockRepo.On("GetLinkByShortCode", shortCode).Return(expectedLink, nil)
-	mockRepo.On("CountClicksByLinkID", uint(1)).Return(expectedClicks, nil)
+	mockRepo.On("GetLinkByID", uint(1)).Return(expectedLink, nil)
+	mockRepo.On("CountClickTotals", uint(1), filter).Return(expectedTotals, nil)
 	
-	link, clicks, err := service.GetLinkStats(shortCode)
+	link, totals, err := service.GetLinkStats(AdminOwner, shortCode, filter)
 	
 	assert.NoError(t, err)
 	assert.Equal(t, expectedLink, link)
-	assert.Equal(t, expectedClicks, clicks)
+	assert.Equal(t, expectedTotals, totals)
 	
 	mockRepo.AssertExpectations(t)
 }
@@ -248,12 +293,507 @@ func TestGetLinkStats_LinkNotFound(t *testing.T) {
 	
 	mockRepo.On("GetLinkByShortCode", shortCode).Return(nil, gorm.ErrRecordNotFound)
 	
-	link, clicks, err := service.GetLinkStats(shortCode)
+	link, totals, err := service.GetLinkStats(AdminOwner, shortCode, repository.ClickFilter{})
 	
 	assert.Error(t, err)
 	assert.Nil(t, link)
-	assert.Equal(t, 0, clicks)
+	assert.Equal(t, models.ClickTotals{}, totals)
 	assert.Equal(t, gorm.ErrRecordNotFound, err)
 	
 	mockRepo.AssertExpectations(t)
-} 
\ No newline at end of file
+} 
+func TestCreateLink_CustomAlias(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	mockRepo.On("GetLinkByShortCode", "spring-sale").Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(nil)
+
+	link, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{CustomAlias: "spring-sale"})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, link)
+	assert.Equal(t, "spring-sale", link.ShortCode)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateLink_CustomAliasAlreadyExists(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	mockRepo.On("GetLinkByShortCode", "spring-sale").Return(&models.Link{ID: 1, ShortCode: "spring-sale"}, nil)
+
+	link, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{CustomAlias: "spring-sale"})
+
+	assert.Nil(t, link)
+	assert.ErrorIs(t, err, ErrAliasAlreadyExists)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CreateLink", mock.Anything)
+}
+
+func TestCreateLink_CustomAliasConcurrentInsert(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	// Le code est libre lors de la vérification, puis pris par une autre requête avant l'insertion
+	mockRepo.On("GetLinkByShortCode", "spring-sale").Return(nil, gorm.ErrRecordNotFound).Once()
+	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(gorm.ErrDuplicatedKey)
+
+	link, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{CustomAlias: "spring-sale"})
+
+	assert.Nil(t, link)
+	assert.ErrorIs(t, err, ErrAliasAlreadyExists)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateLink_GeneratedCodeConcurrentInsert(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	// Le premier code est pris par une autre requête entre la vérification et l'insertion
+	mockRepo.On("GetLinkByShortCode", mock.AnythingOfType("string")).Return(nil, gorm.ErrRecordNotFound).Twice()
+	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(gorm.ErrDuplicatedKey).Once()
+	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(nil).Once()
+
+	link, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, link)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateLink_GeneratedCodeInsertRetriesExhausted(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	mockRepo.On("GetLinkByShortCode", mock.AnythingOfType("string")).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(gorm.ErrDuplicatedKey).Times(maxInsertAttempts)
+
+	link, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{})
+
+	assert.Nil(t, link)
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestValidateAlias(t *testing.T) {
+	assert.NoError(t, ValidateAlias("spring-sale"))
+	assert.NoError(t, ValidateAlias("Promo_2024"))
+
+	assert.ErrorIs(t, ValidateAlias("ab"), ErrInvalidAlias)
+	assert.ErrorIs(t, ValidateAlias("this-alias-is-definitely-way-too-long-to-be-accepted"), ErrInvalidAlias)
+	assert.ErrorIs(t, ValidateAlias("spring sale"), ErrInvalidAlias)
+	assert.ErrorIs(t, ValidateAlias("spring/sale"), ErrInvalidAlias)
+	assert.ErrorIs(t, ValidateAlias("health"), ErrReservedAlias)
+	assert.ErrorIs(t, ValidateAlias("API"), ErrReservedAlias)
+}
+
+func TestCreateLink_InvalidAlias(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	link, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{CustomAlias: "api"})
+
+	assert.Nil(t, link)
+	assert.ErrorIs(t, err, ErrReservedAlias)
+
+	mockRepo.AssertNotCalled(t, "GetLinkByShortCode", mock.Anything)
+	mockRepo.AssertNotCalled(t, "CreateLink", mock.Anything)
+}
+
+func TestCreateLink_WithExpiration(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	mockRepo.On("GetLinkByShortCode", mock.AnythingOfType("string")).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(nil)
+
+	link, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{ExpiresAt: &expiresAt, MaxClicks: 100})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &expiresAt, link.ExpiresAt)
+	assert.Equal(t, 100, link.MaxClicks)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateLink_InvalidExpiration(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	past := time.Now().Add(-time.Hour)
+
+	_, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrInvalidExpiration)
+
+	_, err = service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{MaxClicks: -1})
+	assert.ErrorIs(t, err, ErrInvalidExpiration)
+
+	mockRepo.AssertNotCalled(t, "CreateLink", mock.Anything)
+}
+
+func TestConsumeClick(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	past := time.Now().Add(-time.Hour)
+
+	// Lien sans limite : aucun accès à la base
+	assert.NoError(t, service.ConsumeClick(&models.Link{ID: 1}))
+
+	// Lien expiré par date
+	assert.ErrorIs(t, service.ConsumeClick(&models.Link{ID: 2, ExpiresAt: &past}), ErrLinkExpired)
+
+	// Lien limité en clics
+	mockRepo.On("ConsumeClick", uint(3)).Return(true, nil).Once()
+	mockRepo.On("ConsumeClick", uint(3)).Return(false, nil).Once()
+	assert.NoError(t, service.ConsumeClick(&models.Link{ID: 3, MaxClicks: 1}))
+	assert.ErrorIs(t, service.ConsumeClick(&models.Link{ID: 3, MaxClicks: 1}), ErrLinkExpired)
+
+	// Erreur de base de données
+	mockRepo.On("ConsumeClick", uint(4)).Return(false, errors.New("database error"))
+	err := service.ConsumeClick(&models.Link{ID: 4, MaxClicks: 1})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrLinkExpired)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListLinks_Defaults(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	expectedFilter := repository.LinkFilter{Page: 1, PageSize: DefaultPageSize, SortBy: "created_at", Domain: "example.com"}
+	expectedLinks := []models.Link{{ID: 1, ShortCode: "abc123"}}
+	mockRepo.On("ListLinks", expectedFilter).Return(expectedLinks, int64(1), nil)
+
+	links, total, err := service.ListLinks(AdminOwner, repository.LinkFilter{Domain: " Example.com "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedLinks, links)
+	assert.Equal(t, int64(1), total)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListLinks_PageSizeCapped(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	expectedFilter := repository.LinkFilter{Page: 3, PageSize: MaxPageSize, SortBy: "short_code", SortDesc: true}
+	mockRepo.On("ListLinks", expectedFilter).Return([]models.Link{}, int64(0), nil)
+
+	_, _, err := service.ListLinks(AdminOwner, repository.LinkFilter{Page: 3, PageSize: 1000, SortBy: "short_code", SortDesc: true})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListLinks_InvalidFilter(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	_, _, err := service.ListLinks(AdminOwner, repository.LinkFilter{SortBy: "password"})
+	assert.ErrorIs(t, err, ErrInvalidLinkFilter)
+
+	after := time.Now()
+	before := after.Add(-time.Hour)
+	_, _, err = service.ListLinks(AdminOwner, repository.LinkFilter{CreatedAfter: &after, CreatedBefore: &before})
+	assert.ErrorIs(t, err, ErrInvalidLinkFilter)
+
+	mockRepo.AssertNotCalled(t, "ListLinks", mock.Anything)
+}
+
+func TestUpdateLinkURL(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	existing := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com", Domain: "www.example.com"}
+	mockRepo.On("GetLinkByShortCode", "abc123").Return(existing, nil)
+	mockRepo.On("GetLinkByID", uint(1)).Return(existing, nil)
+	mockRepo.On("UpdateLinkURL", uint(1), "https://Docs.Example.org/page", "docs.example.org").Return(nil)
+
+	link, err := service.UpdateLinkURL(AdminOwner, "abc123", "https://Docs.Example.org/page")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://Docs.Example.org/page", link.LongURL)
+	assert.Equal(t, "docs.example.org", link.Domain)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateLinkURL_NotFound(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	mockRepo.On("GetLinkByShortCode", "nonexistent").Return(nil, gorm.ErrRecordNotFound)
+
+	link, err := service.UpdateLinkURL(AdminOwner, "nonexistent", "https://www.example.org")
+
+	assert.Nil(t, link)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	mockRepo.AssertNotCalled(t, "UpdateLinkURL", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetLinkWebhook(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	existing := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}
+	mockRepo.On("GetLinkByShortCode", "abc123").Return(existing, nil)
+	mockRepo.On("GetLinkByID", uint(1)).Return(existing, nil)
+	mockRepo.On("UpdateLinkWebhook", uint(1), "https://hooks.example.org/links", mock.MatchedBy(func(secret string) bool {
+		return len(secret) == 64
+	})).Return(nil)
+	mockRepo.On("UpdateLinkWebhook", uint(1), "", "").Return(nil)
+
+	link, err := service.SetLinkWebhook(AdminOwner, "abc123", "https://hooks.example.org/links")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.org/links", link.WebhookURL)
+	assert.Len(t, link.WebhookSecret, 64)
+
+	link, err = service.SetLinkWebhook(AdminOwner, "abc123", "")
+	assert.NoError(t, err)
+	assert.Empty(t, link.WebhookURL)
+	assert.Empty(t, link.WebhookSecret)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSetLinkWebhook_InvalidURL(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	for _, webhookURL := range []string{"not-a-url", "ftp://hooks.example.org", "https://"} {
+		link, err := service.SetLinkWebhook(AdminOwner, "abc123", webhookURL)
+		assert.Nil(t, link)
+		assert.ErrorIs(t, err, ErrInvalidWebhookURL, webhookURL)
+	}
+	mockRepo.AssertNotCalled(t, "GetLinkByShortCode", mock.Anything)
+}
+
+func TestCreateLink_InvalidWebhookURL(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	link, err := service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{WebhookURL: "hooks.example.org"})
+
+	assert.Nil(t, link)
+	assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+	mockRepo.AssertNotCalled(t, "CreateLink", mock.Anything)
+}
+
+func TestDeleteLink(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	mockRepo.On("GetLinkByShortCode", "abc123").Return(&models.Link{ID: 7, ShortCode: "abc123"}, nil)
+	mockRepo.On("GetLinkByID", uint(7)).Return(&models.Link{ID: 7, ShortCode: "abc123"}, nil)
+	mockRepo.On("DeleteLink", uint(7)).Return(nil)
+
+	err := service.DeleteLink(AdminOwner, "abc123")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteLink_NotFound(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	mockRepo.On("GetLinkByShortCode", "nonexistent").Return(nil, gorm.ErrRecordNotFound)
+
+	err := service.DeleteLink(AdminOwner, "nonexistent")
+
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	mockRepo.AssertNotCalled(t, "DeleteLink", mock.Anything)
+}
+
+func TestOwner_CanAccess(t *testing.T) {
+	aliceID, bobID := uint(1), uint(2)
+	owned := &models.Link{ID: 1, OwnerID: &aliceID}
+	unowned := &models.Link{ID: 2}
+
+	assert.True(t, AdminOwner.CanAccess(owned))
+	assert.True(t, AdminOwner.CanAccess(unowned))
+	assert.True(t, OwnedBy(aliceID).CanAccess(owned))
+	assert.False(t, OwnedBy(bobID).CanAccess(owned))
+	assert.False(t, OwnedBy(aliceID).CanAccess(unowned), "un lien sans propriétaire n'est accessible qu'aux administrateurs")
+	assert.False(t, Owner{}.CanAccess(owned))
+}
+
+func TestOwner_RoleFor(t *testing.T) {
+	aliceID, workspaceID := uint(1), uint(5)
+	teamLink := &models.Link{ID: 1, OwnerID: &aliceID, WorkspaceID: &workspaceID}
+
+	role, ok := OwnedBy(2).WithRoles(map[uint]models.Role{workspaceID: models.RoleViewer}).RoleFor(teamLink)
+	assert.True(t, ok)
+	assert.Equal(t, models.RoleViewer, role)
+
+	// L'auteur d'un lien d'espace de travail n'y a que son rôle de membre
+	role, ok = OwnedBy(aliceID).WithRoles(map[uint]models.Role{workspaceID: models.RoleEditor}).RoleFor(teamLink)
+	assert.True(t, ok)
+	assert.Equal(t, models.RoleEditor, role)
+	_, ok = OwnedBy(aliceID).RoleFor(teamLink)
+	assert.False(t, ok, "un ancien membre perd l'accès aux liens qu'il a créés dans l'espace de travail")
+
+	role, ok = AdminOwner.RoleFor(teamLink)
+	assert.True(t, ok)
+	assert.Equal(t, models.RoleOwner, role)
+}
+
+func TestCreateLink_SetsOwner(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	mockRepo.On("GetLinkByShortCode", mock.AnythingOfType("string")).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(nil)
+
+	link, err := service.CreateLink(OwnedBy(7), "https://www.example.com", CreateLinkOptions{})
+	assert.NoError(t, err)
+	if assert.NotNil(t, link.OwnerID) {
+		assert.Equal(t, uint(7), *link.OwnerID)
+	}
+
+	link, err = service.CreateLink(AdminOwner, "https://www.example.com", CreateLinkOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, link.OwnerID)
+}
+
+func TestListLinks_ScopedToOwner(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	owner := OwnedBy(7).WithRoles(map[uint]models.Role{5: models.RoleViewer, 2: models.RoleEditor})
+	expectedFilter := repository.LinkFilter{Page: 1, PageSize: DefaultPageSize, SortBy: "created_at",
+		Scope: &repository.LinkScope{UserID: 7, WorkspaceIDs: []uint{2, 5}}}
+	mockRepo.On("ListLinks", expectedFilter).Return([]models.Link{}, int64(0), nil)
+
+	// Le périmètre demandé est remplacé par celui de l'utilisateur
+	_, _, err := service.ListLinks(owner, repository.LinkFilter{Scope: &repository.LinkScope{UserID: 8}})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCheckAvailable(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, service.CheckAvailable(&models.Link{ID: 1}))
+	assert.NoError(t, service.CheckAvailable(&models.Link{ID: 2, MaxClicks: 2, ConsumedClicks: 1}))
+	assert.ErrorIs(t, service.CheckAvailable(&models.Link{ID: 3, ExpiresAt: &past}), ErrLinkExpired)
+	assert.ErrorIs(t, service.CheckAvailable(&models.Link{ID: 4, MaxClicks: 2, ConsumedClicks: 2}), ErrLinkExpired)
+
+	mockRepo.AssertNotCalled(t, "ConsumeClick", mock.Anything)
+}
+
+func TestListLinks_Workspace(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	workspaceID, otherID := uint(5), uint(6)
+	owner := OwnedBy(7).WithRoles(map[uint]models.Role{workspaceID: models.RoleViewer})
+	expectedFilter := repository.LinkFilter{Page: 1, PageSize: DefaultPageSize, SortBy: "created_at",
+		Scope: &repository.LinkScope{UserID: 7, WorkspaceIDs: []uint{workspaceID}}, WorkspaceID: &workspaceID}
+	mockRepo.On("ListLinks", expectedFilter).Return([]models.Link{}, int64(0), nil)
+
+	_, _, err := service.ListLinks(owner, repository.LinkFilter{WorkspaceID: &workspaceID})
+	assert.NoError(t, err)
+
+	_, _, err = service.ListLinks(owner, repository.LinkFilter{WorkspaceID: &otherID})
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLinkMutations_RejectOtherOwners(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	ownerID := uint(7)
+	// Servi par le cache, le lien ne porte pas son propriétaire : il est relu en base
+	mockRepo.On("GetLinkByShortCode", "abc123").Return(&models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com"}, nil)
+	mockRepo.On("GetLinkByID", uint(1)).Return(&models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com", OwnerID: &ownerID}, nil)
+
+	other := OwnedBy(8)
+	_, err := service.GetLink(other, "abc123")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, _, err = service.GetLinkStats(other, "abc123", repository.ClickFilter{})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = service.UpdateLinkURL(other, "abc123", "https://www.example.org")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = service.SetLinkWebhook(other, "abc123", "https://hooks.example.org/links")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, service.DeleteLink(other, "abc123"), gorm.ErrRecordNotFound)
+
+	mockRepo.AssertNotCalled(t, "UpdateLinkWebhook", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateLinkURL", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "DeleteLink", mock.Anything)
+
+	link, err := service.GetLink(OwnedBy(ownerID), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), link.ID)
+}
+
+func TestLinkMutations_RequireEditorRole(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	workspaceID := uint(5)
+	link := &models.Link{ID: 1, ShortCode: "abc123", LongURL: "https://www.example.com", WorkspaceID: &workspaceID}
+	mockRepo.On("GetLinkByShortCode", "abc123").Return(link, nil)
+	mockRepo.On("GetLinkByID", uint(1)).Return(link, nil)
+	mockRepo.On("CountClickTotals", uint(1), repository.ClickFilter{}).Return(models.ClickTotals{TotalClicks: 3}, nil)
+
+	viewer := OwnedBy(7).WithRoles(map[uint]models.Role{workspaceID: models.RoleViewer})
+	_, totals, err := service.GetLinkStats(viewer, "abc123", repository.ClickFilter{})
+	assert.NoError(t, err, "un lecteur consulte les statistiques")
+	assert.Equal(t, 3, totals.TotalClicks)
+
+	_, err = service.UpdateLinkURL(viewer, "abc123", "https://www.example.org")
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+	_, err = service.SetLinkWebhook(viewer, "abc123", "")
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+	assert.ErrorIs(t, service.DeleteLink(viewer, "abc123"), ErrInsufficientRole)
+	mockRepo.AssertNotCalled(t, "UpdateLinkWebhook", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateLinkURL", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "DeleteLink", mock.Anything)
+
+	mockRepo.On("DeleteLink", uint(1)).Return(nil)
+	editor := OwnedBy(8).WithRoles(map[uint]models.Role{workspaceID: models.RoleEditor})
+	assert.NoError(t, service.DeleteLink(editor, "abc123"))
+}
+
+func TestCreateLink_InWorkspace(t *testing.T) {
+	mockRepo := &MockLinkRepository{}
+	service := NewLinkService(mockRepo)
+
+	mockRepo.On("GetLinkByShortCode", mock.AnythingOfType("string")).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("CreateLink", mock.AnythingOfType("*models.Link")).Return(nil)
+
+	workspaceID, otherID := uint(5), uint(6)
+	owner := OwnedBy(7).WithRoles(map[uint]models.Role{workspaceID: models.RoleEditor, otherID: models.RoleViewer})
+
+	link, err := service.CreateLink(owner, "https://www.example.com", CreateLinkOptions{WorkspaceID: &workspaceID})
+	assert.NoError(t, err)
+	assert.Equal(t, &workspaceID, link.WorkspaceID)
+	assert.Equal(t, uint(7), *link.OwnerID, "l'auteur du lien est conservé")
+
+	_, err = service.CreateLink(owner, "https://www.example.com", CreateLinkOptions{WorkspaceID: &otherID})
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+
+	unknownID := uint(9)
+	_, err = service.CreateLink(owner, "https://www.example.com", CreateLinkOptions{WorkspaceID: &unknownID})
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+
+	mockRepo.AssertNumberOfCalls(t, "CreateLink", 1)
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+)
+
+var (
+	ErrLinkQuotaExceeded  = errors.New("monthly link quota exceeded")
+	ErrInvalidUsagePeriod = errors.New("invalid usage period")
+)
+
+// Quotas fixe la consommation mensuelle autorisée à chaque compte (utilisateur ou espace de
+// travail). Une limite nulle ou négative n'impose aucun quota.
+type Quotas struct {
+	LinksPerMonth         int64
+	TrackedClicksPerMonth int64
+}
+
+// QuotaUsage est la consommation d'un quota ; Limit est nil pour un quota illimité.
+type QuotaUsage struct {
+	Used  int64  `json:"used"`
+	Limit *int64 `json:"limit"`
+}
+
+func newQuotaUsage(used, limit int64) QuotaUsage {
+	usage := QuotaUsage{Used: used}
+	if limit > 0 {
+		usage.Limit = &limit
+	}
+	return usage
+}
+
+// Usage résume la consommation d'un compte sur un mois.
+type Usage struct {
+	Account       models.Account `json:"account"`
+	Period        string         `json:"period"`
+	Links         QuotaUsage     `json:"links"`
+	TrackedClicks QuotaUsage     `json:"tracked_clicks"`
+	ResetsAt      time.Time      `json:"resets_at"`
+}
+
+type UsageService struct {
+	usageRepo repository.UsageRepository
+	linkRepo  repository.LinkRepository
+	quotas    Quotas
+	now       func() time.Time
+}
+
+type UsageServiceInterface interface {
+	GetUsage(owner Owner, workspaceID *uint, period string) (*Usage, error)
+}
+
+func NewUsageService(usageRepo repository.UsageRepository, linkRepo repository.LinkRepository, quotas Quotas) *UsageService {
+	return &UsageService{
+		usageRepo: usageRepo,
+		linkRepo:  linkRepo,
+		quotas:    quotas,
+		now:       time.Now,
+	}
+}
+
+// GetUsage retourne la consommation de l'espace de travail, dont owner doit être membre, ou
+// à défaut celle de l'utilisateur de owner. Une période vide désigne le mois en cours.
+func (s *UsageService) GetUsage(owner Owner, workspaceID *uint, period string) (*Usage, error) {
+	var account models.Account
+	if workspaceID != nil {
+		if err := owner.requireRole(*workspaceID, models.RoleViewer); err != nil {
+			return nil, err
+		}
+		account = models.WorkspaceAccount(*workspaceID)
+	} else {
+		if owner.Admin || owner.UserID == 0 {
+			return nil, ErrUserRequired
+		}
+		account = models.UserAccount(owner.UserID)
+	}
+	return s.AccountUsage(account, period)
+}
+
+// AccountUsage retourne la consommation du compte sur la période (le mois en cours si vide).
+func (s *UsageService) AccountUsage(account models.Account, period string) (*Usage, error) {
+	period, err := s.parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := s.usageRepo.GetUsage(account, period)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		counter = &models.UsageCounter{AccountType: account.Type, AccountID: account.ID, Period: period}
+	} else if err != nil {
+		return nil, err
+	}
+	return s.newUsage(*counter), nil
+}
+
+// ListUsage retourne la consommation des comptes actifs sur la période (le mois en cours si vide).
+func (s *UsageService) ListUsage(period string) ([]Usage, error) {
+	period, err := s.parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	counters, err := s.usageRepo.ListUsage(period)
+	if err != nil {
+		return nil, err
+	}
+	usages := make([]Usage, 0, len(counters))
+	for _, counter := range counters {
+		usages = append(usages, *s.newUsage(counter))
+	}
+	return usages, nil
+}
+
+func (s *UsageService) parsePeriod(period string) (string, error) {
+	if period == "" {
+		return models.UsagePeriod(s.now()), nil
+	}
+	if _, err := time.Parse(models.UsagePeriodLayout, period); err != nil {
+		return "", fmt.Errorf("%w: '%s' (expected YYYY-MM)", ErrInvalidUsagePeriod, period)
+	}
+	return period, nil
+}
+
+func (s *UsageService) newUsage(counter models.UsageCounter) *Usage {
+	start, _ := time.Parse(models.UsagePeriodLayout, counter.Period)
+	return &Usage{
+		Account:       counter.Account(),
+		Period:        counter.Period,
+		Links:         newQuotaUsage(counter.LinksCreated, s.quotas.LinksPerMonth),
+		TrackedClicks: newQuotaUsage(counter.TrackedClicks, s.quotas.TrackedClicksPerMonth),
+		ResetsAt:      start.AddDate(0, 1, 0),
+	}
+}
+
+// linkReservation est un lien décompté du quota de son compte avant d'être créé.
+type linkReservation struct {
+	account models.Account
+	period  string
+}
+
+// reserveLink décompte un lien créé par le compte, ou retourne ErrLinkQuotaExceeded s'il
+// a déjà créé ce mois-ci le nombre de liens autorisé. La vérification et l'incrément sont
+// faits par une seule requête : des créations simultanées ne dépassent pas le quota.
+// Sans quota, une erreur de décompte est seulement journalisée (la réservation est alors nil).
+func (s *UsageService) reserveLink(account models.Account) (*linkReservation, error) {
+	reservation := &linkReservation{account: account, period: models.UsagePeriod(s.now())}
+	limit := s.quotas.LinksPerMonth
+	if limit <= 0 {
+		if err := s.usageRepo.AddUsage(account, reservation.period, 1, 0); err != nil {
+			log.Printf("Warning: Failed to record link creation for %s: %v", account, err)
+			return nil, nil
+		}
+		return reservation, nil
+	}
+
+	reserved, err := s.usageRepo.ReserveLink(account, reservation.period, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error checking link quota: %w", err)
+	}
+	if !reserved {
+		return nil, fmt.Errorf("%w: %d links per month", ErrLinkQuotaExceeded, limit)
+	}
+	return reservation, nil
+}
+
+// releaseLink restitue au quota un lien réservé dont la création a échoué.
+func (s *UsageService) releaseLink(reservation *linkReservation) {
+	if err := s.usageRepo.AddUsage(reservation.account, reservation.period, -1, 0); err != nil {
+		log.Printf("Warning: Failed to release link reservation for %s: %v", reservation.account, err)
+	}
+}
+
+type meteredPeriod struct {
+	account models.Account
+	period  string
+}
+
+// MeterClicks retourne les clics d'un lot qui restent dans le quota mensuel de clics de
+// leur compte (mois de chaque clic) ; les autres ne sont pas enregistrés. Les clics de
+// robots, exclus des statistiques par défaut, ne sont pas soumis au quota, pas plus que
+// ceux dont le compte ou la consommation ne peut être lu. Les clics ne sont décomptés
+// qu'une fois enregistrés (RecordClicks) : les workers comptant leurs lots en parallèle,
+// le quota peut être légèrement dépassé.
+func (s *UsageService) MeterClicks(clicks []*models.Click) []*models.Click {
+	limit := s.quotas.TrackedClicksPerMonth
+	if limit <= 0 {
+		return clicks
+	}
+
+	accounts := make(map[uint]*models.Account)
+	remaining := make(map[meteredPeriod]int64)
+	kept := make([]*models.Click, 0, len(clicks))
+
+	for _, click := range clicks {
+		key, ok := s.meteredPeriodOf(click, accounts)
+		if !ok {
+			kept = append(kept, click)
+			continue
+		}
+		left, ok := remaining[key]
+		if !ok {
+			left = limit - s.trackedClicks(key)
+		}
+		if left <= 0 {
+			remaining[key] = 0
+			continue
+		}
+		remaining[key] = left - 1
+		kept = append(kept, click)
+	}
+	return kept
+}
+
+// RecordClicks décompte les clics enregistrés, hors robots, dans la consommation de leur compte.
+func (s *UsageService) RecordClicks(clicks []*models.Click) {
+	accounts := make(map[uint]*models.Account)
+	counts := make(map[meteredPeriod]int64)
+	for _, click := range clicks {
+		if key, ok := s.meteredPeriodOf(click, accounts); ok {
+			counts[key]++
+		}
+	}
+
+	for key, count := range counts {
+		if err := s.usageRepo.AddUsage(key.account, key.period, 0, count); err != nil {
+			log.Printf("Warning: Failed to record %d tracked click(s) for %s: %v", count, key.account, err)
+		}
+	}
+}
+
+// meteredPeriodOf retourne le compte et le mois auxquels le clic est imputé, aucun pour
+// un clic de robot ; accounts garde le compte des liens déjà rencontrés dans le lot.
+func (s *UsageService) meteredPeriodOf(click *models.Click, accounts map[uint]*models.Account) (meteredPeriod, bool) {
+	if click.IsBot {
+		return meteredPeriod{}, false
+	}
+	account, ok := accounts[click.LinkID]
+	if !ok {
+		account = s.accountOfLink(click.LinkID)
+		accounts[click.LinkID] = account
+	}
+	if account == nil {
+		return meteredPeriod{}, false
+	}
+	return meteredPeriod{account: *account, period: models.UsagePeriod(click.Timestamp)}, true
+}
+
+// accountOfLink retourne le compte du lien, ou nil s'il n'en a pas ou n'existe plus.
+func (s *UsageService) accountOfLink(linkID uint) *models.Account {
+	link, err := s.linkRepo.GetLinkByID(linkID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Warning: Unable to find the account of link %d, its clicks are not metered: %v", linkID, err)
+		}
+		return nil
+	}
+	account, ok := models.AccountOf(link)
+	if !ok {
+		return nil
+	}
+	return &account
+}
+
+func (s *UsageService) trackedClicks(key meteredPeriod) int64 {
+	counter, err := s.usageRepo.GetUsage(key.account, key.period)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Warning: Unable to read the usage of %s: %v", key.account, err)
+		}
+		return 0
+	}
+	return counter.TrackedClicks
+}
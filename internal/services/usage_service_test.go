@@ -0,0 +1,197 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type usageFixture struct {
+	links    *LinkService
+	usage    *UsageService
+	linkRepo *repository.MemoryLinkRepository
+	now      *time.Time
+}
+
+func newUsageFixture(quotas Quotas) *usageFixture {
+	now := time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC)
+	store := repository.NewMemoryStore()
+	linkRepo := repository.NewMemoryLinkRepository(store)
+	usage := NewUsageService(repository.NewMemoryUsageRepository(store), linkRepo, quotas)
+	usage.now = func() time.Time { return now }
+	links := NewLinkService(linkRepo)
+	links.SetUsageService(usage)
+	return &usageFixture{links: links, usage: usage, linkRepo: linkRepo, now: &now}
+}
+
+func (f *usageFixture) createLink(t *testing.T, owner Owner, opts CreateLinkOptions) *models.Link {
+	t.Helper()
+	link, err := f.links.CreateLink(owner, "https://www.example.com", opts)
+	require.NoError(t, err)
+	return link
+}
+
+func clicksOf(link *models.Link, timestamp time.Time, n int) []*models.Click {
+	clicks := make([]*models.Click, n)
+	for i := range clicks {
+		clicks[i] = &models.Click{LinkID: link.ID, Timestamp: timestamp}
+	}
+	return clicks
+}
+
+func TestCreateLink_LinkQuota(t *testing.T) {
+	f := newUsageFixture(Quotas{LinksPerMonth: 2})
+	alice := OwnedBy(1).WithRoles(map[uint]models.Role{3: models.RoleEditor})
+	workspaceID := uint(3)
+
+	f.createLink(t, alice, CreateLinkOptions{})
+	f.createLink(t, alice, CreateLinkOptions{})
+	_, err := f.links.CreateLink(alice, "https://www.example.com", CreateLinkOptions{})
+	assert.ErrorIs(t, err, ErrLinkQuotaExceeded)
+
+	// Les liens d'un espace de travail sont imputés à l'espace, ceux d'un administrateur à personne
+	f.createLink(t, alice, CreateLinkOptions{WorkspaceID: &workspaceID})
+	for i := 0; i < 3; i++ {
+		f.createLink(t, AdminOwner, CreateLinkOptions{})
+	}
+
+	usage, err := f.usage.GetUsage(alice, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Links.Used)
+	usage, err = f.usage.GetUsage(alice, &workspaceID, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Links.Used)
+
+	// Le quota repart à zéro chaque mois
+	*f.now = f.now.Add(time.Hour)
+	f.createLink(t, alice, CreateLinkOptions{})
+}
+
+func TestCreateLink_LinkQuotaUnderConcurrency(t *testing.T) {
+	f := newUsageFixture(Quotas{LinksPerMonth: 5})
+	alice := OwnedBy(1)
+
+	var created atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.links.CreateLink(alice, "https://www.example.com", CreateLinkOptions{}); err == nil {
+				created.Add(1)
+			} else {
+				assert.ErrorIs(t, err, ErrLinkQuotaExceeded)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(5), created.Load())
+	usage, err := f.usage.GetUsage(alice, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), usage.Links.Used)
+}
+
+func TestCreateLink_ReleasesLinkQuotaOnFailure(t *testing.T) {
+	f := newUsageFixture(Quotas{LinksPerMonth: 1})
+	alice := OwnedBy(1)
+	f.createLink(t, AdminOwner, CreateLinkOptions{CustomAlias: "taken"})
+
+	_, err := f.links.CreateLink(alice, "https://www.example.com", CreateLinkOptions{CustomAlias: "taken"})
+	assert.ErrorIs(t, err, ErrAliasAlreadyExists)
+
+	// La création échouée n'a pas consommé le quota
+	f.createLink(t, alice, CreateLinkOptions{})
+	usage, err := f.usage.GetUsage(alice, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Links.Used)
+}
+
+func TestMeterClicks_TrackedClicksQuota(t *testing.T) {
+	f := newUsageFixture(Quotas{TrackedClicksPerMonth: 3})
+	personal := f.createLink(t, OwnedBy(1), CreateLinkOptions{})
+	admin := f.createLink(t, AdminOwner, CreateLinkOptions{})
+	march := *f.now
+
+	kept := f.usage.MeterClicks(append(clicksOf(personal, march, 2), clicksOf(admin, march, 2)...))
+	assert.Len(t, kept, 4)
+	f.usage.RecordClicks(kept)
+
+	// Des clics retenus mais pas enregistrés ne consomment pas le quota
+	assert.Len(t, f.usage.MeterClicks(clicksOf(personal, march, 1)), 1)
+
+	kept = f.usage.MeterClicks(append(clicksOf(personal, march, 2), clicksOf(personal, march.Add(time.Hour), 1)...))
+	require.Len(t, kept, 2, "un seul clic de mars reste dans le quota")
+	assert.Equal(t, "2024-04", models.UsagePeriod(kept[1].Timestamp))
+	f.usage.RecordClicks(kept)
+
+	usage, err := f.usage.AccountUsage(models.UserAccount(1), "2024-03")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), usage.TrackedClicks.Used)
+	usage, err = f.usage.AccountUsage(models.UserAccount(1), "2024-04")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.TrackedClicks.Used)
+
+	// Les clics d'un lien supprimé sont conservés sans être décomptés
+	require.NoError(t, f.linkRepo.DeleteLink(personal.ID))
+	assert.Len(t, f.usage.MeterClicks(clicksOf(personal, march, 2)), 2)
+}
+
+func TestMeterClicks_IgnoresBots(t *testing.T) {
+	f := newUsageFixture(Quotas{TrackedClicksPerMonth: 1})
+	link := f.createLink(t, OwnedBy(1), CreateLinkOptions{})
+	march := *f.now
+
+	bots := clicksOf(link, march, 3)
+	for _, click := range bots {
+		click.IsBot = true
+	}
+	clicks := append(bots, clicksOf(link, march, 1)...)
+	kept := f.usage.MeterClicks(clicks)
+	assert.Len(t, kept, 4, "les robots ne sont pas soumis au quota")
+	f.usage.RecordClicks(kept)
+
+	usage, err := f.usage.AccountUsage(models.UserAccount(1), "2024-03")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.TrackedClicks.Used)
+}
+
+func TestUsageService_GetUsage(t *testing.T) {
+	f := newUsageFixture(Quotas{LinksPerMonth: 100})
+	bob := OwnedBy(2).WithRoles(map[uint]models.Role{3: models.RoleViewer})
+	workspaceID, otherWorkspaceID := uint(3), uint(4)
+
+	usage, err := f.usage.GetUsage(bob, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.UserAccount(2), usage.Account)
+	assert.Equal(t, "2024-03", usage.Period)
+	assert.Equal(t, int64(0), usage.Links.Used)
+	require.NotNil(t, usage.Links.Limit)
+	assert.Equal(t, int64(100), *usage.Links.Limit)
+	assert.Nil(t, usage.TrackedClicks.Limit, "un quota nul est illimité")
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), usage.ResetsAt)
+
+	usage, err = f.usage.GetUsage(bob, &workspaceID, "2023-12")
+	require.NoError(t, err)
+	assert.Equal(t, models.WorkspaceAccount(3), usage.Account)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), usage.ResetsAt)
+
+	_, err = f.usage.GetUsage(bob, &otherWorkspaceID, "")
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+	_, err = f.usage.GetUsage(AdminOwner, nil, "")
+	assert.ErrorIs(t, err, ErrUserRequired)
+	_, err = f.usage.GetUsage(bob, nil, "2024-3")
+	assert.ErrorIs(t, err, ErrInvalidUsagePeriod)
+
+	f.createLink(t, OwnedBy(1), CreateLinkOptions{})
+	usages, err := f.usage.ListUsage("")
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, models.UserAccount(1), usages[0].Account)
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+)
+
+// Les jetons de session sont "uss_" suivi de 32 octets aléatoires en hexadécimal.
+const (
+	sessionTokenScheme = "uss_"
+	sessionTokenBytes  = 32
+)
+
+const (
+	DefaultSessionTTL = 30 * 24 * time.Hour
+	MinPasswordLength = 8
+	// MaxPasswordLength est la limite de bcrypt, qui ignorerait les caractères suivants.
+	MaxPasswordLength = 72
+)
+
+var (
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrEmailAlreadyUsed   = errors.New("email already registered")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+// UserServiceOptions règle la durée des sessions et le coût du hachage des mots de passe.
+type UserServiceOptions struct {
+	SessionTTL time.Duration
+	BcryptCost int
+}
+
+func (o UserServiceOptions) withDefaults() UserServiceOptions {
+	if o.SessionTTL <= 0 {
+		o.SessionTTL = DefaultSessionTTL
+	}
+	if o.BcryptCost == 0 {
+		o.BcryptCost = bcrypt.DefaultCost
+	}
+	return o
+}
+
+// SessionToken est le jeton remis à l'ouverture d'une session, à présenter dans
+// l'en-tête "Authorization: Bearer <jeton>". Il n'est conservé que sous forme d'empreinte.
+type SessionToken struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      *models.User `json:"user"`
+}
+
+type UserService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	opts        UserServiceOptions
+	now         func() time.Time
+
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+}
+
+type UserServiceInterface interface {
+	Register(email, password string) (*SessionToken, error)
+	Login(email, password string) (*SessionToken, error)
+	AuthenticateSession(token string) (*models.Session, error)
+	Logout(token string) error
+}
+
+func NewUserService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, opts UserServiceOptions) *UserService {
+	return &UserService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		opts:        opts.withDefaults(),
+		now:         time.Now,
+	}
+}
+
+// NormalizeEmail retourne l'adresse en minuscules, sans nom ni espaces, ou ErrInvalidEmail.
+func NormalizeEmail(email string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" || len(address.Address) > 255 {
+		return "", fmt.Errorf("%w: '%s'", ErrInvalidEmail, email)
+	}
+	return strings.ToLower(address.Address), nil
+}
+
+// CreateUser crée un compte sans ouvrir de session (commande user create).
+func (s *UserService) CreateUser(email, password string) (*models.User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return nil, fmt.Errorf("%w: must be between %d and %d characters", ErrInvalidPassword, MinPasswordLength, MaxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.opts.BcryptCost)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing password: %w", err)
+	}
+
+	user := &models.User{Email: email, PasswordHash: string(hash), CreatedAt: s.now().UTC()}
+	if err := s.userRepo.CreateUser(user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: '%s'", ErrEmailAlreadyUsed, email)
+		}
+		return nil, fmt.Errorf("error creating user: %w", err)
+	}
+	return user, nil
+}
+
+// Register crée un compte et ouvre sa première session.
+func (s *UserService) Register(email, password string) (*SessionToken, error) {
+	user, err := s.CreateUser(email, password)
+	if err != nil {
+		return nil, err
+	}
+	return s.startSession(user)
+}
+
+// Login vérifie les identifiants et ouvre une session. Une adresse inconnue et un
+// mauvais mot de passe retournent la même erreur, en un temps comparable.
+func (s *UserService) Login(email, password string) (*SessionToken, error) {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.userRepo.GetUserByEmail(normalized)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = bcrypt.CompareHashAndPassword(s.getDummyHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if deleted, err := s.sessionRepo.DeleteExpiredSessions(s.now().UTC()); err != nil {
+		log.Printf("Warning: Failed to delete expired sessions: %v", err)
+	} else if deleted > 0 {
+		log.Printf("%d expired session(s) deleted.", deleted)
+	}
+	return s.startSession(user)
+}
+
+func (s *UserService) getDummyHash() []byte {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), s.opts.BcryptCost)
+	})
+	return s.dummyHash
+}
+
+func (s *UserService) startSession(user *models.User) (*SessionToken, error) {
+	secret, err := randomHex(sessionTokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error generating session token: %w", err)
+	}
+	token := sessionTokenScheme + secret
+
+	now := s.now().UTC()
+	session := &models.Session{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.opts.SessionTTL),
+	}
+	if err := s.sessionRepo.CreateSession(session); err != nil {
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+	return &SessionToken{Token: token, ExpiresAt: session.ExpiresAt, User: user}, nil
+}
+
+// IsSessionToken indique si la valeur présentée a le format d'un jeton de session
+// (et non d'une clé d'API).
+func IsSessionToken(token string) bool {
+	return strings.HasPrefix(token, sessionTokenScheme)
+}
+
+// AuthenticateSession retourne la session en cours (avec son utilisateur) correspondant
+// au jeton, ou ErrInvalidSession s'il est inconnu ou expiré.
+func (s *UserService) AuthenticateSession(token string) (*models.Session, error) {
+	if !IsSessionToken(token) {
+		return nil, ErrInvalidSession
+	}
+	session, err := s.sessionRepo.GetSessionByTokenHash(hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.IsExpired(s.now()) {
+		return nil, ErrInvalidSession
+	}
+	return session, nil
+}
+
+// Logout ferme la session du jeton.
+func (s *UserService) Logout(token string) error {
+	session, err := s.AuthenticateSession(token)
+	if err != nil {
+		return err
+	}
+	return s.sessionRepo.DeleteSession(session.ID)
+}
+
+// GetUserByEmail retourne le compte de cette adresse, ou ErrUserNotFound.
+func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByEmail(normalized)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: '%s'", ErrUserNotFound, normalized)
+	}
+	return user, err
+}
+
+func (s *UserService) ListUsers() ([]models.User, error) {
+	return s.userRepo.ListUsers()
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestUserService() (*UserService, *time.Time) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	store := repository.NewMemoryStore()
+	service := NewUserService(repository.NewMemoryUserRepository(store), repository.NewMemorySessionRepository(store),
+		UserServiceOptions{SessionTTL: time.Hour, BcryptCost: bcrypt.MinCost})
+	service.now = func() time.Time { return now }
+	return service, &now
+}
+
+func TestUserService_Register(t *testing.T) {
+	service, now := newTestUserService()
+
+	session, err := service.Register("  Alice@Example.COM ", "correct-horse")
+	require.NoError(t, err)
+	assert.Regexp(t, `^uss_[0-9a-f]{64}$`, session.Token)
+	assert.Equal(t, "alice@example.com", session.User.Email)
+	assert.True(t, now.Add(time.Hour).Equal(session.ExpiresAt))
+	// Seule l'empreinte bcrypt du mot de passe est conservée
+	assert.NotContains(t, session.User.PasswordHash, "correct-horse")
+
+	_, err = service.Register("alice@example.com", "another-password")
+	assert.ErrorIs(t, err, ErrEmailAlreadyUsed)
+
+	for _, email := range []string{"", "alice", "Alice <alice@example.com>"} {
+		_, err = service.Register(email, "correct-horse")
+		assert.ErrorIs(t, err, ErrInvalidEmail, email)
+	}
+	for _, password := range []string{"short", string(make([]byte, MaxPasswordLength+1))} {
+		_, err = service.Register("bob@example.com", password)
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+	}
+}
+
+func TestUserService_LoginAndAuthenticate(t *testing.T) {
+	service, _ := newTestUserService()
+	_, err := service.CreateUser("alice@example.com", "correct-horse")
+	require.NoError(t, err)
+
+	for _, credentials := range [][2]string{
+		{"alice@example.com", "wrong-password"},
+		{"bob@example.com", "correct-horse"},
+		{"not-an-email", "correct-horse"},
+	} {
+		_, err := service.Login(credentials[0], credentials[1])
+		assert.ErrorIs(t, err, ErrInvalidCredentials, credentials[0])
+	}
+
+	session, err := service.Login("ALICE@example.com", "correct-horse")
+	require.NoError(t, err)
+
+	authenticated, err := service.AuthenticateSession(session.Token)
+	require.NoError(t, err)
+	assert.Equal(t, session.User.ID, authenticated.UserID)
+	require.NotNil(t, authenticated.User)
+	assert.Equal(t, "alice@example.com", authenticated.User.Email)
+
+	for _, token := range []string{"", "usk_12345678_secret", session.Token + "0", "uss_" + session.Token[4:len(session.Token)-1] + "0"} {
+		_, err := service.AuthenticateSession(token)
+		assert.ErrorIs(t, err, ErrInvalidSession, token)
+	}
+}
+
+func TestUserService_SessionExpiration(t *testing.T) {
+	service, now := newTestUserService()
+	session, err := service.Register("alice@example.com", "correct-horse")
+	require.NoError(t, err)
+
+	*now = now.Add(time.Hour)
+	_, err = service.AuthenticateSession(session.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	// Une nouvelle connexion supprime les sessions expirées
+	_, err = service.Login("alice@example.com", "correct-horse")
+	require.NoError(t, err)
+	_, err = service.sessionRepo.GetSessionByTokenHash(hashToken(session.Token))
+	assert.Error(t, err)
+}
+
+func TestUserService_Logout(t *testing.T) {
+	service, _ := newTestUserService()
+	first, err := service.Register("alice@example.com", "correct-horse")
+	require.NoError(t, err)
+	second, err := service.Login("alice@example.com", "correct-horse")
+	require.NoError(t, err)
+
+	require.NoError(t, service.Logout(first.Token))
+	_, err = service.AuthenticateSession(first.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	assert.ErrorIs(t, service.Logout(first.Token), ErrInvalidSession)
+
+	// Les autres sessions restent ouvertes
+	_, err = service.AuthenticateSession(second.Token)
+	assert.NoError(t, err)
+}
+
+func TestUserService_GetUserByEmail(t *testing.T) {
+	service, _ := newTestUserService()
+	created, err := service.CreateUser("alice@example.com", "correct-horse")
+	require.NoError(t, err)
+
+	user, err := service.GetUserByEmail(" Alice@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, user.ID)
+
+	_, err = service.GetUserByEmail("bob@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
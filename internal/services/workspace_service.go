@@ -0,0 +1,385 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+)
+
+// Les jetons d'invitation sont "usi_" suivi de 32 octets aléatoires en hexadécimal.
+const (
+	invitationTokenScheme = "usi_"
+	invitationTokenBytes  = 32
+)
+
+const (
+	DefaultInvitationTTL   = 7 * 24 * time.Hour
+	MaxWorkspaceNameLength = 100
+)
+
+var (
+	ErrWorkspaceNotFound       = errors.New("workspace not found")
+	ErrInsufficientRole        = errors.New("insufficient role")
+	ErrInvalidRole             = errors.New("invalid role")
+	ErrInvalidWorkspaceName    = errors.New("invalid workspace name")
+	ErrUserRequired            = errors.New("this action requires a user account")
+	ErrMemberNotFound          = errors.New("member not found")
+	ErrAlreadyMember           = errors.New("user is already a member of the workspace")
+	ErrLastOwner               = errors.New("workspace must keep at least one owner")
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvalidInvitation       = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to another email address")
+)
+
+// WorkspaceWithRole est un espace de travail accompagné du rôle de l'auteur de la requête.
+type WorkspaceWithRole struct {
+	models.Workspace
+	Role models.Role `json:"role"`
+}
+
+type WorkspaceService struct {
+	workspaceRepo  repository.WorkspaceRepository
+	invitationRepo repository.InvitationRepository
+	userRepo       repository.UserRepository
+	now            func() time.Time
+}
+
+type WorkspaceServiceInterface interface {
+	GetRoles(userID uint) (map[uint]models.Role, error)
+	CreateWorkspace(owner Owner, name string) (*WorkspaceWithRole, error)
+	ListWorkspaces(owner Owner) ([]WorkspaceWithRole, error)
+	GetWorkspace(owner Owner, workspaceID uint) (*WorkspaceWithRole, error)
+	ListMembers(owner Owner, workspaceID uint) ([]models.Membership, error)
+	UpdateMemberRole(owner Owner, workspaceID, userID uint, role models.Role) (*models.Membership, error)
+	RemoveMember(owner Owner, workspaceID, userID uint) error
+	CreateInvitation(owner Owner, workspaceID uint, email string, role models.Role) (*models.Invitation, string, error)
+	ListInvitations(owner Owner, workspaceID uint) ([]models.Invitation, error)
+	RevokeInvitation(owner Owner, workspaceID, invitationID uint) error
+	AcceptInvitation(owner Owner, token string) (*models.Membership, error)
+}
+
+func NewWorkspaceService(workspaceRepo repository.WorkspaceRepository, invitationRepo repository.InvitationRepository, userRepo repository.UserRepository) *WorkspaceService {
+	return &WorkspaceService{
+		workspaceRepo:  workspaceRepo,
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		now:            time.Now,
+	}
+}
+
+// ParseRole retourne le rôle nommé (owner, editor ou viewer), ou ErrInvalidRole.
+func ParseRole(name string) (models.Role, error) {
+	role := models.Role(strings.ToLower(strings.TrimSpace(name)))
+	if !role.IsValid() {
+		return "", fmt.Errorf("%w: '%s' (expected owner, editor or viewer)", ErrInvalidRole, name)
+	}
+	return role, nil
+}
+
+// GetRoles retourne le rôle de l'utilisateur dans chacun de ses espaces de travail.
+func (s *WorkspaceService) GetRoles(userID uint) (map[uint]models.Role, error) {
+	memberships, err := s.workspaceRepo.ListMembershipsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[uint]models.Role, len(memberships))
+	for _, membership := range memberships {
+		roles[membership.WorkspaceID] = membership.Role
+	}
+	return roles, nil
+}
+
+// CreateWorkspace crée un espace de travail dont l'utilisateur de owner est propriétaire.
+func (s *WorkspaceService) CreateWorkspace(owner Owner, name string) (*WorkspaceWithRole, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxWorkspaceNameLength {
+		return nil, fmt.Errorf("%w: must be between 1 and %d characters", ErrInvalidWorkspaceName, MaxWorkspaceNameLength)
+	}
+	if owner.UserID == 0 {
+		return nil, ErrUserRequired
+	}
+
+	now := s.now().UTC()
+	workspace := &models.Workspace{Name: name, CreatedAt: now}
+	membership := &models.Membership{UserID: owner.UserID, Role: models.RoleOwner, CreatedAt: now}
+	if err := s.workspaceRepo.CreateWorkspace(workspace, membership); err != nil {
+		return nil, fmt.Errorf("error creating workspace: %w", err)
+	}
+	return &WorkspaceWithRole{Workspace: *workspace, Role: models.RoleOwner}, nil
+}
+
+// ListWorkspaces retourne les espaces de travail de l'utilisateur, ou tous pour un administrateur.
+func (s *WorkspaceService) ListWorkspaces(owner Owner) ([]WorkspaceWithRole, error) {
+	if owner.Admin {
+		workspaces, err := s.workspaceRepo.ListWorkspaces()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]WorkspaceWithRole, 0, len(workspaces))
+		for _, workspace := range workspaces {
+			result = append(result, WorkspaceWithRole{Workspace: workspace, Role: models.RoleOwner})
+		}
+		return result, nil
+	}
+
+	memberships, err := s.workspaceRepo.ListMembershipsByUser(owner.UserID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]WorkspaceWithRole, 0, len(memberships))
+	for _, membership := range memberships {
+		if membership.Workspace != nil {
+			result = append(result, WorkspaceWithRole{Workspace: *membership.Workspace, Role: membership.Role})
+		}
+	}
+	return result, nil
+}
+
+func (s *WorkspaceService) GetWorkspace(owner Owner, workspaceID uint) (*WorkspaceWithRole, error) {
+	return s.authorize(owner, workspaceID, models.RoleViewer)
+}
+
+// authorize retourne l'espace de travail si owner y a au moins le rôle required.
+func (s *WorkspaceService) authorize(owner Owner, workspaceID uint, required models.Role) (*WorkspaceWithRole, error) {
+	if err := owner.requireRole(workspaceID, required); err != nil {
+		return nil, err
+	}
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(workspaceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrWorkspaceNotFound, workspaceID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	role, _ := owner.RoleIn(workspaceID)
+	return &WorkspaceWithRole{Workspace: *workspace, Role: role}, nil
+}
+
+// ListMembers retourne les membres de l'espace de travail, visibles de tous ses membres.
+func (s *WorkspaceService) ListMembers(owner Owner, workspaceID uint) ([]models.Membership, error) {
+	if _, err := s.authorize(owner, workspaceID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.workspaceRepo.ListMembers(workspaceID)
+}
+
+// AddMember ajoute directement un utilisateur existant à l'espace de travail, sans
+// invitation (commande workspace add-member).
+func (s *WorkspaceService) AddMember(owner Owner, workspaceID uint, email string, role models.Role) (*models.Membership, error) {
+	if !role.IsValid() {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidRole, role)
+	}
+	if _, err := s.authorize(owner, workspaceID, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	user, err := s.findUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	membership := &models.Membership{WorkspaceID: workspaceID, UserID: user.ID, Role: role, CreatedAt: s.now().UTC()}
+	if err := s.workspaceRepo.CreateMembership(membership); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: '%s'", ErrAlreadyMember, user.Email)
+		}
+		return nil, fmt.Errorf("error adding member: %w", err)
+	}
+	membership.User = user
+	return membership, nil
+}
+
+func (s *WorkspaceService) findUserByEmail(email string) (*models.User, error) {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByEmail(normalized)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: '%s'", ErrUserNotFound, normalized)
+	}
+	return user, err
+}
+
+// UpdateMemberRole change le rôle d'un membre. Seul un propriétaire le peut, et le dernier
+// propriétaire ne peut pas être rétrogradé.
+func (s *WorkspaceService) UpdateMemberRole(owner Owner, workspaceID, userID uint, role models.Role) (*models.Membership, error) {
+	if !role.IsValid() {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidRole, role)
+	}
+	if _, err := s.authorize(owner, workspaceID, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	membership, err := s.getMembership(workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.Role == models.RoleOwner && role != models.RoleOwner {
+		if err := s.ensureAnotherOwner(workspaceID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.workspaceRepo.UpdateMembershipRole(workspaceID, userID, role); err != nil {
+		return nil, fmt.Errorf("error updating member role: %w", err)
+	}
+	membership.Role = role
+	return membership, nil
+}
+
+// RemoveMember retire un membre de l'espace de travail. Un propriétaire peut retirer
+// n'importe quel membre, chaque membre peut se retirer lui-même ; le dernier propriétaire
+// ne peut pas partir.
+func (s *WorkspaceService) RemoveMember(owner Owner, workspaceID, userID uint) error {
+	required := models.RoleOwner
+	if !owner.Admin && owner.UserID == userID {
+		required = models.RoleViewer
+	}
+	if _, err := s.authorize(owner, workspaceID, required); err != nil {
+		return err
+	}
+	membership, err := s.getMembership(workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if membership.Role == models.RoleOwner {
+		if err := s.ensureAnotherOwner(workspaceID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.workspaceRepo.DeleteMembership(workspaceID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: user %d", ErrMemberNotFound, userID)
+		}
+		return fmt.Errorf("error removing member: %w", err)
+	}
+	return nil
+}
+
+func (s *WorkspaceService) getMembership(workspaceID, userID uint) (*models.Membership, error) {
+	membership, err := s.workspaceRepo.GetMembership(workspaceID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: user %d", ErrMemberNotFound, userID)
+	}
+	return membership, err
+}
+
+func (s *WorkspaceService) ensureAnotherOwner(workspaceID uint) error {
+	owners, err := s.workspaceRepo.CountMembersByRole(workspaceID, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// CreateInvitation invite une adresse e-mail à rejoindre l'espace de travail avec un rôle
+// et retourne le jeton à lui transmettre, qui n'est affiché qu'une fois.
+func (s *WorkspaceService) CreateInvitation(owner Owner, workspaceID uint, email string, role models.Role) (*models.Invitation, string, error) {
+	if !role.IsValid() {
+		return nil, "", fmt.Errorf("%w: '%s'", ErrInvalidRole, role)
+	}
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := s.authorize(owner, workspaceID, models.RoleOwner); err != nil {
+		return nil, "", err
+	}
+
+	if user, err := s.userRepo.GetUserByEmail(email); err == nil {
+		if _, err := s.workspaceRepo.GetMembership(workspaceID, user.ID); err == nil {
+			return nil, "", fmt.Errorf("%w: '%s'", ErrAlreadyMember, email)
+		}
+	}
+
+	secret, err := randomHex(invitationTokenBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating invitation token: %w", err)
+	}
+	token := invitationTokenScheme + secret
+
+	now := s.now().UTC()
+	invitation := &models.Invitation{
+		WorkspaceID: workspaceID,
+		Email:       email,
+		Role:        role,
+		TokenHash:   hashToken(token),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(DefaultInvitationTTL),
+	}
+	if err := s.invitationRepo.CreateInvitation(invitation); err != nil {
+		return nil, "", fmt.Errorf("error creating invitation: %w", err)
+	}
+	return invitation, token, nil
+}
+
+func (s *WorkspaceService) ListInvitations(owner Owner, workspaceID uint) ([]models.Invitation, error) {
+	if _, err := s.authorize(owner, workspaceID, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	return s.invitationRepo.ListInvitations(workspaceID)
+}
+
+func (s *WorkspaceService) RevokeInvitation(owner Owner, workspaceID, invitationID uint) error {
+	if _, err := s.authorize(owner, workspaceID, models.RoleOwner); err != nil {
+		return err
+	}
+	if err := s.invitationRepo.DeleteInvitation(workspaceID, invitationID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %d", ErrInvitationNotFound, invitationID)
+		}
+		return err
+	}
+	return nil
+}
+
+// AcceptInvitation fait de l'utilisateur un membre de l'espace de travail, avec le rôle de
+// l'invitation. L'invitation doit lui avoir été adressée et n'est utilisable qu'une fois.
+func (s *WorkspaceService) AcceptInvitation(owner Owner, token string) (*models.Membership, error) {
+	if owner.UserID == 0 {
+		return nil, ErrUserRequired
+	}
+	if !strings.HasPrefix(token, invitationTokenScheme) {
+		return nil, ErrInvalidInvitation
+	}
+	invitation, err := s.invitationRepo.GetInvitationByTokenHash(hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidInvitation
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invitation.IsExpired(s.now()) {
+		return nil, ErrInvalidInvitation
+	}
+
+	user, err := s.userRepo.GetUserByID(owner.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != invitation.Email {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	membership := &models.Membership{WorkspaceID: invitation.WorkspaceID, UserID: user.ID, Role: invitation.Role, CreatedAt: s.now().UTC()}
+	if err := s.invitationRepo.AcceptInvitation(invitation.ID, membership); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: '%s'", ErrAlreadyMember, user.Email)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, fmt.Errorf("error accepting invitation: %w", err)
+	}
+	membership.Workspace = invitation.Workspace
+	return membership, nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type workspaceFixture struct {
+	service *WorkspaceService
+	now     *time.Time
+	users   map[string]*models.User
+}
+
+// newWorkspaceFixture crée les utilisateurs alice, bob et carol, sans espace de travail.
+func newWorkspaceFixture(t *testing.T) *workspaceFixture {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	store := repository.NewMemoryStore()
+	userRepo := repository.NewMemoryUserRepository(store)
+	service := NewWorkspaceService(repository.NewMemoryWorkspaceRepository(store), repository.NewMemoryInvitationRepository(store), userRepo)
+	service.now = func() time.Time { return now }
+
+	users := make(map[string]*models.User)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		user := &models.User{Email: name + "@example.com", PasswordHash: "hash"}
+		require.NoError(t, userRepo.CreateUser(user))
+		users[name] = user
+	}
+	return &workspaceFixture{service: service, now: &now, users: users}
+}
+
+// owner retourne l'identité de l'utilisateur avec ses rôles actuels, comme LoadWorkspaceRoles.
+func (f *workspaceFixture) owner(t *testing.T, name string) Owner {
+	userID := f.users[name].ID
+	roles, err := f.service.GetRoles(userID)
+	require.NoError(t, err)
+	return OwnedBy(userID).WithRoles(roles)
+}
+
+func TestWorkspaceService_CreateAndList(t *testing.T) {
+	f := newWorkspaceFixture(t)
+
+	workspace, err := f.service.CreateWorkspace(f.owner(t, "alice"), "  Marketing ")
+	require.NoError(t, err)
+	assert.Equal(t, "Marketing", workspace.Name)
+	assert.Equal(t, models.RoleOwner, workspace.Role)
+
+	_, err = f.service.CreateWorkspace(f.owner(t, "alice"), " ")
+	assert.ErrorIs(t, err, ErrInvalidWorkspaceName)
+	_, err = f.service.CreateWorkspace(AdminOwner, "Support")
+	assert.ErrorIs(t, err, ErrUserRequired, "un espace de travail a toujours un propriétaire")
+
+	workspaces, err := f.service.ListWorkspaces(f.owner(t, "alice"))
+	require.NoError(t, err)
+	require.Len(t, workspaces, 1)
+	assert.Equal(t, workspace.ID, workspaces[0].ID)
+
+	workspaces, err = f.service.ListWorkspaces(f.owner(t, "bob"))
+	require.NoError(t, err)
+	assert.Empty(t, workspaces)
+
+	workspaces, err = f.service.ListWorkspaces(AdminOwner)
+	require.NoError(t, err)
+	assert.Len(t, workspaces, 1)
+
+	_, err = f.service.GetWorkspace(f.owner(t, "bob"), workspace.ID)
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+	_, err = f.service.GetWorkspace(AdminOwner, workspace.ID+1)
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+}
+
+func TestWorkspaceService_Invitations(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	workspace, err := f.service.CreateWorkspace(f.owner(t, "alice"), "Marketing")
+	require.NoError(t, err)
+
+	invitation, token, err := f.service.CreateInvitation(f.owner(t, "alice"), workspace.ID, "Bob@Example.com", models.RoleEditor)
+	require.NoError(t, err)
+	assert.Regexp(t, `^usi_[0-9a-f]{64}$`, token)
+	assert.Equal(t, "bob@example.com", invitation.Email)
+	assert.True(t, f.now.Add(DefaultInvitationTTL).Equal(invitation.ExpiresAt))
+
+	_, _, err = f.service.CreateInvitation(f.owner(t, "alice"), workspace.ID, "alice@example.com", models.RoleViewer)
+	assert.ErrorIs(t, err, ErrAlreadyMember)
+	_, _, err = f.service.CreateInvitation(f.owner(t, "alice"), workspace.ID, "dave@example.com", "admin")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+
+	_, err = f.service.AcceptInvitation(f.owner(t, "carol"), token)
+	assert.ErrorIs(t, err, ErrInvitationEmailMismatch)
+	_, err = f.service.AcceptInvitation(AdminOwner, token)
+	assert.ErrorIs(t, err, ErrUserRequired)
+	_, err = f.service.AcceptInvitation(f.owner(t, "bob"), "usi_"+token[5:]+"0")
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+
+	membership, err := f.service.AcceptInvitation(f.owner(t, "bob"), token)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, membership.Role)
+	require.NotNil(t, membership.Workspace)
+	assert.Equal(t, "Marketing", membership.Workspace.Name)
+	assert.Equal(t, map[uint]models.Role{workspace.ID: models.RoleEditor}, f.owner(t, "bob").Roles)
+
+	_, err = f.service.AcceptInvitation(f.owner(t, "bob"), token)
+	assert.ErrorIs(t, err, ErrInvalidInvitation, "une invitation ne sert qu'une fois")
+
+	// Seul un propriétaire invite
+	_, _, err = f.service.CreateInvitation(f.owner(t, "bob"), workspace.ID, "carol@example.com", models.RoleViewer)
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+}
+
+func TestWorkspaceService_InvitationExpiration(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	workspace, err := f.service.CreateWorkspace(f.owner(t, "alice"), "Marketing")
+	require.NoError(t, err)
+	_, token, err := f.service.CreateInvitation(f.owner(t, "alice"), workspace.ID, "bob@example.com", models.RoleViewer)
+	require.NoError(t, err)
+
+	*f.now = f.now.Add(DefaultInvitationTTL)
+	_, err = f.service.AcceptInvitation(f.owner(t, "bob"), token)
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+}
+
+func TestWorkspaceService_RevokeInvitation(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	workspace, err := f.service.CreateWorkspace(f.owner(t, "alice"), "Marketing")
+	require.NoError(t, err)
+	invitation, token, err := f.service.CreateInvitation(f.owner(t, "alice"), workspace.ID, "bob@example.com", models.RoleViewer)
+	require.NoError(t, err)
+
+	invitations, err := f.service.ListInvitations(f.owner(t, "alice"), workspace.ID)
+	require.NoError(t, err)
+	assert.Len(t, invitations, 1)
+
+	require.NoError(t, f.service.RevokeInvitation(f.owner(t, "alice"), workspace.ID, invitation.ID))
+	assert.ErrorIs(t, f.service.RevokeInvitation(f.owner(t, "alice"), workspace.ID, invitation.ID), ErrInvitationNotFound)
+
+	_, err = f.service.AcceptInvitation(f.owner(t, "bob"), token)
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+}
+
+func TestWorkspaceService_ManageMembers(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	workspace, err := f.service.CreateWorkspace(f.owner(t, "alice"), "Marketing")
+	require.NoError(t, err)
+
+	_, err = f.service.AddMember(AdminOwner, workspace.ID, "bob@example.com", models.RoleViewer)
+	require.NoError(t, err)
+	_, err = f.service.AddMember(AdminOwner, workspace.ID, "BOB@example.com", models.RoleEditor)
+	assert.ErrorIs(t, err, ErrAlreadyMember)
+	_, err = f.service.AddMember(AdminOwner, workspace.ID, "dave@example.com", models.RoleEditor)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	members, err := f.service.ListMembers(f.owner(t, "bob"), workspace.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+
+	bobID, aliceID := f.users["bob"].ID, f.users["alice"].ID
+	_, err = f.service.UpdateMemberRole(f.owner(t, "bob"), workspace.ID, bobID, models.RoleOwner)
+	assert.ErrorIs(t, err, ErrInsufficientRole, "un membre ne peut pas s'attribuer de rôle")
+
+	// Le dernier propriétaire ne peut être ni rétrogradé ni retiré
+	_, err = f.service.UpdateMemberRole(f.owner(t, "alice"), workspace.ID, aliceID, models.RoleEditor)
+	assert.ErrorIs(t, err, ErrLastOwner)
+	assert.ErrorIs(t, f.service.RemoveMember(f.owner(t, "alice"), workspace.ID, aliceID), ErrLastOwner)
+
+	membership, err := f.service.UpdateMemberRole(f.owner(t, "alice"), workspace.ID, bobID, models.RoleOwner)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleOwner, membership.Role)
+	require.NoError(t, f.service.RemoveMember(f.owner(t, "alice"), workspace.ID, aliceID))
+
+	_, err = f.service.UpdateMemberRole(f.owner(t, "bob"), workspace.ID, aliceID, models.RoleViewer)
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+}
+
+func TestWorkspaceService_MembersLeaveOnTheirOwn(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	workspace, err := f.service.CreateWorkspace(f.owner(t, "alice"), "Marketing")
+	require.NoError(t, err)
+	for _, name := range []string{"bob", "carol"} {
+		_, err = f.service.AddMember(AdminOwner, workspace.ID, name+"@example.com", models.RoleViewer)
+		require.NoError(t, err)
+	}
+
+	bobID, carolID := f.users["bob"].ID, f.users["carol"].ID
+	assert.ErrorIs(t, f.service.RemoveMember(f.owner(t, "bob"), workspace.ID, carolID), ErrInsufficientRole)
+	require.NoError(t, f.service.RemoveMember(f.owner(t, "bob"), workspace.ID, bobID))
+	assert.Empty(t, f.owner(t, "bob").Roles)
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole(" Editor ")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, role)
+
+	_, err = ParseRole("admin")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
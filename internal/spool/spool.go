@@ -0,0 +1,627 @@
+package spool
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+)
+
+// FsyncPolicy indique quand les écritures du spool sont forcées sur le disque.
+type FsyncPolicy string
+
+const (
+	FsyncAlways   FsyncPolicy = "always"   // à chaque clic : aucune perte, débit réduit
+	FsyncInterval FsyncPolicy = "interval" // périodiquement : perte bornée à l'intervalle en cas de panne machine
+	FsyncNever    FsyncPolicy = "never"    // laissé au système : survit à un crash du processus seulement
+)
+
+const (
+	segmentExt     = ".seg"
+	checkpointFile = "checkpoint"
+	headerSize     = 8
+	maxRecordSize  = 1 << 20
+
+	DefaultSegmentMaxBytes = 8 << 20
+	DefaultFsyncInterval   = 200 * time.Millisecond
+)
+
+var (
+	ErrFull   = errors.New("click spool is full")
+	ErrClosed = errors.New("click spool is closed")
+)
+
+type Options struct {
+	SegmentMaxBytes int64 // taille à partir de laquelle un nouveau segment est ouvert
+	// MaxDiskBytes borne les clics non acquittés du spool, 0 = illimité. Les segments
+	// entièrement acquittés sont supprimés ensuite : l'espace disque utilisé peut
+	// dépasser cette limite de la taille d'un segment.
+	MaxDiskBytes  int64
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+}
+
+// segmentPosition désigne une position dans un segment.
+type segmentPosition struct {
+	seq    uint64
+	offset int64
+}
+
+type segment struct {
+	seq      uint64
+	path     string
+	size     int64 // octets écrits et lisibles
+	sealed   bool  // plus aucune écriture
+	readDone bool  // entièrement transmis aux workers
+	start    int64 // position de lecture, initialisée par le checkpoint
+	// inflight liste, dans l'ordre de lecture, la position des clics transmis aux
+	// workers et pas encore tous acquittés ; acked marque ceux déjà acquittés.
+	inflight []int64
+	acked    map[int64]bool
+}
+
+// committed retourne la position avant laquelle tous les clics du segment ont été
+// acquittés : c'est depuis elle que le segment est rejoué après un arrêt brutal.
+func (seg *segment) committed() int64 {
+	if len(seg.inflight) > 0 {
+		return seg.inflight[0]
+	}
+	return seg.start
+}
+
+// ack marque le clic situé à offset comme acquitté et fait avancer la position de reprise.
+func (seg *segment) ack(offset int64) {
+	if len(seg.inflight) == 0 || offset < seg.inflight[0] {
+		return
+	}
+	seg.acked[offset] = true
+	for len(seg.inflight) > 0 && seg.acked[seg.inflight[0]] {
+		delete(seg.acked, seg.inflight[0])
+		seg.inflight = seg.inflight[1:]
+	}
+}
+
+// Spool est un journal d'écriture anticipée des clics : chaque clic est ajouté à un segment
+// sur disque avant d'être transmis aux workers, puis le segment est supprimé lorsque
+// tous ses clics ont été acquittés (enregistrés en base). La position des clics acquittés
+// est enregistrée dans un checkpoint au fil des acquittements : au démarrage suivant,
+// seuls les clics qui la suivent sont rejoués. La livraison est « au moins une fois » :
+// un arrêt brutal peut rejouer les clics acquittés depuis le dernier checkpoint.
+type Spool struct {
+	dir  string
+	opts Options
+
+	mu         sync.Mutex
+	segments   []*segment
+	active     *os.File
+	lastSeq    uint64
+	checkpoint segmentPosition
+	dirty      bool
+	closed     bool
+
+	notify  chan struct{}
+	stop    chan struct{}
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func Open(dir string, opts Options) (*Spool, error) {
+	if opts.SegmentMaxBytes <= 0 {
+		opts.SegmentMaxBytes = DefaultSegmentMaxBytes
+	}
+	if opts.Fsync == "" {
+		opts.Fsync = FsyncInterval
+	}
+	if opts.FsyncInterval <= 0 {
+		opts.FsyncInterval = DefaultFsyncInterval
+	}
+	switch opts.Fsync {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, fmt.Errorf("unsupported spool fsync policy '%s' (always, interval or never)", opts.Fsync)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating spool directory '%s': %w", dir, err)
+	}
+
+	s := &Spool{
+		dir:    dir,
+		opts:   opts,
+		notify: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+	if err := s.openSegment(); err != nil {
+		return nil, err
+	}
+
+	if opts.Fsync == FsyncInterval {
+		s.wg.Add(1)
+		go s.syncLoop()
+	}
+	return s, nil
+}
+
+// recover charge les segments laissés par une exécution précédente et tronque
+// un éventuel enregistrement incomplet en fin de segment (arrêt brutal pendant une écriture).
+func (s *Spool) recover() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+segmentExt))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		size, err := validLength(path)
+		if err != nil {
+			return fmt.Errorf("error reading spool segment '%s': %w", path, err)
+		}
+		s.segments = append(s.segments, newSegment(seq, path, size, true))
+		s.lastSeq = max(s.lastSeq, seq)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+
+	checkpoint, ok := s.readCheckpoint()
+	if !ok {
+		return nil
+	}
+	// Les numéros de segment ne sont jamais réutilisés : un checkpoint ne peut désigner
+	// que le segment pour lequel il a été écrit.
+	s.checkpoint = checkpoint
+	s.lastSeq = max(s.lastSeq, checkpoint.seq)
+	for _, seg := range s.segments {
+		if seg.seq == checkpoint.seq && checkpoint.offset <= seg.size {
+			seg.start = checkpoint.offset
+		}
+	}
+	return nil
+}
+
+func newSegment(seq uint64, path string, size int64, sealed bool) *segment {
+	return &segment{seq: seq, path: path, size: size, sealed: sealed, acked: make(map[int64]bool)}
+}
+
+func validLength(path string) (int64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	for offset < info.Size() {
+		_, n, err := readRecord(file, offset)
+		if err != nil {
+			break
+		}
+		offset += n
+	}
+	if offset < info.Size() {
+		log.Printf("Warning: Truncating corrupted tail of spool segment %s (%d bytes)", path, info.Size()-offset)
+		if err := file.Truncate(offset); err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
+
+func (s *Spool) openSegment() error {
+	seq := s.lastSeq + 1
+	path := filepath.Join(s.dir, fmt.Sprintf("%016d%s", seq, segmentExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("error creating spool segment '%s': %w", path, err)
+	}
+	s.active = file
+	s.lastSeq = seq
+	s.segments = append(s.segments, newSegment(seq, path, 0, false))
+	return nil
+}
+
+func (s *Spool) activeSegment() *segment {
+	return s.segments[len(s.segments)-1]
+}
+
+// rotate scelle le segment courant et en ouvre un nouveau. Appelé avec s.mu verrouillé.
+func (s *Spool) rotate() error {
+	if err := s.active.Sync(); err != nil {
+		return err
+	}
+	if err := s.active.Close(); err != nil {
+		return err
+	}
+	s.activeSegment().sealed = true
+	s.dirty = false
+	return s.openSegment()
+}
+
+// Enqueue ajoute un clic au spool. Il est durable dès le retour selon la politique de fsync.
+func (s *Spool) Enqueue(event models.ClickEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxRecordSize {
+		return fmt.Errorf("click event too large for spool (%d bytes)", len(payload))
+	}
+	record := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[headerSize:], payload)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.opts.MaxDiskBytes > 0 && s.unackedBytes()+int64(len(record)) > s.opts.MaxDiskBytes {
+		return ErrFull
+	}
+	if seg := s.activeSegment(); seg.size > 0 && seg.size+int64(len(record)) > s.opts.SegmentMaxBytes {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("error rotating spool segment: %w", err)
+		}
+	}
+
+	seg := s.activeSegment()
+	if _, err := s.active.Write(record); err != nil {
+		// Une écriture partielle serait prise pour un enregistrement corrompu : on l'efface.
+		if truncErr := s.active.Truncate(seg.size); truncErr != nil {
+			log.Printf("ERROR: Failed to roll back partial spool write in %s: %v", seg.path, truncErr)
+		}
+		return fmt.Errorf("error writing to spool: %w", err)
+	}
+	seg.size += int64(len(record))
+	s.signal()
+
+	if s.opts.Fsync != FsyncAlways {
+		s.dirty = true
+		return nil
+	}
+	if err := s.active.Sync(); err != nil {
+		s.dirty = true
+		return fmt.Errorf("error syncing spool: %w", err)
+	}
+	return nil
+}
+
+func (s *Spool) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Spool) syncLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.opts.FsyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.dirty && !s.closed {
+				if err := s.active.Sync(); err != nil {
+					log.Printf("Warning: Failed to sync click spool: %v", err)
+				} else {
+					s.dirty = false
+				}
+			}
+			s.mu.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Start transmet les clics du spool aux workers via out, en commençant par ceux
+// laissés par l'exécution précédente. L'envoi bloque si les workers sont saturés :
+// les clics s'accumulent alors sur disque plutôt qu'en mémoire.
+func (s *Spool) Start(out chan<- models.ClickEvent) {
+	s.wg.Add(1)
+	go s.deliver(out)
+}
+
+// Stop interrompt la transmission aux workers. Les clics non transmis restent sur disque.
+// Il doit être appelé avant la fermeture du channel des workers.
+func (s *Spool) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	s.wg.Wait()
+}
+
+type cursor struct {
+	seg    *segment
+	file   *os.File
+	offset int64
+}
+
+func (c *cursor) close() {
+	if c.file != nil {
+		c.file.Close()
+	}
+	c.seg, c.file = nil, nil
+}
+
+func (s *Spool) deliver(out chan<- models.ClickEvent) {
+	defer s.wg.Done()
+	var cur cursor
+	defer cur.close()
+
+	for {
+		event, ok := s.next(&cur)
+		if !ok {
+			select {
+			case <-s.notify:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+
+		select {
+		case out <- event:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// next lit le prochain clic disponible. Retourne false s'il faut attendre de nouvelles écritures.
+func (s *Spool) next(cur *cursor) (models.ClickEvent, bool) {
+	for {
+		if cur.seg == nil {
+			s.mu.Lock()
+			for _, seg := range s.segments {
+				if !seg.readDone {
+					cur.seg = seg
+					cur.offset = seg.start
+					break
+				}
+			}
+			s.mu.Unlock()
+			if cur.seg == nil {
+				return models.ClickEvent{}, false
+			}
+		}
+
+		s.mu.Lock()
+		limit, sealed := cur.seg.size, cur.seg.sealed
+		s.mu.Unlock()
+
+		if cur.offset >= limit {
+			if !sealed {
+				return models.ClickEvent{}, false
+			}
+			s.finishReading(cur.seg)
+			cur.close()
+			continue
+		}
+
+		if cur.file == nil {
+			file, err := os.Open(cur.seg.path)
+			if err != nil {
+				log.Printf("ERROR: Failed to open spool segment %s, skipping it: %v", cur.seg.path, err)
+				s.finishReading(cur.seg)
+				cur.close()
+				continue
+			}
+			cur.file = file
+		}
+
+		payload, n, err := readRecord(cur.file, cur.offset)
+		if err != nil {
+			log.Printf("ERROR: Corrupted record in spool segment %s at offset %d, skipping the rest: %v", cur.seg.path, cur.offset, err)
+			s.finishReading(cur.seg)
+			cur.close()
+			continue
+		}
+		offset := cur.offset
+		cur.offset += n
+
+		var event models.ClickEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			log.Printf("ERROR: Invalid click event in spool segment %s, skipping it: %v", cur.seg.path, err)
+			s.mu.Lock()
+			cur.seg.start = cur.offset
+			s.mu.Unlock()
+			continue
+		}
+		event.SpoolSegment = cur.seg.seq
+		event.SpoolOffset = offset
+
+		s.mu.Lock()
+		cur.seg.inflight = append(cur.seg.inflight, offset)
+		cur.seg.start = cur.offset
+		s.mu.Unlock()
+		return event, true
+	}
+}
+
+func (s *Spool) finishReading(seg *segment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seg.readDone = true
+	s.removeIfDone(seg)
+}
+
+// Ack signale que des clics issus du spool ont été traités par les workers, puis
+// enregistre la nouvelle position de reprise si elle a avancé.
+func (s *Spool) Ack(events []models.ClickEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range events {
+		if event.SpoolSegment == 0 {
+			continue
+		}
+		for _, seg := range s.segments {
+			if seg.seq == event.SpoolSegment {
+				seg.ack(event.SpoolOffset)
+				s.removeIfDone(seg)
+				break
+			}
+		}
+	}
+	if err := s.saveCheckpoint(); err != nil {
+		log.Printf("Warning: Failed to save click spool checkpoint: %v", err)
+	}
+}
+
+// unackedBytes retourne le nombre d'octets de clics pas encore acquittés. Appelé avec s.mu verrouillé.
+func (s *Spool) unackedBytes() int64 {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size - seg.committed()
+	}
+	return total
+}
+
+// removeIfDone supprime un segment scellé, entièrement transmis et acquitté. Appelé avec s.mu verrouillé.
+func (s *Spool) removeIfDone(seg *segment) {
+	if !seg.sealed || !seg.readDone || len(seg.inflight) > 0 {
+		return
+	}
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to remove spool segment %s: %v", seg.path, err)
+		return
+	}
+	for i, candidate := range s.segments {
+		if candidate == seg {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+}
+
+// Close ferme le segment courant. Il doit être appelé après l'arrêt de la transmission
+// et des workers : les segments entièrement acquittés sont supprimés et la position de
+// reprise est enregistrée pour ne pas rejouer les clics acquittés au prochain démarrage.
+func (s *Spool) Close() error {
+	s.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if err := s.active.Sync(); err != nil {
+		return err
+	}
+	if err := s.active.Close(); err != nil {
+		return err
+	}
+	s.activeSegment().sealed = true
+
+	unacked := 0
+	for _, seg := range append([]*segment(nil), s.segments...) {
+		unacked += len(seg.inflight)
+		if seg.start == seg.size {
+			seg.readDone = true
+			s.removeIfDone(seg)
+		}
+	}
+	if unacked > 0 {
+		log.Printf("Warning: %d spooled click(s) were not acknowledged and will be replayed on next start", unacked)
+	}
+	return s.saveCheckpoint()
+}
+
+// Pending retourne le nombre d'octets de clics pas encore acquittés, à rejouer au
+// prochain démarrage si le spool est fermé.
+func (s *Spool) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unackedBytes()
+}
+
+// saveCheckpoint enregistre la position de reprise du plus ancien segment restant,
+// si elle a changé. Appelé avec s.mu verrouillé.
+func (s *Spool) saveCheckpoint() error {
+	var checkpoint segmentPosition
+	if len(s.segments) > 0 {
+		oldest := s.segments[0]
+		checkpoint = segmentPosition{seq: oldest.seq, offset: oldest.committed()}
+	}
+	if checkpoint == s.checkpoint {
+		return nil
+	}
+
+	path := filepath.Join(s.dir, checkpointFile)
+	tmp := path + ".tmp"
+	content := fmt.Sprintf("%d %d\n", checkpoint.seq, checkpoint.offset)
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	s.checkpoint = checkpoint
+	return nil
+}
+
+func (s *Spool) readCheckpoint() (segmentPosition, bool) {
+	content, err := os.ReadFile(filepath.Join(s.dir, checkpointFile))
+	if err != nil {
+		return segmentPosition{}, false
+	}
+	var checkpoint segmentPosition
+	if _, err := fmt.Sscanf(string(content), "%d %d", &checkpoint.seq, &checkpoint.offset); err != nil {
+		return segmentPosition{}, false
+	}
+	return checkpoint, true
+}
+
+// readRecord lit l'enregistrement situé à offset : longueur, CRC32 puis clic encodé en JSON.
+func readRecord(r io.ReaderAt, offset int64) ([]byte, int64, error) {
+	header := make([]byte, headerSize)
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return nil, 0, err
+	}
+	length := binary.LittleEndian.Uint32(header[0:4])
+	if length == 0 || length > maxRecordSize {
+		return nil, 0, fmt.Errorf("invalid record length %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := r.ReadAt(payload, offset+headerSize); err != nil {
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+	return payload, headerSize + int64(length), nil
+}
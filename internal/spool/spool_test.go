@@ -0,0 +1,254 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func event(linkID uint) models.ClickEvent {
+	return models.ClickEvent{
+		LinkID:    linkID,
+		Timestamp: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		UserAgent: "Mozilla/5.0",
+		IPAddress: "192.168.1.1",
+	}
+}
+
+func receive(t *testing.T, out <-chan models.ClickEvent, count int) []models.ClickEvent {
+	var events []models.ClickEvent
+	for len(events) < count {
+		select {
+		case e := <-out:
+			events = append(events, e)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for spooled events, got %d/%d", len(events), count)
+		}
+	}
+	return events
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	return paths
+}
+
+func TestSpool_DeliversInOrder(t *testing.T) {
+	s, err := Open(t.TempDir(), Options{Fsync: FsyncAlways})
+	require.NoError(t, err)
+	out := make(chan models.ClickEvent, 10)
+	s.Start(out)
+
+	for i := uint(1); i <= 3; i++ {
+		require.NoError(t, s.Enqueue(event(i)))
+	}
+
+	events := receive(t, out, 3)
+	assert.Equal(t, uint(1), events[0].LinkID)
+	assert.Equal(t, uint(3), events[2].LinkID)
+	assert.Equal(t, "192.168.1.1", events[0].IPAddress)
+	assert.Equal(t, uint64(1), events[0].SpoolSegment)
+
+	s.Ack(events)
+	require.NoError(t, s.Close())
+	assert.Equal(t, int64(0), s.Pending())
+}
+
+func TestSpool_RotatesAndRemovesAcknowledgedSegments(t *testing.T) {
+	dir := t.TempDir()
+	// Segments minuscules : un clic par segment
+	s, err := Open(dir, Options{SegmentMaxBytes: 10, Fsync: FsyncNever})
+	require.NoError(t, err)
+
+	for i := uint(1); i <= 3; i++ {
+		require.NoError(t, s.Enqueue(event(i)))
+	}
+	assert.Len(t, segmentFiles(t, dir), 3)
+
+	out := make(chan models.ClickEvent, 10)
+	s.Start(out)
+	events := receive(t, out, 3)
+	assert.Equal(t, []uint64{1, 2, 3}, []uint64{events[0].SpoolSegment, events[1].SpoolSegment, events[2].SpoolSegment})
+
+	// Seuls les segments scellés et acquittés sont supprimés
+	s.Ack(events[:1])
+	assert.Len(t, segmentFiles(t, dir), 2)
+	s.Ack(events[1:])
+	assert.Equal(t, []string{filepath.Join(dir, "0000000000000003.seg")}, segmentFiles(t, dir))
+
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_ReplaysUnacknowledgedEventsAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, Options{Fsync: FsyncAlways})
+	require.NoError(t, err)
+	out := make(chan models.ClickEvent, 10)
+	s.Start(out)
+
+	require.NoError(t, s.Enqueue(event(1)))
+	require.NoError(t, s.Enqueue(event(2)))
+	receive(t, out, 2)
+	// Arrêt brutal : ni acquittement ni Close
+	s.Stop()
+
+	reopened, err := Open(dir, Options{Fsync: FsyncAlways})
+	require.NoError(t, err)
+	replayed := make(chan models.ClickEvent, 10)
+	reopened.Start(replayed)
+
+	events := receive(t, replayed, 2)
+	assert.Equal(t, uint(1), events[0].LinkID)
+	assert.Equal(t, uint(2), events[1].LinkID)
+	reopened.Ack(events)
+	require.NoError(t, reopened.Close())
+}
+
+func TestSpool_CheckpointAvoidsReplayOfAcknowledgedEventsAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, Options{Fsync: FsyncAlways})
+	require.NoError(t, err)
+	out := make(chan models.ClickEvent, 10)
+	s.Start(out)
+
+	for i := uint(1); i <= 4; i++ {
+		require.NoError(t, s.Enqueue(event(i)))
+	}
+	events := receive(t, out, 4)
+	// Acquittements dans le désordre : seuls les clics 1 et 2 sont acquittés sans trou
+	s.Ack([]models.ClickEvent{events[1]})
+	s.Ack([]models.ClickEvent{events[3]})
+	s.Ack([]models.ClickEvent{events[0]})
+	// Arrêt brutal : pas de Close
+	s.Stop()
+
+	reopened, err := Open(dir, Options{Fsync: FsyncAlways})
+	require.NoError(t, err)
+	replayed := make(chan models.ClickEvent, 10)
+	reopened.Start(replayed)
+
+	events = receive(t, replayed, 2)
+	assert.Equal(t, uint(3), events[0].LinkID)
+	assert.Equal(t, uint(4), events[1].LinkID)
+	select {
+	case e := <-replayed:
+		t.Fatalf("Unexpected event replayed: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+	reopened.Ack(events)
+	require.NoError(t, reopened.Close())
+	assert.Equal(t, int64(0), reopened.Pending())
+}
+
+func TestSpool_TruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, Options{Fsync: FsyncAlways})
+	require.NoError(t, err)
+	require.NoError(t, s.Enqueue(event(1)))
+	require.NoError(t, s.Enqueue(event(2)))
+	s.Stop()
+
+	// Écriture interrompue en plein enregistrement
+	path := segmentFiles(t, dir)[0]
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-5))
+
+	reopened, err := Open(dir, Options{Fsync: FsyncAlways})
+	require.NoError(t, err)
+	out := make(chan models.ClickEvent, 10)
+	reopened.Start(out)
+
+	events := receive(t, out, 1)
+	assert.Equal(t, uint(1), events[0].LinkID)
+	select {
+	case e := <-out:
+		t.Fatalf("Unexpected event replayed: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, reopened.Close())
+}
+
+func TestSpool_RejectsEventsBeyondMaxDisk(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, Options{MaxDiskBytes: 200, Fsync: FsyncNever})
+	require.NoError(t, err)
+
+	var accepted int
+	for i := uint(1); i <= 10; i++ {
+		if err := s.Enqueue(event(i)); err != nil {
+			assert.ErrorIs(t, err, ErrFull)
+			break
+		}
+		accepted++
+	}
+
+	assert.Greater(t, accepted, 0)
+	assert.Less(t, accepted, 10)
+	assert.LessOrEqual(t, s.Pending(), int64(200))
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_MaxDiskCountsOnlyUnacknowledgedEvents(t *testing.T) {
+	dir := t.TempDir()
+	// Limite inférieure à la taille d'un segment : les clics acquittés ne doivent pas la consommer
+	s, err := Open(dir, Options{MaxDiskBytes: 200, Fsync: FsyncNever})
+	require.NoError(t, err)
+	out := make(chan models.ClickEvent, 10)
+	s.Start(out)
+
+	for i := uint(1); i <= 10; i++ {
+		require.NoError(t, s.Enqueue(event(i)))
+		s.Ack(receive(t, out, 1))
+	}
+	assert.Equal(t, int64(0), s.Pending())
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_CheckpointAvoidsReplayAfterGracefulClose(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, Options{Fsync: FsyncInterval, FsyncInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	out := make(chan models.ClickEvent, 10)
+	s.Start(out)
+
+	require.NoError(t, s.Enqueue(event(1)))
+	s.Ack(receive(t, out, 1))
+	s.Stop()
+	// Clic reçu après l'arrêt de la transmission : il doit être rejoué
+	require.NoError(t, s.Enqueue(event(2)))
+	require.NoError(t, s.Close())
+
+	reopened, err := Open(dir, Options{Fsync: FsyncAlways})
+	require.NoError(t, err)
+	replayed := make(chan models.ClickEvent, 10)
+	reopened.Start(replayed)
+
+	events := receive(t, replayed, 1)
+	assert.Equal(t, uint(2), events[0].LinkID)
+	select {
+	case e := <-replayed:
+		t.Fatalf("Unexpected event replayed: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, reopened.Close())
+}
+
+func TestSpool_EnqueueAfterClose(t *testing.T) {
+	s, err := Open(t.TempDir(), Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	assert.ErrorIs(t, s.Enqueue(event(1)), ErrClosed)
+}
+
+func TestOpen_InvalidFsyncPolicy(t *testing.T) {
+	_, err := Open(t.TempDir(), Options{Fsync: "sometimes"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package useragent
+
+import (
+	"strings"
+
+	"github.com/Edofo/bitly-clone/internal/botdetect"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+
+	Other = "Other"
+)
+
+type Info struct {
+	Browser string
+	OS      string
+	Device  string
+}
+
+type rule struct {
+	family   string
+	contains []string
+}
+
+// Les règles sont évaluées dans l'ordre : la plupart des navigateurs reprennent
+// les jetons de leurs prédécesseurs (Edge contient "Chrome/", Chrome contient "Safari/"...),
+// les familles les plus spécifiques doivent donc passer en premier.
+var browserRules = []rule{
+	{"Edge", []string{"edg/", "edge/", "edga/", "edgios/"}},
+	{"Opera", []string{"opr/", "opera", "opios/"}},
+	{"Samsung Internet", []string{"samsungbrowser"}},
+	{"Yandex", []string{"yabrowser"}},
+	{"Vivaldi", []string{"vivaldi"}},
+	{"Firefox", []string{"firefox/", "fxios/"}},
+	{"Chrome", []string{"chrome/", "crios/", "chromium/"}},
+	{"Safari", []string{"version/"}},
+	{"Internet Explorer", []string{"msie ", "trident/"}},
+}
+
+var osRules = []rule{
+	{"Windows Phone", []string{"windows phone"}},
+	{"Windows", []string{"windows"}},
+	{"iOS", []string{"iphone", "ipad", "ipod"}},
+	{"Android", []string{"android"}},
+	{"Chrome OS", []string{"cros"}},
+	{"macOS", []string{"macintosh", "mac os x"}},
+	{"Linux", []string{"linux", "x11"}},
+}
+
+var tabletTokens = []string{"ipad", "tablet", "kindle", "silk/", "playbook"}
+
+var mobileTokens = []string{"mobi", "iphone", "ipod", "windows phone", "opera mini"}
+
+// Parse déduit la famille de navigateur, la famille de système et la classe
+// d'appareil à partir d'un en-tête User-Agent.
+func Parse(userAgent string) Info {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+
+	info := Info{
+		Browser: match(ua, browserRules),
+		OS:      match(ua, osRules),
+		Device:  DeviceDesktop,
+	}
+
+	switch {
+	case botdetect.IsBotUserAgent(ua):
+		info.Device = DeviceBot
+	case containsAny(ua, tabletTokens):
+		info.Device = DeviceTablet
+	case strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		// Sur Android, seuls les téléphones annoncent "Mobile".
+		info.Device = DeviceTablet
+	case containsAny(ua, mobileTokens) || strings.Contains(ua, "android"):
+		info.Device = DeviceMobile
+	}
+
+	if info.Browser == "Safari" && !strings.Contains(ua, "safari/") {
+		info.Browser = Other
+	}
+
+	return info
+}
+
+func match(ua string, rules []rule) string {
+	for _, r := range rules {
+		if containsAny(ua, r.contains) {
+			return r.family
+		}
+	}
+	return Other
+}
+
+func containsAny(s string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(s, token) {
+			return true
+		}
+	}
+	return false
+}
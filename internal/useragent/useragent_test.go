@@ -0,0 +1,82 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name      string
+		userAgent string
+		expected  Info
+	}{
+		{
+			"chrome windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			Info{Browser: "Chrome", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			"edge windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51",
+			Info{Browser: "Edge", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			"safari macos",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15",
+			Info{Browser: "Safari", OS: "macOS", Device: DeviceDesktop},
+		},
+		{
+			"firefox linux",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+			Info{Browser: "Firefox", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			"safari iphone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			Info{Browser: "Safari", OS: "iOS", Device: DeviceMobile},
+		},
+		{
+			"chrome ipad",
+			"Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/124.0.6367.88 Mobile/15E148 Safari/604.1",
+			Info{Browser: "Chrome", OS: "iOS", Device: DeviceTablet},
+		},
+		{
+			"samsung android phone",
+			"Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Mobile Safari/537.36",
+			Info{Browser: "Samsung Internet", OS: "Android", Device: DeviceMobile},
+		},
+		{
+			"chrome android tablet",
+			"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			Info{Browser: "Chrome", OS: "Android", Device: DeviceTablet},
+		},
+		{
+			"googlebot",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			Info{Browser: Other, OS: Other, Device: DeviceBot},
+		},
+		{
+			"curl",
+			"curl/8.4.0",
+			Info{Browser: Other, OS: Other, Device: DeviceBot},
+		},
+		{
+			"empty",
+			"",
+			Info{Browser: Other, OS: Other, Device: DeviceBot},
+		},
+		{
+			"internet explorer",
+			"Mozilla/5.0 (Windows NT 10.0; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			Info{Browser: "Internet Explorer", OS: "Windows", Device: DeviceDesktop},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Parse(tc.userAgent))
+		})
+	}
+}
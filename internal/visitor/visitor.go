@@ -0,0 +1,123 @@
+package visitor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+)
+
+const saltSize = 32
+
+const dayLayout = "2006-01-02"
+
+// Hasher calcule une empreinte anonyme des visiteurs à partir de l'IP et du User-Agent.
+// Le sel est aléatoire, change chaque jour (UTC) et les sels expirés sont supprimés :
+// une empreinte ne permet ni de retrouver l'IP, ni de suivre un visiteur d'un jour à l'autre.
+// Le Hasher est utilisable par plusieurs goroutines.
+type Hasher struct {
+	salts repository.VisitorSaltRepository
+	now   func() time.Time
+
+	mu     sync.Mutex
+	cache  map[string][]byte
+	purged string
+}
+
+func NewHasher(salts repository.VisitorSaltRepository) *Hasher {
+	return &Hasher{
+		salts: salts,
+		now:   time.Now,
+		cache: make(map[string][]byte),
+	}
+}
+
+// Hash retourne l'empreinte hexadécimale (SHA-256) du visiteur pour le jour UTC de at.
+func (h *Hasher) Hash(ip, userAgent string, at time.Time) (string, error) {
+	salt, err := h.salt(at.UTC().Format(dayLayout))
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.New()
+	digest.Write(salt)
+	digest.Write([]byte(ip))
+	digest.Write([]byte{0})
+	digest.Write([]byte(userAgent))
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// Enrich renseigne l'empreinte du visiteur d'un clic. En cas d'erreur, le clic est
+// enregistré sans empreinte et n'est pas compté parmi les visiteurs uniques.
+func (h *Hasher) Enrich(click *models.Click) {
+	hash, err := h.Hash(click.IPAddress, click.UserAgent, click.Timestamp)
+	if err != nil {
+		log.Printf("Warning: Failed to compute visitor hash for LinkID %d: %v", click.LinkID, err)
+		return
+	}
+	click.VisitorHash = hash
+}
+
+// salt retourne le sel du jour. La base n'est interrogée qu'en l'absence du sel en
+// mémoire, hors du verrou : les autres goroutines continuent de calculer des empreintes
+// pendant ce temps. Plusieurs goroutines peuvent charger le même jour simultanément ;
+// GetOrCreateSalt leur retourne le même sel.
+func (h *Hasher) salt(day string) ([]byte, error) {
+	h.rotate()
+
+	h.mu.Lock()
+	salt, ok := h.cache[day]
+	h.mu.Unlock()
+	if ok {
+		return salt, nil
+	}
+
+	candidate := make([]byte, saltSize)
+	if _, err := rand.Read(candidate); err != nil {
+		return nil, fmt.Errorf("error generating visitor salt: %w", err)
+	}
+	salt, err := h.salts.GetOrCreateSalt(day, candidate)
+	if err != nil {
+		return nil, fmt.Errorf("error loading visitor salt for %s: %w", day, err)
+	}
+
+	h.mu.Lock()
+	h.cache[day] = salt
+	h.mu.Unlock()
+	return salt, nil
+}
+
+// rotate oublie les sels antérieurs à la veille, en mémoire comme en base.
+// La veille est conservée pour les clics traités peu après minuit.
+func (h *Hasher) rotate() {
+	oldest := h.now().UTC().AddDate(0, 0, -1).Format(dayLayout)
+
+	h.mu.Lock()
+	if h.purged == oldest {
+		h.mu.Unlock()
+		return
+	}
+	for day := range h.cache {
+		if day < oldest {
+			delete(h.cache, day)
+		}
+	}
+	// Marqué avant la suppression en base pour qu'une seule goroutine s'en charge.
+	h.purged = oldest
+	h.mu.Unlock()
+
+	if err := h.salts.DeleteSaltsBefore(oldest); err != nil {
+		log.Printf("Warning: Failed to delete expired visitor salts: %v", err)
+		h.mu.Lock()
+		if h.purged == oldest {
+			h.purged = ""
+		}
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,145 @@
+package visitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupHasher(t *testing.T, now time.Time) (*Hasher, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.VisitorSalt{}))
+
+	hasher := NewHasher(repository.NewVisitorSaltRepository(db))
+	hasher.now = func() time.Time { return now }
+	return hasher, db
+}
+
+func TestHasher_SameVisitorSameDay(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	hasher, _ := setupHasher(t, now)
+
+	first, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	second, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now)
+	require.NoError(t, err)
+	other, err := hasher.Hash("192.168.1.2", "Mozilla/5.0", now)
+	require.NoError(t, err)
+
+	assert.Len(t, first, 64)
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+	assert.NotContains(t, first, "192.168")
+}
+
+func TestHasher_RotatesDaily(t *testing.T) {
+	now := time.Date(2024, 3, 10, 0, 30, 0, 0, time.UTC)
+	hasher, _ := setupHasher(t, now)
+
+	yesterday, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now.Add(-time.Hour))
+	require.NoError(t, err)
+	today, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, yesterday, today)
+}
+
+func TestHasher_SharedSaltSurvivesRestart(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	hasher, db := setupHasher(t, now)
+
+	before, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now)
+	require.NoError(t, err)
+
+	// Un nouveau processus réutilise le sel du jour stocké en base
+	restarted := NewHasher(repository.NewVisitorSaltRepository(db))
+	restarted.now = func() time.Time { return now }
+	after, err := restarted.Hash("192.168.1.1", "Mozilla/5.0", now)
+	require.NoError(t, err)
+
+	assert.Equal(t, before, after)
+}
+
+func TestHasher_DeletesExpiredSalts(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	hasher, db := setupHasher(t, now)
+
+	require.NoError(t, db.Create(&models.VisitorSalt{Day: "2024-03-01", Salt: []byte("old")}).Error)
+
+	_, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now)
+	require.NoError(t, err)
+
+	var days []string
+	require.NoError(t, db.Model(&models.VisitorSalt{}).Order("day").Pluck("day", &days).Error)
+	assert.Equal(t, []string{"2024-03-10"}, days)
+}
+
+func TestHasher_Enrich(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	hasher, _ := setupHasher(t, now)
+
+	click := &models.Click{LinkID: 1, Timestamp: now, IPAddress: "192.168.1.1", UserAgent: "Mozilla/5.0"}
+	hasher.Enrich(click)
+
+	expected, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now)
+	require.NoError(t, err)
+	assert.Equal(t, expected, click.VisitorHash)
+}
+
+// blockingSaltRepository bloque le chargement des sels d'un jour jusqu'à la fermeture de release.
+type blockingSaltRepository struct {
+	repository.VisitorSaltRepository
+	day     string
+	release chan struct{}
+}
+
+func (r *blockingSaltRepository) GetOrCreateSalt(day string, candidate []byte) ([]byte, error) {
+	if day == r.day {
+		<-r.release
+	}
+	return r.VisitorSaltRepository.GetOrCreateSalt(day, candidate)
+}
+
+func TestHasher_LoadsSaltOutsideLock(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	hasher, db := setupHasher(t, now)
+	salts := &blockingSaltRepository{
+		VisitorSaltRepository: repository.NewVisitorSaltRepository(db),
+		day:                   "2024-03-09",
+		release:               make(chan struct{}),
+	}
+	hasher.salts = salts
+
+	_, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now)
+	require.NoError(t, err)
+
+	// Le chargement du sel de la veille est bloqué...
+	loaded := make(chan error, 1)
+	go func() {
+		_, err := hasher.Hash("192.168.1.1", "Mozilla/5.0", now.AddDate(0, 0, -1))
+		loaded <- err
+	}()
+
+	// ...sans empêcher le calcul des empreintes du jour, dont le sel est en mémoire
+	done := make(chan error, 1)
+	go func() {
+		_, err := hasher.Hash("192.168.1.2", "Mozilla/5.0", now)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Hash blocked behind another day's salt lookup")
+	}
+
+	close(salts.release)
+	require.NoError(t, <-loaded)
+}
@@ -1,35 +1,365 @@
 package workers
 
 import (
+	"context"
+	"errors"
 	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Edofo/bitly-clone/internal/models"
 	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/Edofo/bitly-clone/internal/useragent"
+	"gorm.io/gorm"
 )
 
-func StartClickWorkers(workerCount int, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository) {
-	log.Printf("Starting %d click worker(s)...", workerCount)
-	for i := 0; i < workerCount; i++ {
-		go clickWorker(clickEventsChan, clickRepo)
+// ClickEnricher complète un clic avant son enregistrement (géolocalisation...).
+// Les implémentations sont partagées entre les workers et doivent être sûres en concurrence.
+type ClickEnricher interface {
+	Enrich(click *models.Click)
+}
+
+// ClickAcknowledger est notifié des clics traités, par exemple pour les retirer du spool
+// sur disque. Sont acquittés les clics enregistrés, ceux écartés par le ClickMeter et ceux
+// que la base refuse définitivement : ceux dont l'enregistrement a échoué pour une raison
+// passagère ou qui ont été abandonnés restent à rejouer.
+type ClickAcknowledger interface {
+	Ack(events []models.ClickEvent)
+}
+
+// ClickMeter applique par exemple un quota mensuel de clics : MeterClicks retourne les
+// clics d'un lot à enregistrer, RecordClicks décompte ensuite ceux qui ont été enregistrés.
+type ClickMeter interface {
+	MeterClicks(clicks []*models.Click) []*models.Click
+	RecordClicks(clicks []*models.Click)
+}
+
+// BatchOptions règle le regroupement des clics avant enregistrement : un lot est écrit
+// dès qu'il atteint Size clics ou que son plus ancien clic attend depuis MaxLatency.
+type BatchOptions struct {
+	Size       int
+	MaxLatency time.Duration
+}
+
+const (
+	DefaultBatchSize       = 100
+	DefaultBatchMaxLatency = 500 * time.Millisecond
+)
+
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.Size <= 0 {
+		o.Size = DefaultBatchSize
+	}
+	if o.MaxLatency <= 0 {
+		o.MaxLatency = DefaultBatchMaxLatency
+	}
+	return o
+}
+
+// DrainReport résume le devenir des clics traités par le pool depuis son démarrage.
+type DrainReport struct {
+	Persisted int64
+	Failed    int64
+	Abandoned int64
+	// OverQuota compte les clics écartés par le ClickMeter, acquittés sans être enregistrés.
+	OverQuota int64
+	// Rejected compte les clics que la base refuse définitivement (lien supprimé, donnée
+	// invalide...), acquittés sans être enregistrés : les rejouer échouerait à nouveau.
+	Rejected int64
+}
+
+// ClickWorkerPool regroupe les workers qui enregistrent les clics.
+// Le channel appartient à l'appelant : il le ferme pour signaler qu'aucun clic n'arrivera
+// plus, puis appelle Drain pour attendre l'enregistrement des clics encore en attente.
+type ClickWorkerPool struct {
+	workerCount     int
+	batch           BatchOptions
+	clickEventsChan <-chan models.ClickEvent
+	clickRepo       repository.ClickRepository
+	enrichers       []ClickEnricher
+	acknowledger    ClickAcknowledger
+	meter           ClickMeter
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	persisted atomic.Int64
+	failed    atomic.Int64
+	abandoned atomic.Int64
+	overQuota atomic.Int64
+	rejected  atomic.Int64
+}
+
+func NewClickWorkerPool(workerCount int, batch BatchOptions, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository, enrichers ...ClickEnricher) *ClickWorkerPool {
+	return &ClickWorkerPool{
+		workerCount:     workerCount,
+		batch:           batch.withDefaults(),
+		clickEventsChan: clickEventsChan,
+		clickRepo:       clickRepo,
+		enrichers:       enrichers,
+	}
+}
+
+// SetAcknowledger enregistre le destinataire des acquittements. À appeler avant Start.
+func (p *ClickWorkerPool) SetAcknowledger(acknowledger ClickAcknowledger) {
+	p.acknowledger = acknowledger
+}
+
+// SetMeter enregistre le décompte appliqué à chaque lot. À appeler avant Start.
+func (p *ClickWorkerPool) SetMeter(meter ClickMeter) {
+	p.meter = meter
+}
+
+// Start lance les workers. Ils s'arrêtent quand le channel est fermé et vidé,
+// ou immédiatement quand ctx est annulé ou que Stop est appelé.
+func (p *ClickWorkerPool) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	log.Printf("Starting %d click worker(s) (batch size %d, max latency %v)...", p.workerCount, p.batch.Size, p.batch.MaxLatency)
+	for i := 0; i < p.workerCount; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.run(ctx)
+		}()
+	}
+}
+
+// Stop interrompt les workers sans attendre l'enregistrement des clics en attente.
+func (p *ClickWorkerPool) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// Wait bloque jusqu'à l'arrêt de tous les workers.
+func (p *ClickWorkerPool) Wait() {
+	p.wg.Wait()
+}
+
+// Drain attend que les workers aient enregistré les clics restants, le channel ayant été fermé.
+// Passé le délai de ctx, les workers sont interrompus et les clics non enregistrés,
+// y compris ceux restés dans le channel, sont comptés comme abandonnés.
+func (p *ClickWorkerPool) Drain(ctx context.Context) DrainReport {
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		p.Stop()
+		<-done
+	}
+
+	for {
+		select {
+		case _, ok := <-p.clickEventsChan:
+			if !ok {
+				return p.Report()
+			}
+			p.abandoned.Add(1)
+		default:
+			return p.Report()
+		}
+	}
+}
+
+func (p *ClickWorkerPool) Report() DrainReport {
+	return DrainReport{
+		Persisted: p.persisted.Load(),
+		Failed:    p.failed.Load(),
+		Abandoned: p.abandoned.Load(),
+		OverQuota: p.overQuota.Load(),
+		Rejected:  p.rejected.Load(),
+	}
+}
+
+// run accumule les clics reçus et les enregistre par lots.
+// Le lot en cours est écrit avant de rendre la main lorsque le channel est fermé.
+func (p *ClickWorkerPool) run(ctx context.Context) {
+	pending := make([]*models.Click, 0, p.batch.Size)
+	events := make([]models.ClickEvent, 0, p.batch.Size)
+	deadline := time.NewTimer(p.batch.MaxLatency)
+	deadline.Stop()
+
+	flush := func() {
+		deadline.Stop()
+		if len(pending) == 0 {
+			return
+		}
+		clicks := pending
+		if p.meter != nil {
+			clicks = p.meter.MeterClicks(pending)
+			p.overQuota.Add(int64(len(pending) - len(clicks)))
+		}
+		var saved, rejected map[*models.Click]bool
+		if len(clicks) > 0 {
+			saved, rejected = p.save(clicks)
+		}
+		if p.meter != nil && len(saved) > 0 {
+			p.meter.RecordClicks(savedClicks(clicks, saved))
+		}
+		if p.acknowledger != nil {
+			p.acknowledger.Ack(processedEvents(pending, events, clicks, saved, rejected))
+		}
+		pending = make([]*models.Click, 0, p.batch.Size)
+		events = make([]models.ClickEvent, 0, p.batch.Size)
+	}
+
+	for {
+		if ctx.Err() != nil {
+			p.abandoned.Add(int64(len(pending)))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+		case event, ok := <-p.clickEventsChan:
+			if !ok {
+				flush()
+				return
+			}
+			pending = append(pending, newClick(event, p.enrichers))
+			events = append(events, event)
+			if len(pending) >= p.batch.Size {
+				flush()
+			} else if len(pending) == 1 {
+				deadline.Reset(p.batch.MaxLatency)
+			}
+		case <-deadline.C:
+			flush()
+		}
 	}
 }
 
-func clickWorker(clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository) {
-	for event := range clickEventsChan { 
-		click := &models.Click{
-			LinkID:    event.LinkID,
-			Timestamp: event.Timestamp,
-			UserAgent: event.UserAgent,
-			IPAddress: event.IPAddress,
+// save enregistre un lot et retourne les clics enregistrés et ceux refusés définitivement ;
+// si l'INSERT groupé échoue, les clics sont réessayés un par un pour ne perdre que ceux
+// réellement invalides.
+func (p *ClickWorkerPool) save(clicks []*models.Click) (saved, rejected map[*models.Click]bool) {
+	saved = make(map[*models.Click]bool, len(clicks))
+	err := p.clickRepo.CreateClicks(clicks)
+	if err == nil {
+		p.persisted.Add(int64(len(clicks)))
+		for _, click := range clicks {
+			saved[click] = true
 		}
+		return saved, nil
+	}
+	log.Printf("Warning: Failed to save batch of %d clicks, retrying one by one: %v", len(clicks), err)
+
+	for _, click := range clicks {
+		if err := p.clickRepo.CreateClick(click); err != nil {
+			if permanentError(err) {
+				if rejected == nil {
+					rejected = make(map[*models.Click]bool)
+				}
+				rejected[click] = true
+				p.rejected.Add(1)
+				log.Printf("ERROR: Dropping click for LinkID %d, it can never be saved: %v", click.LinkID, err)
+				continue
+			}
+			p.failed.Add(1)
+			log.Printf("ERROR: Failed to save click for LinkID %d: %v", click.LinkID, err)
+			continue
+		}
+		p.persisted.Add(1)
+		saved[click] = true
+	}
+	return saved, rejected
+}
+
+// permanentError indique si l'enregistrement d'un clic échouera quelle que soit la tentative :
+// violation de contrainte (lien supprimé entre-temps...) ou donnée refusée par la base.
+// Les autres erreurs (base indisponible, délai dépassé...) sont supposées passagères.
+func permanentError(err error) bool {
+	switch {
+	case errors.Is(err, gorm.ErrForeignKeyViolated),
+		errors.Is(err, gorm.ErrCheckConstraintViolated),
+		errors.Is(err, gorm.ErrDuplicatedKey),
+		errors.Is(err, gorm.ErrInvalidData),
+		errors.Is(err, gorm.ErrInvalidValue):
+		return true
+	}
+	// Les erreurs PostgreSQL non traduites par GORM exposent leur SQLSTATE : les classes 22
+	// (valeur trop longue, encodage invalide...) et 23 (contraintes) ne se corrigent pas seules.
+	var sqlErr interface{ SQLState() string }
+	if errors.As(err, &sqlErr) {
+		code := sqlErr.SQLState()
+		return strings.HasPrefix(code, "22") || strings.HasPrefix(code, "23")
+	}
+	return false
+}
 
-		err := clickRepo.CreateClick(click)
-		if err != nil {
-			log.Printf("ERROR: Failed to save click for LinkID %d (UserAgent: %s, IP: %s): %v",
-				event.LinkID, event.UserAgent, event.IPAddress, err)
+// savedClicks retourne, dans l'ordre du lot, les clics enregistrés par save.
+func savedClicks(clicks []*models.Click, saved map[*models.Click]bool) []*models.Click {
+	if len(saved) == len(clicks) {
+		return clicks
+	}
+	result := make([]*models.Click, 0, len(saved))
+	for _, click := range clicks {
+		if saved[click] {
+			result = append(result, click)
+		}
+	}
+	return result
+}
 
-		} else {
-			log.Printf("Click recorded successfully for LinkID %d", event.LinkID)
+// processedEvents retourne les événements à acquitter : ceux dont le clic a été enregistré
+// ou refusé définitivement, et ceux que le ClickMeter a écartés. pending et events sont
+// alignés ; kept est le sous-ensemble de pending retenu par le ClickMeter.
+func processedEvents(pending []*models.Click, events []models.ClickEvent, kept []*models.Click, saved, rejected map[*models.Click]bool) []models.ClickEvent {
+	retained := make(map[*models.Click]bool, len(kept))
+	for _, click := range kept {
+		retained[click] = true
+	}
+	processed := make([]models.ClickEvent, 0, len(events))
+	for i, click := range pending {
+		if saved[click] || rejected[click] || !retained[click] {
+			processed = append(processed, events[i])
 		}
 	}
+	return processed
+}
+
+func newClick(event models.ClickEvent, enrichers []ClickEnricher) *models.Click {
+	agent := useragent.Parse(event.UserAgent)
+	click := &models.Click{
+		LinkID:         event.LinkID,
+		Timestamp:      event.Timestamp,
+		UserAgent:      truncate(event.UserAgent, 255),
+		IPAddress:      event.IPAddress,
+		Referrer:       truncate(event.Referrer, 2048),
+		ReferrerDomain: truncate(models.ReferrerDomain(event.Referrer), 255),
+		Browser:        agent.Browser,
+		OS:             agent.OS,
+		DeviceClass:    agent.Device,
+		IsBot:          event.IsBot || agent.Device == useragent.DeviceBot,
+	}
+	if click.IsBot {
+		click.DeviceClass = useragent.DeviceBot
+	}
+
+	for _, enricher := range enrichers {
+		enricher.Enrich(click)
+	}
+	return click
+}
+
+// truncate ramène value à la taille de sa colonne, maxLength octets, sans couper un caractère
+// en deux : PostgreSQL refuserait le clic pour une valeur trop longue ou un UTF-8 invalide.
+func truncate(value string, maxLength int) string {
+	value = strings.ToValidUTF8(value, "")
+	if len(value) <= maxLength {
+		return value
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
 }
@@ -0,0 +1,405 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Edofo/bitly-clone/internal/models"
+	"github.com/Edofo/bitly-clone/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordingClickRepository mémorise les lots reçus ; failBatches simule l'échec des INSERT groupés
+// et block retient les INSERT jusqu'à sa fermeture.
+type recordingClickRepository struct {
+	repository.ClickRepository
+
+	mu          sync.Mutex
+	batches     [][]*models.Click
+	singles     []*models.Click
+	failBatches bool
+	block       chan struct{}
+	flushed     chan int
+}
+
+func newRecordingClickRepository() *recordingClickRepository {
+	return &recordingClickRepository{flushed: make(chan int, 100)}
+}
+
+func (r *recordingClickRepository) CreateClicks(clicks []*models.Click) error {
+	if r.failBatches {
+		return errors.New("batch insert failed")
+	}
+	if r.block != nil {
+		<-r.block
+	}
+	r.mu.Lock()
+	r.batches = append(r.batches, clicks)
+	r.mu.Unlock()
+	r.flushed <- len(clicks)
+	return nil
+}
+
+func (r *recordingClickRepository) CreateClick(click *models.Click) error {
+	r.mu.Lock()
+	r.singles = append(r.singles, click)
+	r.mu.Unlock()
+	r.flushed <- 1
+	return nil
+}
+
+func waitFlush(t *testing.T, repo *recordingClickRepository) int {
+	select {
+	case n := <-repo.flushed:
+		return n
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for click flush")
+		return 0
+	}
+}
+
+func startPool(repo repository.ClickRepository, opts BatchOptions) (*ClickWorkerPool, chan models.ClickEvent) {
+	events := make(chan models.ClickEvent, 10)
+	pool := NewClickWorkerPool(1, opts, events, repo)
+	pool.Start(context.Background())
+	return pool, events
+}
+
+func TestClickWorkerPool_FlushesWhenBatchIsFull(t *testing.T) {
+	repo := newRecordingClickRepository()
+	pool, events := startPool(repo, BatchOptions{Size: 3, MaxLatency: time.Hour})
+	defer pool.Stop()
+
+	for i := 0; i < 3; i++ {
+		events <- models.ClickEvent{LinkID: 1, Timestamp: time.Now()}
+	}
+
+	assert.Equal(t, 3, waitFlush(t, repo))
+}
+
+func TestClickWorkerPool_FlushesAfterMaxLatency(t *testing.T) {
+	repo := newRecordingClickRepository()
+	pool, events := startPool(repo, BatchOptions{Size: 100, MaxLatency: 20 * time.Millisecond})
+	defer pool.Stop()
+
+	start := time.Now()
+	events <- models.ClickEvent{LinkID: 1, Timestamp: time.Now()}
+
+	assert.Equal(t, 1, waitFlush(t, repo))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestClickWorkerPool_DrainFlushesPendingClicks(t *testing.T) {
+	repo := newRecordingClickRepository()
+	pool, events := startPool(repo, BatchOptions{Size: 100, MaxLatency: time.Hour})
+
+	events <- models.ClickEvent{LinkID: 1, Timestamp: time.Now()}
+	events <- models.ClickEvent{LinkID: 2, Timestamp: time.Now()}
+	close(events)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	report := pool.Drain(ctx)
+
+	assert.Equal(t, DrainReport{Persisted: 2}, report)
+	require.Len(t, repo.batches, 1)
+	assert.Len(t, repo.batches[0], 2)
+}
+
+func TestClickWorkerPool_DrainAbandonsAfterDeadline(t *testing.T) {
+	repo := newRecordingClickRepository()
+	repo.block = make(chan struct{})
+	pool, events := startPool(repo, BatchOptions{Size: 1, MaxLatency: time.Hour})
+
+	// Le premier lot reste bloqué en base, les suivants attendent dans le channel
+	for i := 0; i < 4; i++ {
+		events <- models.ClickEvent{LinkID: 1, Timestamp: time.Now()}
+	}
+	close(events)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	go func() {
+		// Débloque la base une fois le délai dépassé et les workers interrompus
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		close(repo.block)
+	}()
+	report := pool.Drain(ctx)
+
+	assert.Equal(t, int64(1), report.Persisted)
+	assert.Equal(t, int64(3), report.Abandoned)
+}
+
+func TestClickWorkerPool_StopWithoutClosingChannel(t *testing.T) {
+	repo := newRecordingClickRepository()
+	pool, events := startPool(repo, BatchOptions{Size: 100, MaxLatency: time.Hour})
+
+	events <- models.ClickEvent{LinkID: 1, Timestamp: time.Now()}
+	pool.Stop()
+	pool.Wait()
+	events <- models.ClickEvent{LinkID: 2, Timestamp: time.Now()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	report := pool.Drain(ctx)
+
+	assert.Equal(t, int64(0), report.Persisted)
+	assert.Equal(t, int64(2), report.Abandoned)
+}
+
+func TestClickWorkerPool_FallsBackToSingleInserts(t *testing.T) {
+	repo := newRecordingClickRepository()
+	repo.failBatches = true
+	pool, events := startPool(repo, BatchOptions{Size: 2, MaxLatency: time.Hour})
+
+	events <- models.ClickEvent{LinkID: 1, Timestamp: time.Now()}
+	events <- models.ClickEvent{LinkID: 2, Timestamp: time.Now()}
+	close(events)
+
+	report := pool.Drain(context.Background())
+
+	assert.Equal(t, int64(2), report.Persisted)
+	assert.Empty(t, repo.batches)
+	assert.Len(t, repo.singles, 2)
+}
+
+func setupBenchmarkDB(b *testing.B) (*gorm.DB, uint) {
+	path := filepath.Join(b.TempDir(), "bench.db")
+	db, err := gorm.Open(sqlite.Open(path+"?_busy_timeout=5000&_journal_mode=WAL"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(b, err)
+	require.NoError(b, db.AutoMigrate(&models.Link{}, &models.Click{}))
+
+	link := &models.Link{ShortCode: "bench", LongURL: "https://www.example.com"}
+	require.NoError(b, db.Create(link).Error)
+	return db, link.ID
+}
+
+// BenchmarkClickWorkers compare l'enregistrement clic par clic (batch=1, comportement
+// historique) aux INSERT groupés, avec 5 workers sur une base SQLite fichier.
+func BenchmarkClickWorkers(b *testing.B) {
+	for _, size := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			db, linkID := setupBenchmarkDB(b)
+			repo := repository.NewClickRepository(db)
+			events := make(chan models.ClickEvent, 1000)
+			pool := NewClickWorkerPool(5, BatchOptions{Size: size, MaxLatency: 50 * time.Millisecond}, events, repo)
+			pool.Start(context.Background())
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				events <- models.ClickEvent{
+					LinkID:    linkID,
+					Timestamp: time.Now().UTC(),
+					UserAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+					IPAddress: "192.168.1.1",
+				}
+			}
+			close(events)
+			pool.Wait()
+			b.StopTimer()
+
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "clicks/s")
+		})
+	}
+}
+
+// quotaMeter laisse passer les clics tant que moins de limit clics ont été décomptés.
+type quotaMeter struct {
+	limit    int
+	recorded int
+}
+
+func (m *quotaMeter) MeterClicks(clicks []*models.Click) []*models.Click {
+	if left := m.limit - m.recorded; len(clicks) > left {
+		clicks = clicks[:max(left, 0)]
+	}
+	return clicks
+}
+
+func (m *quotaMeter) RecordClicks(clicks []*models.Click) {
+	m.recorded += len(clicks)
+}
+
+type countingAcknowledger struct {
+	acked atomic.Int64
+}
+
+func (a *countingAcknowledger) Ack(events []models.ClickEvent) {
+	a.acked.Add(int64(len(events)))
+}
+
+func TestClickWorkerPool_MetersClicks(t *testing.T) {
+	repo := newRecordingClickRepository()
+	acknowledger := &countingAcknowledger{}
+	events := make(chan models.ClickEvent, 10)
+	pool := NewClickWorkerPool(1, BatchOptions{Size: 2, MaxLatency: time.Hour}, events, repo)
+	pool.SetMeter(&quotaMeter{limit: 3})
+	pool.SetAcknowledger(acknowledger)
+	pool.Start(context.Background())
+
+	// Le second lot est en partie, le troisième entièrement hors quota
+	for i := 0; i < 6; i++ {
+		events <- models.ClickEvent{LinkID: 1, Timestamp: time.Now()}
+	}
+	close(events)
+
+	report := pool.Drain(context.Background())
+
+	assert.Equal(t, DrainReport{Persisted: 3, OverQuota: 3}, report)
+	require.Len(t, repo.batches, 2)
+	assert.Len(t, repo.batches[1], 1)
+	assert.Equal(t, int64(6), acknowledger.acked.Load(), "les clics écartés sont tout de même acquittés")
+}
+
+func TestClickWorkerPool_MetersOnlySavedClicks(t *testing.T) {
+	meter := &quotaMeter{limit: 10}
+	events := make(chan models.ClickEvent, 10)
+	pool := NewClickWorkerPool(1, BatchOptions{Size: 3, MaxLatency: time.Hour}, events, &outageClickRepository{failLinkID: 2})
+	pool.SetMeter(meter)
+	pool.Start(context.Background())
+
+	for _, linkID := range []uint{1, 2, 1} {
+		events <- models.ClickEvent{LinkID: linkID, Timestamp: time.Now()}
+	}
+	close(events)
+	pool.Drain(context.Background())
+
+	assert.Equal(t, 2, meter.recorded, "le clic non enregistré n'est pas décompté")
+}
+
+// outageClickRepository simule une base indisponible pour les clics d'un lien,
+// ou qui les refuse avec err si elle est renseignée.
+type outageClickRepository struct {
+	repository.ClickRepository
+	failLinkID uint
+	err        error
+}
+
+func (r *outageClickRepository) CreateClicks(clicks []*models.Click) error {
+	return errors.New("database is unavailable")
+}
+
+func (r *outageClickRepository) CreateClick(click *models.Click) error {
+	if click.LinkID == r.failLinkID {
+		if r.err != nil {
+			return r.err
+		}
+		return errors.New("database is unavailable")
+	}
+	return nil
+}
+
+type recordingAcknowledger struct {
+	mu     sync.Mutex
+	events []models.ClickEvent
+}
+
+func (a *recordingAcknowledger) Ack(events []models.ClickEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, events...)
+}
+
+func TestClickWorkerPool_AcknowledgesOnlySavedClicks(t *testing.T) {
+	acknowledger := &recordingAcknowledger{}
+	events := make(chan models.ClickEvent, 10)
+	pool := NewClickWorkerPool(1, BatchOptions{Size: 3, MaxLatency: time.Hour}, events, &outageClickRepository{failLinkID: 2})
+	pool.SetAcknowledger(acknowledger)
+	pool.Start(context.Background())
+
+	for _, linkID := range []uint{1, 2, 1} {
+		events <- models.ClickEvent{LinkID: linkID, Timestamp: time.Now(), SpoolSegment: 1, SpoolOffset: int64(linkID)}
+	}
+	close(events)
+
+	report := pool.Drain(context.Background())
+
+	assert.Equal(t, DrainReport{Persisted: 2, Failed: 1}, report)
+	require.Len(t, acknowledger.events, 2, "le clic non enregistré reste à rejouer")
+	for _, event := range acknowledger.events {
+		assert.Equal(t, uint(1), event.LinkID)
+	}
+}
+
+// pgError imite les erreurs PostgreSQL non traduites par GORM, qui exposent leur SQLSTATE.
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string    { return "ERROR (SQLSTATE " + e.code + ")" }
+func (e *pgError) SQLState() string { return e.code }
+
+func TestClickWorkerPool_AcknowledgesRejectedClicks(t *testing.T) {
+	for name, err := range map[string]error{
+		"deleted link":   fmt.Errorf("insert click: %w", gorm.ErrForeignKeyViolated),
+		"value too long": &pgError{code: "22001"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			acknowledger := &recordingAcknowledger{}
+			events := make(chan models.ClickEvent, 10)
+			pool := NewClickWorkerPool(1, BatchOptions{Size: 3, MaxLatency: time.Hour}, events, &outageClickRepository{failLinkID: 2, err: err})
+			pool.SetAcknowledger(acknowledger)
+			pool.Start(context.Background())
+
+			for _, linkID := range []uint{1, 2, 1} {
+				events <- models.ClickEvent{LinkID: linkID, Timestamp: time.Now()}
+			}
+			close(events)
+
+			report := pool.Drain(context.Background())
+
+			assert.Equal(t, DrainReport{Persisted: 2, Rejected: 1}, report)
+			assert.Len(t, acknowledger.events, 3, "le clic refusé ne reste pas dans le spool")
+		})
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	assert.True(t, permanentError(gorm.ErrForeignKeyViolated))
+	assert.True(t, permanentError(&pgError{code: "22021"}))
+	assert.True(t, permanentError(fmt.Errorf("wrapped: %w", &pgError{code: "23503"})))
+
+	assert.False(t, permanentError(errors.New("database is unavailable")))
+	assert.False(t, permanentError(&pgError{code: "57P01"}), "arrêt du serveur")
+	assert.False(t, permanentError(context.DeadlineExceeded))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "abcde", truncate("abcdefgh", 5))
+	// "é" occupe deux octets : il n'est pas coupé en deux
+	assert.Equal(t, "abcd", truncate("abcdé", 5))
+	assert.Equal(t, "ab", truncate("ab\xffc\xfe", 2))
+	assert.Equal(t, "abc", truncate("a\xffbc", 5))
+}
+
+func TestNewClick_TruncatesLongFields(t *testing.T) {
+	referrer := "https://" + strings.Repeat("é", 200) + ".example.com/" + strings.Repeat("p", 3000)
+	click := newClick(models.ClickEvent{
+		LinkID:    1,
+		UserAgent: "Mozilla/5.0 " + strings.Repeat("ü", 300),
+		Referrer:  referrer,
+	}, nil)
+
+	assert.LessOrEqual(t, len(click.UserAgent), 255)
+	assert.True(t, utf8.ValidString(click.UserAgent))
+	assert.LessOrEqual(t, len(click.Referrer), 2048)
+	assert.True(t, utf8.ValidString(click.Referrer))
+	assert.LessOrEqual(t, len(click.ReferrerDomain), 255)
+	assert.True(t, utf8.ValidString(click.ReferrerDomain))
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	// Base des fuseaux horaires embarquée pour les statistiques (--tz, ?tz=) sur les systèmes sans tzdata.
+	_ "time/tzdata"
+
 	"github.com/Edofo/bitly-clone/cmd"
 	_ "github.com/Edofo/bitly-clone/cmd/cli"
 	_ "github.com/Edofo/bitly-clone/cmd/server"